@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"crypto/x509"
 	"fmt"
 	"log"
+	"os"
 	"path"
 
 	"github.com/c-bata/go-prompt"
@@ -12,7 +14,10 @@ import (
 
 	"github.com/shreyner/gophkeeper/internal/client/command"
 	"github.com/shreyner/gophkeeper/internal/client/config"
+	"github.com/shreyner/gophkeeper/internal/client/pkg/clipboard"
+	"github.com/shreyner/gophkeeper/internal/client/pkg/dirlock"
 	"github.com/shreyner/gophkeeper/internal/client/pkg/promptcmd"
+	"github.com/shreyner/gophkeeper/internal/client/pkg/transfer"
 	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultclient"
 	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultcrypt"
 	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultsync"
@@ -42,8 +47,16 @@ func main() {
 		return
 	}
 
+	revokeWatcher := vaultclient.NewRevokeWatcher()
+	tokenRefresher := vaultclient.NewTokenRefresher()
+
 	creds := credentials.NewClientTLSFromCert(certPool, cfg.ServerName)
-	conn, err := grpc.Dial(cfg.HostGRPC, grpc.WithTransportCredentials(creds))
+	conn, err := grpc.Dial(
+		cfg.HostGRPC,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithChainUnaryInterceptor(revokeWatcher.UnaryInterceptor(), tokenRefresher.UnaryInterceptor()),
+		grpc.WithChainStreamInterceptor(revokeWatcher.StreamInterceptor(), tokenRefresher.StreamInterceptor()),
+	)
 
 	if err != nil {
 		log.Fatal(err)
@@ -57,36 +70,54 @@ func main() {
 	appState := state.New()
 
 	vclient := vaultclient.New(cfg, appState, gophKeeperClient)
+	tokenRefresher.SetRefresh(vclient.Refresh)
 	vcrypt := vaultcrypt.New()
 
-	loginVaultStorage := storage.NewLoginVaultStorage(vcrypt)
-	fileVaultStorage := storage.NewFileVaultStorage(vcrypt, vclient)
+	err = os.MkdirAll(cfg.DataFolder, 0700)
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
 
-	err = loginVaultStorage.LoadFromLocalFile(path.Join(cfg.DataFolder, "site-login.db"))
+	dataLock, err := dirlock.Acquire(cfg.DataFolder)
 	if err != nil {
 		log.Fatal(err)
 		return
 	}
 	defer func() {
-		err = loginVaultStorage.SaveToFile(path.Join(cfg.DataFolder, "site-login.db"))
-		if err != nil {
-			log.Println("error saved data to file", err)
-			return
-		}
+		_ = dataLock.Release()
 	}()
-	err = fileVaultStorage.LoadFromLocalFile(path.Join(cfg.DataFolder, "file.db"))
+
+	backend, err := storage.OpenBoltBackend(path.Join(cfg.DataFolder, "vault.bolt"))
 	if err != nil {
 		log.Fatal(err)
 		return
 	}
 	defer func() {
-		err = fileVaultStorage.SaveToFile(path.Join(cfg.DataFolder, "file.db"))
+		err = backend.Close()
 		if err != nil {
-			log.Println("error saved data to file", err)
+			log.Println("error closed storage", err)
 			return
 		}
 	}()
 
+	loginVaultStorage := storage.NewLoginVaultStorage(vcrypt, backend)
+	fileVaultStorage := storage.NewFileVaultStorage(vcrypt, vclient, backend)
+
+	migratedFiles := storage.NewMigratedFiles(cfg.DataFolder, "site-login.db", "file.db")
+
+	err = loginVaultStorage.MigrateFromGobFile(path.Join(cfg.DataFolder, "site-login.db"))
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+
+	err = fileVaultStorage.MigrateFromGobFile(path.Join(cfg.DataFolder, "file.db"))
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+
 	vsync := vaultsync.New(
 		vcrypt,
 		vclient,
@@ -96,10 +127,41 @@ func main() {
 		},
 	)
 
+	err = vsync.LoadFromLocalFile(path.Join(cfg.DataFolder, "sync.db"))
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+	defer func() {
+		err = vsync.SaveToFile(path.Join(cfg.DataFolder, "sync.db"))
+		if err != nil {
+			log.Println("error saved data to file", err)
+			return
+		}
+	}()
+
+	clip := clipboard.New(os.Stdout, cfg.ClipboardClearDelay)
+	defer func() {
+		_ = clip.Clear()
+	}()
+
+	lockCommand := command.NewLockCommand(appState, vcrypt, clip)
+	deviceCommand := command.NewDeviceCommand(vclient, vcrypt, vsync, appState, backend, migratedFiles)
+	revokeWatcher.OnRevoked(deviceCommand.WipeLocalData)
+
+	transfers := transfer.NewManager(cfg.TransferParallel, func(info transfer.Info) {
+		fmt.Printf("\nTransfer is finished: %v\n", info)
+	})
+	defer transfers.Close()
+
 	commands := command.NewCommands(
 		vclient,
 		vcrypt,
 		vsync,
+		lockCommand,
+		deviceCommand,
+		clip,
+		transfers,
 		loginVaultStorage,
 		fileVaultStorage,
 	)
@@ -113,12 +175,23 @@ func main() {
 		appState,
 		commands,
 	)
+	promptcmd.SetIdleLock(cfg.IdleLockTimeout, lockCommand.Lock)
+	promptcmd.SetStatus(func() string {
+		return vsync.Status().String()
+	})
+
+	ctxSync, cancelSync := context.WithCancel(context.Background())
+	defer cancelSync()
+
+	go vsync.Watch(ctxSync)
+	go vsync.AutoSync(ctxSync, cfg.AutoSyncInterval, cfg.AutoSyncDebounce)
 
 	t := prompt.New(
 		promptcmd.Executor,
 		promptcmd.Completer,
 		prompt.OptionTitle("Gophkeeper"),
 		prompt.OptionPrefix("> "),
+		prompt.OptionLivePrefix(promptcmd.LivePrefix),
 		prompt.OptionSetExitCheckerOnInput(promptcmd.ExitChecker),
 	)
 
@@ -0,0 +1,81 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/shreyner/gophkeeper/internal/client/pkg/accountexport"
+	"github.com/shreyner/gophkeeper/internal/client/pkg/atomicfile"
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultclient"
+)
+
+type AccountCommand struct {
+	vclient       *vaultclient.Client
+	deviceCommand *DeviceCommand
+}
+
+func NewAccountCommand(vclient *vaultclient.Client, deviceCommand *DeviceCommand) *AccountCommand {
+	command := AccountCommand{
+		vclient:       vclient,
+		deviceCommand: deviceCommand,
+	}
+
+	return &command
+}
+
+// RunExport saves encrypted vaults and references of uploaded files to zip archive: account-export <file>
+func (c *AccountCommand) RunExport(ctx context.Context, args []string) {
+	if len(args) < 1 {
+		fmt.Println("incorrect file path")
+		return
+	}
+
+	err := atomicfile.WriteFile(args[0], 0600, func(w io.Writer) error {
+		exportWriter := accountexport.NewWriter(w, time.Now().UTC())
+
+		if err := c.vclient.AccountExport(ctx, exportWriter.WritePage); err != nil {
+			return err
+		}
+
+		return exportWriter.Close()
+	})
+
+	if err != nil {
+		fmt.Println("Error export account: ", err)
+		return
+	}
+
+	fmt.Println("Account is exported to", args[0])
+}
+
+// RunDelete removes account on server and local data: account-delete <password> [two-factor code]
+func (c *AccountCommand) RunDelete(ctx context.Context, args []string) {
+	if len(args) < 1 {
+		fmt.Println("incorrect password")
+		return
+	}
+
+	var code string
+	if len(args) > 1 {
+		code = args[1]
+	}
+
+	err := c.vclient.AccountDelete(ctx, args[0], code)
+
+	if errors.Is(err, vaultclient.ErrSecondFactorRequired) {
+		fmt.Println("Two-factor authentication is enabled: account-delete <password> <code>")
+		return
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	c.deviceCommand.wipeLocalData()
+
+	fmt.Println("Account is deleted, local data is wiped")
+}
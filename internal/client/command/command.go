@@ -1,7 +1,11 @@
 package command
 
 import (
+	"context"
+
+	"github.com/shreyner/gophkeeper/internal/client/pkg/clipboard"
 	"github.com/shreyner/gophkeeper/internal/client/pkg/promptcmd"
+	"github.com/shreyner/gophkeeper/internal/client/pkg/transfer"
 	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultclient"
 	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultcrypt"
 	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultsync"
@@ -12,27 +16,124 @@ func NewCommands(
 	vclient *vaultclient.Client,
 	vaultCrypt *vaultcrypt.VaultCrypt,
 	vsync *vaultsync.VaultSync,
+	lockCommand *LockCommand,
+	deviceCommand *DeviceCommand,
+	clip *clipboard.Clipboard,
+	transfers *transfer.Manager,
 	siteLoginStorage *storage.LoginVaultStorage,
 	fileStorage *storage.FileVaultStorage,
 ) []promptcmd.Command {
 	loginCommand := NewLoginCommand(vclient, vaultCrypt, vsync)
 	siteLoginCommand := NewSiteLoginCommand(vclient, vaultCrypt, siteLoginStorage)
 	syncCommand := NewSyncCommand(vsync)
-	fileCommand := NewFileCommand(vclient, vaultCrypt, fileStorage)
+	conflictCommand := NewConflictCommand(vsync)
+	fileCommand := NewFileCommand(vclient, vaultCrypt, vsync, transfers, fileStorage)
+	transferCommand := NewTransferCommand(transfers)
+	usageCommand := NewUsageCommand(vclient)
+	totpCommand := NewTOTPCommand(vclient)
+	copyCommand := NewCopyCommand(clip, siteLoginStorage, fileStorage)
+	accountCommand := NewAccountCommand(vclient, deviceCommand)
 
 	return []promptcmd.Command{
 		{
 			Command:     "login",
-			Description: "Authenticate or registration user",
+			Description: "Authenticate user: <login> <password> [two-factor code]",
 			Auth:        promptcmd.CommandAuthNot,
 			Run:         loginCommand.Run,
 		},
+		{
+			Command:     "login-code",
+			Description: "Finish login by two-factor code or backup code",
+			Auth:        promptcmd.CommandAuthNot,
+			Run:         loginCommand.RunLoginCode,
+		},
+		{
+			Command:     "register",
+			Description: "Create account: <login> <password> <password again> [invite code]",
+			Auth:        promptcmd.CommandAuthNot,
+			Run:         loginCommand.RunRegister,
+		},
+		{
+			Command:     "invite-create",
+			Description: "Create invite code for registration, admin only",
+			Auth:        promptcmd.CommandAuthNeed,
+			Run:         loginCommand.RunInviteCreate,
+		},
 		{
 			Command:     "check",
 			Description: "Authenticate user",
 			Auth:        promptcmd.CommandAuthNeed,
 			Run:         loginCommand.RunCheck,
 		},
+		{
+			Command:     "lock",
+			Description: "Wipe vault key from memory",
+			Auth:        promptcmd.CommandAuthNeed,
+			Run:         lockCommand.Run,
+		},
+		{
+			Command:     "unlock",
+			Description: "Unlock vault by master password",
+			Auth:        promptcmd.CommandAuthLocked,
+			Run:         lockCommand.RunUnlock,
+		},
+
+		// Two-factor authentication
+
+		{
+			Command:     "2fa-setup",
+			Description: "Show QR code of new two-factor secret",
+			Auth:        promptcmd.CommandAuthNeed,
+			Run:         totpCommand.RunSetup,
+		},
+		{
+			Command:     "2fa-enable",
+			Description: "Enable two-factor authentication by code from app, backup codes are shown once",
+			Auth:        promptcmd.CommandAuthNeed,
+			Run:         totpCommand.RunEnable,
+		},
+		{
+			Command:     "2fa-disable",
+			Description: "Disable two-factor authentication by code from app or backup code",
+			Auth:        promptcmd.CommandAuthNeed,
+			Run:         totpCommand.RunDisable,
+		},
+
+		// Devices
+
+		{
+			Command:     "devices",
+			Description: "Show devices of account",
+			Auth:        promptcmd.CommandAuthNeed,
+			Run:         deviceCommand.RunList,
+		},
+		{
+			Command:     "device-rename",
+			Description: "Rename device by ID: <id> <name>",
+			Auth:        promptcmd.CommandAuthNeed,
+			Run:         deviceCommand.RunRename,
+		},
+		{
+			Command:     "device-revoke",
+			Description: "Revoke device by ID, its local data is wiped on next request",
+			Auth:        promptcmd.CommandAuthNeed,
+			Run:         deviceCommand.RunRevoke,
+		},
+
+		// Account
+
+		{
+			Command:     "account-export",
+			Description: "Save encrypted vaults and references of files to zip archive: <file>",
+			Auth:        promptcmd.CommandAuthNeed,
+			Run:         accountCommand.RunExport,
+		},
+		{
+			Command:     "account-delete",
+			Description: "Delete account with all data on server, can't be undone: <password> [two-factor code]",
+			Auth:        promptcmd.CommandAuthNeed,
+			Run:         accountCommand.RunDelete,
+		},
 
 		// Vault Site Login
 
@@ -44,7 +145,7 @@ func NewCommands(
 		},
 		{
 			Command:     "site-login-view",
-			Description: "Show login password by ID",
+			Description: "Show login password by ID, secrets are masked without --reveal",
 			Auth:        promptcmd.CommandAuthNeed,
 			Run:         siteLoginCommand.RunViewLogin,
 		},
@@ -52,19 +153,19 @@ func NewCommands(
 			Command:     "site-login-create",
 			Description: "Create login password",
 			Auth:        promptcmd.CommandAuthNeed,
-			Run:         siteLoginCommand.RunCreate,
+			Run:         withSyncRequest(vsync, siteLoginCommand.RunCreate),
 		},
 		{
 			Command:     "site-login-delete",
 			Description: "Create by ID",
 			Auth:        promptcmd.CommandAuthNeed,
-			Run:         siteLoginCommand.RunDelete,
+			Run:         withSyncRequest(vsync, siteLoginCommand.RunDelete),
 		},
 		{
 			Command:     "site-login-update",
 			Description: "Create update id login password",
 			Auth:        promptcmd.CommandAuthNeed,
-			Run:         siteLoginCommand.RunUpdate,
+			Run:         withSyncRequest(vsync, siteLoginCommand.RunUpdate),
 		},
 
 		{
@@ -75,13 +176,13 @@ func NewCommands(
 		},
 		{
 			Command:     "file-upload",
-			Description: "Encrypted and upload file to vault",
+			Description: "Encrypted and upload file to vault in background",
 			Auth:        promptcmd.CommandAuthNeed,
 			Run:         fileCommand.RunUpload,
 		},
 		{
 			Command:     "file-download",
-			Description: "Download file by ID",
+			Description: "Download file by ID to folder in background: file-download <id> <folder>",
 			Auth:        promptcmd.CommandAuthNeed,
 			Run:         fileCommand.RunDownload,
 		},
@@ -89,7 +190,34 @@ func NewCommands(
 			Command:     "file-delete",
 			Description: "Delete file by ID",
 			Auth:        promptcmd.CommandAuthNeed,
-			Run:         fileCommand.RunDelete,
+			Run:         withSyncRequest(vsync, fileCommand.RunDelete),
+		},
+
+		{
+			Command:     "transfers",
+			Description: "Show uploads and downloads with progress",
+			Auth:        promptcmd.CommandAuthNeed,
+			Run:         transferCommand.RunList,
+		},
+		{
+			Command:     "transfer-cancel",
+			Description: "Cancel upload or download by transfer ID",
+			Auth:        promptcmd.CommandAuthNeed,
+			Run:         transferCommand.RunCancel,
+		},
+
+		{
+			Command:     "usage",
+			Description: "Show used storage and items with quotas",
+			Auth:        promptcmd.CommandAuthNeed,
+			Run:         usageCommand.Run,
+		},
+
+		{
+			Command:     "copy",
+			Description: "Copy field to clipboard: copy <kind> <id> [field]",
+			Auth:        promptcmd.CommandAuthNeed,
+			Run:         copyCommand.Run,
 		},
 
 		{
@@ -98,6 +226,27 @@ func NewCommands(
 			Auth:        promptcmd.CommandAuthNeed,
 			Run:         syncCommand.Run,
 		},
+		{
+			Command:     "conflicts",
+			Description: "Show sync conflicts with field diff, secrets are masked without --reveal",
+			Auth:        promptcmd.CommandAuthNeed,
+			Run:         conflictCommand.RunList,
+		},
+		{
+			Command:     "resolve",
+			Description: "Resolve conflict: resolve <vault-id> local|remote|both|merge [field=local|remote]",
+			Auth:        promptcmd.CommandAuthNeed,
+			Run:         withSyncRequest(vsync, conflictCommand.RunResolve),
+		},
 	}
 
 }
+
+// withSyncRequest asks background sync to upload local mutation made by run
+func withSyncRequest(vsync *vaultsync.VaultSync, run promptcmd.RunnerFunc) promptcmd.RunnerFunc {
+	return func(ctx context.Context, args []string) {
+		run(ctx, args)
+
+		vsync.RequestSync()
+	}
+}
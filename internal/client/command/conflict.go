@@ -0,0 +1,115 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultdata"
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultsync"
+)
+
+type ConflictCommand struct {
+	vsync *vaultsync.VaultSync
+}
+
+func NewConflictCommand(
+	vsync *vaultsync.VaultSync,
+) *ConflictCommand {
+	command := ConflictCommand{
+		vsync: vsync,
+	}
+
+	return &command
+}
+
+func (c *ConflictCommand) RunList(_ context.Context, args []string) {
+	conflicts, err := c.vsync.Conflicts()
+
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if len(conflicts) == 0 {
+		fmt.Println("No conflicts")
+		return
+	}
+
+	isReveal := hasFlag(args, revealFlag)
+
+	for _, conflict := range conflicts {
+		fmt.Printf(
+			"Kind: %v, ID: %v, VaultID: %v, LocalVersion: %v, RemoteVersion: %v\n",
+			conflict.Kind,
+			conflict.ID,
+			conflict.VaultID,
+			conflict.LocalVersion,
+			conflict.RemoteVersion,
+		)
+
+		if conflict.IsLocalDeleted {
+			fmt.Println("  local: deleted")
+		}
+
+		if conflict.IsRemoteDeleted {
+			fmt.Println("  remote: deleted")
+			continue
+		}
+
+		for _, field := range conflict.Fields {
+			if !field.IsChanged() {
+				continue
+			}
+
+			local, remote := field.Local, field.Remote
+
+			if field.IsSecret && !isReveal {
+				local, remote = maskSecret(local), maskSecret(remote)
+			}
+
+			fmt.Printf("  %v: %q -> %q\n", field.Name, local, remote)
+		}
+	}
+}
+
+// RunResolve resolve <vault-id> local|remote|both|merge [field=local|remote ...]
+func (c *ConflictCommand) RunResolve(_ context.Context, args []string) {
+	if len(args) < 2 {
+		fmt.Println("incorrect vault ID and strategy")
+		return
+	}
+
+	vaultID := args[0]
+
+	resolve := vaultdata.ConflictResolve{
+		Strategy: vaultdata.ResolveStrategy(args[1]),
+		Fields:   make(map[string]vaultdata.ResolveStrategy),
+	}
+
+	if !resolve.Strategy.IsValid() {
+		fmt.Println("incorrect strategy, expected local, remote, both or merge")
+		return
+	}
+
+	for _, arg := range args[2:] {
+		field, strategy, ok := strings.Cut(arg, "=")
+		fieldStrategy := vaultdata.ResolveStrategy(strategy)
+
+		if !ok || (fieldStrategy != vaultdata.ResolveLocal && fieldStrategy != vaultdata.ResolveRemote) {
+			fmt.Printf("incorrect field %q, expected field=local or field=remote\n", arg)
+			return
+		}
+
+		resolve.Fields[field] = fieldStrategy
+	}
+
+	err := c.vsync.ResolveConflict(vaultID, resolve)
+
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println("Conflict resolved, run sync to upload result")
+}
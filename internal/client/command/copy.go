@@ -0,0 +1,120 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/shreyner/gophkeeper/internal/client/pkg/clipboard"
+	"github.com/shreyner/gophkeeper/internal/client/storage"
+)
+
+type CopyCommand struct {
+	clipboard         *clipboard.Clipboard
+	loginVaultStorage *storage.LoginVaultStorage
+	fileStorage       *storage.FileVaultStorage
+}
+
+func NewCopyCommand(
+	clipboard *clipboard.Clipboard,
+	loginVaultStorage *storage.LoginVaultStorage,
+	fileStorage *storage.FileVaultStorage,
+) *CopyCommand {
+	command := CopyCommand{
+		clipboard:         clipboard,
+		loginVaultStorage: loginVaultStorage,
+		fileStorage:       fileStorage,
+	}
+
+	return &command
+}
+
+// Run copy <kind> <id> [field]
+func (c *CopyCommand) Run(_ context.Context, args []string) {
+	if len(args) < 2 {
+		fmt.Println("incorrect kind and ID")
+		return
+	}
+
+	kind, vaultID := args[0], args[1]
+
+	var field string
+	if len(args) > 2 {
+		field = args[2]
+	}
+
+	ID, err := strconv.ParseUint(vaultID, 10, 32)
+
+	if err != nil {
+		fmt.Println("Invalid ID")
+		return
+	}
+
+	var value string
+
+	switch kind {
+	case storage.SiteLoginVaultStorageType:
+		value, err = c.siteLoginField(uint32(ID), field)
+	case storage.FileVaultStorageType:
+		value, err = c.fileField(uint32(ID), field)
+	default:
+		fmt.Printf("unknown kind %q\n", kind)
+		return
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	err = c.clipboard.Copy(value)
+
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println("Copied to clipboard")
+}
+
+func (c *CopyCommand) siteLoginField(id uint32, field string) (string, error) {
+	switch field {
+	case "", "password":
+		data, err := c.loginVaultStorage.ViewDataByID(id)
+		if err != nil {
+			return "", err
+		}
+
+		return data.Password, nil
+	case "login":
+		data, err := c.loginVaultStorage.ViewDataByID(id)
+		if err != nil {
+			return "", err
+		}
+
+		return data.Login, nil
+	case "site":
+		model, err := c.loginVaultStorage.GetByID(id)
+		if err != nil {
+			return "", err
+		}
+
+		return model.GetSite(), nil
+	}
+
+	return "", fmt.Errorf("unknown field %q, expected password, login or site", field)
+}
+
+func (c *CopyCommand) fileField(id uint32, field string) (string, error) {
+	switch field {
+	case "", "name":
+		model, err := c.fileStorage.GetByID(id)
+		if err != nil {
+			return "", err
+		}
+
+		return model.GetFileName(), nil
+	}
+
+	return "", fmt.Errorf("unknown field %q, expected name", field)
+}
@@ -0,0 +1,135 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultclient"
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultcrypt"
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultsync"
+	"github.com/shreyner/gophkeeper/internal/client/state"
+	"github.com/shreyner/gophkeeper/internal/client/storage"
+)
+
+type DeviceCommand struct {
+	vclient       *vaultclient.Client
+	vaultCrypt    *vaultcrypt.VaultCrypt
+	vsync         *vaultsync.VaultSync
+	appState      *state.State
+	backend       storage.Backend
+	migratedFiles *storage.MigratedFiles
+}
+
+func NewDeviceCommand(
+	vclient *vaultclient.Client,
+	vaultCrypt *vaultcrypt.VaultCrypt,
+	vsync *vaultsync.VaultSync,
+	appState *state.State,
+	backend storage.Backend,
+	migratedFiles *storage.MigratedFiles,
+) *DeviceCommand {
+	command := DeviceCommand{
+		vclient:       vclient,
+		vaultCrypt:    vaultCrypt,
+		vsync:         vsync,
+		appState:      appState,
+		backend:       backend,
+		migratedFiles: migratedFiles,
+	}
+
+	return &command
+}
+
+func (c *DeviceCommand) RunList(ctx context.Context, _ []string) {
+	devices, err := c.vclient.DeviceList(ctx)
+
+	if err != nil {
+		fmt.Println("Error load devices: ", err)
+		return
+	}
+
+	for _, device := range devices {
+		current := ""
+		if device.IsCurrent {
+			current = " (current)"
+		}
+
+		fmt.Printf(
+			"ID: %v, Name: %v%v, Platform: %v, Created: %v, LastSeen: %v, SyncCursor: %v\n",
+			device.ID,
+			device.Name,
+			current,
+			device.Platform,
+			device.CreatedAt.Local().Format("2006-01-02 15:04:05"),
+			device.LastSeenAt.Local().Format("2006-01-02 15:04:05"),
+			device.LastSyncCursor,
+		)
+	}
+}
+
+func (c *DeviceCommand) RunRename(ctx context.Context, args []string) {
+	if len(args) < 2 {
+		fmt.Println("incorrect device ID and name")
+		return
+	}
+
+	err := c.vclient.DeviceRename(ctx, args[0], strings.Join(args[1:], " "))
+
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println("Device renamed")
+}
+
+func (c *DeviceCommand) RunRevoke(ctx context.Context, args []string) {
+	if len(args) < 1 {
+		fmt.Println("incorrect device ID")
+		return
+	}
+
+	err := c.vclient.DeviceRevoke(ctx, args[0])
+
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println("Device revoked")
+}
+
+// WipeLocalData removes local vaults, sync cursor, vault key and migrated files after the device is revoked on server
+func (c *DeviceCommand) WipeLocalData() {
+	if c.appState.GetUserToken() == "" {
+		return
+	}
+
+	c.wipeLocalData()
+
+	fmt.Println("\nThis device is revoked, local data is wiped. Login again")
+}
+
+// wipeLocalData logs out and removes client state in data folder: local vaults, sync cursor, vault key and
+// migrated gob files. Files downloaded by user are not touched
+func (c *DeviceCommand) wipeLocalData() {
+	c.vclient.Logout()
+	c.appState.Logout()
+	c.vaultCrypt.Reset()
+
+	err := c.backend.Clear()
+	if err != nil {
+		fmt.Println("\nError wipe local storage: ", err)
+	}
+
+	err = c.vsync.Reset()
+	if err != nil {
+		fmt.Println("\nError reset sync: ", err)
+	}
+
+	err = c.migratedFiles.Remove()
+	if err != nil {
+		fmt.Println("\nError remove migrated files: ", err)
+	}
+}
@@ -4,27 +4,36 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 
+	"github.com/shreyner/gophkeeper/internal/client/pkg/transfer"
 	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultclient"
 	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultcrypt"
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultsync"
 	"github.com/shreyner/gophkeeper/internal/client/storage"
 )
 
 type FileCommand struct {
 	vclient     *vaultclient.Client
 	vaultCrypt  *vaultcrypt.VaultCrypt
+	vsync       *vaultsync.VaultSync
+	transfers   *transfer.Manager
 	fileStorage *storage.FileVaultStorage
 }
 
 func NewFileCommand(
 	vclient *vaultclient.Client,
 	vaultCrypt *vaultcrypt.VaultCrypt,
+	vsync *vaultsync.VaultSync,
+	transfers *transfer.Manager,
 	fileStorage *storage.FileVaultStorage,
 ) *FileCommand {
 	command := FileCommand{
 		vclient:     vclient,
 		vaultCrypt:  vaultCrypt,
+		vsync:       vsync,
+		transfers:   transfers,
 		fileStorage: fileStorage,
 	}
 
@@ -32,7 +41,12 @@ func NewFileCommand(
 }
 
 func (c *FileCommand) RunView(_ context.Context, _ []string) {
-	arr := c.fileStorage.GetAll()
+	arr, err := c.fileStorage.GetAll()
+
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
 
 	for _, model := range arr {
 		fmt.Printf(
@@ -45,7 +59,7 @@ func (c *FileCommand) RunView(_ context.Context, _ []string) {
 	}
 }
 
-func (c *FileCommand) RunUpload(ctx context.Context, args []string) {
+func (c *FileCommand) RunUpload(_ context.Context, args []string) {
 	if len(args) < 1 {
 		fmt.Println("incorrect login and password")
 		return
@@ -58,21 +72,38 @@ func (c *FileCommand) RunUpload(ctx context.Context, args []string) {
 		return
 	}
 
-	fileOut, err := os.Open(filePath)
+	fileInfo, err := os.Stat(filePath)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
-	defer fileOut.Close()
 
-	err = c.fileStorage.UploadFile(ctx, fileOut)
-	if err != nil {
-		fmt.Println(err)
-		return
-	}
+	id := c.transfers.Start(
+		transfer.DirectionUpload,
+		fileInfo.Name(),
+		fileInfo.Size(),
+		func(ctx context.Context, progress *transfer.Progress) error {
+			fileOut, err := os.Open(filePath)
+			if err != nil {
+				return err
+			}
+			defer fileOut.Close()
+
+			err = c.fileStorage.UploadFile(ctx, progress.Reader(fileOut), fileInfo.Name(), fileInfo.Size())
+			if err != nil {
+				return err
+			}
+
+			c.vsync.RequestSync()
+
+			return nil
+		},
+	)
+
+	fmt.Printf("Transfer %v is started, show progress with: transfers\n", id)
 }
 
-func (c *FileCommand) RunDownload(ctx context.Context, args []string) {
+func (c *FileCommand) RunDownload(_ context.Context, args []string) {
 	if len(args) < 2 {
 		fmt.Println("incorrect ID and path")
 		return
@@ -91,12 +122,50 @@ func (c *FileCommand) RunDownload(ctx context.Context, args []string) {
 		return
 	}
 
-	err = c.fileStorage.DownloadFile(ctx, uint32(ID), filePath)
+	model, err := c.fileStorage.GetByID(uint32(ID))
 
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
+
+	id := c.transfers.Start(
+		transfer.DirectionDownload,
+		model.GetFileName(),
+		model.GetSize(),
+		func(ctx context.Context, progress *transfer.Progress) error {
+			return c.downloadFile(ctx, model.ID, filepath.Join(filePath, model.GetFileName()), progress)
+		},
+	)
+
+	fmt.Printf("Transfer %v is started, show progress with: transfers\n", id)
+}
+
+// downloadFile removes partial file when download fails
+func (c *FileCommand) downloadFile(ctx context.Context, id uint32, filePath string, progress *transfer.Progress) error {
+	file, err := os.Create(filePath)
+
+	if err != nil {
+		return err
+	}
+
+	err = c.fileStorage.DownloadFile(ctx, id, progress.Writer(file))
+
+	if err == nil {
+		err = file.Sync()
+	}
+
+	closeErr := file.Close()
+
+	if err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		_ = os.Remove(filePath)
+	}
+
+	return err
 }
 
 func (c *FileCommand) RunDelete(ctx context.Context, args []string) {
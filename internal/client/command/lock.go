@@ -0,0 +1,70 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/shreyner/gophkeeper/internal/client/pkg/clipboard"
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultcrypt"
+	"github.com/shreyner/gophkeeper/internal/client/state"
+)
+
+type LockCommand struct {
+	appState   *state.State
+	vaultCrypt *vaultcrypt.VaultCrypt
+	clipboard  *clipboard.Clipboard
+}
+
+func NewLockCommand(
+	appState *state.State,
+	vaultCrypt *vaultcrypt.VaultCrypt,
+	clipboard *clipboard.Clipboard,
+) *LockCommand {
+	command := LockCommand{
+		appState:   appState,
+		vaultCrypt: vaultCrypt,
+		clipboard:  clipboard,
+	}
+
+	return &command
+}
+
+// Lock wipes vault key and copied secret, commands which need auth are refused until unlock
+func (c *LockCommand) Lock() {
+	c.vaultCrypt.Lock()
+	c.appState.SetLocked(true)
+
+	if err := c.clipboard.Clear(); err != nil {
+		fmt.Println("Can't clear clipboard:", err)
+	}
+}
+
+func (c *LockCommand) Run(_ context.Context, _ []string) {
+	c.Lock()
+
+	fmt.Println("Vault locked")
+}
+
+func (c *LockCommand) RunUnlock(_ context.Context, args []string) {
+	if len(args) < 1 {
+		fmt.Println("incorrect master password")
+		return
+	}
+
+	err := c.vaultCrypt.Unlock(args[0])
+
+	if errors.Is(err, vaultcrypt.ErrInvalidMasterPassword) {
+		fmt.Println("incorrect master password")
+		return
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	c.appState.SetLocked(false)
+
+	fmt.Println("Vault unlocked")
+}
@@ -2,6 +2,7 @@ package command
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultclient"
@@ -13,6 +14,8 @@ type LoginCommand struct {
 	vclient    *vaultclient.Client
 	vaultCrypt *vaultcrypt.VaultCrypt
 	vsync      *vaultsync.VaultSync
+
+	pendingKey *vaultcrypt.MasterKey // set to vault when second factor is passed
 }
 
 func NewLoginCommand(
@@ -44,24 +47,126 @@ func (c *LoginCommand) Run(ctx context.Context, args []string) {
 
 	err := c.vclient.Login(ctx, login, password)
 
+	if errors.Is(err, vaultclient.ErrSecondFactorRequired) {
+		// Key is derived now, so password isn't kept until code is entered
+		c.setPendingKey(login, password)
+
+		if len(args) > 2 {
+			c.RunLoginCode(ctx, args[2:])
+			return
+		}
+
+		fmt.Println("Two-factor authentication is enabled, enter code from app or backup code: login-code <code>")
+		return
+	}
+
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
 
-	err = c.vaultCrypt.SetMasterPassword(login, password)
+	c.resetPendingKey()
+	c.setMasterPassword(login, password)
+}
+
+// RunLoginCode finishes login by code of authenticator app or by backup code
+func (c *LoginCommand) RunLoginCode(ctx context.Context, args []string) {
+	if len(args) < 1 {
+		fmt.Println("incorrect code")
+		return
+	}
+
+	err := c.vclient.LoginSecondFactor(ctx, args[0])
 
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
 
-	//err = c.vsync.Sync()
-	//
-	//if err != nil {
-	//	fmt.Println("Error first sync storage: ", err)
-	//	return
-	//}
+	if c.pendingKey == nil {
+		return
+	}
+
+	err = c.vaultCrypt.SetMasterKey(c.pendingKey)
+	c.pendingKey = nil
+
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+// setPendingKey keeps key of master password until second factor is passed
+func (c *LoginCommand) setPendingKey(login, password string) {
+	c.resetPendingKey()
+
+	masterKey, err := vaultcrypt.DeriveMasterKey(login, password)
+
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	c.pendingKey = masterKey
+}
+
+func (c *LoginCommand) resetPendingKey() {
+	if c.pendingKey == nil {
+		return
+	}
+
+	c.pendingKey.Wipe()
+	c.pendingKey = nil
+}
+
+// RunRegister creates account: register <login> <password> <password again> [invite code]
+func (c *LoginCommand) RunRegister(ctx context.Context, args []string) {
+	if len(args) < 3 {
+		fmt.Println("incorrect login, password and password confirmation")
+		return
+	}
+
+	login, password, confirmation := args[0], args[1], args[2]
+
+	if password != confirmation {
+		fmt.Println("passwords don't match")
+		return
+	}
+
+	var inviteCode string
+	if len(args) > 3 {
+		inviteCode = args[3]
+	}
+
+	err := c.vclient.Register(ctx, login, password, inviteCode)
+
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	c.setMasterPassword(login, password)
+
+	fmt.Println("Account is created")
+}
+
+func (c *LoginCommand) RunInviteCreate(ctx context.Context, _ []string) {
+	code, expiresAt, err := c.vclient.InviteCreate(ctx)
+
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Printf("Invite code: %v, expires at: %v\n", code, expiresAt.Local().Format("2006-01-02 15:04:05"))
+}
+
+func (c *LoginCommand) setMasterPassword(login, password string) {
+	err := c.vaultCrypt.SetMasterPassword(login, password)
+
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
 }
 
 func (c *LoginCommand) RunCheck(ctx context.Context, _ []string) {
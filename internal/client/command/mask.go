@@ -0,0 +1,26 @@
+package command
+
+import "strings"
+
+const revealFlag = "--reveal"
+
+const secretMask = "********"
+
+// maskSecret hides secret from terminal scrollback. Mask has fixed length to not leak secret length.
+func maskSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+
+	return secretMask
+}
+
+func hasFlag(args []string, flag string) bool {
+	for _, arg := range args {
+		if strings.TrimSpace(arg) == flag {
+			return true
+		}
+	}
+
+	return false
+}
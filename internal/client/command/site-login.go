@@ -57,7 +57,12 @@ func (c *SiteLoginCommand) RunCreate(ctx context.Context, args []string) {
 }
 
 func (c *SiteLoginCommand) RunView(_ context.Context, _ []string) {
-	arr := c.loginVaultStorage.GetAll()
+	arr, err := c.loginVaultStorage.GetAll()
+
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
 
 	for _, model := range arr {
 		fmt.Printf(
@@ -94,7 +99,13 @@ func (c *SiteLoginCommand) RunViewLogin(ctx context.Context, args []string) {
 		return
 	}
 
-	fmt.Printf("ID: %v, Login: %v, Password: %v\n", ID, siteLoginData.Login, siteLoginData.Password)
+	password := maskSecret(siteLoginData.Password)
+
+	if hasFlag(args[1:], revealFlag) {
+		password = siteLoginData.Password
+	}
+
+	fmt.Printf("ID: %v, Login: %v, Password: %v\n", ID, siteLoginData.Login, password)
 
 }
 
@@ -0,0 +1,81 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/shreyner/gophkeeper/internal/client/pkg/qrterm"
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultclient"
+)
+
+type TOTPCommand struct {
+	vclient *vaultclient.Client
+}
+
+func NewTOTPCommand(vclient *vaultclient.Client) *TOTPCommand {
+	command := TOTPCommand{
+		vclient: vclient,
+	}
+
+	return &command
+}
+
+func (c *TOTPCommand) RunSetup(ctx context.Context, _ []string) {
+	secret, uri, err := c.vclient.TOTPSetup(ctx)
+
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println("Scan QR code by authenticator app or enter secret manually")
+	fmt.Println()
+
+	if err := qrterm.Print(os.Stdout, uri); err != nil {
+		fmt.Println("Can't show QR code:", err)
+	}
+
+	fmt.Println()
+	fmt.Println("Secret:", secret)
+	fmt.Println("URI:", uri)
+	fmt.Println()
+	fmt.Println("Confirm by code from app: 2fa-enable <code>")
+}
+
+func (c *TOTPCommand) RunEnable(ctx context.Context, args []string) {
+	if len(args) < 1 {
+		fmt.Println("incorrect code")
+		return
+	}
+
+	backupCodes, err := c.vclient.TOTPEnable(ctx, args[0])
+
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println("Two-factor authentication is enabled")
+	fmt.Println("Save backup codes, each of them can be used once instead of code from app:")
+
+	for _, code := range backupCodes {
+		fmt.Println("  ", code)
+	}
+}
+
+func (c *TOTPCommand) RunDisable(ctx context.Context, args []string) {
+	if len(args) < 1 {
+		fmt.Println("incorrect code")
+		return
+	}
+
+	err := c.vclient.TOTPDisable(ctx, args[0])
+
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println("Two-factor authentication is disabled")
+}
@@ -0,0 +1,57 @@
+package command
+
+import (
+	"fmt"
+	"strconv"
+
+	"golang.org/x/net/context"
+
+	"github.com/shreyner/gophkeeper/internal/client/pkg/transfer"
+)
+
+type TransferCommand struct {
+	transfers *transfer.Manager
+}
+
+func NewTransferCommand(transfers *transfer.Manager) *TransferCommand {
+	command := TransferCommand{
+		transfers: transfers,
+	}
+
+	return &command
+}
+
+func (c *TransferCommand) RunList(_ context.Context, _ []string) {
+	infos := c.transfers.List()
+
+	if len(infos) == 0 {
+		fmt.Println("No transfers")
+		return
+	}
+
+	for _, info := range infos {
+		fmt.Println(info)
+	}
+}
+
+func (c *TransferCommand) RunCancel(_ context.Context, args []string) {
+	if len(args) < 1 {
+		fmt.Println("incorrect transfer ID")
+		return
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		fmt.Println("Invalid ID")
+		return
+	}
+
+	err = c.transfers.Cancel(id)
+
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Printf("Transfer %v is canceled\n", id)
+}
@@ -0,0 +1,46 @@
+package command
+
+import (
+	"fmt"
+	"strconv"
+
+	"golang.org/x/net/context"
+
+	"github.com/shreyner/gophkeeper/internal/client/pkg/transfer"
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultclient"
+)
+
+type UsageCommand struct {
+	vclient *vaultclient.Client
+}
+
+func NewUsageCommand(vclient *vaultclient.Client) *UsageCommand {
+	command := UsageCommand{
+		vclient: vclient,
+	}
+
+	return &command
+}
+
+func (c *UsageCommand) Run(ctx context.Context, _ []string) {
+	usage, err := c.vclient.Usage(ctx)
+
+	if err != nil {
+		fmt.Println("Error load usage: ", err)
+		return
+	}
+
+	fmt.Println("Files:", formatUsage(usage.BlobBytes, usage.MaxBlobBytes, transfer.FormatBytes))
+	fmt.Println("Items:", formatUsage(usage.Items, usage.MaxItems, func(n int64) string {
+		return strconv.FormatInt(n, 10)
+	}))
+}
+
+// formatUsage shows percent of limit, zero limit is unlimited
+func formatUsage(used, limit int64, format func(int64) string) string {
+	if limit == 0 {
+		return format(used) + " (unlimited)"
+	}
+
+	return fmt.Sprintf("%s / %s (%d%%)", format(used), format(limit), used*100/limit)
+}
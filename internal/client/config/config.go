@@ -1,6 +1,10 @@
 package config
 
-import "github.com/caarlos0/env/v7"
+import (
+	"time"
+
+	"github.com/caarlos0/env/v7"
+)
 
 type Config struct {
 	HostGRPC   string `env:"HOST_GRPC" envDefault:":3200"`
@@ -11,6 +15,14 @@ type Config struct {
 	CertFile string `env:"CERT_FILE,file" envDefault:"./cert/server-cert.pem"`
 
 	DataFolder string `env:"DATA_FOLDER" envDefault:"./data"`
+
+	IdleLockTimeout     time.Duration `env:"IDLE_LOCK_TIMEOUT" envDefault:"5m"`
+	ClipboardClearDelay time.Duration `env:"CLIPBOARD_CLEAR_DELAY" envDefault:"30s"`
+	AutoSyncInterval    time.Duration `env:"AUTO_SYNC_INTERVAL" envDefault:"1m"`
+	AutoSyncDebounce    time.Duration `env:"AUTO_SYNC_DEBOUNCE" envDefault:"2s"`
+	TransferParallel    int           `env:"TRANSFER_PARALLEL" envDefault:"2"`
+
+	DeviceName string `env:"DEVICE_NAME"` // hostname is used when empty
 }
 
 func New() *Config {
@@ -0,0 +1,147 @@
+// Package accountexport writes account export as zip archive.
+//
+// Archive has manifest.json, vaults.jsonl and blobs.jsonl. Every line of jsonl file is one record.
+// Vaults stay encrypted, vault key is derived from master password with login of manifest as salt
+package accountexport
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultdata"
+)
+
+const (
+	Format        = "gophkeeper-account-export"
+	FormatVersion = 1
+
+	ManifestFile = "manifest.json"
+	VaultsFile   = "vaults.jsonl"
+	BlobsFile    = "blobs.jsonl"
+)
+
+var ErrPageOrder = errors.New("accountexport: vaults after blobs")
+
+type Manifest struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	Login      string    `json:"login"`
+	ExportedAt time.Time `json:"exported_at"`
+	Vaults     int       `json:"vaults"`
+	Blobs      int       `json:"blobs"`
+}
+
+type Vault struct {
+	ID      string `json:"id"`
+	Version int    `json:"version"`
+	Vault   []byte `json:"vault"`
+	S3URL   string `json:"s3,omitempty"`
+}
+
+type Blob struct {
+	ObjectName string    `json:"object_name"`
+	VaultID    string    `json:"vault_id,omitempty"`
+	Size       int64     `json:"size"`
+	CreatedAt  time.Time `json:"created_at"`
+	Location   string    `json:"location"`
+}
+
+// Writer writes pages as they come, zip has only one open file, so vaults must come before blobs
+type Writer struct {
+	zw       *zip.Writer
+	vaults   *json.Encoder
+	blobs    *json.Encoder
+	manifest Manifest
+}
+
+func NewWriter(w io.Writer, exportedAt time.Time) *Writer {
+	writer := Writer{
+		zw: zip.NewWriter(w),
+		manifest: Manifest{
+			Format:     Format,
+			Version:    FormatVersion,
+			ExportedAt: exportedAt,
+		},
+	}
+
+	return &writer
+}
+
+func (w *Writer) WritePage(page *vaultdata.AccountExportPage) error {
+	if page.Login != "" {
+		w.manifest.Login = page.Login
+	}
+
+	if len(page.Vaults) != 0 && w.blobs != nil {
+		return ErrPageOrder
+	}
+
+	for _, v := range page.Vaults {
+		if w.vaults == nil {
+			file, err := w.zw.Create(VaultsFile)
+
+			if err != nil {
+				return err
+			}
+
+			w.vaults = json.NewEncoder(file)
+		}
+
+		err := w.vaults.Encode(Vault{ID: v.ID, Version: v.Version, Vault: v.Vault, S3URL: v.S3URL})
+
+		if err != nil {
+			return err
+		}
+
+		w.manifest.Vaults++
+	}
+
+	for _, b := range page.Blobs {
+		if w.blobs == nil {
+			file, err := w.zw.Create(BlobsFile)
+
+			if err != nil {
+				return err
+			}
+
+			w.blobs = json.NewEncoder(file)
+		}
+
+		err := w.blobs.Encode(Blob{
+			ObjectName: b.ObjectName,
+			VaultID:    b.VaultID,
+			Size:       b.Size,
+			CreatedAt:  b.CreatedAt,
+			Location:   b.Location,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		w.manifest.Blobs++
+	}
+
+	return nil
+}
+
+// Close writes manifest with counts of records and finishes archive
+func (w *Writer) Close() error {
+	file, err := w.zw.Create(ManifestFile)
+
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+
+	if err = encoder.Encode(w.manifest); err != nil {
+		return err
+	}
+
+	return w.zw.Close()
+}
@@ -0,0 +1,119 @@
+package accountexport
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultdata"
+)
+
+var testNow = time.Date(2022, 12, 1, 10, 0, 0, 0, time.UTC)
+
+func readArchive(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	files := make(map[string][]byte)
+
+	for _, f := range zr.File {
+		r, err := f.Open()
+		require.NoError(t, err)
+
+		content, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+
+		files[f.Name] = content
+	}
+
+	return files
+}
+
+func readLines(t *testing.T, data []byte, record func() interface{}) []interface{} {
+	t.Helper()
+
+	records := make([]interface{}, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for scanner.Scan() {
+		r := record()
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), r))
+
+		records = append(records, r)
+	}
+
+	return records
+}
+
+func TestWriter(t *testing.T) {
+	var buffer bytes.Buffer
+
+	w := NewWriter(&buffer, testNow)
+
+	require.NoError(t, w.WritePage(&vaultdata.AccountExportPage{Login: "alice"}))
+	require.NoError(t, w.WritePage(&vaultdata.AccountExportPage{
+		Vaults: []vaultdata.VaultSyncData{
+			{ID: "v1", Version: 2, Vault: []byte{1, 2, 3}},
+			{ID: "v2", Version: 1, Vault: []byte{4}, S3URL: "/blobs/o1"},
+		},
+	}))
+	require.NoError(t, w.WritePage(&vaultdata.AccountExportPage{
+		Blobs: []vaultdata.AccountBlob{
+			{ObjectName: "o1", VaultID: "v2", Size: 10, CreatedAt: testNow, Location: "/blobs/o1"},
+		},
+	}))
+
+	assert.ErrorIs(t, w.WritePage(&vaultdata.AccountExportPage{
+		Vaults: []vaultdata.VaultSyncData{{ID: "v3"}},
+	}), ErrPageOrder)
+
+	require.NoError(t, w.Close())
+
+	files := readArchive(t, buffer.Bytes())
+
+	manifest := Manifest{}
+	require.NoError(t, json.Unmarshal(files[ManifestFile], &manifest))
+	assert.Equal(t, Manifest{
+		Format:     Format,
+		Version:    FormatVersion,
+		Login:      "alice",
+		ExportedAt: testNow,
+		Vaults:     2,
+		Blobs:      1,
+	}, manifest)
+
+	vaults := readLines(t, files[VaultsFile], func() interface{} { return &Vault{} })
+	assert.Equal(t, []interface{}{
+		&Vault{ID: "v1", Version: 2, Vault: []byte{1, 2, 3}},
+		&Vault{ID: "v2", Version: 1, Vault: []byte{4}, S3URL: "/blobs/o1"},
+	}, vaults)
+
+	blobs := readLines(t, files[BlobsFile], func() interface{} { return &Blob{} })
+	assert.Equal(t, []interface{}{
+		&Blob{ObjectName: "o1", VaultID: "v2", Size: 10, CreatedAt: testNow, Location: "/blobs/o1"},
+	}, blobs)
+}
+
+func TestWriter_Empty(t *testing.T) {
+	var buffer bytes.Buffer
+
+	w := NewWriter(&buffer, testNow)
+
+	require.NoError(t, w.WritePage(&vaultdata.AccountExportPage{Login: "alice"}))
+	require.NoError(t, w.Close())
+
+	files := readArchive(t, buffer.Bytes())
+
+	assert.Len(t, files, 1)
+	assert.Contains(t, files, ManifestFile)
+}
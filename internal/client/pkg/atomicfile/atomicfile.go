@@ -0,0 +1,81 @@
+// Package atomicfile replaces files atomically, so a crash leaves either old or new content.
+package atomicfile
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WriteFile writes content by write to temp file in the same folder, syncs it and renames over filePath
+func WriteFile(filePath string, perm os.FileMode, write func(w io.Writer) error) error {
+	dir, name := filepath.Split(filePath)
+
+	if dir == "" {
+		dir = "."
+	}
+
+	tmpFile, err := os.CreateTemp(dir, name+".tmp-*")
+
+	if err != nil {
+		return err
+	}
+
+	tmpPath := tmpFile.Name()
+
+	err = writeAndSync(tmpFile, perm, write)
+
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	err = os.Rename(tmpPath, filePath)
+
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+func writeAndSync(file *os.File, perm os.FileMode, write func(w io.Writer) error) error {
+	err := write(file)
+
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	err = file.Chmod(perm)
+
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	err = file.Sync()
+
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// syncDir makes rename durable. Some platforms can't open folder for sync, it is skipped there
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+
+	if err != nil {
+		return nil
+	}
+
+	defer d.Close()
+
+	_ = d.Sync()
+
+	return nil
+}
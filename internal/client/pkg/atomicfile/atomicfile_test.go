@@ -0,0 +1,59 @@
+package atomicfile
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFile(t *testing.T) {
+	t.Run("create and replace", func(t *testing.T) {
+		require := require.New(t)
+		filePath := filepath.Join(t.TempDir(), "site-login.db")
+
+		err := WriteFile(filePath, 0600, func(w io.Writer) error {
+			_, err := w.Write([]byte("first"))
+			return err
+		})
+		require.NoError(err)
+
+		err = WriteFile(filePath, 0600, func(w io.Writer) error {
+			_, err := w.Write([]byte("second"))
+			return err
+		})
+		require.NoError(err)
+
+		content, err := os.ReadFile(filePath)
+		require.NoError(err)
+		assert.Equal(t, "second", string(content))
+	})
+
+	t.Run("failed write keeps old content", func(t *testing.T) {
+		require := require.New(t)
+		dir := t.TempDir()
+		filePath := filepath.Join(dir, "site-login.db")
+
+		require.NoError(os.WriteFile(filePath, []byte("old"), 0600))
+
+		errWrite := errors.New("write error")
+
+		err := WriteFile(filePath, 0600, func(w io.Writer) error {
+			_, _ = w.Write([]byte("partial"))
+			return errWrite
+		})
+		require.ErrorIs(err, errWrite)
+
+		content, err := os.ReadFile(filePath)
+		require.NoError(err)
+		assert.Equal(t, "old", string(content))
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(err)
+		assert.Len(t, entries, 1, "temp file must be removed")
+	})
+}
@@ -0,0 +1,113 @@
+// Package clipboard puts text to system clipboard by OSC 52 terminal escape sequence.
+// It works over SSH without X11, because terminal emulator on user side owns clipboard.
+package clipboard
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+type Clipboard struct {
+	out        io.Writer
+	clearDelay time.Duration
+	inTmux     bool
+
+	generation uint64
+	timer      *time.Timer
+	isCopied   bool // text is in clipboard until it is cleared
+
+	mux sync.Mutex
+}
+
+func New(out io.Writer, clearDelay time.Duration) *Clipboard {
+	c := Clipboard{
+		out:        out,
+		clearDelay: clearDelay,
+		inTmux:     os.Getenv("TMUX") != "",
+	}
+
+	return &c
+}
+
+// Copy writes text to clipboard and schedules clear after clearDelay.
+// Terminals don't allow reading clipboard back, so content counts as changed
+// only when Copy was called again before the delay expired.
+func (c *Clipboard) Copy(text string) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if err := c.write(text); err != nil {
+		return err
+	}
+
+	c.generation++
+	c.isCopied = true
+
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+
+	if c.clearDelay <= 0 {
+		return nil
+	}
+
+	generation := c.generation
+	c.timer = time.AfterFunc(c.clearDelay, func() {
+		c.clear(generation)
+	})
+
+	return nil
+}
+
+// Clear wipes clipboard at once if copied text is not cleared yet, for example on exit or lock.
+func (c *Clipboard) Clear() error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if !c.isCopied {
+		return nil
+	}
+
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+
+	c.isCopied = false
+
+	return c.write("")
+}
+
+func (c *Clipboard) clear(generation uint64) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	_ = c.write("")
+	c.timer = nil
+	c.isCopied = false
+}
+
+func (c *Clipboard) write(text string) error {
+	_, err := io.WriteString(c.out, sequence(text, c.inTmux))
+
+	return err
+}
+
+func sequence(text string, inTmux bool) string {
+	seq := fmt.Sprintf("\x1b]52;c;%s\x07", base64.StdEncoding.EncodeToString([]byte(text)))
+
+	if inTmux {
+		// tmux passes escape to outer terminal only in DCS passthrough
+		return fmt.Sprintf("\x1bPtmux;\x1b%s\x1b\\", seq)
+	}
+
+	return seq
+}
@@ -0,0 +1,129 @@
+package clipboard
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type syncBuffer struct {
+	buf bytes.Buffer
+	mux sync.Mutex
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	return b.buf.String()
+}
+
+func Test_sequence(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		inTmux bool
+		want   string
+	}{
+		{
+			name: "plain terminal",
+			text: "123",
+			want: "\x1b]52;c;MTIz\x07",
+		},
+		{
+			name:   "inside tmux",
+			text:   "123",
+			inTmux: true,
+			want:   "\x1bPtmux;\x1b\x1b]52;c;MTIz\x07\x1b\\",
+		},
+		{
+			name: "clear",
+			text: "",
+			want: "\x1b]52;c;\x07",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, sequence(tt.text, tt.inTmux))
+		})
+	}
+}
+
+func TestClipboard_Copy(t *testing.T) {
+	t.Run("clear after delay", func(t *testing.T) {
+		out := syncBuffer{}
+		c := New(&out, 10*time.Millisecond)
+		c.inTmux = false
+
+		assert.Nil(t, c.Copy("123"))
+
+		assert.Eventually(t, func() bool {
+			return out.String() == sequence("123", false)+sequence("", false)
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("don't clear changed content by old timer", func(t *testing.T) {
+		out := syncBuffer{}
+		c := New(&out, 50*time.Millisecond)
+		c.inTmux = false
+
+		assert.Nil(t, c.Copy("123"))
+		c.clear(c.generation - 1)
+		assert.Nil(t, c.Copy("456"))
+		c.clear(c.generation - 1)
+
+		assert.Equal(t, sequence("123", false)+sequence("456", false), out.String())
+	})
+}
+
+func TestClipboard_Clear(t *testing.T) {
+	tests := []struct {
+		name       string
+		clearDelay time.Duration
+		copy       bool
+		want       string
+	}{
+		{
+			name:       "Copied text is cleared at once",
+			clearDelay: time.Hour,
+			copy:       true,
+			want:       sequence("123", false) + sequence("", false),
+		},
+		{
+			name: "Copied text is cleared without clear delay",
+			copy: true,
+			want: sequence("123", false) + sequence("", false),
+		},
+		{
+			name:       "Nothing is copied",
+			clearDelay: time.Hour,
+			want:       "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := syncBuffer{}
+			c := New(&out, tt.clearDelay)
+			c.inTmux = false
+
+			if tt.copy {
+				assert.Nil(t, c.Copy("123"))
+			}
+
+			assert.Nil(t, c.Clear())
+			assert.Nil(t, c.Clear())
+
+			assert.Equal(t, tt.want, out.String())
+		})
+	}
+}
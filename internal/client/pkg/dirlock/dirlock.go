@@ -0,0 +1,36 @@
+// Package dirlock holds exclusive lock on data folder, so only one client process works with it.
+package dirlock
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+const lockFileName = ".lock"
+
+var ErrLocked = errors.New("data folder is used by another process")
+
+type Lock struct {
+	file *os.File
+	path string
+}
+
+// Acquire locks dir without waiting. It returns ErrLocked when dir is locked by another process
+func Acquire(dir string) (*Lock, error) {
+	lockPath := filepath.Join(dir, lockFileName)
+
+	file, err := lockFile(lockPath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	l := Lock{file: file, path: lockPath}
+
+	return &l, nil
+}
+
+func (l *Lock) Release() error {
+	return unlockFile(l.file, l.path)
+}
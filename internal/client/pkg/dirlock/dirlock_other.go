@@ -0,0 +1,33 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package dirlock
+
+import (
+	"errors"
+	"os"
+)
+
+// lockFile creates lock file exclusively. Lock file stays after crash and must be removed by hand
+func lockFile(lockPath string) (*os.File, error) {
+	file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0600)
+
+	if errors.Is(err, os.ErrExist) {
+		return nil, ErrLocked
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+func unlockFile(file *os.File, lockPath string) error {
+	err := file.Close()
+
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(lockPath)
+}
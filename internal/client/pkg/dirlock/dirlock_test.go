@@ -0,0 +1,26 @@
+package dirlock
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquire(t *testing.T) {
+	t.Run("second lock fails until release", func(t *testing.T) {
+		require := require.New(t)
+		dir := t.TempDir()
+
+		l, err := Acquire(dir)
+		require.NoError(err)
+
+		_, err = Acquire(dir)
+		require.ErrorIs(err, ErrLocked)
+
+		require.NoError(l.Release())
+
+		l, err = Acquire(dir)
+		require.NoError(err)
+		require.NoError(l.Release())
+	})
+}
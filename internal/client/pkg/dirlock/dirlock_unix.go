@@ -0,0 +1,44 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package dirlock
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// lockFile takes flock, it is released by OS when process dies
+func lockFile(lockPath string) (*os.File, error) {
+	file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0600)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = unix.Flock(int(file.Fd()), unix.LOCK_EX|unix.LOCK_NB)
+
+	if errors.Is(err, unix.EWOULDBLOCK) {
+		_ = file.Close()
+		return nil, ErrLocked
+	}
+
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return file, nil
+}
+
+func unlockFile(file *os.File, _ string) error {
+	err := unix.Flock(int(file.Fd()), unix.LOCK_UN)
+
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	return file.Close()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/c-bata/go-prompt"
 	"github.com/shreyner/gophkeeper/internal/client/state"
@@ -13,10 +14,15 @@ const (
 	CommandAuthAny = iota
 	CommandAuthNeed
 	CommandAuthNot
+	CommandAuthLocked // available only while vault is locked
 )
 
 type RunnerFunc func(ctx context.Context, args []string)
 
+type LockFunc func()
+
+type StatusFunc func() string
+
 type Command struct {
 	Command     string
 	Description string
@@ -25,6 +31,7 @@ type Command struct {
 }
 
 var exitCommand = "exit"
+var promptPrefix = "> "
 var exitCommandDescription = "Exit program"
 
 //type AppState struct{}
@@ -35,8 +42,15 @@ type PromptCMD struct {
 
 	suggestsBeforeAuth []prompt.Suggest
 	suggestsAfterAuth  []prompt.Suggest
+	suggestsLocked     []prompt.Suggest
+
+	mapCommands map[string]Command
 
-	mapRunnersCommand map[string]RunnerFunc
+	idleTimeout time.Duration
+	idleTimer   *time.Timer
+	lock        LockFunc
+
+	status StatusFunc
 }
 
 func New(appState *state.State, commands []Command) *PromptCMD {
@@ -46,8 +60,9 @@ func New(appState *state.State, commands []Command) *PromptCMD {
 
 	suggestsBeforeAuth := make([]prompt.Suggest, 0)
 	suggestsAfterAuth := make([]prompt.Suggest, 0)
+	suggestsLocked := make([]prompt.Suggest, 0)
 
-	mapRunnersCommand := make(map[string]RunnerFunc, len(commands))
+	mapCommands := make(map[string]Command, len(commands))
 
 	for _, command := range commands {
 		suggest := prompt.Suggest{
@@ -68,21 +83,82 @@ func New(appState *state.State, commands []Command) *PromptCMD {
 			suggestsBeforeAuth = append(suggestsBeforeAuth, suggest)
 		}
 
-		mapRunnersCommand[command.Command] = command.Run
+		if command.Auth == CommandAuthLocked {
+			suggestsLocked = append(suggestsLocked, suggest)
+		}
+
+		mapCommands[command.Command] = command
 	}
 
 	suggestsBeforeAuth = append(suggestsBeforeAuth, prompt.Suggest{Text: exitCommand, Description: exitCommandDescription})
 	suggestsAfterAuth = append(suggestsAfterAuth, prompt.Suggest{Text: exitCommand, Description: exitCommandDescription})
+	suggestsLocked = append(suggestsLocked, prompt.Suggest{Text: exitCommand, Description: exitCommandDescription})
 
 	promptcmd.suggestsBeforeAuth = suggestsBeforeAuth
 	promptcmd.suggestsAfterAuth = suggestsAfterAuth
-	promptcmd.mapRunnersCommand = mapRunnersCommand
+	promptcmd.suggestsLocked = suggestsLocked
+	promptcmd.mapCommands = mapCommands
 
 	return &promptcmd
 }
 
+// SetIdleLock calls lock after timeout without user input. Zero timeout disables auto-lock.
+func (p *PromptCMD) SetIdleLock(timeout time.Duration, lock LockFunc) {
+	p.idleTimeout = timeout
+	p.lock = lock
+
+	if timeout <= 0 {
+		return
+	}
+
+	p.idleTimer = time.AfterFunc(timeout, p.idleLock)
+}
+
+func (p *PromptCMD) idleLock() {
+	if !p.appState.IsAuth() || p.appState.IsLocked() {
+		return
+	}
+
+	p.lock()
+
+	fmt.Println("\nVault locked after inactivity. Enter master password with: unlock <password>")
+}
+
+func (p *PromptCMD) touch() {
+	if p.idleTimer == nil {
+		return
+	}
+
+	p.idleTimer.Reset(p.idleTimeout)
+}
+
+// SetStatus shows status before prompt, e.g. pending sync changes
+func (p *PromptCMD) SetStatus(status StatusFunc) {
+	p.status = status
+}
+
+func (p *PromptCMD) LivePrefix() (string, bool) {
+	if p.status == nil || !p.appState.IsAuth() {
+		return promptPrefix, false
+	}
+
+	status := p.status()
+
+	if status == "" {
+		return promptPrefix, false
+	}
+
+	return fmt.Sprintf("[%s] %s", status, promptPrefix), true
+}
+
 func (p *PromptCMD) Completer(d prompt.Document) []prompt.Suggest {
-	if p.appState.IsAuth {
+	p.touch()
+
+	if p.appState.IsAuth() && p.appState.IsLocked() {
+		return prompt.FilterHasPrefix(p.suggestsLocked, d.GetWordBeforeCursor(), true)
+	}
+
+	if p.appState.IsAuth() {
 		return prompt.FilterHasPrefix(p.suggestsAfterAuth, d.GetWordBeforeCursor(), true)
 	}
 
@@ -90,6 +166,8 @@ func (p *PromptCMD) Completer(d prompt.Document) []prompt.Suggest {
 }
 
 func (p *PromptCMD) Executor(s string) {
+	p.touch()
+
 	command, args := p.parseCommand(s)
 	ctx := context.Background()
 
@@ -101,14 +179,26 @@ func (p *PromptCMD) Executor(s string) {
 		return
 	}
 
-	runner, ok := p.mapRunnersCommand[command]
+	cmd, ok := p.mapCommands[command]
 
 	if !ok {
 		fmt.Println("Command not found")
 		return
 	}
 
-	runner(ctx, args)
+	isLocked := p.appState.IsLocked()
+
+	if isLocked && cmd.Auth == CommandAuthNeed {
+		fmt.Println("Vault is locked. Enter master password with: unlock <password>")
+		return
+	}
+
+	if !isLocked && cmd.Auth == CommandAuthLocked {
+		fmt.Println("Vault is not locked")
+		return
+	}
+
+	cmd.Run(ctx, args)
 }
 
 func (p *PromptCMD) ExitChecker(in string, breakline bool) bool {
@@ -1,8 +1,10 @@
 package promptcmd
 
 import (
+	"context"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/c-bata/go-prompt"
 	"github.com/shreyner/gophkeeper/internal/client/state"
@@ -156,7 +158,9 @@ func TestPromptCMD_Completer(t *testing.T) {
 			}
 
 			s := state.New()
-			s.IsAuth = tt.fields.isAuth
+			if tt.fields.isAuth {
+				s.SetUserToken("token")
+			}
 
 			p := New(s, commands)
 
@@ -252,3 +256,109 @@ func TestPromptCMD_parseCommand(t *testing.T) {
 		})
 	}
 }
+
+func TestPromptCMD_Executor_Locked(t *testing.T) {
+	t.Run("refuse auth commands while locked", func(t *testing.T) {
+		assert := assert.New(t)
+		s := state.New()
+		s.SetUserToken("token")
+		s.SetLocked(true)
+
+		var called []string
+
+		commands := []Command{
+			{
+				Command: "sync",
+				Run:     func(_ context.Context, _ []string) { called = append(called, "sync") },
+				Auth:    CommandAuthNeed,
+			},
+			{
+				Command: "unlock",
+				Run:     func(_ context.Context, _ []string) { called = append(called, "unlock") },
+				Auth:    CommandAuthLocked,
+			},
+		}
+
+		p := New(s, commands)
+
+		p.Executor("sync")
+		p.Executor("unlock 123")
+
+		assert.Equal([]string{"unlock"}, called)
+		assert.Equal([]prompt.Suggest{{Text: "unlock"}, {Text: "exit", Description: "Exit program"}}, p.Completer(prompt.Document{}))
+	})
+
+	t.Run("idle timeout locks", func(t *testing.T) {
+		s := state.New()
+		s.SetUserToken("token")
+
+		locked := make(chan struct{})
+
+		p := New(s, []Command{})
+		p.SetIdleLock(10*time.Millisecond, func() {
+			s.SetLocked(true)
+			close(locked)
+		})
+
+		select {
+		case <-locked:
+		case <-time.After(time.Second):
+			t.Fatal("vault wasn't locked after idle timeout")
+		}
+	})
+}
+
+func TestPromptCMD_LivePrefix(t *testing.T) {
+	tests := []struct {
+		name     string
+		isAuth   bool
+		status   StatusFunc
+		want     string
+		wantLive bool
+	}{
+		{
+			name:     "without status",
+			isAuth:   true,
+			status:   nil,
+			want:     "> ",
+			wantLive: false,
+		},
+		{
+			name:     "not auth",
+			isAuth:   false,
+			status:   func() string { return "2 pending" },
+			want:     "> ",
+			wantLive: false,
+		},
+		{
+			name:     "empty status",
+			isAuth:   true,
+			status:   func() string { return "" },
+			want:     "> ",
+			wantLive: false,
+		},
+		{
+			name:     "pending",
+			isAuth:   true,
+			status:   func() string { return "offline, 2 pending" },
+			want:     "[offline, 2 pending] > ",
+			wantLive: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := state.New()
+			if tt.isAuth {
+				s.SetUserToken("token")
+			}
+
+			p := New(s, []Command{})
+			p.SetStatus(tt.status)
+
+			got, gotLive := p.LivePrefix()
+
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantLive, gotLive)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+// Package qrterm - QR code of short text printed to terminal, it is used to import TOTP secret by phone
+package qrterm
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/skip2/go-qrcode"
+)
+
+const quietZone = 2
+
+// Print writes QR code of text by half blocks, two rows of modules per line.
+// Light modules are printed by blocks, so code is read from terminal with dark background
+func Print(w io.Writer, text string) error {
+	code, err := qrcode.New(text, qrcode.Medium)
+
+	if err != nil {
+		return err
+	}
+
+	// Quiet zone of library is 4 modules, the narrow one fits small terminals
+	code.DisableBorder = true
+
+	modules := code.Bitmap()
+	size := len(modules)
+
+	buf := bufio.NewWriter(w)
+
+	isLight := func(x, y int) bool {
+		if x < 0 || y < 0 || x >= size || y >= size {
+			return true
+		}
+
+		return !modules[y][x]
+	}
+
+	for y := -quietZone; y < size+quietZone; y += 2 {
+		for x := -quietZone; x < size+quietZone; x++ {
+			top, bottom := isLight(x, y), isLight(x, y+1)
+
+			switch {
+			case top && bottom:
+				_, _ = buf.WriteString("█")
+			case top:
+				_, _ = buf.WriteString("▀")
+			case bottom:
+				_, _ = buf.WriteString("▄")
+			default:
+				_, _ = buf.WriteString(" ")
+			}
+		}
+
+		_, _ = buf.WriteString("\n")
+	}
+
+	return buf.Flush()
+}
@@ -0,0 +1,60 @@
+package qrterm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/skip2/go-qrcode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrint(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		wantErr bool
+	}{
+		{
+			name: "TOTP URI",
+			text: "otpauth://totp/Gophkeeper:alex?secret=JBSWY3DPEHPK3PXP&issuer=Gophkeeper",
+		},
+		{
+			name:    "Too long",
+			text:    strings.Repeat("a", 3000),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+
+			err := Print(&out, tt.text)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+
+			code, err := qrcode.New(tt.text, qrcode.Medium)
+			require.NoError(t, err)
+
+			code.DisableBorder = true
+			size := len(code.Bitmap()) + quietZone*2
+
+			lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+
+			assert.Len(t, lines, (size+1)/2)
+
+			for _, line := range lines {
+				assert.Equal(t, size, len([]rune(line)))
+			}
+
+			assert.Equal(t, strings.Repeat("█", size), lines[0], "quiet zone is light")
+			assert.Equal(t, strings.Repeat("█", quietZone)+" ", string([]rune(lines[1])[:quietZone+1]), "finder pattern starts dark")
+		})
+	}
+}
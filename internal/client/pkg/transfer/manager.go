@@ -0,0 +1,253 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrNotFound = errors.New("transfer not found")
+
+var ErrFinished = errors.New("transfer is finished")
+
+// Job transfers data and reports progress, it must stop when ctx is done
+type Job func(ctx context.Context, progress *Progress) error
+
+type transfer struct {
+	id        int
+	direction Direction
+	name      string
+	progress  Progress
+	job       Job
+
+	state      State
+	err        error
+	startedAt  time.Time
+	finishedAt time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Manager runs transfers in background in order of start, at most parallel transfers run at once
+type Manager struct {
+	parallel int
+	onFinish func(Info)
+
+	mux       sync.Mutex
+	lastID    int
+	running   int
+	queue     []*transfer
+	transfers map[int]*transfer
+
+	wg sync.WaitGroup
+}
+
+// NewManager creates manager, onFinish is called for every finished transfer when set
+func NewManager(parallel int, onFinish func(Info)) *Manager {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	m := Manager{
+		parallel:  parallel,
+		onFinish:  onFinish,
+		transfers: make(map[int]*transfer),
+	}
+
+	return &m
+}
+
+// Start queues job and returns transfer ID
+func (m *Manager) Start(direction Direction, name string, total int64, job Job) int {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.lastID++
+
+	t := transfer{
+		id:        m.lastID,
+		direction: direction,
+		name:      name,
+		job:       job,
+		state:     StateQueued,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+	t.progress.SetTotal(total)
+
+	m.transfers[t.id] = &t
+	m.queue = append(m.queue, &t)
+	m.wg.Add(1)
+
+	m.dispatch()
+
+	return t.id
+}
+
+// dispatch runs queued transfers while there are free slots. Caller must hold m.mux
+func (m *Manager) dispatch() {
+	for m.running < m.parallel && len(m.queue) != 0 {
+		t := m.queue[0]
+		m.queue = m.queue[1:]
+
+		m.running++
+		t.state = StateRunning
+		t.startedAt = time.Now()
+
+		go m.run(t)
+	}
+}
+
+func (m *Manager) run(t *transfer) {
+	err := t.job(t.ctx, &t.progress)
+
+	if err != nil && t.ctx.Err() != nil {
+		err = t.ctx.Err()
+	}
+
+	m.mux.Lock()
+	m.running--
+	m.dispatch()
+	m.mux.Unlock()
+
+	m.finish(t, err)
+}
+
+func (m *Manager) finish(t *transfer, err error) {
+	defer m.wg.Done()
+
+	t.cancel()
+
+	m.mux.Lock()
+
+	t.finishedAt = time.Now()
+	t.err = err
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		t.state = StateCanceled
+	case err != nil:
+		t.state = StateFailed
+	default:
+		t.state = StateDone
+	}
+
+	info := t.info()
+	m.mux.Unlock()
+
+	if m.onFinish != nil {
+		m.onFinish(info)
+	}
+}
+
+// info returns snapshot. Caller must hold m.mux
+func (t *transfer) info() Info {
+	var elapsed time.Duration
+
+	if !t.startedAt.IsZero() {
+		end := t.finishedAt
+
+		if end.IsZero() {
+			end = time.Now()
+		}
+
+		elapsed = end.Sub(t.startedAt)
+	}
+
+	return Info{
+		ID:        t.id,
+		Direction: t.direction,
+		Name:      t.name,
+		State:     t.state,
+		Done:      t.progress.Done(),
+		Total:     t.progress.Total(),
+		Elapsed:   elapsed,
+		Err:       t.err,
+	}
+}
+
+// List returns snapshots of all transfers ordered by ID
+func (m *Manager) List() []Info {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	arr := make([]Info, 0, len(m.transfers))
+
+	for _, t := range m.transfers {
+		arr = append(arr, t.info())
+	}
+
+	sort.Slice(arr, func(i, j int) bool {
+		return arr[i].ID < arr[j].ID
+	})
+
+	return arr
+}
+
+// Cancel stops queued or running transfer
+func (m *Manager) Cancel(id int) error {
+	m.mux.Lock()
+
+	t, ok := m.transfers[id]
+
+	if !ok {
+		m.mux.Unlock()
+		return ErrNotFound
+	}
+
+	if t.state.IsFinished() {
+		m.mux.Unlock()
+		return ErrFinished
+	}
+
+	t.cancel()
+
+	isQueued := m.removeFromQueue(t)
+	m.mux.Unlock()
+
+	if isQueued {
+		m.finish(t, context.Canceled)
+	}
+
+	return nil
+}
+
+// removeFromQueue returns false when transfer is already running. Caller must hold m.mux
+func (m *Manager) removeFromQueue(t *transfer) bool {
+	for i, queued := range m.queue {
+		if queued == t {
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// Close cancels all transfers and waits them
+func (m *Manager) Close() {
+	m.mux.Lock()
+	ids := make([]int, 0, len(m.transfers))
+
+	for id := range m.transfers {
+		ids = append(ids, id)
+	}
+
+	m.mux.Unlock()
+
+	for _, id := range ids {
+		_ = m.Cancel(id)
+	}
+
+	m.wg.Wait()
+}
+
+// Wait waits all started transfers
+func (m *Manager) Wait() {
+	m.wg.Wait()
+}
@@ -0,0 +1,120 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_Start(t *testing.T) {
+	t.Run("parallel transfers are bounded", func(t *testing.T) {
+		var running, maxRunning int32
+
+		manager := NewManager(2, nil)
+
+		for i := 0; i < 6; i++ {
+			manager.Start(DirectionUpload, "file", 0, func(ctx context.Context, _ *Progress) error {
+				current := atomic.AddInt32(&running, 1)
+				defer atomic.AddInt32(&running, -1)
+
+				for {
+					max := atomic.LoadInt32(&maxRunning)
+
+					if current <= max || atomic.CompareAndSwapInt32(&maxRunning, max, current) {
+						break
+					}
+				}
+
+				time.Sleep(10 * time.Millisecond)
+
+				return nil
+			})
+		}
+
+		manager.Wait()
+
+		assert.Equal(t, int32(2), maxRunning)
+
+		for _, info := range manager.List() {
+			assert.Equal(t, StateDone, info.State)
+		}
+	})
+
+	t.Run("progress and error are reported", func(t *testing.T) {
+		require := require.New(t)
+		finished := make(chan Info, 1)
+
+		manager := NewManager(1, func(info Info) {
+			finished <- info
+		})
+
+		errTransfer := errors.New("broken")
+
+		id := manager.Start(DirectionDownload, "screen.png", 10, func(_ context.Context, progress *Progress) error {
+			_, err := io.Copy(progress.Writer(io.Discard), progress.Reader(bytes.NewReader(make([]byte, 4))))
+			require.NoError(err)
+
+			return errTransfer
+		})
+
+		info := <-finished
+
+		assert.Equal(t, id, info.ID)
+		assert.Equal(t, StateFailed, info.State)
+		assert.Equal(t, int64(8), info.Done)
+		assert.Equal(t, int64(10), info.Total)
+		assert.ErrorIs(t, info.Err, errTransfer)
+	})
+}
+
+func TestManager_Cancel(t *testing.T) {
+	require := require.New(t)
+	manager := NewManager(1, nil)
+
+	started := make(chan struct{})
+
+	running := manager.Start(DirectionUpload, "big", 0, func(ctx context.Context, _ *Progress) error {
+		close(started)
+		<-ctx.Done()
+
+		return errors.New("connection closed")
+	})
+	queued := manager.Start(DirectionUpload, "queued", 0, func(_ context.Context, _ *Progress) error {
+		return nil
+	})
+
+	<-started
+
+	require.NoError(manager.Cancel(queued))
+	require.NoError(manager.Cancel(running))
+	manager.Wait()
+
+	infos := manager.List()
+	require.Len(infos, 2)
+	assert.Equal(t, StateCanceled, infos[0].State)
+	assert.Equal(t, StateCanceled, infos[1].State)
+
+	assert.ErrorIs(t, manager.Cancel(running), ErrFinished)
+	assert.ErrorIs(t, manager.Cancel(42), ErrNotFound)
+}
+
+func TestInfo_String(t *testing.T) {
+	info := Info{
+		ID:        1,
+		Direction: DirectionUpload,
+		Name:      "screen.png",
+		State:     StateRunning,
+		Done:      5 << 20,
+		Total:     10 << 20,
+		Elapsed:   2 * time.Second,
+	}
+
+	assert.Equal(t, "ID: 1, upload screen.png, running, 5.0 MiB / 10.0 MiB (50%), 2.5 MiB/s", info.String())
+}
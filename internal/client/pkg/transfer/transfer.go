@@ -0,0 +1,150 @@
+package transfer
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+type Direction string
+
+const (
+	DirectionUpload   Direction = "upload"
+	DirectionDownload Direction = "download"
+)
+
+type State string
+
+const (
+	StateQueued   State = "queued"
+	StateRunning  State = "running"
+	StateDone     State = "done"
+	StateFailed   State = "failed"
+	StateCanceled State = "canceled"
+)
+
+func (s State) IsFinished() bool {
+	return s == StateDone || s == StateFailed || s == StateCanceled
+}
+
+// Progress counts bytes of one transfer, it is safe for concurrent use
+type Progress struct {
+	done  int64
+	total int64
+}
+
+// Add counts transferred bytes
+func (p *Progress) Add(n int64) {
+	atomic.AddInt64(&p.done, n)
+}
+
+// SetTotal sets expected size, zero means unknown size
+func (p *Progress) SetTotal(total int64) {
+	atomic.StoreInt64(&p.total, total)
+}
+
+func (p *Progress) Done() int64 {
+	return atomic.LoadInt64(&p.done)
+}
+
+func (p *Progress) Total() int64 {
+	return atomic.LoadInt64(&p.total)
+}
+
+// Reader counts bytes read from r
+func (p *Progress) Reader(r io.Reader) io.Reader {
+	return &progressReader{r: r, p: p}
+}
+
+// Writer counts bytes written to w
+func (p *Progress) Writer(w io.Writer) io.Writer {
+	return &progressWriter{w: w, p: p}
+}
+
+type progressReader struct {
+	r io.Reader
+	p *Progress
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.p.Add(int64(n))
+
+	return n, err
+}
+
+type progressWriter struct {
+	w io.Writer
+	p *Progress
+}
+
+func (w *progressWriter) Write(b []byte) (int, error) {
+	n, err := w.w.Write(b)
+	w.p.Add(int64(n))
+
+	return n, err
+}
+
+// Info is snapshot of transfer
+type Info struct {
+	ID        int
+	Direction Direction
+	Name      string
+	State     State
+	Done      int64
+	Total     int64
+	Elapsed   time.Duration
+	Err       error
+}
+
+// Throughput returns bytes per second while transfer was running
+func (i Info) Throughput() float64 {
+	if i.Elapsed <= 0 {
+		return 0
+	}
+
+	return float64(i.Done) / i.Elapsed.Seconds()
+}
+
+func (i Info) String() string {
+	progress := FormatBytes(i.Done)
+
+	if i.Total > 0 {
+		progress = fmt.Sprintf("%s / %s (%d%%)", progress, FormatBytes(i.Total), i.Done*100/i.Total)
+	}
+
+	line := fmt.Sprintf(
+		"ID: %v, %v %v, %v, %v, %v/s",
+		i.ID,
+		i.Direction,
+		i.Name,
+		i.State,
+		progress,
+		FormatBytes(int64(i.Throughput())),
+	)
+
+	if i.Err != nil {
+		line += fmt.Sprintf(", error: %v", i.Err)
+	}
+
+	return line
+}
+
+// FormatBytes returns size in binary units, e.g. 1.5 MiB
+func FormatBytes(n int64) string {
+	const unit = 1024
+
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
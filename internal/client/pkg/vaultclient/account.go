@@ -0,0 +1,105 @@
+package vaultclient
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultdata"
+	"github.com/shreyner/gophkeeper/proto"
+)
+
+// AccountExport calls apply for every page of account export, vaults come before blobs
+func (s *Client) AccountExport(ctx context.Context, apply func(*vaultdata.AccountExportPage) error) error {
+	if s.appState.GetUserToken() == "" {
+		return ErrNotAuth
+	}
+
+	ctxWithMetadata := s.outgoingContext(ctx)
+
+	stream, err := s.client.AccountExport(ctxWithMetadata, &empty.Empty{})
+
+	if err != nil {
+		return err
+	}
+
+	for {
+		page, err := stream.Recv()
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		blobs := make([]vaultdata.AccountBlob, 0, len(page.Blobs))
+
+		for _, b := range page.Blobs {
+			blob := vaultdata.AccountBlob{
+				ObjectName: b.ObjectName,
+				Size:       b.Size,
+				CreatedAt:  b.CreatedAt.AsTime(),
+				Location:   b.Location,
+			}
+
+			if b.VaultId != nil {
+				blob.VaultID = b.VaultId.Value
+			}
+
+			blobs = append(blobs, blob)
+		}
+
+		err = apply(&vaultdata.AccountExportPage{
+			Login:  page.Login,
+			Vaults: vaultsFromResponse(page.Vaults),
+			Blobs:  blobs,
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// AccountDelete removes account on server, code is required when two-factor authentication is enabled
+func (s *Client) AccountDelete(ctx context.Context, password, code string) error {
+	if s.appState.GetUserToken() == "" {
+		return ErrNotAuth
+	}
+
+	ctxWithMetadata := s.outgoingContext(ctx)
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctxWithMetadata, 5*time.Minute)
+	defer cancel()
+
+	request := proto.AccountDeleteRequest{
+		Password: password,
+		Code:     code,
+	}
+
+	_, err := s.client.AccountDelete(ctxWithTimeout, &request)
+
+	switch status.Code(err) {
+	case codes.OK:
+	case codes.FailedPrecondition:
+		return ErrSecondFactorRequired
+	case codes.PermissionDenied:
+		// Message of wrong code equals to message of server
+		if status.Convert(err).Message() == ErrInvalidSecondFactor.Error() {
+			return ErrInvalidSecondFactor
+		}
+
+		return ErrInvalidCredentials
+	default:
+		return err
+	}
+
+	return nil
+}
@@ -5,13 +5,17 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"strings"
+	"os"
+	"runtime"
+	"sync"
 	"time"
 
 	"github.com/golang/protobuf/ptypes/empty"
 	"github.com/shreyner/gophkeeper/internal/client/config"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 
 	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultdata"
@@ -22,13 +26,28 @@ var (
 	_ VClient = (*Client)(nil)
 )
 
+const (
+	syncPageSize         = 100
+	maxSyncStreamResumes = 3
+)
+
 type Client struct {
 	appState   vaultdata.State
 	hostREST   string
 	httpClient *http.Client
+	deviceName string
+
+	client proto.GophkeeperClient
+
+	tokenMux sync.RWMutex
+	token    string // access token sent with every call
+
+	refreshMux   sync.Mutex
+	refreshToken string
+	challenge    string // login waits for second factor code
 
-	client   proto.GophkeeperClient
-	metadata metadata.MD
+	uploadChunkSize int
+	retryDelay      time.Duration
 }
 
 func New(cfg *config.Config, appState vaultdata.State, client proto.GophkeeperClient) *Client {
@@ -36,46 +55,236 @@ func New(cfg *config.Config, appState vaultdata.State, client proto.GophkeeperCl
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: cfg.Insecure},
 	}
 
-	httpClient := http.Client{
-		Transport: &tr,
-	}
+	httpClient := http.Client{}
 
 	s := Client{
 		appState:   appState,
 		client:     client,
 		hostREST:   cfg.HostREST,
 		httpClient: &httpClient,
+		deviceName: cfg.DeviceName,
+
+		uploadChunkSize: defaultUploadChunkSize,
+		retryDelay:      defaultRetryDelay,
+	}
+
+	if s.deviceName == "" {
+		s.deviceName, _ = os.Hostname()
 	}
 
-	s.metadata = metadata.New(map[string]string{})
+	s.httpClient.Transport = &refreshTransport{base: &tr, client: &s}
 
 	return &s
 }
 
+func (s *Client) Register(ctx context.Context, login, password, inviteCode string) error {
+	request := proto.RegisterRequest{
+		Login:          login,
+		Password:       password,
+		InviteCode:     inviteCode,
+		DeviceName:     s.deviceName,
+		DevicePlatform: devicePlatform(),
+	}
+
+	registerResponse, err := s.client.Register(ctx, &request)
+
+	if status.Code(err) == codes.AlreadyExists {
+		return ErrLoginAlreadyExist
+	}
+
+	if err != nil {
+		return err
+	}
+
+	s.startSession(registerResponse)
+
+	return nil
+}
+
 func (s *Client) Login(ctx context.Context, login, password string) error {
 	request := proto.LoginRequest{
-		Login:    login,
-		Password: password,
+		Login:          login,
+		Password:       password,
+		DeviceName:     s.deviceName,
+		DevicePlatform: devicePlatform(),
 	}
 
 	loginResponse, err := s.client.Login(ctx, &request)
 
+	if status.Code(err) == codes.PermissionDenied {
+		return ErrInvalidCredentials
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if loginResponse.SecondFactorRequired {
+		s.refreshMux.Lock()
+		s.challenge = loginResponse.Challenge
+		s.refreshMux.Unlock()
+
+		return ErrSecondFactorRequired
+	}
+
+	s.startSession(loginResponse)
+
+	return nil
+}
+
+// LoginSecondFactor finishes login started by Login with code of authenticator app or backup code
+func (s *Client) LoginSecondFactor(ctx context.Context, code string) error {
+	s.refreshMux.Lock()
+	challenge := s.challenge
+	s.refreshMux.Unlock()
+
+	if challenge == "" {
+		return ErrNoLoginChallenge
+	}
+
+	request := proto.LoginSecondFactorRequest{
+		Challenge:      challenge,
+		Code:           code,
+		DeviceName:     s.deviceName,
+		DevicePlatform: devicePlatform(),
+	}
+
+	loginResponse, err := s.client.LoginSecondFactor(ctx, &request)
+
+	if status.Code(err) == codes.PermissionDenied {
+		return ErrInvalidSecondFactor
+	}
+
 	if err != nil {
+		if status.Code(err) == codes.Unauthenticated {
+			s.refreshMux.Lock()
+			s.challenge = ""
+			s.refreshMux.Unlock()
+		}
+
 		return err
 	}
 
-	s.metadata.Set("token", loginResponse.AuthToken)
-	s.appState.SetUserToken(loginResponse.AuthToken)
+	s.refreshMux.Lock()
+	defer s.refreshMux.Unlock()
+
+	s.challenge = ""
+	s.setTokens(loginResponse)
 
 	return nil
 }
 
+func (s *Client) startSession(response *proto.LoginResponse) {
+	s.refreshMux.Lock()
+	defer s.refreshMux.Unlock()
+
+	s.setTokens(response)
+}
+
+// setTokens saves access and refresh tokens. Caller must hold s.refreshMux
+func (s *Client) setTokens(response *proto.LoginResponse) {
+	s.refreshToken = response.RefreshToken
+	s.setToken(response.AuthToken)
+	s.appState.SetUserToken(response.AuthToken)
+}
+
+// Refresh exchanges refresh token to new access token. Concurrent calls with the same expired token
+// refresh it once. Dead session is closed and ErrSessionExpired is returned, so user can login again
+func (s *Client) Refresh(ctx context.Context, expiredToken string) (string, error) {
+	s.refreshMux.Lock()
+	defer s.refreshMux.Unlock()
+
+	if token := s.appState.GetUserToken(); token != expiredToken && token != "" {
+		return token, nil
+	}
+
+	if s.refreshToken == "" {
+		return "", ErrNotAuth
+	}
+
+	request := proto.RefreshTokenRequest{
+		RefreshToken: s.refreshToken,
+	}
+
+	response, err := s.client.RefreshToken(ctx, &request)
+
+	if status.Code(err) == codes.Unauthenticated && !IsDeviceRevoked(err) {
+		s.refreshToken = ""
+		s.setToken("")
+		s.appState.Logout()
+
+		return "", ErrSessionExpired
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	s.setTokens(response)
+
+	return response.AuthToken, nil
+}
+
+func devicePlatform() string {
+	return runtime.GOOS + "/" + runtime.GOARCH
+}
+
+// InviteCreate asks server for invite code, only admin can create it
+func (s *Client) InviteCreate(ctx context.Context) (string, time.Time, error) {
+	if s.appState.GetUserToken() == "" {
+		return "", time.Time{}, ErrNotAuth
+	}
+
+	ctxWithMetadata := s.outgoingContext(ctx)
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctxWithMetadata, 30*time.Second)
+	defer cancel()
+
+	response, err := s.client.InviteCreate(ctxWithTimeout, &empty.Empty{})
+
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return response.Code, response.ExpiresAt.AsTime(), nil
+}
+
+// Logout stops sending tokens of current session, app state is reset by caller
+func (s *Client) Logout() {
+	s.refreshMux.Lock()
+	defer s.refreshMux.Unlock()
+
+	s.refreshToken = ""
+	s.setToken("")
+}
+
+func (s *Client) setToken(token string) {
+	s.tokenMux.Lock()
+	defer s.tokenMux.Unlock()
+
+	s.token = token
+}
+
+// outgoingContext attaches access token to ctx. Metadata is built for every call,
+// so calls never share it with token updates
+func (s *Client) outgoingContext(ctx context.Context) context.Context {
+	s.tokenMux.RLock()
+	token := s.token
+	s.tokenMux.RUnlock()
+
+	if token == "" {
+		return metadata.NewOutgoingContext(ctx, metadata.MD{})
+	}
+
+	return metadata.NewOutgoingContext(ctx, metadata.Pairs("token", token))
+}
+
 func (s *Client) Check(ctx context.Context) error {
 	if s.appState.GetUserToken() == "" {
 		return ErrNotAuth
 	}
 
-	ctxWithMetadata := metadata.NewOutgoingContext(ctx, s.metadata)
+	ctxWithMetadata := s.outgoingContext(ctx)
 
 	response, err := s.client.CheckAuth(ctxWithMetadata, &empty.Empty{})
 
@@ -88,12 +297,38 @@ func (s *Client) Check(ctx context.Context) error {
 	return nil
 }
 
+func (s *Client) Usage(ctx context.Context) (*vaultdata.Usage, error) {
+	if s.appState.GetUserToken() == "" {
+		return nil, ErrNotAuth
+	}
+
+	ctxWithMetadata := s.outgoingContext(ctx)
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctxWithMetadata, 30*time.Second)
+	defer cancel()
+
+	response, err := s.client.Usage(ctxWithTimeout, &empty.Empty{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	usage := vaultdata.Usage{
+		BlobBytes:    response.BlobBytes,
+		MaxBlobBytes: response.MaxBlobBytes,
+		Items:        response.Items,
+		MaxItems:     response.MaxItems,
+	}
+
+	return &usage, nil
+}
+
 func (s *Client) VaultSync(ctx context.Context, vaultSync []vaultdata.VaultSyncVersion) ([]vaultdata.VaultSyncData, error) {
 	if s.appState.GetUserToken() == "" {
 		return nil, ErrNotAuth
 	}
 
-	ctxWithMetadata := metadata.NewOutgoingContext(ctx, s.metadata)
+	ctxWithMetadata := s.outgoingContext(ctx)
 
 	arrRequest := make([]*proto.VaultSyncRequest_VaultVersion, 0, len(vaultSync))
 
@@ -119,9 +354,140 @@ func (s *Client) VaultSync(ctx context.Context, vaultSync []vaultdata.VaultSyncV
 		return nil, err
 	}
 
-	resultArr := make([]vaultdata.VaultSyncData, 0, len(response.UpdatedVaults))
+	return vaultsFromResponse(response.UpdatedVaults), nil
+}
+
+// VaultChanges loads vaults changed after cursor and returns cursor for the next call
+func (s *Client) VaultChanges(ctx context.Context, cursor int64) ([]vaultdata.VaultSyncData, int64, error) {
+	if s.appState.GetUserToken() == "" {
+		return nil, 0, ErrNotAuth
+	}
+
+	ctxWithMetadata := s.outgoingContext(ctx)
 
-	for _, v := range response.UpdatedVaults {
+	request := proto.VaultChangesRequest{
+		SinceCursor: cursor,
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctxWithMetadata, 30*time.Second)
+	defer cancel()
+
+	response, err := s.client.VaultChanges(ctxWithTimeout, &request)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return vaultsFromResponse(response.Vaults), response.Cursor, nil
+}
+
+// VaultSyncStream calls apply for every page of vaults changed after cursor as soon as page arrives.
+// Interrupted stream is resumed from the last applied page by continuation token
+func (s *Client) VaultSyncStream(ctx context.Context, cursor int64, apply func([]vaultdata.VaultSyncData, int64) error) error {
+	if s.appState.GetUserToken() == "" {
+		return ErrNotAuth
+	}
+
+	ctxWithMetadata := s.outgoingContext(ctx)
+
+	request := proto.VaultSyncStreamRequest{
+		SinceCursor: cursor,
+		PageSize:    syncPageSize,
+	}
+
+	for resumes := 0; ; resumes++ {
+		err := s.recvSyncPages(ctxWithMetadata, &request, apply)
+
+		if err == nil {
+			return nil
+		}
+
+		isResumable := request.PageToken != "" && status.Code(err) == codes.Unavailable
+
+		// Expired token is already refreshed by interceptor, metadata has the new one
+		if IsTokenExpired(err) {
+			isResumable = true
+		}
+
+		if !isResumable || resumes >= maxSyncStreamResumes || ctx.Err() != nil {
+			return err
+		}
+	}
+}
+
+// recvSyncPages applies pages until the last one and remembers token of the next page in request
+func (s *Client) recvSyncPages(ctx context.Context, request *proto.VaultSyncStreamRequest, apply func([]vaultdata.VaultSyncData, int64) error) error {
+	stream, err := s.client.VaultSyncStream(ctx, request)
+
+	if err != nil {
+		return err
+	}
+
+	for {
+		page, err := stream.Recv()
+
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		err = apply(vaultsFromResponse(page.Vaults), page.Cursor)
+
+		if err != nil {
+			return err
+		}
+
+		request.PageToken = page.NextPageToken
+
+		if page.NextPageToken == "" {
+			return nil
+		}
+	}
+}
+
+// WatchVaults calls apply for every change pushed by server until stream is closed
+func (s *Client) WatchVaults(ctx context.Context, cursor int64, apply func([]vaultdata.VaultSyncData, int64) error) error {
+	token := s.appState.GetUserToken()
+
+	if token == "" {
+		return ErrNotAuth
+	}
+
+	// Stream lives in background, so metadata is not shared with prompt requests
+	ctxWithMetadata := metadata.NewOutgoingContext(ctx, metadata.Pairs("token", token))
+
+	request := proto.VaultChangesRequest{
+		SinceCursor: cursor,
+	}
+
+	stream, err := s.client.WatchVaults(ctxWithMetadata, &request)
+
+	if err != nil {
+		return err
+	}
+
+	for {
+		response, err := stream.Recv()
+
+		if err != nil {
+			return err
+		}
+
+		err = apply(vaultsFromResponse(response.Vaults), response.Cursor)
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func vaultsFromResponse(vaults []*proto.VaultSyncResponse_Vault) []vaultdata.VaultSyncData {
+	resultArr := make([]vaultdata.VaultSyncData, 0, len(vaults))
+
+	for _, v := range vaults {
 		var s3URL string
 
 		if v.S3 != nil {
@@ -139,7 +505,7 @@ func (s *Client) VaultSync(ctx context.Context, vaultSync []vaultdata.VaultSyncV
 		resultArr = append(resultArr, data)
 	}
 
-	return resultArr, nil
+	return resultArr
 }
 
 func (s *Client) VaultCreate(ctx context.Context, encryptedVault []byte, s3URL string) (*vaultdata.VaultClientSyncResult, error) {
@@ -147,7 +513,7 @@ func (s *Client) VaultCreate(ctx context.Context, encryptedVault []byte, s3URL s
 		return nil, ErrNotAuth
 	}
 
-	ctxWithMetadata := metadata.NewOutgoingContext(ctx, s.metadata)
+	ctxWithMetadata := s.outgoingContext(ctx)
 
 	var s3URLRequest *wrapperspb.StringValue
 
@@ -165,6 +531,10 @@ func (s *Client) VaultCreate(ctx context.Context, encryptedVault []byte, s3URL s
 
 	response, err := s.client.VaultCreate(ctxWithTimeout, &request)
 
+	if status.Code(err) == codes.ResourceExhausted {
+		return nil, ErrQuotaExceeded
+	}
+
 	if err != nil {
 		return nil, err
 	}
@@ -177,120 +547,141 @@ func (s *Client) VaultCreate(ctx context.Context, encryptedVault []byte, s3URL s
 	return &d, nil
 }
 
-func (s *Client) VaultUpdate(ctx context.Context, id string, version int, encryptedVault []byte) (*vaultdata.VaultClientSyncResult, error) {
+// VaultBatch applies operations in one server transaction, results are in the order of operations
+func (s *Client) VaultBatch(ctx context.Context, operations []vaultdata.VaultBatchOperation) ([]vaultdata.VaultBatchResult, error) {
 	if s.appState.GetUserToken() == "" {
 		return nil, ErrNotAuth
 	}
 
-	ctxWithMetadata := metadata.NewOutgoingContext(ctx, s.metadata)
+	ctxWithMetadata := s.outgoingContext(ctx)
 
-	request := proto.VaultUpdateRequest{
-		Id:      id,
-		Vault:   encryptedVault,
-		Version: int32(version),
+	requestOperations := make([]*proto.VaultBatchRequest_Operation, 0, len(operations))
+
+	for _, operation := range operations {
+		requestOperation := proto.VaultBatchRequest_Operation{
+			Id:      operation.ID,
+			Vault:   operation.Vault,
+			Version: int32(operation.Version),
+		}
+
+		switch operation.Type {
+		case vaultdata.VaultBatchCreate:
+			requestOperation.Type = proto.VaultBatchRequest_Operation_CREATE
+		case vaultdata.VaultBatchUpdate:
+			requestOperation.Type = proto.VaultBatchRequest_Operation_UPDATE
+		case vaultdata.VaultBatchDelete:
+			requestOperation.Type = proto.VaultBatchRequest_Operation_DELETE
+		}
+
+		if operation.S3URL != "" {
+			requestOperation.S3 = wrapperspb.String(operation.S3URL)
+		}
+
+		requestOperations = append(requestOperations, &requestOperation)
+	}
+
+	request := proto.VaultBatchRequest{
+		Operations: requestOperations,
 	}
 
 	ctxWithTimeout, cancel := context.WithTimeout(ctxWithMetadata, 30*time.Second)
 	defer cancel()
 
-	response, err := s.client.VaultUpdate(ctxWithTimeout, &request)
+	response, err := s.client.VaultBatch(ctxWithTimeout, &request)
+
+	if status.Code(err) == codes.ResourceExhausted {
+		return nil, ErrQuotaExceeded
+	}
 
 	if err != nil {
 		return nil, err
 	}
 
-	d := vaultdata.VaultClientSyncResult{
-		ID:      id,
-		Version: int(response.Version),
+	if len(response.Results) != len(operations) {
+		return nil, ErrInvalidBatchResponse
 	}
 
-	return &d, nil
-}
+	results := make([]vaultdata.VaultBatchResult, 0, len(response.Results))
 
-func (s *Client) VaultDelete(ctx context.Context, id string, version int) error {
-	if s.appState.GetUserToken() == "" {
-		return ErrNotAuth
+	for _, r := range response.Results {
+		result := vaultdata.VaultBatchResult{
+			ID:         r.Id,
+			Version:    int(r.Version),
+			IsConflict: r.Status == proto.VaultBatchResponse_Result_CONFLICT,
+			IsNotFound: r.Status == proto.VaultBatchResponse_Result_NOT_FOUND,
+		}
+
+		results = append(results, result)
 	}
 
-	ctxWithMetadata := metadata.NewOutgoingContext(ctx, s.metadata)
+	return results, nil
+}
 
-	request := proto.VaultDeleteRequest{
-		Id:      id,
-		Version: int32(version),
+func (s *Client) VaultUpdate(ctx context.Context, id string, version int, encryptedVault []byte, s3URL string) (*vaultdata.VaultClientSyncResult, error) {
+	if s.appState.GetUserToken() == "" {
+		return nil, ErrNotAuth
 	}
 
-	ctxWithTimeout, cancel := context.WithTimeout(ctxWithMetadata, 30*time.Second)
-	defer cancel()
+	ctxWithMetadata := s.outgoingContext(ctx)
 
-	_, err := s.client.VaultDelete(ctxWithTimeout, &request)
+	var s3URLRequest *wrapperspb.StringValue
 
-	if err != nil {
-		return err
+	if s3URL != "" {
+		s3URLRequest = wrapperspb.String(s3URL)
 	}
 
-	return nil
-}
-
-func (s *Client) VaultUpload(ctx context.Context, r io.Reader) (string, error) {
-	if s.appState.GetUserToken() == "" {
-		return "", ErrNotAuth
+	request := proto.VaultUpdateRequest{
+		Id:      id,
+		Vault:   encryptedVault,
+		Version: int32(version),
+		S3:      s3URLRequest,
 	}
 
-	ctxWithTimeout, cancel := context.WithTimeout(ctx, 30*time.Second)
+	ctxWithTimeout, cancel := context.WithTimeout(ctxWithMetadata, 30*time.Second)
 	defer cancel()
 
-	request, err := http.NewRequestWithContext(
-		ctxWithTimeout,
-		http.MethodPut,
-		fmt.Sprintf("%s/upload", s.hostREST),
-		r,
-	)
+	response, err := s.client.VaultUpdate(ctxWithTimeout, &request)
 
-	if err != nil {
-		return "", err
+	if status.Code(err) == codes.AlreadyExists {
+		return nil, ErrVaultConflict
 	}
 
-	request.Header.Set("Authorization", s.appState.GetUserToken())
-	request.Header.Set("Content-Type", "application/octet-stream")
-
-	response, err := s.httpClient.Do(request)
-
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	defer response.Body.Close()
-
-	respBytes, err := io.ReadAll(response.Body)
-
-	if err != nil {
-		return "", err
+	d := vaultdata.VaultClientSyncResult{
+		ID:      id,
+		Version: int(response.Version),
 	}
 
-	return strings.TrimSpace(string(respBytes)), nil
+	return &d, nil
 }
 
-func (s *Client) VaultDownload(ctx context.Context, url string) (io.ReadCloser, error) {
+func (s *Client) VaultDelete(ctx context.Context, id string, version int) error {
 	if s.appState.GetUserToken() == "" {
-		return nil, ErrNotAuth
+		return ErrNotAuth
 	}
 
-	request, err := http.NewRequestWithContext(
-		ctx,
-		http.MethodGet,
-		url,
-		nil,
-	)
+	ctxWithMetadata := s.outgoingContext(ctx)
 
-	if err != nil {
-		return nil, err
+	request := proto.VaultDeleteRequest{
+		Id:      id,
+		Version: int32(version),
 	}
 
-	response, err := http.DefaultClient.Do(request)
+	ctxWithTimeout, cancel := context.WithTimeout(ctxWithMetadata, 30*time.Second)
+	defer cancel()
+
+	_, err := s.client.VaultDelete(ctxWithTimeout, &request)
+
+	if status.Code(err) == codes.AlreadyExists {
+		return ErrVaultConflict
+	}
 
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return response.Body, nil
+	return nil
 }
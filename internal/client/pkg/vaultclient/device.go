@@ -0,0 +1,83 @@
+package vaultclient
+
+import (
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"golang.org/x/net/context"
+
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultdata"
+	"github.com/shreyner/gophkeeper/proto"
+)
+
+func (s *Client) DeviceList(ctx context.Context) ([]vaultdata.Device, error) {
+	if s.appState.GetUserToken() == "" {
+		return nil, ErrNotAuth
+	}
+
+	ctxWithMetadata := s.outgoingContext(ctx)
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctxWithMetadata, 30*time.Second)
+	defer cancel()
+
+	response, err := s.client.DeviceList(ctxWithTimeout, &empty.Empty{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	devices := make([]vaultdata.Device, 0, len(response.Devices))
+
+	for _, d := range response.Devices {
+		devices = append(devices, vaultdata.Device{
+			ID:             d.Id,
+			Name:           d.Name,
+			Platform:       d.Platform,
+			CreatedAt:      d.CreatedAt.AsTime(),
+			LastSeenAt:     d.LastSeenAt.AsTime(),
+			LastSyncCursor: d.LastSyncCursor,
+			IsCurrent:      d.IsCurrent,
+		})
+	}
+
+	return devices, nil
+}
+
+func (s *Client) DeviceRename(ctx context.Context, id, name string) error {
+	if s.appState.GetUserToken() == "" {
+		return ErrNotAuth
+	}
+
+	ctxWithMetadata := s.outgoingContext(ctx)
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctxWithMetadata, 30*time.Second)
+	defer cancel()
+
+	request := proto.DeviceRenameRequest{
+		Id:   id,
+		Name: name,
+	}
+
+	_, err := s.client.DeviceRename(ctxWithTimeout, &request)
+
+	return err
+}
+
+func (s *Client) DeviceRevoke(ctx context.Context, id string) error {
+	if s.appState.GetUserToken() == "" {
+		return ErrNotAuth
+	}
+
+	ctxWithMetadata := s.outgoingContext(ctx)
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctxWithMetadata, 30*time.Second)
+	defer cancel()
+
+	request := proto.DeviceRevokeRequest{
+		Id: id,
+	}
+
+	_, err := s.client.DeviceRevoke(ctxWithTimeout, &request)
+
+	return err
+}
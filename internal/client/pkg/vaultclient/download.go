@@ -0,0 +1,161 @@
+package vaultclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+const maxDownloadRetries = 5
+
+type downloadStatusError struct {
+	status int
+}
+
+func (e *downloadStatusError) Error() string {
+	return fmt.Sprintf("download failed with status %d", e.status)
+}
+
+// VaultDownload streams uploaded object through server, server checks that object belongs to user.
+// Dropped connection is continued from read offset by range request
+func (s *Client) VaultDownload(ctx context.Context, s3URL string) (io.ReadCloser, error) {
+	if s.appState.GetUserToken() == "" {
+		return nil, ErrNotAuth
+	}
+
+	download := blobDownload{
+		ctx:    ctx,
+		client: s,
+		url:    fmt.Sprintf("%s/blobs/%s", s.hostREST, url.PathEscape(blobObjectName(s3URL))),
+	}
+
+	err := download.open()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &download, nil
+}
+
+// blobObjectName returns object name from location of uploaded object, object name is the last path segment
+func blobObjectName(s3URL string) string {
+	return s3URL[strings.LastIndex(s3URL, "/")+1:]
+}
+
+type blobDownload struct {
+	ctx    context.Context
+	client *Client
+	url    string
+
+	body    io.ReadCloser
+	offset  int64
+	retries int
+}
+
+func (d *blobDownload) Read(p []byte) (int, error) {
+	for {
+		n, err := d.body.Read(p)
+		d.offset += int64(n)
+
+		// Body returns the same error again on the next read
+		if err == nil || errors.Is(err, io.EOF) || n != 0 {
+			return n, err
+		}
+
+		if !isRetryableDownloadError(d.ctx, err) || d.retries >= maxDownloadRetries {
+			return 0, err
+		}
+
+		err = d.reopen()
+
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (d *blobDownload) Close() error {
+	return d.body.Close()
+}
+
+func (d *blobDownload) reopen() error {
+	_ = d.body.Close()
+
+	for {
+		d.retries++
+
+		select {
+		case <-d.ctx.Done():
+			return d.ctx.Err()
+		case <-time.After(d.client.retryDelay * time.Duration(d.retries)):
+		}
+
+		err := d.open()
+
+		if err == nil || !isRetryableDownloadError(d.ctx, err) || d.retries >= maxDownloadRetries {
+			return err
+		}
+	}
+}
+
+// open requests object from current offset
+func (d *blobDownload) open() error {
+	request, err := http.NewRequestWithContext(d.ctx, http.MethodGet, d.url, nil)
+
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Authorization", d.client.appState.GetUserToken())
+
+	expectedStatus := http.StatusOK
+
+	if d.offset != 0 {
+		request.Header.Set("Range", fmt.Sprintf("bytes=%d-", d.offset))
+		expectedStatus = http.StatusPartialContent
+	}
+
+	response, err := d.client.httpClient.Do(request)
+
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode != expectedStatus {
+		_ = response.Body.Close()
+
+		if response.StatusCode == http.StatusNotFound {
+			return ErrBlobNotFound
+		}
+
+		return &downloadStatusError{status: response.StatusCode}
+	}
+
+	d.body = response.Body
+
+	return nil
+}
+
+func isRetryableDownloadError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var statusErr *downloadStatusError
+
+	if errors.As(err, &statusErr) {
+		return statusErr.status >= http.StatusInternalServerError
+	}
+
+	var netErr net.Error
+
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
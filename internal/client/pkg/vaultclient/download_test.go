@@ -0,0 +1,108 @@
+package vaultclient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// fakeBlobServer serves one object and drops connection of chosen requests after half of response
+type fakeBlobServer struct {
+	mux sync.Mutex
+
+	data     []byte
+	requests int
+	dropAt   map[int]bool
+	ranges   []string
+}
+
+func (f *fakeBlobServer) ServeHTTP(wr http.ResponseWriter, r *http.Request) {
+	f.mux.Lock()
+	f.requests++
+	request := f.requests
+	f.ranges = append(f.ranges, r.Header.Get("Range"))
+	f.mux.Unlock()
+
+	if r.URL.Path != "/blobs/object" || r.Header.Get("Authorization") != "token" {
+		wr.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if !f.dropAt[request] {
+		http.ServeContent(wr, r, "object", time.Time{}, bytes.NewReader(f.data))
+		return
+	}
+
+	offset := 0
+
+	if rangeHeader := r.Header.Get("Range"); rangeHeader != "" {
+		offset, _ = strconv.Atoi(rangeHeader[len("bytes=") : len(rangeHeader)-1])
+		wr.Header().Set("Content-Range", "bytes "+strconv.Itoa(offset)+"-"+strconv.Itoa(len(f.data)-1)+"/"+strconv.Itoa(len(f.data)))
+		wr.Header().Set("Content-Length", strconv.Itoa(len(f.data)-offset))
+		wr.WriteHeader(http.StatusPartialContent)
+	} else {
+		wr.Header().Set("Content-Length", strconv.Itoa(len(f.data)))
+		wr.WriteHeader(http.StatusOK)
+	}
+
+	part := f.data[offset : offset+(len(f.data)-offset)/2]
+	_, _ = wr.Write(part)
+	wr.(http.Flusher).Flush()
+
+	conn, _, _ := wr.(http.Hijacker).Hijack()
+	_ = conn.Close()
+}
+
+func TestClient_VaultDownload(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+
+	tests := []struct {
+		name       string
+		dropAt     map[int]bool
+		wantRanges int
+	}{
+		{
+			name: "Without failures",
+		},
+		{
+			name:       "Dropped connections are continued by range",
+			dropAt:     map[int]bool{1: true, 2: true},
+			wantRanges: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+			server := fakeBlobServer{data: data, dropAt: tt.dropAt}
+			client := newTestClient(t, &server)
+
+			body, err := client.VaultDownload(context.Background(), "http://localhost:9000/vault/object")
+			require.NoError(err)
+
+			downloaded, err := io.ReadAll(body)
+			require.NoError(err)
+			require.NoError(body.Close())
+
+			assert.Equal(t, data, downloaded)
+			assert.Equal(t, tt.wantRanges+1, server.requests)
+			assert.Empty(t, server.ranges[0])
+		})
+	}
+
+	t.Run("Object of other user is not found", func(t *testing.T) {
+		server := fakeBlobServer{data: data}
+		client := newTestClient(t, &server)
+
+		_, err := client.VaultDownload(context.Background(), "http://localhost:9000/vault/other")
+
+		assert.ErrorIs(t, err, ErrBlobNotFound)
+	})
+}
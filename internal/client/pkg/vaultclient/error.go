@@ -3,3 +3,25 @@ package vaultclient
 import "errors"
 
 var ErrNotAuth = errors.New("Not authorized")
+
+var ErrInvalidCredentials = errors.New("invalid login or password")
+
+var ErrSecondFactorRequired = errors.New("second factor required")
+
+var ErrNoLoginChallenge = errors.New("login with password first")
+
+var ErrInvalidSecondFactor = errors.New("invalid two-factor code")
+
+var ErrSessionExpired = errors.New("session expired, login again")
+
+var ErrLoginAlreadyExist = errors.New("login already exist")
+
+var ErrVaultConflict = errors.New("vault conflict")
+
+var ErrInvalidBatchResponse = errors.New("invalid batch response")
+
+var ErrUploadOffset = errors.New("upload offset mismatch")
+
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+var ErrBlobNotFound = errors.New("uploaded file not found")
@@ -9,12 +9,18 @@ import (
 )
 
 type VClient interface {
+	Register(ctx context.Context, login, password, inviteCode string) error
 	Login(ctx context.Context, login, password string) error
 	Check(ctx context.Context) error
+	Usage(ctx context.Context) (*vaultdata.Usage, error)
 	VaultSync(ctx context.Context, vaultSync []vaultdata.VaultSyncVersion) ([]vaultdata.VaultSyncData, error)
+	VaultChanges(ctx context.Context, cursor int64) ([]vaultdata.VaultSyncData, int64, error)
+	VaultSyncStream(ctx context.Context, cursor int64, apply func([]vaultdata.VaultSyncData, int64) error) error
+	WatchVaults(ctx context.Context, cursor int64, apply func([]vaultdata.VaultSyncData, int64) error) error
 	VaultCreate(ctx context.Context, encryptedVault []byte, s3URL string) (*vaultdata.VaultClientSyncResult, error)
-	VaultUpdate(ctx context.Context, id string, version int, encryptedVault []byte) (*vaultdata.VaultClientSyncResult, error)
+	VaultUpdate(ctx context.Context, id string, version int, encryptedVault []byte, s3URL string) (*vaultdata.VaultClientSyncResult, error)
 	VaultDelete(ctx context.Context, id string, version int) error
+	VaultBatch(ctx context.Context, operations []vaultdata.VaultBatchOperation) ([]vaultdata.VaultBatchResult, error)
 	VaultUpload(ctx context.Context, r io.Reader) (string, error)
 	VaultDownload(ctx context.Context, url string) (io.ReadCloser, error)
 }
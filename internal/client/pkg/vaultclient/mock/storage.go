@@ -64,6 +64,66 @@ func (mr *MockVClientMockRecorder) Login(ctx, login, password interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockVClient)(nil).Login), ctx, login, password)
 }
 
+// Register mocks base method.
+func (m *MockVClient) Register(ctx context.Context, login, password, inviteCode string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Register", ctx, login, password, inviteCode)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Register indicates an expected call of Register.
+func (mr *MockVClientMockRecorder) Register(ctx, login, password, inviteCode interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Register", reflect.TypeOf((*MockVClient)(nil).Register), ctx, login, password, inviteCode)
+}
+
+// Usage mocks base method.
+func (m *MockVClient) Usage(ctx context.Context) (*vaultdata.Usage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Usage", ctx)
+	ret0, _ := ret[0].(*vaultdata.Usage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Usage indicates an expected call of Usage.
+func (mr *MockVClientMockRecorder) Usage(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Usage", reflect.TypeOf((*MockVClient)(nil).Usage), ctx)
+}
+
+// VaultBatch mocks base method.
+func (m *MockVClient) VaultBatch(ctx context.Context, operations []vaultdata.VaultBatchOperation) ([]vaultdata.VaultBatchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VaultBatch", ctx, operations)
+	ret0, _ := ret[0].([]vaultdata.VaultBatchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VaultBatch indicates an expected call of VaultBatch.
+func (mr *MockVClientMockRecorder) VaultBatch(ctx, operations interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VaultBatch", reflect.TypeOf((*MockVClient)(nil).VaultBatch), ctx, operations)
+}
+
+// VaultChanges mocks base method.
+func (m *MockVClient) VaultChanges(ctx context.Context, cursor int64) ([]vaultdata.VaultSyncData, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VaultChanges", ctx, cursor)
+	ret0, _ := ret[0].([]vaultdata.VaultSyncData)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// VaultChanges indicates an expected call of VaultChanges.
+func (mr *MockVClientMockRecorder) VaultChanges(ctx, cursor interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VaultChanges", reflect.TypeOf((*MockVClient)(nil).VaultChanges), ctx, cursor)
+}
+
 // VaultCreate mocks base method.
 func (m *MockVClient) VaultCreate(ctx context.Context, encryptedVault []byte, s3URL string) (*vaultdata.VaultClientSyncResult, error) {
 	m.ctrl.T.Helper()
@@ -80,17 +140,17 @@ func (mr *MockVClientMockRecorder) VaultCreate(ctx, encryptedVault, s3URL interf
 }
 
 // VaultDelete mocks base method.
-func (m *MockVClient) VaultDelete(ctx context.Context, ID string, version int) error {
+func (m *MockVClient) VaultDelete(ctx context.Context, id string, version int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "VaultDelete", ctx, ID, version)
+	ret := m.ctrl.Call(m, "VaultDelete", ctx, id, version)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // VaultDelete indicates an expected call of VaultDelete.
-func (mr *MockVClientMockRecorder) VaultDelete(ctx, ID, version interface{}) *gomock.Call {
+func (mr *MockVClientMockRecorder) VaultDelete(ctx, id, version interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VaultDelete", reflect.TypeOf((*MockVClient)(nil).VaultDelete), ctx, ID, version)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VaultDelete", reflect.TypeOf((*MockVClient)(nil).VaultDelete), ctx, id, version)
 }
 
 // VaultDownload mocks base method.
@@ -123,19 +183,33 @@ func (mr *MockVClientMockRecorder) VaultSync(ctx, vaultSync interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VaultSync", reflect.TypeOf((*MockVClient)(nil).VaultSync), ctx, vaultSync)
 }
 
+// VaultSyncStream mocks base method.
+func (m *MockVClient) VaultSyncStream(ctx context.Context, cursor int64, apply func([]vaultdata.VaultSyncData, int64) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VaultSyncStream", ctx, cursor, apply)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VaultSyncStream indicates an expected call of VaultSyncStream.
+func (mr *MockVClientMockRecorder) VaultSyncStream(ctx, cursor, apply interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VaultSyncStream", reflect.TypeOf((*MockVClient)(nil).VaultSyncStream), ctx, cursor, apply)
+}
+
 // VaultUpdate mocks base method.
-func (m *MockVClient) VaultUpdate(ctx context.Context, ID string, version int, encryptedVault []byte) (*vaultdata.VaultClientSyncResult, error) {
+func (m *MockVClient) VaultUpdate(ctx context.Context, id string, version int, encryptedVault []byte, s3URL string) (*vaultdata.VaultClientSyncResult, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "VaultUpdate", ctx, ID, version, encryptedVault)
+	ret := m.ctrl.Call(m, "VaultUpdate", ctx, id, version, encryptedVault, s3URL)
 	ret0, _ := ret[0].(*vaultdata.VaultClientSyncResult)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // VaultUpdate indicates an expected call of VaultUpdate.
-func (mr *MockVClientMockRecorder) VaultUpdate(ctx, ID, version, encryptedVault interface{}) *gomock.Call {
+func (mr *MockVClientMockRecorder) VaultUpdate(ctx, id, version, encryptedVault, s3URL interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VaultUpdate", reflect.TypeOf((*MockVClient)(nil).VaultUpdate), ctx, ID, version, encryptedVault)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VaultUpdate", reflect.TypeOf((*MockVClient)(nil).VaultUpdate), ctx, id, version, encryptedVault, s3URL)
 }
 
 // VaultUpload mocks base method.
@@ -152,3 +226,17 @@ func (mr *MockVClientMockRecorder) VaultUpload(ctx, r interface{}) *gomock.Call
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VaultUpload", reflect.TypeOf((*MockVClient)(nil).VaultUpload), ctx, r)
 }
+
+// WatchVaults mocks base method.
+func (m *MockVClient) WatchVaults(ctx context.Context, cursor int64, apply func([]vaultdata.VaultSyncData, int64) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WatchVaults", ctx, cursor, apply)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WatchVaults indicates an expected call of WatchVaults.
+func (mr *MockVClientMockRecorder) WatchVaults(ctx, cursor, apply interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WatchVaults", reflect.TypeOf((*MockVClient)(nil).WatchVaults), ctx, cursor, apply)
+}
@@ -0,0 +1,185 @@
+package vaultclient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// messageTokenExpired must be equal to the message of server auth interceptor and middleware
+const messageTokenExpired = "token expired"
+
+const refreshTokenMethod = "/gophkeeper.Gophkeeper/RefreshToken"
+
+// IsTokenExpired reports that server refused the access token because it is expired
+func IsTokenExpired(err error) bool {
+	st, ok := status.FromError(err)
+
+	return ok && st.Code() == codes.Unauthenticated && st.Message() == messageTokenExpired
+}
+
+// RefreshFunc returns new access token instead of expired one
+type RefreshFunc func(ctx context.Context, expiredToken string) (string, error)
+
+// TokenRefresher gets new access token when call fails because token is expired.
+// Unary call is repeated with new token, stream is refreshed and its error is returned to be reopened by caller
+type TokenRefresher struct {
+	mux     sync.RWMutex
+	refresh RefreshFunc
+}
+
+func NewTokenRefresher() *TokenRefresher {
+	r := TokenRefresher{}
+
+	return &r
+}
+
+// SetRefresh binds refresher to client, connection is dialed before client is created
+func (r *TokenRefresher) SetRefresh(fn RefreshFunc) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.refresh = fn
+}
+
+func (r *TokenRefresher) refreshToken(ctx context.Context) (string, error) {
+	r.mux.RLock()
+	refresh := r.refresh
+	r.mux.RUnlock()
+
+	if refresh == nil {
+		return "", ErrNotAuth
+	}
+
+	return refresh(ctx, outgoingToken(ctx))
+}
+
+func (r *TokenRefresher) UnaryInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		if !IsTokenExpired(err) || method == refreshTokenMethod {
+			return err
+		}
+
+		token, err := r.refreshToken(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		return invoker(withOutgoingToken(ctx, token), method, req, reply, cc, opts...)
+	}
+}
+
+func (r *TokenRefresher) StreamInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+
+		if err != nil {
+			if IsTokenExpired(err) {
+				_, _ = r.refreshToken(ctx)
+			}
+
+			return nil, err
+		}
+
+		return &refreshClientStream{ClientStream: stream, refresher: r, ctx: ctx}, nil
+	}
+}
+
+type refreshClientStream struct {
+	grpc.ClientStream
+	refresher *TokenRefresher
+	ctx       context.Context
+}
+
+func (s *refreshClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+
+	if IsTokenExpired(err) {
+		_, _ = s.refresher.refreshToken(s.ctx)
+	}
+
+	return err
+}
+
+func outgoingToken(ctx context.Context) string {
+	md, ok := metadata.FromOutgoingContext(ctx)
+
+	if !ok {
+		return ""
+	}
+
+	if values := md.Get("token"); len(values) != 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+func withOutgoingToken(ctx context.Context, token string) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	md.Set("token", token)
+
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// refreshTransport repeats REST request with new access token when server answers that token is expired
+type refreshTransport struct {
+	base   http.RoundTripper
+	client *Client
+}
+
+func (t *refreshTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	response, err := t.base.RoundTrip(request)
+
+	if err != nil || response.StatusCode != http.StatusUnauthorized {
+		return response, err
+	}
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, 1024))
+	_ = response.Body.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	response.Body = io.NopCloser(bytes.NewReader(body))
+
+	if strings.TrimSpace(string(body)) != messageTokenExpired {
+		return response, nil
+	}
+
+	if request.Body != nil && request.GetBody == nil {
+		return response, nil
+	}
+
+	token, err := t.client.Refresh(request.Context(), request.Header.Get("Authorization"))
+
+	if err != nil {
+		return response, nil
+	}
+
+	retry := request.Clone(request.Context())
+	retry.Header.Set("Authorization", token)
+
+	if request.GetBody != nil {
+		retry.Body, err = request.GetBody()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return t.base.RoundTrip(retry)
+}
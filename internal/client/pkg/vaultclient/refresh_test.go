@@ -0,0 +1,220 @@
+package vaultclient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/shreyner/gophkeeper/proto"
+)
+
+// fakeRefreshClient answers RefreshToken only, other methods aren't called by tests
+type fakeRefreshClient struct {
+	proto.GophkeeperClient
+
+	mux       sync.Mutex
+	refreshes int
+	err       error
+}
+
+func (f *fakeRefreshClient) RefreshToken(_ context.Context, in *proto.RefreshTokenRequest, _ ...grpc.CallOption) (*proto.LoginResponse, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.refreshes++
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	response := proto.LoginResponse{
+		AuthToken:    "new-token",
+		RefreshToken: in.RefreshToken + "-next",
+	}
+
+	return &response, nil
+}
+
+func TestClient_Refresh(t *testing.T) {
+	tests := []struct {
+		name       string
+		refreshErr error
+		wantToken  string
+		wantErr    error
+		wantAuth   bool
+	}{
+		{
+			name:      "Success refresh",
+			wantToken: "new-token",
+			wantAuth:  true,
+		},
+		{
+			name:       "Refresh token expired",
+			refreshErr: status.Error(codes.Unauthenticated, "refresh token is invalid or expired"),
+			wantErr:    ErrSessionExpired,
+			wantAuth:   false,
+		},
+		{
+			name:       "Server unavailable",
+			refreshErr: status.Error(codes.Unavailable, "unavailable"),
+			wantErr:    status.Error(codes.Unavailable, "unavailable"),
+			wantAuth:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := fakeRefreshClient{err: tt.refreshErr}
+
+			client := newTestClient(t, http.NotFoundHandler())
+			client.client = &fake
+			client.startSession(&proto.LoginResponse{AuthToken: "token", RefreshToken: "refresh"})
+
+			token, err := client.Refresh(context.Background(), "token")
+
+			if tt.wantErr != nil {
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.wantToken, token)
+			assert.Equal(t, tt.wantAuth, client.appState.GetUserToken() != "")
+		})
+	}
+
+	t.Run("Token refreshed by concurrent call is reused", func(t *testing.T) {
+		fake := fakeRefreshClient{}
+
+		client := newTestClient(t, http.NotFoundHandler())
+		client.client = &fake
+		client.startSession(&proto.LoginResponse{AuthToken: "token", RefreshToken: "refresh"})
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				token, err := client.Refresh(context.Background(), "token")
+
+				assert.NoError(t, err)
+				assert.Equal(t, "new-token", token)
+			}()
+		}
+
+		wg.Wait()
+
+		assert.Equal(t, 1, fake.refreshes)
+		assert.Equal(t, "refresh-next", client.refreshToken)
+	})
+}
+
+func TestClient_outgoingContext(t *testing.T) {
+	client := newTestClient(t, http.NotFoundHandler())
+	client.client = &fakeRefreshClient{}
+	client.startSession(&proto.LoginResponse{AuthToken: "token", RefreshToken: "refresh"})
+
+	ctx := client.outgoingContext(context.Background())
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 5; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+
+			_, _ = client.Refresh(context.Background(), "token")
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			assert.NotEmpty(t, outgoingToken(client.outgoingContext(context.Background())))
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, "token", outgoingToken(ctx))
+	assert.Equal(t, "new-token", outgoingToken(client.outgoingContext(context.Background())))
+
+	client.Logout()
+
+	assert.Empty(t, outgoingToken(client.outgoingContext(context.Background())))
+}
+
+func TestTokenRefresher_UnaryInterceptor(t *testing.T) {
+	r := NewTokenRefresher()
+
+	var expiredTokens []string
+	r.SetRefresh(func(_ context.Context, expiredToken string) (string, error) {
+		expiredTokens = append(expiredTokens, expiredToken)
+		return "new-token", nil
+	})
+
+	var sentTokens []string
+	invoker := func(ctx context.Context, _ string, _, _ interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		token := outgoingToken(ctx)
+		sentTokens = append(sentTokens, token)
+
+		if token != "new-token" {
+			return status.Error(codes.Unauthenticated, "token expired")
+		}
+
+		return nil
+	}
+
+	ctx := metadata.NewOutgoingContext(context.Background(), metadata.Pairs("token", "old-token"))
+
+	err := r.UnaryInterceptor()(ctx, "/gophkeeper.Gophkeeper/VaultChanges", nil, nil, nil, invoker)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"old-token"}, expiredTokens)
+	assert.Equal(t, []string{"old-token", "new-token"}, sentTokens)
+
+	err = r.UnaryInterceptor()(ctx, refreshTokenMethod, nil, nil, nil, invoker)
+
+	assert.True(t, IsTokenExpired(err))
+	assert.Len(t, expiredTokens, 1)
+}
+
+func TestClient_refreshTransport(t *testing.T) {
+	var bodies []string
+
+	handler := http.HandlerFunc(func(wr http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+
+		if r.Header.Get("Authorization") != "new-token" {
+			http.Error(wr, "token expired", http.StatusUnauthorized)
+			return
+		}
+
+		wr.WriteHeader(http.StatusNoContent)
+	})
+
+	client := newTestClient(t, handler)
+	client.client = &fakeRefreshClient{}
+	client.startSession(&proto.LoginResponse{AuthToken: "token", RefreshToken: "refresh"})
+
+	response, err := client.doUploadRequest(context.Background(), http.MethodPatch, client.hostREST+"/uploads/1", bytes.NewReader([]byte("chunk")), nil)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	assert.Equal(t, http.StatusNoContent, response.StatusCode)
+	assert.Equal(t, []string{"chunk", "chunk"}, bodies)
+	assert.Equal(t, "new-token", client.appState.GetUserToken())
+}
@@ -0,0 +1,91 @@
+package vaultclient
+
+import (
+	"sync"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// messageDeviceRevoked must be equal to the message of server auth interceptor
+const messageDeviceRevoked = "device revoked"
+
+// IsDeviceRevoked reports that server refused the token because its device was revoked
+func IsDeviceRevoked(err error) bool {
+	st, ok := status.FromError(err)
+
+	return ok && st.Code() == codes.Unauthenticated && st.Message() == messageDeviceRevoked
+}
+
+// RevokeWatcher watches responses of every call and notifies once the device is revoked.
+// Callback runs in own goroutine, because the call may be made under locks of the callback.
+type RevokeWatcher struct {
+	mux       sync.Mutex
+	onRevoked func()
+}
+
+func NewRevokeWatcher() *RevokeWatcher {
+	w := RevokeWatcher{}
+
+	return &w
+}
+
+func (w *RevokeWatcher) OnRevoked(fn func()) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	w.onRevoked = fn
+}
+
+func (w *RevokeWatcher) check(err error) {
+	if !IsDeviceRevoked(err) {
+		return
+	}
+
+	go func() {
+		w.mux.Lock()
+		defer w.mux.Unlock()
+
+		if w.onRevoked != nil {
+			w.onRevoked()
+		}
+	}()
+}
+
+func (w *RevokeWatcher) UnaryInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		w.check(err)
+
+		return err
+	}
+}
+
+func (w *RevokeWatcher) StreamInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+
+		if err != nil {
+			w.check(err)
+			return nil, err
+		}
+
+		return &revokeClientStream{ClientStream: stream, watcher: w}, nil
+	}
+}
+
+type revokeClientStream struct {
+	grpc.ClientStream
+	watcher *RevokeWatcher
+}
+
+func (s *revokeClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+
+	s.watcher.check(err)
+
+	return err
+}
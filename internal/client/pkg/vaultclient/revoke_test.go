@@ -0,0 +1,85 @@
+package vaultclient
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsDeviceRevoked(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "Revoked",
+			err:  status.Error(codes.Unauthenticated, "device revoked"),
+			want: true,
+		},
+		{
+			name: "Other unauthenticated",
+			err:  status.Error(codes.Unauthenticated, "token expired"),
+			want: false,
+		},
+		{
+			name: "Permission denied",
+			err:  status.Error(codes.PermissionDenied, "device revoked"),
+			want: false,
+		},
+		{
+			name: "Not status",
+			err:  errors.New("device revoked"),
+			want: false,
+		},
+		{
+			name: "Nil",
+			err:  nil,
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsDeviceRevoked(tt.err))
+		})
+	}
+}
+
+func TestRevokeWatcher_UnaryInterceptor(t *testing.T) {
+	w := NewRevokeWatcher()
+
+	revoked := make(chan struct{}, 1)
+	w.OnRevoked(func() {
+		revoked <- struct{}{}
+	})
+
+	interceptor := w.UnaryInterceptor()
+
+	invoke := func(err error) error {
+		return interceptor(context.Background(), "/test", nil, nil, nil,
+			func(_ context.Context, _ string, _, _ interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+				return err
+			},
+		)
+	}
+
+	err := invoke(status.Error(codes.NotFound, "not found"))
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	err = invoke(status.Error(codes.Unauthenticated, "device revoked"))
+	assert.True(t, IsDeviceRevoked(err))
+
+	select {
+	case <-revoked:
+	case <-time.After(time.Second):
+		t.Fatal("OnRevoked callback isn't called")
+	}
+
+	assert.Empty(t, revoked)
+}
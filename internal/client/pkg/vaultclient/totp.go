@@ -0,0 +1,74 @@
+package vaultclient
+
+import (
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"golang.org/x/net/context"
+
+	"github.com/shreyner/gophkeeper/proto"
+)
+
+// TOTPSetup returns secret and otpauth URI, second factor is enabled after TOTPEnable with code
+func (s *Client) TOTPSetup(ctx context.Context) (string, string, error) {
+	if s.appState.GetUserToken() == "" {
+		return "", "", ErrNotAuth
+	}
+
+	ctxWithMetadata := s.outgoingContext(ctx)
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctxWithMetadata, 30*time.Second)
+	defer cancel()
+
+	response, err := s.client.TOTPSetup(ctxWithTimeout, &empty.Empty{})
+
+	if err != nil {
+		return "", "", err
+	}
+
+	return response.Secret, response.Uri, nil
+}
+
+// TOTPEnable confirms secret by code and returns one-time backup codes
+func (s *Client) TOTPEnable(ctx context.Context, code string) ([]string, error) {
+	if s.appState.GetUserToken() == "" {
+		return nil, ErrNotAuth
+	}
+
+	ctxWithMetadata := s.outgoingContext(ctx)
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctxWithMetadata, 30*time.Second)
+	defer cancel()
+
+	request := proto.TOTPCodeRequest{
+		Code: code,
+	}
+
+	response, err := s.client.TOTPEnable(ctxWithTimeout, &request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return response.BackupCodes, nil
+}
+
+// TOTPDisable turns second factor off, code of authenticator app or backup code is required
+func (s *Client) TOTPDisable(ctx context.Context, code string) error {
+	if s.appState.GetUserToken() == "" {
+		return ErrNotAuth
+	}
+
+	ctxWithMetadata := s.outgoingContext(ctx)
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctxWithMetadata, 30*time.Second)
+	defer cancel()
+
+	request := proto.TOTPCodeRequest{
+		Code: code,
+	}
+
+	_, err := s.client.TOTPDisable(ctxWithTimeout, &request)
+
+	return err
+}
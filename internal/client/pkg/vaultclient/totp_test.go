@@ -0,0 +1,70 @@
+package vaultclient
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/shreyner/gophkeeper/proto"
+)
+
+// fakeLoginClient asks second factor and accepts code "123456" only
+type fakeLoginClient struct {
+	proto.GophkeeperClient
+}
+
+func (f *fakeLoginClient) Login(_ context.Context, _ *proto.LoginRequest, _ ...grpc.CallOption) (*proto.LoginResponse, error) {
+	response := proto.LoginResponse{
+		SecondFactorRequired: true,
+		Challenge:            "challenge",
+	}
+
+	return &response, nil
+}
+
+func (f *fakeLoginClient) LoginSecondFactor(_ context.Context, in *proto.LoginSecondFactorRequest, _ ...grpc.CallOption) (*proto.LoginResponse, error) {
+	if in.Challenge != "challenge" {
+		return nil, status.Error(codes.Unauthenticated, "invalid login challenge")
+	}
+
+	if in.Code != "123456" {
+		return nil, status.Error(codes.PermissionDenied, "invalid two-factor code")
+	}
+
+	response := proto.LoginResponse{
+		AuthToken:    "token",
+		RefreshToken: "refresh",
+	}
+
+	return &response, nil
+}
+
+func TestClient_LoginSecondFactor(t *testing.T) {
+	client := newTestClient(t, http.NotFoundHandler())
+	client.client = &fakeLoginClient{}
+	client.appState.Logout()
+
+	err := client.LoginSecondFactor(context.Background(), "123456")
+	assert.ErrorIs(t, err, ErrNoLoginChallenge)
+
+	err = client.Login(context.Background(), "alex", "password")
+	assert.ErrorIs(t, err, ErrSecondFactorRequired)
+	assert.Empty(t, client.appState.GetUserToken())
+
+	err = client.LoginSecondFactor(context.Background(), "000000")
+	assert.ErrorIs(t, err, ErrInvalidSecondFactor)
+	assert.Empty(t, client.appState.GetUserToken())
+
+	err = client.LoginSecondFactor(context.Background(), "123456")
+	require.NoError(t, err)
+
+	assert.Equal(t, "token", client.appState.GetUserToken())
+	assert.Equal(t, "refresh", client.refreshToken)
+	assert.Empty(t, client.challenge)
+}
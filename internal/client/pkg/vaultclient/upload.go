@@ -0,0 +1,292 @@
+package vaultclient
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	headerUploadOffset = "Upload-Offset"
+
+	// defaultUploadChunkSize is bigger than minimal S3 part of 5MB, every chunk except the last one must be bigger
+	defaultUploadChunkSize = 8 << 20
+	defaultRetryDelay      = time.Second
+
+	maxUploadRetries     = 5
+	uploadRequestTimeout = 60 * time.Second
+)
+
+type uploadStatusError struct {
+	status int
+}
+
+func (e *uploadStatusError) Error() string {
+	return fmt.Sprintf("upload failed with status %d", e.status)
+}
+
+// VaultUpload sends data by chunks. Failed chunk is sent again from offset saved by server,
+// so dropped connection doesn't restart upload from zero. Returns location of uploaded object
+func (s *Client) VaultUpload(ctx context.Context, r io.Reader) (string, error) {
+	if s.appState.GetUserToken() == "" {
+		return "", ErrNotAuth
+	}
+
+	uploadURL, err := s.createUpload(ctx)
+
+	if err != nil {
+		return "", err
+	}
+
+	location, err := s.uploadChunks(ctx, uploadURL, r)
+
+	if err != nil {
+		// Upload can't be resumed without source data, so parts are dropped
+		ctxAbort, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		_ = s.abortUpload(ctxAbort, uploadURL)
+
+		return "", err
+	}
+
+	return location, nil
+}
+
+func (s *Client) uploadChunks(ctx context.Context, uploadURL string, r io.Reader) (string, error) {
+	// Only current chunk is kept in memory, server offset is always on chunk border
+	chunk := make([]byte, s.uploadChunkSize)
+
+	var offset int64
+
+	for {
+		n, err := io.ReadFull(r, chunk)
+
+		isLast := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+
+		if err != nil && !isLast {
+			return "", err
+		}
+
+		if n != 0 {
+			offset, err = s.sendChunkWithRetry(ctx, uploadURL, offset, chunk[:n])
+
+			if err != nil {
+				return "", err
+			}
+		}
+
+		if isLast {
+			break
+		}
+	}
+
+	return s.completeUpload(ctx, uploadURL)
+}
+
+func (s *Client) sendChunkWithRetry(ctx context.Context, uploadURL string, offset int64, chunk []byte) (int64, error) {
+	nextOffset := offset + int64(len(chunk))
+
+	var err error
+
+	for attempt := 0; attempt <= maxUploadRetries; attempt++ {
+		if attempt != 0 {
+			select {
+			case <-ctx.Done():
+				return 0, ctx.Err()
+			case <-time.After(s.retryDelay * time.Duration(attempt)):
+			}
+
+			serverOffset, offsetErr := s.uploadOffset(ctx, uploadURL)
+
+			if offsetErr != nil {
+				err = offsetErr
+				continue
+			}
+
+			if serverOffset == nextOffset {
+				// Chunk is saved, only response was lost
+				return nextOffset, nil
+			}
+
+			if serverOffset != offset {
+				return 0, ErrUploadOffset
+			}
+		}
+
+		var savedOffset int64
+
+		savedOffset, err = s.sendChunk(ctx, uploadURL, offset, chunk)
+
+		if err == nil {
+			return savedOffset, nil
+		}
+
+		if !isRetryableUploadError(ctx, err) {
+			return 0, err
+		}
+	}
+
+	return 0, err
+}
+
+func isRetryableUploadError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var statusErr *uploadStatusError
+
+	if errors.As(err, &statusErr) {
+		return statusErr.status >= http.StatusInternalServerError || statusErr.status == http.StatusConflict
+	}
+
+	var netErr net.Error
+
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+func (s *Client) createUpload(ctx context.Context) (string, error) {
+	response, err := s.doUploadRequest(ctx, http.MethodPost, fmt.Sprintf("%s/uploads", s.hostREST), nil, nil)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusCreated {
+		return "", &uploadStatusError{status: response.StatusCode}
+	}
+
+	return s.hostREST + response.Header.Get("Location"), nil
+}
+
+func (s *Client) uploadOffset(ctx context.Context, uploadURL string) (int64, error) {
+	response, err := s.doUploadRequest(ctx, http.MethodHead, uploadURL, nil, nil)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return 0, &uploadStatusError{status: response.StatusCode}
+	}
+
+	return strconv.ParseInt(response.Header.Get(headerUploadOffset), 10, 64)
+}
+
+func (s *Client) sendChunk(ctx context.Context, uploadURL string, offset int64, chunk []byte) (int64, error) {
+	headers := map[string]string{
+		headerUploadOffset: strconv.FormatInt(offset, 10),
+		"Content-Type":     "application/octet-stream",
+	}
+
+	response, err := s.doUploadRequest(ctx, http.MethodPatch, uploadURL, bytes.NewReader(chunk), headers)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusRequestEntityTooLarge {
+		return 0, ErrQuotaExceeded
+	}
+
+	if response.StatusCode != http.StatusNoContent {
+		return 0, &uploadStatusError{status: response.StatusCode}
+	}
+
+	return strconv.ParseInt(response.Header.Get(headerUploadOffset), 10, 64)
+}
+
+func (s *Client) completeUpload(ctx context.Context, uploadURL string) (string, error) {
+	response, err := s.doUploadRequest(ctx, http.MethodPost, uploadURL+"/complete", nil, nil)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", &uploadStatusError{status: response.StatusCode}
+	}
+
+	respBytes, err := io.ReadAll(response.Body)
+
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(respBytes)), nil
+}
+
+func (s *Client) abortUpload(ctx context.Context, uploadURL string) error {
+	response, err := s.doUploadRequest(ctx, http.MethodDelete, uploadURL, nil, nil)
+
+	if err != nil {
+		return err
+	}
+
+	return response.Body.Close()
+}
+
+// doUploadRequest sends request with own timeout. Body is *bytes.Reader, so chunk is sent with content length
+func (s *Client) doUploadRequest(ctx context.Context, method, url string, body *bytes.Reader, headers map[string]string) (*http.Response, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, uploadRequestTimeout)
+
+	var bodyReader io.Reader
+
+	if body != nil {
+		bodyReader = body
+	}
+
+	request, err := http.NewRequestWithContext(ctxWithTimeout, method, url, bodyReader)
+
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	request.Header.Set("Authorization", s.appState.GetUserToken())
+
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+
+	response, err := s.httpClient.Do(request)
+
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	response.Body = &cancelBody{ReadCloser: response.Body, cancel: cancel}
+
+	return response, nil
+}
+
+// cancelBody releases request context when response body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+
+	return err
+}
@@ -0,0 +1,162 @@
+package vaultclient
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/shreyner/gophkeeper/internal/client/config"
+	"github.com/shreyner/gophkeeper/internal/client/state"
+)
+
+// fakeUploadServer keeps one upload in memory and fails chosen chunk requests
+type fakeUploadServer struct {
+	mux sync.Mutex
+
+	data      []byte
+	patches   int
+	failAfter map[int]bool // patch number -> chunk is saved, but connection is dropped
+	failNow   map[int]bool // patch number -> chunk is rejected with 500
+	quota     int          // chunk over quota is rejected with 413, zero is unlimited
+	aborted   bool
+}
+
+func (f *fakeUploadServer) ServeHTTP(wr http.ResponseWriter, r *http.Request) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/uploads":
+		wr.Header().Set("Location", "/uploads/1")
+		wr.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodHead:
+		wr.Header().Set(headerUploadOffset, strconv.Itoa(len(f.data)))
+		wr.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodPatch:
+		f.patches++
+
+		if f.failNow[f.patches] {
+			wr.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		offset, _ := strconv.Atoi(r.Header.Get(headerUploadOffset))
+
+		if offset != len(f.data) {
+			wr.WriteHeader(http.StatusConflict)
+			return
+		}
+
+		chunk, _ := io.ReadAll(r.Body)
+
+		if f.quota != 0 && len(f.data)+len(chunk) > f.quota {
+			wr.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		f.data = append(f.data, chunk...)
+
+		if f.failAfter[f.patches] {
+			conn, _, _ := wr.(http.Hijacker).Hijack()
+			_ = conn.Close()
+			return
+		}
+
+		wr.Header().Set(headerUploadOffset, strconv.Itoa(len(f.data)))
+		wr.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && r.URL.Path == "/uploads/1/complete":
+		_, _ = fmt.Fprintln(wr, "http://s3/vault/1")
+	case r.Method == http.MethodDelete:
+		f.aborted = true
+		wr.WriteHeader(http.StatusNoContent)
+	default:
+		wr.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestClient(t *testing.T, handler http.Handler) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	appState := state.New()
+	appState.SetUserToken("token")
+
+	client := New(&config.Config{HostREST: server.URL}, appState, nil)
+	client.uploadChunkSize = 4
+	client.retryDelay = time.Millisecond
+
+	return client
+}
+
+func TestClient_VaultUpload(t *testing.T) {
+	data := []byte("0123456789")
+
+	tests := []struct {
+		name      string
+		failAfter map[int]bool
+		failNow   map[int]bool
+	}{
+		{
+			name: "Without failures",
+		},
+		{
+			name:      "Response of saved chunk is lost",
+			failAfter: map[int]bool{2: true},
+		},
+		{
+			name:    "Chunk is rejected",
+			failNow: map[int]bool{1: true, 3: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+			server := fakeUploadServer{failAfter: tt.failAfter, failNow: tt.failNow}
+			client := newTestClient(t, &server)
+
+			location, err := client.VaultUpload(context.Background(), bytes.NewReader(data))
+
+			require.NoError(err)
+			assert.Equal(t, "http://s3/vault/1", location)
+			assert.Equal(t, data, server.data)
+			assert.False(t, server.aborted)
+		})
+	}
+
+	t.Run("Upload is aborted after retries", func(t *testing.T) {
+		failNow := make(map[int]bool)
+
+		for i := 1; i <= maxUploadRetries+1; i++ {
+			failNow[i] = true
+		}
+
+		server := fakeUploadServer{failNow: failNow}
+		client := newTestClient(t, &server)
+
+		_, err := client.VaultUpload(context.Background(), bytes.NewReader(data))
+
+		assert.Error(t, err)
+		assert.True(t, server.aborted)
+	})
+
+	t.Run("Quota exceeded is not retried", func(t *testing.T) {
+		server := fakeUploadServer{quota: 6}
+		client := newTestClient(t, &server)
+
+		_, err := client.VaultUpload(context.Background(), bytes.NewReader(data))
+
+		assert.ErrorIs(t, err, ErrQuotaExceeded)
+		assert.Equal(t, 2, server.patches)
+		assert.True(t, server.aborted)
+	})
+}
@@ -0,0 +1,88 @@
+// Package vaultcompress packs vault payload before encryption.
+//
+// Packed payload starts with header: magic byte and format byte. Gob stream never starts with magic byte,
+// so payloads of previous versions without header are returned by Unpack as is
+package vaultcompress
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+)
+
+type Format byte
+
+const (
+	FormatNone Format = iota
+	FormatDeflate
+)
+
+const (
+	// magic is in range 0x80-0xF7 which gob never uses for the first byte of message length
+	magic = 0xC7
+
+	headerSize = 2
+
+	// MinSize of payload for compression, smaller payloads grow because of deflate overhead
+	MinSize = 256
+)
+
+var ErrUnknownFormat = errors.New("unknown compression format")
+
+// Pack compresses payload when it is big enough and compression makes it smaller
+func Pack(data []byte) ([]byte, error) {
+	if len(data) < MinSize {
+		return withHeader(FormatNone, data), nil
+	}
+
+	var buffer bytes.Buffer
+
+	buffer.Write([]byte{magic, byte(FormatDeflate)})
+
+	w, err := flate.NewWriter(&buffer, flate.DefaultCompression)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = w.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+
+	if buffer.Len() >= len(data)+headerSize {
+		return withHeader(FormatNone, data), nil
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// Unpack returns original payload, payload without header is returned as is
+func Unpack(data []byte) ([]byte, error) {
+	if len(data) < headerSize || data[0] != magic {
+		return data, nil
+	}
+
+	switch Format(data[1]) {
+	case FormatNone:
+		return data[headerSize:], nil
+	case FormatDeflate:
+		r := flate.NewReader(bytes.NewReader(data[headerSize:]))
+		defer r.Close()
+
+		return io.ReadAll(r)
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+func withHeader(format Format, data []byte) []byte {
+	packed := make([]byte, 0, len(data)+headerSize)
+	packed = append(packed, magic, byte(format))
+
+	return append(packed, data...)
+}
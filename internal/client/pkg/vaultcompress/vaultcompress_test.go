@@ -0,0 +1,73 @@
+package vaultcompress
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/gob"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPack(t *testing.T) {
+	randomData := make([]byte, 1024)
+	_, _ = rand.Read(randomData)
+
+	tests := []struct {
+		name       string
+		data       []byte
+		wantFormat Format
+	}{
+		{
+			name:       "Small payload is not compressed",
+			data:       []byte("alex"),
+			wantFormat: FormatNone,
+		},
+		{
+			name:       "Big payload is compressed",
+			data:       bytes.Repeat([]byte("login password "), 100),
+			wantFormat: FormatDeflate,
+		},
+		{
+			name:       "Incompressible payload is not compressed",
+			data:       randomData,
+			wantFormat: FormatNone,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			packed, err := Pack(tt.data)
+			require.NoError(err)
+			assert.Equal(t, tt.wantFormat, Format(packed[1]))
+
+			if tt.wantFormat == FormatDeflate {
+				assert.Less(t, len(packed), len(tt.data))
+			}
+
+			unpacked, err := Unpack(packed)
+			require.NoError(err)
+			assert.Equal(t, tt.data, unpacked)
+		})
+	}
+}
+
+func TestUnpack(t *testing.T) {
+	t.Run("Gob payload without header", func(t *testing.T) {
+		require := require.New(t)
+
+		var buffer bytes.Buffer
+		require.NoError(gob.NewEncoder(&buffer).Encode(struct{ Data []byte }{Data: make([]byte, 300)}))
+
+		unpacked, err := Unpack(buffer.Bytes())
+		require.NoError(err)
+		assert.Equal(t, buffer.Bytes(), unpacked)
+	})
+
+	t.Run("Unknown format", func(t *testing.T) {
+		_, err := Unpack([]byte{magic, 42, 1, 2})
+		assert.ErrorIs(t, err, ErrUnknownFormat)
+	})
+}
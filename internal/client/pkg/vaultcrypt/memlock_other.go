@@ -0,0 +1,11 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package vaultcrypt
+
+func allocLocked(size int) ([]byte, error) {
+	return make([]byte, size), nil
+}
+
+func freeLocked(buf []byte) {
+	wipe(buf)
+}
@@ -0,0 +1,28 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package vaultcrypt
+
+import "golang.org/x/sys/unix"
+
+// allocLocked allocates a buffer outside the Go heap and pins it in RAM,
+// so key material is never written to swap. When RLIMIT_MEMLOCK does not
+// allow pinning, the buffer is still returned and only loses swap protection.
+func allocLocked(size int) ([]byte, error) {
+	buf, err := unix.Mmap(-1, 0, size, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_ANON|unix.MAP_PRIVATE)
+
+	if err != nil {
+		return nil, err
+	}
+
+	_ = unix.Mlock(buf)
+
+	return buf, nil
+}
+
+// freeLocked wipes and releases a buffer created by allocLocked.
+func freeLocked(buf []byte) {
+	wipe(buf)
+
+	_ = unix.Munlock(buf)
+	_ = unix.Munmap(buf)
+}
@@ -4,20 +4,27 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/sha256"
+	"crypto/subtle"
 	"errors"
-	"fmt"
 	"io"
+	"sync"
 
 	"golang.org/x/crypto/scrypt"
+
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultcompress"
 )
 
 var ErrNotSetKey = errors.New("don't set key")
+var ErrInvalidMasterPassword = errors.New("invalid master password")
 
 type VaultCrypt struct {
-	aesGCM cipher.AEAD
-	nonce  []byte
+	key      []byte // locked in memory, wiped on Lock
+	salt     []byte
+	keyCheck []byte
 
 	isSetKey bool
+
+	mux sync.RWMutex
 }
 
 func New() *VaultCrypt {
@@ -33,48 +40,91 @@ func (c *VaultCrypt) setKey(key []byte) error {
 	sh.Write(key)
 
 	hashKey := sh.Sum(nil)
+	defer wipe(hashKey)
 
-	aesBlock, err := aes.NewCipher(hashKey)
+	lockedKey, err := allocLocked(len(hashKey))
 
 	if err != nil {
 		return err
 	}
 
-	aesGCM, err := cipher.NewGCM(aesBlock)
+	copy(lockedKey, hashKey)
 
-	if err != nil {
-		return err
+	if c.key != nil {
+		freeLocked(c.key)
 	}
 
-	nonce := hashKey[len(hashKey)-aesGCM.NonceSize():]
-
-	c.aesGCM = aesGCM
-	c.nonce = nonce
+	c.key = lockedKey
+	c.keyCheck = keyCheckSum(hashKey)
 	c.isSetKey = true
 
 	return nil
 }
 
+// aead builds cipher for every call, so expanded key lives only while data is processed
+func (c *VaultCrypt) aead() (cipher.AEAD, []byte, error) {
+	aesBlock, err := aes.NewCipher(c.key)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	aesGCM, err := cipher.NewGCM(aesBlock)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce := c.key[len(c.key)-aesGCM.NonceSize():]
+
+	return aesGCM, nonce, nil
+}
+
 func (c *VaultCrypt) Encrypt(data []byte) ([]byte, error) {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+
 	if !c.isSetKey {
 		return nil, ErrNotSetKey
 	}
 
-	return c.aesGCM.Seal(nil, c.nonce, data, nil), nil
+	aesGCM, nonce, err := c.aead()
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Ciphertext can't be compressed, so plaintext is packed before sealing
+	packed, err := vaultcompress.Pack(data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return aesGCM.Seal(nil, nonce, packed, nil), nil
 }
 
 func (c *VaultCrypt) Decrypt(data []byte) ([]byte, error) {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+
 	if !c.isSetKey {
 		return nil, ErrNotSetKey
 	}
 
-	decryptedData, err := c.aesGCM.Open(nil, c.nonce, data, nil)
+	aesGCM, nonce, err := c.aead()
+
+	if err != nil {
+		return nil, err
+	}
+
+	decryptedData, err := aesGCM.Open(nil, nonce, data, nil)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return decryptedData, nil
+	return vaultcompress.Unpack(decryptedData)
 }
 
 func (c *VaultCrypt) EncryptStream(out io.Writer, key []byte) (*cipher.StreamWriter, error) {
@@ -119,19 +169,140 @@ func (c *VaultCrypt) DecryptStream(in io.Reader, key []byte) (*cipher.StreamRead
 	}, nil
 }
 
+// MasterKey is derived from master password before it is set, e.g. while login waits for second factor
+type MasterKey struct {
+	login string
+	key   []byte
+}
+
+// DeriveMasterKey derives key of master password, so password isn't kept until key is set by SetMasterKey
+func DeriveMasterKey(login, password string) (*MasterKey, error) {
+	key, err := deriveKey(login, password)
+
+	if err != nil {
+		return nil, err
+	}
+
+	masterKey := MasterKey{
+		login: login,
+		key:   key,
+	}
+
+	return &masterKey, nil
+}
+
+// Wipe clears key which won't be set
+func (k *MasterKey) Wipe() {
+	wipe(k.key)
+}
+
 func (c *VaultCrypt) SetMasterPassword(login, password string) error {
-	key, err := scrypt.Key([]byte(password), []byte(login), 1<<15, 8, 1, 32)
+	masterKey, err := DeriveMasterKey(login, password)
 
 	if err != nil {
-		fmt.Println(err)
 		return err
 	}
 
-	err = c.setKey(key)
+	return c.SetMasterKey(masterKey)
+}
+
+// SetMasterKey sets key derived by DeriveMasterKey and wipes it
+func (c *VaultCrypt) SetMasterKey(masterKey *MasterKey) error {
+	defer masterKey.Wipe()
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	err := c.setKey(masterKey.key)
 
 	if err != nil {
 		return err
 	}
 
+	c.salt = []byte(masterKey.login)
+
 	return nil
 }
+
+// Lock wipes the vault key from memory. Salt and key check stay, so Unlock can verify the master password.
+func (c *VaultCrypt) Lock() {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if c.key != nil {
+		freeLocked(c.key)
+		c.key = nil
+	}
+
+	c.isSetKey = false
+}
+
+// Reset wipes the vault key together with salt and key check, vault can't be unlocked until SetMasterPassword
+func (c *VaultCrypt) Reset() {
+	c.Lock()
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.salt = nil
+	c.keyCheck = nil
+}
+
+// Unlock restores the vault key after Lock. It returns ErrInvalidMasterPassword
+// when the password doesn't match the one set by SetMasterPassword.
+func (c *VaultCrypt) Unlock(password string) error {
+	c.mux.RLock()
+	salt := c.salt
+	keyCheck := c.keyCheck
+	c.mux.RUnlock()
+
+	if salt == nil {
+		return ErrNotSetKey
+	}
+
+	key, err := deriveKey(string(salt), password)
+
+	if err != nil {
+		return err
+	}
+
+	defer wipe(key)
+
+	sh := sha256.New()
+	sh.Write(key)
+
+	hashKey := sh.Sum(nil)
+	defer wipe(hashKey)
+
+	if subtle.ConstantTimeCompare(keyCheckSum(hashKey), keyCheck) != 1 {
+		return ErrInvalidMasterPassword
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return c.setKey(key)
+}
+
+func (c *VaultCrypt) IsLocked() bool {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+
+	return !c.isSetKey && c.salt != nil
+}
+
+func deriveKey(login, password string) ([]byte, error) {
+	return scrypt.Key([]byte(password), []byte(login), 1<<15, 8, 1, 32)
+}
+
+func keyCheckSum(hashKey []byte) []byte {
+	sum := sha256.Sum256(hashKey)
+
+	return sum[:]
+}
+
+func wipe(buf []byte) {
+	for i := range buf {
+		buf[i] = 0
+	}
+}
@@ -2,6 +2,7 @@ package vaultcrypt
 
 import (
 	"encoding/base64"
+	"errors"
 	"reflect"
 	"testing"
 )
@@ -30,7 +31,7 @@ func TestVaultCrypt_Encrypt(t *testing.T) {
 			args: args{
 				data: []byte("123"),
 			},
-			want:    "wVba8uysA12MosMFCcVfVn1SnQ==",
+			want:    "N2TYGKOOK+WFa7ABK0ZguBrMxfjI",
 			wantErr: false,
 		},
 	}
@@ -74,6 +75,18 @@ func TestVaultCrypt_Decrypt(t *testing.T) {
 				login:    "Alex",
 				password: "123",
 			},
+			args: args{
+				data: "N2TYGKOOK+WFa7ABK0ZguBrMxfjI",
+			},
+			want:    []byte("123"),
+			wantErr: false,
+		},
+		{
+			name: "Data encrypted before compression",
+			fields: fields{
+				login:    "Alex",
+				password: "123",
+			},
 			args: args{
 				data: "wVba8uysA12MosMFCcVfVn1SnQ==",
 			},
@@ -103,3 +116,96 @@ func TestVaultCrypt_Decrypt(t *testing.T) {
 		})
 	}
 }
+
+func TestVaultCrypt_LockUnlock(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		wantErr  error
+	}{
+		{
+			name:     "Success unlock",
+			password: "123",
+			wantErr:  nil,
+		},
+		{
+			name:     "Invalid master password",
+			password: "1234",
+			wantErr:  ErrInvalidMasterPassword,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New()
+			if err := c.SetMasterPassword("Alex", "123"); err != nil {
+				t.Fatalf("SetMasterPassword() error = %v", err)
+			}
+
+			encrypted, _ := c.Encrypt([]byte("123"))
+
+			c.Lock()
+
+			if !c.IsLocked() {
+				t.Errorf("IsLocked() = false, want true")
+			}
+
+			if _, err := c.Decrypt(encrypted); !errors.Is(err, ErrNotSetKey) {
+				t.Errorf("Decrypt() after lock error = %v, want %v", err, ErrNotSetKey)
+			}
+
+			err := c.Unlock(tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Unlock() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			got, err := c.Decrypt(encrypted)
+			if err != nil {
+				t.Errorf("Decrypt() after unlock error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, []byte("123")) {
+				t.Errorf("Decrypt() after unlock got = %v, want %v", got, []byte("123"))
+			}
+		})
+	}
+}
+
+func TestVaultCrypt_SetMasterKey(t *testing.T) {
+	masterKey, err := DeriveMasterKey("Alex", "123")
+	if err != nil {
+		t.Fatalf("DeriveMasterKey() error = %v", err)
+	}
+
+	c := New()
+
+	if _, err := c.Encrypt([]byte("123")); !errors.Is(err, ErrNotSetKey) {
+		t.Errorf("Encrypt() before SetMasterKey error = %v, want %v", err, ErrNotSetKey)
+	}
+
+	if err := c.SetMasterKey(masterKey); err != nil {
+		t.Fatalf("SetMasterKey() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(masterKey.key, make([]byte, len(masterKey.key))) {
+		t.Errorf("SetMasterKey() didn't wipe derived key")
+	}
+
+	wantBytes, _ := base64.StdEncoding.DecodeString("N2TYGKOOK+WFa7ABK0ZguBrMxfjI")
+
+	got, err := c.Encrypt([]byte("123"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, wantBytes) {
+		t.Errorf("Encrypt() got = %v, want key of master password", got)
+	}
+
+	if err := c.Unlock("123"); err != nil {
+		t.Errorf("Unlock() error = %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package vaultdata
+
+type ResolveStrategy string
+
+const (
+	ResolveLocal  ResolveStrategy = "local"
+	ResolveRemote ResolveStrategy = "remote"
+	ResolveBoth   ResolveStrategy = "both"
+	ResolveMerge  ResolveStrategy = "merge"
+)
+
+func (s ResolveStrategy) IsValid() bool {
+	switch s {
+	case ResolveLocal, ResolveRemote, ResolveBoth, ResolveMerge:
+		return true
+	}
+
+	return false
+}
+
+// ConflictResolve describes how to resolve conflict. Fields is used only by ResolveMerge:
+// field name to ResolveLocal or ResolveRemote, missing fields keep local value.
+type ConflictResolve struct {
+	Strategy ResolveStrategy
+	Fields   map[string]ResolveStrategy
+}
+
+type ConflictFieldDiff struct {
+	Name     string
+	Local    string
+	Remote   string
+	IsSecret bool
+}
+
+func (d ConflictFieldDiff) IsChanged() bool {
+	return d.Local != d.Remote
+}
+
+type Conflict struct {
+	Kind    string
+	ID      uint32
+	VaultID string
+
+	LocalVersion    int
+	RemoteVersion   int
+	IsLocalDeleted  bool
+	IsRemoteDeleted bool
+
+	Fields []ConflictFieldDiff
+}
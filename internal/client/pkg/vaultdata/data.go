@@ -1,5 +1,7 @@
 package vaultdata
 
+import "time"
+
 type VaultSyncVersion struct {
 	ID      string
 	Version int
@@ -19,3 +21,61 @@ type VaultClientSyncResult struct {
 	ID      string
 	Version int
 }
+
+type VaultBatchOperationType int
+
+const (
+	VaultBatchCreate VaultBatchOperationType = iota
+	VaultBatchUpdate
+	VaultBatchDelete
+)
+
+type VaultBatchOperation struct {
+	Type    VaultBatchOperationType
+	ID      string // for update and delete
+	Vault   []byte
+	Version int
+	S3URL   string
+}
+
+type VaultBatchResult struct {
+	ID         string
+	Version    int
+	IsConflict bool
+	IsNotFound bool
+}
+
+// Usage of server storage, zero max value means unlimited
+type Usage struct {
+	BlobBytes    int64
+	MaxBlobBytes int64
+	Items        int64
+	MaxItems     int64
+}
+
+// Device is a client session registered on login
+type Device struct {
+	ID             string
+	Name           string
+	Platform       string
+	CreatedAt      time.Time
+	LastSeenAt     time.Time
+	LastSyncCursor int64
+	IsCurrent      bool
+}
+
+// AccountBlob is reference of uploaded file, VaultID is empty when file isn't linked to vault
+type AccountBlob struct {
+	ObjectName string
+	VaultID    string
+	Size       int64
+	CreatedAt  time.Time
+	Location   string
+}
+
+// AccountExportPage is part of account export, Login is set only in the first page
+type AccountExportPage struct {
+	Login  string
+	Vaults []VaultSyncData
+	Blobs  []AccountBlob
+}
@@ -3,3 +3,7 @@ package vaultdata
 import "errors"
 
 var ErrNotFoundVaultInStorage = errors.New("not found")
+
+var ErrNotFoundConflict = errors.New("conflict not found")
+
+var ErrInvalidResolve = errors.New("invalid conflict resolve")
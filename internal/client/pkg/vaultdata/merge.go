@@ -0,0 +1,63 @@
+package vaultdata
+
+// FieldVersions keeps for every field the vault version where the field was changed last time.
+// Server serializes updates by vault version, so it works as logical clock for the fields.
+type FieldVersions map[string]int
+
+func (v FieldVersions) Copy() FieldVersions {
+	c := make(FieldVersions, len(v))
+
+	for k, version := range v {
+		c[k] = version
+	}
+
+	return c
+}
+
+// FieldMerge is result of MergeFields: fields which must take remote value and fields changed on both sides.
+type FieldMerge struct {
+	Remote     []string
+	Collisions []string
+}
+
+func (m FieldMerge) HasCollisions() bool {
+	return len(m.Collisions) != 0
+}
+
+// MergeFields compares local and remote values of conflicted vault. baseVersion is the last version
+// synced by local device, dirty are fields changed locally after it. Remote field without version
+// is an old payload, then any different value counts as remote change.
+func MergeFields(
+	fields []string,
+	baseVersion int,
+	dirty map[string]bool,
+	remoteVersions FieldVersions,
+	local, remote map[string]string,
+) FieldMerge {
+	result := FieldMerge{
+		Remote:     make([]string, 0),
+		Collisions: make([]string, 0),
+	}
+
+	for _, field := range fields {
+		if local[field] == remote[field] {
+			continue
+		}
+
+		remoteVersion, ok := remoteVersions[field]
+		isRemoteChanged := !ok || remoteVersion > baseVersion
+
+		if !isRemoteChanged {
+			continue
+		}
+
+		if dirty[field] {
+			result.Collisions = append(result.Collisions, field)
+			continue
+		}
+
+		result.Remote = append(result.Remote, field)
+	}
+
+	return result
+}
@@ -0,0 +1,72 @@
+package vaultdata
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeFields(t *testing.T) {
+	fields := []string{"login", "password", "site"}
+
+	type args struct {
+		dirty          map[string]bool
+		remoteVersions FieldVersions
+		local          map[string]string
+		remote         map[string]string
+	}
+	tests := []struct {
+		name string
+		args args
+		want FieldMerge
+	}{
+		{
+			name: "different fields changed",
+			args: args{
+				dirty:          map[string]bool{"password": true},
+				remoteVersions: FieldVersions{"login": 0, "password": 0, "site": 3},
+				local:          map[string]string{"login": "alex", "password": "new", "site": "vk.com"},
+				remote:         map[string]string{"login": "alex", "password": "old", "site": "vk.ru"},
+			},
+			want: FieldMerge{Remote: []string{"site"}, Collisions: []string{}},
+		},
+		{
+			name: "same field changed",
+			args: args{
+				dirty:          map[string]bool{"password": true},
+				remoteVersions: FieldVersions{"login": 0, "password": 3, "site": 0},
+				local:          map[string]string{"login": "alex", "password": "local", "site": "vk.com"},
+				remote:         map[string]string{"login": "alex", "password": "remote", "site": "vk.com"},
+			},
+			want: FieldMerge{Remote: []string{}, Collisions: []string{"password"}},
+		},
+		{
+			name: "same value is not collision",
+			args: args{
+				dirty:          map[string]bool{"password": true},
+				remoteVersions: FieldVersions{"login": 0, "password": 3, "site": 0},
+				local:          map[string]string{"login": "alex", "password": "same", "site": "vk.com"},
+				remote:         map[string]string{"login": "alex", "password": "same", "site": "vk.com"},
+			},
+			want: FieldMerge{Remote: []string{}, Collisions: []string{}},
+		},
+		{
+			name: "old payload without versions",
+			args: args{
+				dirty:          map[string]bool{"password": true},
+				remoteVersions: nil,
+				local:          map[string]string{"login": "alex", "password": "local", "site": "vk.com"},
+				remote:         map[string]string{"login": "polly", "password": "remote", "site": "vk.com"},
+			},
+			want: FieldMerge{Remote: []string{"login"}, Collisions: []string{"password"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MergeFields(fields, 2, tt.args.dirty, tt.args.remoteVersions, tt.args.local, tt.args.remote)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
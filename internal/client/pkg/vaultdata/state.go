@@ -3,4 +3,5 @@ package vaultdata
 type State interface {
 	SetUserToken(token string)
 	GetUserToken() string
+	Logout()
 }
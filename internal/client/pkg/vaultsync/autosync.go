@@ -0,0 +1,226 @@
+package vaultsync
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultclient"
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultcrypt"
+)
+
+const (
+	autoSyncBackoffMin = time.Second
+	autoSyncBackoffMax = 5 * time.Minute
+)
+
+type SyncStatus struct {
+	Pending   int
+	Conflicts int
+	LastError error
+}
+
+func (st SyncStatus) IsOffline() bool {
+	code := status.Code(st.LastError)
+
+	return code == codes.Unavailable || code == codes.DeadlineExceeded
+}
+
+// String returns short status for prompt, empty when everything is synced
+func (st SyncStatus) String() string {
+	parts := make([]string, 0, 3)
+
+	if st.IsOffline() {
+		parts = append(parts, "offline")
+	} else if st.LastError != nil {
+		parts = append(parts, "sync failed")
+	}
+
+	if st.Pending != 0 {
+		parts = append(parts, fmt.Sprintf("%d pending", st.Pending))
+	}
+
+	if st.Conflicts != 0 {
+		parts = append(parts, fmt.Sprintf("%d conflicts", st.Conflicts))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// Status returns counts of local changes kept by countChanges, it doesn't read storages on every render of prompt
+func (s *VaultSync) Status() SyncStatus {
+	s.statusMux.RLock()
+	isCounted := s.isCounted
+	s.statusMux.RUnlock()
+
+	if !isCounted {
+		s.countChanges()
+	}
+
+	s.statusMux.RLock()
+	st := SyncStatus{
+		Pending:   s.pending,
+		Conflicts: s.conflicts,
+		LastError: s.lastSyncErr,
+	}
+	s.statusMux.RUnlock()
+
+	if isSyncNotReady(st.LastError) {
+		st.LastError = nil
+	}
+
+	return st
+}
+
+// countChanges counts local changes waiting for server acknowledge and conflicts. It is called after
+// local mutation, sync and applied remote changes
+func (s *VaultSync) countChanges() {
+	s.countMux.Lock()
+	defer s.countMux.Unlock()
+
+	pending, conflicts := 0, 0
+
+	for _, storage := range s.storages {
+		arr, err := storage.LoadForSync()
+
+		if err != nil {
+			continue
+		}
+
+		for _, v := range arr {
+			if v.GetIsConflict() {
+				conflicts++
+				continue
+			}
+
+			if v.GetIsNew() || v.GetIsUpdate() || v.GetIsDelete() {
+				pending++
+			}
+		}
+	}
+
+	s.statusMux.Lock()
+	s.pending = pending
+	s.conflicts = conflicts
+	s.isCounted = true
+	s.statusMux.Unlock()
+}
+
+// RequestSync counts local mutation as pending and asks auto sync loop to sync soon, it never blocks on loop
+func (s *VaultSync) RequestSync() {
+	s.countChanges()
+
+	select {
+	case s.requests <- struct{}{}:
+	default:
+	}
+}
+
+// AutoSync syncs every interval and after requests debounced by debounce until ctx is done.
+// Failed sync is retried with exponential backoff, local changes stay pending in storages
+func (s *VaultSync) AutoSync(ctx context.Context, interval, debounce time.Duration) {
+	backoff := time.Duration(0)
+
+	timer := s.newTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C():
+		case <-s.requests:
+			if !s.waitDebounce(ctx, debounce) {
+				return
+			}
+		}
+
+		// Vault can't be encrypted while locked, changes stay pending
+		if s.vcrypt.IsLocked() {
+			timer.Reset(interval)
+			continue
+		}
+
+		err := s.Sync()
+
+		next := interval
+
+		if err != nil && !isSyncNotReady(err) {
+			backoff *= 2
+			if backoff < autoSyncBackoffMin {
+				backoff = autoSyncBackoffMin
+			}
+			if backoff > autoSyncBackoffMax {
+				backoff = autoSyncBackoffMax
+			}
+
+			next = backoff
+		} else {
+			backoff = 0
+		}
+
+		timer.Reset(next)
+	}
+}
+
+// waitDebounce waits until requests are quiet for debounce
+func (s *VaultSync) waitDebounce(ctx context.Context, debounce time.Duration) bool {
+	timer := s.newTimer(debounce)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-s.requests:
+			timer.Reset(debounce)
+		case <-timer.C():
+			return true
+		}
+	}
+}
+
+// syncTimer is timer of auto sync loop
+type syncTimer interface {
+	C() <-chan time.Time
+	Reset(d time.Duration)
+	Stop()
+}
+
+type realTimer struct {
+	timer *time.Timer
+}
+
+func newRealTimer(d time.Duration) syncTimer {
+	return &realTimer{timer: time.NewTimer(d)}
+}
+
+func (t *realTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+// Reset drops tick fired before reset, so loop doesn't wake up right after it
+func (t *realTimer) Reset(d time.Duration) {
+	if !t.timer.Stop() {
+		select {
+		case <-t.timer.C:
+		default:
+		}
+	}
+
+	t.timer.Reset(d)
+}
+
+func (t *realTimer) Stop() {
+	t.timer.Stop()
+}
+
+// isSyncNotReady reports that user is not logged in or vault is locked, it is not a sync failure
+func isSyncNotReady(err error) bool {
+	return errors.Is(err, vaultclient.ErrNotAuth) || errors.Is(err, vaultcrypt.ErrNotSetKey)
+}
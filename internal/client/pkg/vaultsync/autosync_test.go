@@ -0,0 +1,253 @@
+package vaultsync
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultclient"
+	vaultclientmock "github.com/shreyner/gophkeeper/internal/client/pkg/vaultclient/mock"
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultcrypt"
+)
+
+func TestSyncStatus_String(t *testing.T) {
+	tests := []struct {
+		name   string
+		status SyncStatus
+		want   string
+	}{
+		{
+			name:   "synced",
+			status: SyncStatus{},
+			want:   "",
+		},
+		{
+			name:   "pending",
+			status: SyncStatus{Pending: 2},
+			want:   "2 pending",
+		},
+		{
+			name:   "offline",
+			status: SyncStatus{Pending: 1, LastError: status.Error(codes.Unavailable, "connection refused")},
+			want:   "offline, 1 pending",
+		},
+		{
+			name:   "failed with conflicts",
+			status: SyncStatus{Conflicts: 1, LastError: errors.New("error")},
+			want:   "sync failed, 1 conflicts",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.status.String())
+		})
+	}
+}
+
+// timerEvent is start or reset of fake timer with its duration
+type timerEvent struct {
+	action string
+	d      time.Duration
+	timer  *fakeTimer
+}
+
+// fakeClock creates timers fired only by test. Every start and reset is sent to events,
+// so test steps the loop one event after another
+type fakeClock struct {
+	events chan timerEvent
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{events: make(chan timerEvent)}
+}
+
+func (c *fakeClock) newTimer(d time.Duration) syncTimer {
+	timer := fakeTimer{clock: c, c: make(chan time.Time, 1)}
+	c.events <- timerEvent{action: "start", d: d, timer: &timer}
+
+	return &timer
+}
+
+// next returns next start or reset of timer made by loop
+func (c *fakeClock) next(t *testing.T) timerEvent {
+	select {
+	case event := <-c.events:
+		return event
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "loop doesn't start or reset timer")
+		return timerEvent{}
+	}
+}
+
+type fakeTimer struct {
+	clock *fakeClock
+	c     chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Reset(d time.Duration) {
+	t.clock.events <- timerEvent{action: "reset", d: d, timer: t}
+}
+
+func (t *fakeTimer) Stop() {}
+
+func (t *fakeTimer) fire() {
+	t.c <- time.Time{}
+}
+
+func TestVaultSync_AutoSync(t *testing.T) {
+	const interval = time.Minute
+
+	errUnavailable := status.Error(codes.Unavailable, "connection refused")
+
+	tests := []struct {
+		name     string
+		isLocked bool
+		syncErrs []error
+		want     []time.Duration
+	}{
+		{
+			name:     "Synced by interval",
+			syncErrs: []error{nil, nil},
+			want:     []time.Duration{interval, interval},
+		},
+		{
+			name:     "Failed sync is retried with growing backoff",
+			syncErrs: []error{errUnavailable, errUnavailable, errUnavailable, nil, errUnavailable},
+			want:     []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, interval, time.Second},
+		},
+		{
+			name: "Backoff is limited",
+			syncErrs: []error{
+				errUnavailable, errUnavailable, errUnavailable, errUnavailable, errUnavailable,
+				errUnavailable, errUnavailable, errUnavailable, errUnavailable, errUnavailable,
+			},
+			want: []time.Duration{
+				time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second,
+				32 * time.Second, 64 * time.Second, 128 * time.Second, 256 * time.Second, autoSyncBackoffMax,
+			},
+		},
+		{
+			name:     "Not logged in user is not retried by backoff",
+			syncErrs: []error{vaultclient.ErrNotAuth},
+			want:     []time.Duration{interval},
+		},
+		{
+			name:     "Locked vault is not synced",
+			isLocked: true,
+			want:     []time.Duration{interval, interval},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			vclient := vaultclientmock.NewMockVClient(ctrl)
+
+			calls := make([]*gomock.Call, 0, len(tt.syncErrs))
+
+			for _, err := range tt.syncErrs {
+				calls = append(calls, vclient.EXPECT().VaultSyncStream(gomock.Any(), gomock.Any(), gomock.Any()).Return(err))
+			}
+
+			gomock.InOrder(calls...)
+
+			vcrypt := vaultcrypt.New()
+
+			if tt.isLocked {
+				require.NoError(t, vcrypt.SetMasterPassword("alex", "password"))
+				vcrypt.Lock()
+			}
+
+			clock := newFakeClock()
+
+			vsync := New(vcrypt, vclient, nil)
+			vsync.newTimer = clock.newTimer
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+
+			go func() {
+				vsync.AutoSync(ctx, interval, time.Second)
+				close(done)
+			}()
+
+			event := clock.next(t)
+			assert.Equal(t, timerEvent{action: "start", d: interval, timer: event.timer}, event)
+
+			got := make([]time.Duration, 0, len(tt.want))
+
+			for range tt.want {
+				event.timer.fire()
+
+				reset := clock.next(t)
+				assert.Equal(t, "reset", reset.action)
+
+				got = append(got, reset.d)
+			}
+
+			cancel()
+			<-done
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestVaultSync_AutoSync_Debounce(t *testing.T) {
+	const (
+		interval = time.Minute
+		debounce = time.Second
+	)
+
+	ctrl := gomock.NewController(t)
+	vclient := vaultclientmock.NewMockVClient(ctrl)
+
+	// Requests during debounce are synced once
+	vclient.EXPECT().VaultSyncStream(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+	clock := newFakeClock()
+
+	vsync := New(vaultcrypt.New(), vclient, nil)
+	vsync.newTimer = clock.newTimer
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		vsync.AutoSync(ctx, interval, debounce)
+		close(done)
+	}()
+
+	loopTimer := clock.next(t).timer
+
+	vsync.RequestSync()
+
+	event := clock.next(t)
+	assert.Equal(t, "start", event.action)
+	assert.Equal(t, debounce, event.d)
+
+	debounceTimer := event.timer
+
+	vsync.RequestSync()
+
+	event = clock.next(t)
+	assert.Equal(t, timerEvent{action: "reset", d: debounce, timer: debounceTimer}, event, "debounce is restarted by request")
+
+	debounceTimer.fire()
+
+	event = clock.next(t)
+	assert.Equal(t, timerEvent{action: "reset", d: interval, timer: loopTimer}, event, "interval starts after sync")
+
+	cancel()
+	<-done
+}
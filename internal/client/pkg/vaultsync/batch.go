@@ -0,0 +1,133 @@
+package vaultsync
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultdata"
+)
+
+// syncBatchSize bounds operations in one VaultBatch request
+const syncBatchSize = 100
+
+type batchItem struct {
+	operation vaultdata.VaultBatchOperation
+	data      dataSync
+}
+
+// pushVaults uploads local changes by batches, each batch is applied by server in one transaction
+func (s *VaultSync) pushVaults(created, updated, deleted []dataSync) error {
+	items := make([]batchItem, 0, len(created)+len(updated)+len(deleted))
+
+	for _, d := range created {
+		item, err := s.newBatchItem(vaultdata.VaultBatchCreate, d)
+		if err != nil {
+			return err
+		}
+
+		items = append(items, item)
+	}
+
+	for _, d := range deleted {
+		item, err := s.newBatchItem(vaultdata.VaultBatchDelete, d)
+		if err != nil {
+			return err
+		}
+
+		items = append(items, item)
+	}
+
+	for _, d := range updated {
+		item, err := s.newBatchItem(vaultdata.VaultBatchUpdate, d)
+		if err != nil {
+			return err
+		}
+
+		items = append(items, item)
+	}
+
+	for start := 0; start < len(items); start += syncBatchSize {
+		end := start + syncBatchSize
+		if end > len(items) {
+			end = len(items)
+		}
+
+		err := s.pushBatch(items[start:end])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *VaultSync) newBatchItem(operationType vaultdata.VaultBatchOperationType, d dataSync) (batchItem, error) {
+	operation := vaultdata.VaultBatchOperation{
+		Type:    operationType,
+		ID:      d.vault.GetVaultID(),
+		Version: d.vault.GetVersion(),
+		S3URL:   d.s3URL,
+	}
+
+	if operationType != vaultdata.VaultBatchDelete {
+		storage := s.storages[d.typeVaultStorage]
+		dstVault, err := storage.SerializeToVault(d.vault)
+
+		if err != nil {
+			return batchItem{}, err
+		}
+
+		vsd := vaultSyncData{
+			TypeVaultStorage: d.typeVaultStorage,
+			Data:             dstVault,
+		}
+
+		encrypted, err := s.EncryptVault(vsd)
+
+		if err != nil {
+			return batchItem{}, err
+		}
+
+		operation.Vault = encrypted
+	}
+
+	return batchItem{operation: operation, data: d}, nil
+}
+
+func (s *VaultSync) pushBatch(items []batchItem) error {
+	ctx := context.Background()
+
+	operations := make([]vaultdata.VaultBatchOperation, len(items))
+
+	for i, item := range items {
+		operations[i] = item.operation
+	}
+
+	results, err := s.vclient.VaultBatch(ctx, operations)
+
+	if err != nil {
+		return err
+	}
+
+	for i, result := range results {
+		item := items[i]
+
+		// Server has newer version, conflict is saved after loading it
+		if result.IsConflict || result.IsNotFound {
+			continue
+		}
+
+		storage := s.storages[item.data.typeVaultStorage]
+
+		if item.operation.Type == vaultdata.VaultBatchDelete {
+			err = storage.ConfirmDeleteAfterSyncByID(item.data.vault)
+		} else {
+			err = storage.UpdateAfterSyncByID(item.data.vault, result.ID, result.Version)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package vaultsync_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	vaultclientmock "github.com/shreyner/gophkeeper/internal/client/pkg/vaultclient/mock"
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultcrypt"
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultdata"
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultsync"
+	vaultsyncmock "github.com/shreyner/gophkeeper/internal/client/pkg/vaultsync/mock"
+)
+
+func TestVaultSync_Sync_Batch(t *testing.T) {
+	t.Run("pending deletes are sent by bounded batches", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+		ctrl := gomock.NewController(t)
+
+		vclient := vaultclientmock.NewMockVClient(ctrl)
+		storage := vaultsyncmock.NewMockStorageSyncer(ctrl)
+
+		pending := make([]vaultsync.DataSyncer, 0, 150)
+
+		for i := 0; i < 150; i++ {
+			d := vaultsyncmock.NewMockDataSyncer(ctrl)
+			d.EXPECT().GetVaultID().Return(fmt.Sprintf("vault-%d", i)).AnyTimes()
+			d.EXPECT().GetVersion().Return(1).AnyTimes()
+			d.EXPECT().GetS3URL().Return("").AnyTimes()
+			d.EXPECT().GetIsConflict().Return(false).AnyTimes()
+			d.EXPECT().GetIsNew().Return(false).AnyTimes()
+			d.EXPECT().GetIsDelete().Return(true).AnyTimes()
+			d.EXPECT().GetIsUpdate().Return(false).AnyTimes()
+
+			pending = append(pending, d)
+		}
+
+		storage.EXPECT().GetKind().Return("login").AnyTimes()
+		storage.EXPECT().LoadForSync().Return(pending, nil).AnyTimes()
+
+		batchSizes := make([]int, 0)
+
+		vclient.EXPECT().
+			VaultBatch(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, operations []vaultdata.VaultBatchOperation) ([]vaultdata.VaultBatchResult, error) {
+				batchSizes = append(batchSizes, len(operations))
+
+				results := make([]vaultdata.VaultBatchResult, len(operations))
+
+				for i, operation := range operations {
+					results[i] = vaultdata.VaultBatchResult{
+						ID:         operation.ID,
+						Version:    operation.Version,
+						IsConflict: operation.ID == "vault-0",
+					}
+				}
+
+				return results, nil
+			}).
+			Times(2)
+
+		storage.EXPECT().ConfirmDeleteAfterSyncByID(gomock.Any()).Return(nil).Times(149)
+
+		vclient.EXPECT().VaultSyncStream(gomock.Any(), int64(0), gomock.Any()).Return(nil)
+
+		vsync := vaultsync.New(vaultcrypt.New(), vclient, []vaultsync.StorageSyncer{storage})
+
+		err := vsync.Sync()
+
+		require.NoError(err)
+		assert.Equal([]int{100, 50}, batchSizes)
+	})
+}
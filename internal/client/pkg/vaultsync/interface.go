@@ -1,6 +1,8 @@
 //go:generate ./bin/mockgen -source=./interfaces.go -destination=./mock/storage.go -package=vaultsync
 package vaultsync
 
+import "github.com/shreyner/gophkeeper/internal/client/pkg/vaultdata"
+
 //type StorageID uint32
 //type VaultID string
 
@@ -13,6 +15,7 @@ type DataSyncer interface {
 	GetIsNew() bool
 	GetIsDelete() bool
 	GetIsUpdate() bool
+	GetIsConflict() bool
 	IsNeedSync() bool
 }
 
@@ -33,5 +36,9 @@ type StorageSyncer interface {
 	UpdateDataStorage(externalID string, version int, data interface{}) error
 	DeleteDataStorage(externalID string, version int) error
 
-	SetConflictFlag(ID uint32) error
+	// For conflicts
+	SetConflict(ID uint32, remote vaultdata.VaultSyncData, data interface{}) error
+	MergeConflict(ID uint32, remote vaultdata.VaultSyncData, data interface{}) (bool, error)
+	GetConflicts() ([]vaultdata.Conflict, error)
+	ResolveConflict(externalID string, resolve vaultdata.ConflictResolve) error
 }
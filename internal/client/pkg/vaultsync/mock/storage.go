@@ -8,6 +8,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	vaultdata "github.com/shreyner/gophkeeper/internal/client/pkg/vaultdata"
 	vaultsync "github.com/shreyner/gophkeeper/internal/client/pkg/vaultsync"
 )
 
@@ -48,6 +49,20 @@ func (mr *MockDataSyncerMockRecorder) GetID() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetID", reflect.TypeOf((*MockDataSyncer)(nil).GetID))
 }
 
+// GetIsConflict mocks base method.
+func (m *MockDataSyncer) GetIsConflict() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIsConflict")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// GetIsConflict indicates an expected call of GetIsConflict.
+func (mr *MockDataSyncerMockRecorder) GetIsConflict() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIsConflict", reflect.TypeOf((*MockDataSyncer)(nil).GetIsConflict))
+}
+
 // GetIsDelete mocks base method.
 func (m *MockDataSyncer) GetIsDelete() bool {
 	m.ctrl.T.Helper()
@@ -226,6 +241,21 @@ func (mr *MockStorageSyncerMockRecorder) DeserializeFromVault(arg0 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeserializeFromVault", reflect.TypeOf((*MockStorageSyncer)(nil).DeserializeFromVault), arg0)
 }
 
+// GetConflicts mocks base method.
+func (m *MockStorageSyncer) GetConflicts() ([]vaultdata.Conflict, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConflicts")
+	ret0, _ := ret[0].([]vaultdata.Conflict)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConflicts indicates an expected call of GetConflicts.
+func (mr *MockStorageSyncerMockRecorder) GetConflicts() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConflicts", reflect.TypeOf((*MockStorageSyncer)(nil).GetConflicts))
+}
+
 // GetKind mocks base method.
 func (m *MockStorageSyncer) GetKind() string {
 	m.ctrl.T.Helper()
@@ -255,6 +285,35 @@ func (mr *MockStorageSyncerMockRecorder) LoadForSync() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadForSync", reflect.TypeOf((*MockStorageSyncer)(nil).LoadForSync))
 }
 
+// MergeConflict mocks base method.
+func (m *MockStorageSyncer) MergeConflict(ID uint32, remote vaultdata.VaultSyncData, data interface{}) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MergeConflict", ID, remote, data)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MergeConflict indicates an expected call of MergeConflict.
+func (mr *MockStorageSyncerMockRecorder) MergeConflict(ID, remote, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MergeConflict", reflect.TypeOf((*MockStorageSyncer)(nil).MergeConflict), ID, remote, data)
+}
+
+// ResolveConflict mocks base method.
+func (m *MockStorageSyncer) ResolveConflict(externalID string, resolve vaultdata.ConflictResolve) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveConflict", externalID, resolve)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResolveConflict indicates an expected call of ResolveConflict.
+func (mr *MockStorageSyncerMockRecorder) ResolveConflict(externalID, resolve interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveConflict", reflect.TypeOf((*MockStorageSyncer)(nil).ResolveConflict), externalID, resolve)
+}
+
 // SerializeToVault mocks base method.
 func (m *MockStorageSyncer) SerializeToVault(data interface{}) ([]byte, error) {
 	m.ctrl.T.Helper()
@@ -270,18 +329,18 @@ func (mr *MockStorageSyncerMockRecorder) SerializeToVault(data interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SerializeToVault", reflect.TypeOf((*MockStorageSyncer)(nil).SerializeToVault), data)
 }
 
-// SetConflictFlag mocks base method.
-func (m *MockStorageSyncer) SetConflictFlag(ID uint32) error {
+// SetConflict mocks base method.
+func (m *MockStorageSyncer) SetConflict(ID uint32, remote vaultdata.VaultSyncData, data interface{}) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SetConflictFlag", ID)
+	ret := m.ctrl.Call(m, "SetConflict", ID, remote, data)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// SetConflictFlag indicates an expected call of SetConflictFlag.
-func (mr *MockStorageSyncerMockRecorder) SetConflictFlag(ID interface{}) *gomock.Call {
+// SetConflict indicates an expected call of SetConflict.
+func (mr *MockStorageSyncerMockRecorder) SetConflict(ID, remote, data interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetConflictFlag", reflect.TypeOf((*MockStorageSyncer)(nil).SetConflictFlag), ID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetConflict", reflect.TypeOf((*MockStorageSyncer)(nil).SetConflict), ID, remote, data)
 }
 
 // UpdateAfterSyncByID mocks base method.
@@ -6,7 +6,13 @@ import (
 	"encoding/gob"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
 
+	"github.com/shreyner/gophkeeper/internal/client/pkg/atomicfile"
 	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultclient"
 	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultcrypt"
 	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultdata"
@@ -17,6 +23,20 @@ type VaultSync struct {
 	vclient vaultclient.VClient
 
 	storages map[string]StorageSyncer
+
+	mux        sync.Mutex
+	cursor     int64  // last change sequence loaded from server
+	filePathDB string // cursor is saved after every change when set
+
+	requests chan struct{}                   // sync requested after local mutation
+	newTimer func(d time.Duration) syncTimer // timers of auto sync loop, tests replace them
+
+	countMux    sync.Mutex // one count of changes at a time, so older count doesn't overwrite newer one
+	statusMux   sync.RWMutex
+	lastSyncErr error
+	pending     int
+	conflicts   int
+	isCounted   bool
 }
 
 func New(
@@ -25,8 +45,10 @@ func New(
 	storages []StorageSyncer,
 ) *VaultSync {
 	s := VaultSync{
-		vcrypt:  vcrypt,
-		vclient: vclient,
+		vcrypt:   vcrypt,
+		vclient:  vclient,
+		requests: make(chan struct{}, 1),
+		newTimer: newRealTimer,
 	}
 
 	mapStorages := make(map[string]StorageSyncer, len(storages))
@@ -40,146 +62,128 @@ func New(
 	return &s
 }
 
-type dataSync struct {
-	typeVaultStorage string
-	vault            DataSyncer
-	s3URL            string
+type syncSavedState struct {
+	Cursor int64
 }
 
-type vaultSyncData struct {
-	TypeVaultStorage string
-	Data             []byte
-}
+func (s *VaultSync) LoadFromLocalFile(filePathDB string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
 
-func (v *VaultSync) EncryptVault(src vaultSyncData) ([]byte, error) {
-	var buffer bytes.Buffer
-	err := gob.NewEncoder(&buffer).Encode(src)
+	s.filePathDB = filePathDB
 
-	if err != nil {
-		return nil, err
-	}
+	file, err := os.Open(filePathDB)
 
-	encryptedData, err := v.vcrypt.Encrypt(buffer.Bytes())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
 
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return encryptedData, nil
-}
+	defer file.Close()
 
-func (v *VaultSync) DecryptVault(encryptedDist []byte) (*vaultSyncData, error) {
-	dst, err := v.vcrypt.Decrypt(encryptedDist)
+	var savedState syncSavedState
 
-	if err != nil {
-		return nil, err
-	}
+	err = gob.NewDecoder(file).Decode(&savedState)
 
-	var src vaultSyncData
-
-	err = gob.NewDecoder(bytes.NewReader(dst)).Decode(&src)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
 
 	if err != nil {
-		return &src, err
+		return err
 	}
 
-	return &src, nil
-}
+	s.cursor = savedState.Cursor
 
-func (v *VaultSync) createVault(arr []dataSync) error {
-	ctx := context.Background()
+	return nil
+}
 
-	for _, d := range arr {
-		storage := v.storages[d.typeVaultStorage]
-		dstVault, err := storage.SerializeToVault(d.vault)
+func (s *VaultSync) SaveToFile(filePathDB string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
 
-		if err != nil {
-			return err
-		}
+	return s.writeToFile(filePathDB)
+}
 
-		vsd := vaultSyncData{
-			TypeVaultStorage: d.typeVaultStorage,
-			Data:             dstVault,
-		}
+// Reset forgets cursor, so next sync loads all vaults from server
+func (s *VaultSync) Reset() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
 
-		encrypted, err := v.EncryptVault(vsd)
+	s.cursor = 0
 
-		if err != nil {
-			return err
-		}
+	// Local data is wiped before reset
+	s.countChanges()
 
-		createdInfo, err := v.vclient.VaultCreate(ctx, encrypted, d.s3URL)
-
-		if err != nil {
-			return err
-		}
+	return s.persist()
+}
 
-		err = storage.UpdateAfterSyncByID(d.vault, createdInfo.ID, createdInfo.Version)
-		if err != nil {
-			return err
-		}
+// persist saves cursor after change. Caller must hold s.mux
+func (s *VaultSync) persist() error {
+	if s.filePathDB == "" {
+		return nil
 	}
 
-	return nil
+	return s.writeToFile(s.filePathDB)
 }
 
-func (s *VaultSync) updateVault(arr []dataSync) error {
-	ctx := context.Background()
-
-	for _, d := range arr {
-		storage := s.storages[d.typeVaultStorage]
-		dstVault, err := storage.SerializeToVault(d.vault)
+func (s *VaultSync) writeToFile(filePathDB string) error {
+	savedState := syncSavedState{
+		Cursor: s.cursor,
+	}
 
-		if err != nil {
-			return err
-		}
+	return atomicfile.WriteFile(filePathDB, 0600, func(w io.Writer) error {
+		return gob.NewEncoder(w).Encode(&savedState)
+	})
+}
 
-		vsd := vaultSyncData{
-			TypeVaultStorage: d.typeVaultStorage,
-			Data:             dstVault,
-		}
+type dataSync struct {
+	typeVaultStorage string
+	vault            DataSyncer
+	s3URL            string
+}
 
-		encrypted, err := s.EncryptVault(vsd)
+type vaultSyncData struct {
+	TypeVaultStorage string
+	Data             []byte
+}
 
-		if err != nil {
-			return err
-		}
+func (v *VaultSync) EncryptVault(src vaultSyncData) ([]byte, error) {
+	var buffer bytes.Buffer
+	err := gob.NewEncoder(&buffer).Encode(src)
 
-		createdInfo, err := s.vclient.VaultUpdate(ctx, d.vault.GetVaultID(), d.vault.GetVersion(), encrypted)
+	if err != nil {
+		return nil, err
+	}
 
-		if err != nil {
-			return err
-		}
+	encryptedData, err := v.vcrypt.Encrypt(buffer.Bytes())
 
-		err = storage.UpdateAfterSyncByID(d.vault, createdInfo.ID, createdInfo.Version)
-		if err != nil {
-			return err
-		}
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return encryptedData, nil
 }
 
-func (s *VaultSync) deleteVault(arr []dataSync) error {
-	ctx := context.Background()
-
-	for _, d := range arr {
-		storage := s.storages[d.typeVaultStorage]
+func (v *VaultSync) DecryptVault(encryptedDist []byte) (*vaultSyncData, error) {
+	dst, err := v.vcrypt.Decrypt(encryptedDist)
 
-		err := s.vclient.VaultDelete(ctx, d.vault.GetVaultID(), d.vault.GetVersion())
+	if err != nil {
+		return nil, err
+	}
 
-		if err != nil {
-			return err
-		}
+	var src vaultSyncData
 
-		err = storage.ConfirmDeleteAfterSyncByID(d.vault)
+	err = gob.NewDecoder(bytes.NewReader(dst)).Decode(&src)
 
-		if err != nil {
-			return err
-		}
+	if err != nil {
+		return &src, err
 	}
 
-	return nil
+	return &src, nil
 }
 
 func (s *VaultSync) createVaultStorage(data []vaultdata.VaultSyncData) error {
@@ -261,8 +265,23 @@ func (s *VaultSync) deleteVaultStorage(data []vaultdata.VaultSyncData) error {
 }
 
 func (s *VaultSync) Sync() error {
+	err := s.sync()
+
+	s.countChanges()
+
+	s.statusMux.Lock()
+	s.lastSyncErr = err
+	s.statusMux.Unlock()
+
+	return err
+}
+
+func (s *VaultSync) sync() error {
 	ctx := context.Background()
 
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
 	// First
 	newVaultForStorage := make([]dataSync, 0)
 	updateVaultForStorage := make([]dataSync, 0)
@@ -282,6 +301,10 @@ func (s *VaultSync) Sync() error {
 				s3URL:            v.GetS3URL(),
 			}
 
+			if v.GetIsConflict() {
+				continue
+			}
+
 			if v.GetIsNew() {
 				newVaultForStorage = append(newVaultForStorage, d)
 				continue
@@ -299,23 +322,18 @@ func (s *VaultSync) Sync() error {
 		}
 	}
 
-	err := s.createVault(newVaultForStorage)
+	err := s.pushVaults(newVaultForStorage, updateVaultForStorage, deleteVaultForStorage)
 	if err != nil {
 		return err
 	}
 
-	err = s.deleteVault(deleteVaultForStorage)
-	if err != nil {
-		return err
-	}
-
-	err = s.updateVault(updateVaultForStorage)
-	if err != nil {
-		return err
-	}
+	// Second, every page moves cursor, so interrupted sync continues from the last applied page
 
-	// Second
+	return s.vclient.VaultSyncStream(ctx, s.cursor, s.applyChanges)
+}
 
+// applyChanges saves remote changes to storages and moves cursor. Caller must hold s.mux
+func (s *VaultSync) applyChanges(responseVaultSync []vaultdata.VaultSyncData, cursor int64) error {
 	localVaults := make(map[string]dataSync)
 
 	for typeVaultStorage, storage := range s.storages {
@@ -334,38 +352,41 @@ func (s *VaultSync) Sync() error {
 		}
 	}
 
-	vaultsVersionForRequest := make([]vaultdata.VaultSyncVersion, 0, len(localVaults))
-
-	for id, v := range localVaults {
-		d := vaultdata.VaultSyncVersion{
-			ID:      id,
-			Version: v.vault.GetVersion(),
-		}
-		vaultsVersionForRequest = append(vaultsVersionForRequest, d)
-	}
-
-	responseVaultSync, err := s.vclient.VaultSync(ctx, vaultsVersionForRequest)
-
-	if err != nil {
-		return err
-	}
-
 	newVault := make([]vaultdata.VaultSyncData, 0)
 	updateVault := make([]vaultdata.VaultSyncData, 0)
 	deletedVault := make([]vaultdata.VaultSyncData, 0)
+	mergedVault := make([]dataSync, 0)
 
 	for _, responseData := range responseVaultSync {
 		vaultCurrent, ok := localVaults[responseData.ID]
 
 		if !ok {
-			newVault = append(newVault, responseData)
+			if !responseData.IsDeleted {
+				newVault = append(newVault, responseData)
+			}
+
 			continue
 		}
 
-		if responseData.Version > vaultCurrent.vault.GetVersion() && vaultCurrent.vault.GetIsUpdate() {
-			fmt.Printf("Vault Type: %v, id: %v conflict merge. Please resolve conflict for vault\n", vaultCurrent.typeVaultStorage, vaultCurrent.vault.GetID())
-			storage, _ := s.storages[vaultCurrent.typeVaultStorage]
-			err = storage.SetConflictFlag(vaultCurrent.vault.GetID())
+		if isConflict(vaultCurrent.vault, responseData) {
+			isMerged, err := s.mergeConflict(vaultCurrent, responseData)
+			if err != nil {
+				return err
+			}
+
+			if isMerged {
+				mergedVault = append(mergedVault, vaultCurrent)
+				continue
+			}
+
+			fmt.Printf(
+				"Vault Type: %v, id: %v conflict merge. Please resolve conflict with: resolve %v local|remote|both|merge\n",
+				vaultCurrent.typeVaultStorage,
+				vaultCurrent.vault.GetID(),
+				vaultCurrent.vault.GetVaultID(),
+			)
+
+			err = s.setConflict(vaultCurrent, responseData)
 			if err != nil {
 				// TODO: Add Log
 				return err
@@ -379,10 +400,15 @@ func (s *VaultSync) Sync() error {
 			continue
 		}
 
+		if responseData.Version <= vaultCurrent.vault.GetVersion() {
+			// Own change came back by cursor
+			continue
+		}
+
 		updateVault = append(updateVault, responseData)
 	}
 
-	err = s.deleteVaultStorage(deletedVault)
+	err := s.deleteVaultStorage(deletedVault)
 	if err != nil {
 		return err
 	}
@@ -397,5 +423,170 @@ func (s *VaultSync) Sync() error {
 		return err
 	}
 
+	s.cursor = cursor
+
+	err = s.persist()
+	if err != nil {
+		return err
+	}
+
+	// Merged vaults are loaded again, storage saved them with remote version and merged fields
+	mergedVault, err = s.reloadVaults(mergedVault)
+	if err != nil {
+		return err
+	}
+
+	// Upload merged vaults against remote version
+	err = s.pushVaults(nil, mergedVault, nil)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
+
+// reloadVaults returns current state of vaults from their storages, vaults removed meanwhile are skipped
+func (s *VaultSync) reloadVaults(vaults []dataSync) ([]dataSync, error) {
+	if len(vaults) == 0 {
+		return vaults, nil
+	}
+
+	loaded := make(map[string]map[string]DataSyncer)
+	result := make([]dataSync, 0, len(vaults))
+
+	for _, d := range vaults {
+		byVaultID, ok := loaded[d.typeVaultStorage]
+
+		if !ok {
+			arr, err := s.storages[d.typeVaultStorage].LoadForSync()
+
+			if err != nil {
+				return nil, err
+			}
+
+			byVaultID = make(map[string]DataSyncer, len(arr))
+
+			for _, v := range arr {
+				byVaultID[v.GetVaultID()] = v
+			}
+
+			loaded[d.typeVaultStorage] = byVaultID
+		}
+
+		v, ok := byVaultID[d.vault.GetVaultID()]
+
+		if !ok {
+			continue
+		}
+
+		result = append(result, dataSync{
+			typeVaultStorage: d.typeVaultStorage,
+			vault:            v,
+			s3URL:            v.GetS3URL(),
+		})
+	}
+
+	return result, nil
+}
+
+func isConflict(local DataSyncer, remote vaultdata.VaultSyncData) bool {
+	isRemoteNewer := remote.Version > local.GetVersion()
+
+	if local.GetIsUpdate() || local.GetIsConflict() {
+		return isRemoteNewer || remote.IsDeleted
+	}
+
+	if local.GetIsDelete() {
+		return isRemoteNewer && !remote.IsDeleted
+	}
+
+	return false
+}
+
+// mergeConflict merges vaults automatically when local and remote changed different fields
+func (s *VaultSync) mergeConflict(local dataSync, remote vaultdata.VaultSyncData) (bool, error) {
+	if remote.IsDeleted {
+		return false, nil
+	}
+
+	storage := s.storages[local.typeVaultStorage]
+
+	vsd, err := s.DecryptVault(remote.Vault)
+
+	if err != nil {
+		return false, err
+	}
+
+	d, err := storage.DeserializeFromVault(vsd.Data)
+
+	if err != nil {
+		return false, err
+	}
+
+	return storage.MergeConflict(local.vault.GetID(), remote, d)
+}
+
+func (s *VaultSync) setConflict(local dataSync, remote vaultdata.VaultSyncData) error {
+	storage := s.storages[local.typeVaultStorage]
+
+	if remote.IsDeleted {
+		return storage.SetConflict(local.vault.GetID(), remote, nil)
+	}
+
+	vsd, err := s.DecryptVault(remote.Vault)
+
+	if err != nil {
+		return err
+	}
+
+	d, err := storage.DeserializeFromVault(vsd.Data)
+
+	if err != nil {
+		return err
+	}
+
+	return storage.SetConflict(local.vault.GetID(), remote, d)
+}
+
+func (s *VaultSync) Conflicts() ([]vaultdata.Conflict, error) {
+	conflicts := make([]vaultdata.Conflict, 0)
+
+	for _, storage := range s.storages {
+		storageConflicts, err := storage.GetConflicts()
+
+		if err != nil {
+			return nil, err
+		}
+
+		conflicts = append(conflicts, storageConflicts...)
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].Kind != conflicts[j].Kind {
+			return conflicts[i].Kind < conflicts[j].Kind
+		}
+
+		return conflicts[i].ID < conflicts[j].ID
+	})
+
+	return conflicts, nil
+}
+
+// ResolveConflict resolves conflict by vault ID. Result is uploaded on next Sync
+func (s *VaultSync) ResolveConflict(vaultID string, resolve vaultdata.ConflictResolve) error {
+	if !resolve.Strategy.IsValid() {
+		return vaultdata.ErrInvalidResolve
+	}
+
+	for _, storage := range s.storages {
+		err := storage.ResolveConflict(vaultID, resolve)
+
+		if errors.Is(err, vaultdata.ErrNotFoundConflict) {
+			continue
+		}
+
+		return err
+	}
+
+	return vaultdata.ErrNotFoundConflict
+}
@@ -0,0 +1,238 @@
+package vaultsync_test
+
+import (
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	vaultclientmock "github.com/shreyner/gophkeeper/internal/client/pkg/vaultclient/mock"
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultcrypt"
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultdata"
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultsync"
+	vaultsyncmock "github.com/shreyner/gophkeeper/internal/client/pkg/vaultsync/mock"
+	"github.com/shreyner/gophkeeper/internal/client/storage"
+)
+
+func TestVaultSync_Sync_Pages(t *testing.T) {
+	t.Run("cursor moves after every applied page", func(t *testing.T) {
+		require := require.New(t)
+		ctrl := gomock.NewController(t)
+
+		vclient := vaultclientmock.NewMockVClient(ctrl)
+		storage := vaultsyncmock.NewMockStorageSyncer(ctrl)
+
+		storage.EXPECT().GetKind().Return("login").AnyTimes()
+		storage.EXPECT().LoadForSync().Return(nil, nil).AnyTimes()
+
+		type page struct {
+			vaults []vaultdata.VaultSyncData
+			cursor int64
+		}
+
+		pages := []page{
+			{vaults: []vaultdata.VaultSyncData{{ID: "vault-1", IsDeleted: true}}, cursor: 3},
+			{vaults: []vaultdata.VaultSyncData{{ID: "vault-2", Vault: []byte("broken")}}, cursor: 5},
+		}
+
+		gomock.InOrder(
+			vclient.EXPECT().
+				VaultSyncStream(gomock.Any(), int64(0), gomock.Any()).
+				DoAndReturn(func(_ context.Context, _ int64, apply func([]vaultdata.VaultSyncData, int64) error) error {
+					for _, p := range pages {
+						if err := apply(p.vaults, p.cursor); err != nil {
+							return err
+						}
+					}
+
+					return nil
+				}),
+			vclient.EXPECT().
+				VaultSyncStream(gomock.Any(), int64(3), gomock.Any()).
+				Return(nil),
+		)
+
+		vsync := vaultsync.New(vaultcrypt.New(), vclient, []vaultsync.StorageSyncer{storage})
+		require.NoError(vsync.LoadFromLocalFile(path.Join(t.TempDir(), "sync.db")))
+
+		require.Error(vsync.Sync(), "second page can't be decrypted")
+		require.NoError(vsync.Sync())
+	})
+}
+
+func TestVaultSync_Sync_Merge(t *testing.T) {
+	t.Run("merged vault is pushed as saved after merge", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+		ctrl := gomock.NewController(t)
+
+		vclient := vaultclientmock.NewMockVClient(ctrl)
+		storage := vaultsyncmock.NewMockStorageSyncer(ctrl)
+
+		newVault := func(id uint32, version int) *vaultsyncmock.MockDataSyncer {
+			d := vaultsyncmock.NewMockDataSyncer(ctrl)
+			d.EXPECT().GetID().Return(id).AnyTimes()
+			d.EXPECT().GetVaultID().Return("vault-1").AnyTimes()
+			d.EXPECT().GetVersion().Return(version).AnyTimes()
+			d.EXPECT().GetS3URL().Return("").AnyTimes()
+			d.EXPECT().GetIsConflict().Return(false).AnyTimes()
+			d.EXPECT().GetIsNew().Return(false).AnyTimes()
+			d.EXPECT().GetIsDelete().Return(false).AnyTimes()
+			d.EXPECT().GetIsUpdate().Return(true).AnyTimes()
+
+			return d
+		}
+
+		edited := newVault(1, 1)
+		merged := newVault(1, 2)
+
+		local := []vaultsync.DataSyncer{edited}
+
+		storage.EXPECT().GetKind().Return("login").AnyTimes()
+		storage.EXPECT().LoadForSync().DoAndReturn(func() ([]vaultsync.DataSyncer, error) {
+			return local, nil
+		}).AnyTimes()
+		storage.EXPECT().SerializeToVault(gomock.Any()).DoAndReturn(func(data interface{}) ([]byte, error) {
+			if data == merged {
+				return []byte("merged"), nil
+			}
+
+			return []byte("edited"), nil
+		}).AnyTimes()
+		storage.EXPECT().DeserializeFromVault([]byte("edited")).Return("remote", nil)
+		storage.EXPECT().MergeConflict(uint32(1), gomock.Any(), "remote").DoAndReturn(
+			func(_ uint32, _ vaultdata.VaultSyncData, _ interface{}) (bool, error) {
+				local = []vaultsync.DataSyncer{merged}
+
+				return true, nil
+			},
+		)
+
+		pushed := make([][]vaultdata.VaultBatchOperation, 0)
+
+		vclient.EXPECT().
+			VaultBatch(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, operations []vaultdata.VaultBatchOperation) ([]vaultdata.VaultBatchResult, error) {
+				pushed = append(pushed, operations)
+
+				// Remote record was edited too, first push is refused
+				if len(pushed) == 1 {
+					return []vaultdata.VaultBatchResult{{ID: "vault-1", IsConflict: true}}, nil
+				}
+
+				return []vaultdata.VaultBatchResult{{ID: "vault-1", Version: 3}}, nil
+			}).
+			Times(2)
+
+		vclient.EXPECT().
+			VaultSyncStream(gomock.Any(), int64(0), gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ int64, apply func([]vaultdata.VaultSyncData, int64) error) error {
+				// Remote edit carries payload of the same shape, storage deserializes it
+				remote := vaultdata.VaultSyncData{ID: "vault-1", Version: 2, Vault: pushed[0][0].Vault}
+
+				return apply([]vaultdata.VaultSyncData{remote}, 4)
+			})
+
+		storage.EXPECT().UpdateAfterSyncByID(merged, "vault-1", 3).Return(nil)
+
+		vcrypt := vaultcrypt.New()
+		require.NoError(vcrypt.SetMasterPassword("login", "password"))
+
+		vsync := vaultsync.New(vcrypt, vclient, []vaultsync.StorageSyncer{storage})
+
+		require.NoError(vsync.Sync())
+
+		require.Len(pushed, 2)
+		require.Len(pushed[1], 1)
+		assert.Equal(vaultdata.VaultBatchUpdate, pushed[1][0].Type)
+		assert.Equal(2, pushed[1][0].Version, "merged vault is pushed against remote version")
+		assert.NotEqual(pushed[0][0].Vault, pushed[1][0].Vault, "merged fields are pushed")
+	})
+}
+
+func TestVaultSync_Sync_Compression(t *testing.T) {
+	t.Run("compressible record gets smaller on the wire", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+		ctrl := gomock.NewController(t)
+
+		vcrypt := vaultcrypt.New()
+		require.NoError(vcrypt.SetMasterPassword("login", "password"))
+
+		loginStorage := storage.NewLoginVaultStorage(vcrypt, storage.NewMemoryBackend())
+
+		password := strings.Repeat("correct horse battery staple ", 200)
+		require.NoError(loginStorage.Create(&storage.LoginSecreteData{Login: "alex", Password: password}, "vk.com"))
+
+		vclient := vaultclientmock.NewMockVClient(ctrl)
+
+		var pushed []vaultdata.VaultBatchOperation
+
+		vclient.EXPECT().
+			VaultBatch(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, operations []vaultdata.VaultBatchOperation) ([]vaultdata.VaultBatchResult, error) {
+				pushed = operations
+
+				return []vaultdata.VaultBatchResult{{ID: "vault-1", Version: 1}}, nil
+			})
+		vclient.EXPECT().VaultSyncStream(gomock.Any(), int64(0), gomock.Any()).Return(nil)
+
+		vsync := vaultsync.New(vcrypt, vclient, []vaultsync.StorageSyncer{loginStorage})
+
+		require.NoError(vsync.Sync())
+
+		require.Len(pushed, 1)
+		assert.Less(len(pushed[0].Vault), len(password)/4)
+	})
+}
+
+func TestVaultSync_Status(t *testing.T) {
+	t.Run("changes are counted after local mutation and sync, not on every status", func(t *testing.T) {
+		assert := assert.New(t)
+		ctrl := gomock.NewController(t)
+
+		newVault := func(isNew, isConflict bool) vaultsync.DataSyncer {
+			d := vaultsyncmock.NewMockDataSyncer(ctrl)
+			d.EXPECT().GetIsNew().Return(isNew).AnyTimes()
+			d.EXPECT().GetIsUpdate().Return(false).AnyTimes()
+			d.EXPECT().GetIsDelete().Return(false).AnyTimes()
+			d.EXPECT().GetIsConflict().Return(isConflict).AnyTimes()
+
+			return d
+		}
+
+		vclient := vaultclientmock.NewMockVClient(ctrl)
+		storage := vaultsyncmock.NewMockStorageSyncer(ctrl)
+
+		storage.EXPECT().GetKind().Return("login").AnyTimes()
+
+		gomock.InOrder(
+			// First status
+			storage.EXPECT().LoadForSync().Return([]vaultsync.DataSyncer{newVault(true, false)}, nil),
+			// Local mutation
+			storage.EXPECT().LoadForSync().Return([]vaultsync.DataSyncer{newVault(true, false), newVault(false, true)}, nil),
+			// Sync pushes nothing and counts changes after it
+			storage.EXPECT().LoadForSync().Return(nil, nil),
+			storage.EXPECT().LoadForSync().Return(nil, nil),
+		)
+
+		vclient.EXPECT().VaultSyncStream(gomock.Any(), int64(0), gomock.Any()).Return(nil)
+
+		vsync := vaultsync.New(vaultcrypt.New(), vclient, []vaultsync.StorageSyncer{storage})
+
+		assert.Equal(vaultsync.SyncStatus{Pending: 1}, vsync.Status())
+		assert.Equal(vaultsync.SyncStatus{Pending: 1}, vsync.Status(), "storages are not read again")
+
+		vsync.RequestSync()
+
+		assert.Equal(vaultsync.SyncStatus{Pending: 1, Conflicts: 1}, vsync.Status())
+
+		assert.NoError(vsync.Sync())
+
+		assert.Equal(vaultsync.SyncStatus{}, vsync.Status())
+	})
+}
@@ -0,0 +1,86 @@
+package vaultsync
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultclient"
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultdata"
+)
+
+var errWatchLocked = errors.New("vault is locked")
+
+const (
+	watchRetryDelayMin = time.Second
+	watchRetryDelayMax = time.Minute
+)
+
+// Watch applies changes pushed by server in background until ctx is done.
+// Stream is reopened with backoff after errors and while user is not authorized
+func (s *VaultSync) Watch(ctx context.Context) {
+	delay := watchRetryDelayMin
+
+	for {
+		s.mux.Lock()
+		cursor := s.cursor
+		s.mux.Unlock()
+
+		isReceived := false
+
+		err := s.vclient.WatchVaults(ctx, cursor, func(data []vaultdata.VaultSyncData, nextCursor int64) error {
+			isReceived = true
+			return s.applyWatched(data, nextCursor)
+		})
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		if isReceived {
+			delay = watchRetryDelayMin
+		}
+
+		// Server closes stream when access token expires, it is reopened with refreshed one
+		if err != nil && !errors.Is(err, vaultclient.ErrNotAuth) && !errors.Is(err, errWatchLocked) &&
+			!vaultclient.IsTokenExpired(err) && status.Code(err) != codes.Unavailable {
+			fmt.Println("Watch vaults error:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > watchRetryDelayMax {
+			delay = watchRetryDelayMax
+		}
+	}
+}
+
+func (s *VaultSync) applyWatched(data []vaultdata.VaultSyncData, cursor int64) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if cursor <= s.cursor {
+		return nil
+	}
+
+	// Vaults can't be decrypted while locked. Stream is closed to reopen it later from own cursor
+	if s.vcrypt.IsLocked() {
+		return errWatchLocked
+	}
+
+	err := s.applyChanges(data, cursor)
+
+	// Remote change may be conflict with local one
+	s.countChanges()
+
+	return err
+}
@@ -11,8 +11,9 @@ var (
 )
 
 type State struct {
-	IsAuth    bool
+	isAuth    bool
 	userToken string
+	isLocked  bool
 
 	mux sync.RWMutex
 }
@@ -28,7 +29,15 @@ func (s *State) SetUserToken(token string) {
 	defer s.mux.Unlock()
 
 	s.userToken = token
-	s.IsAuth = true
+	s.isAuth = true
+}
+
+// IsAuth reports that user is logged in, it is read by prompt and idle lock from other goroutines
+func (s *State) IsAuth() bool {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	return s.isAuth
 }
 
 func (s *State) GetUserToken() string {
@@ -37,3 +46,27 @@ func (s *State) GetUserToken() string {
 
 	return s.userToken
 }
+
+// Logout forgets user token, commands which need auth are refused until next login
+func (s *State) Logout() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.userToken = ""
+	s.isAuth = false
+	s.isLocked = false
+}
+
+func (s *State) SetLocked(isLocked bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.isLocked = isLocked
+}
+
+func (s *State) IsLocked() bool {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	return s.isLocked
+}
@@ -37,3 +37,22 @@ func TestState_GetUserToken(t *testing.T) {
 		}
 	})
 }
+
+func TestState_Logout(t *testing.T) {
+	t.Run("Forget token and lock", func(t *testing.T) {
+		s := New()
+
+		s.SetUserToken("some token")
+		s.SetLocked(true)
+
+		s.Logout()
+
+		if got := s.GetUserToken(); got != "" {
+			t.Errorf("GetUserToken() = %v, want empty string", got)
+		}
+
+		if s.IsAuth() || s.IsLocked() {
+			t.Errorf("IsAuth() = %v, IsLocked() = %v, want false", s.IsAuth(), s.IsLocked())
+		}
+	})
+}
@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	IndexExternalID = "external_id"
+	IndexSite       = "site"
+)
+
+var ErrNotFoundRecord = errors.New("record not found")
+
+// Backend keeps serialized records of every storage kind with secondary indexes
+type Backend interface {
+	NextID(kind string) (uint32, error)
+
+	Get(kind string, id uint32) ([]byte, error)
+	ForEach(kind string, fn func(id uint32, value []byte) error) error
+
+	// Put replaces record and its index entries. Empty index value is not indexed
+	Put(kind string, id uint32, value []byte, indexes map[string]string) error
+	Delete(kind string, id uint32) error
+
+	FindByIndex(kind, index, value string) ([]uint32, error)
+
+	// Clear deletes records of every kind, it is used to wipe data of revoked device
+	Clear() error
+
+	Close() error
+}
+
+func idToKey(id uint32) []byte {
+	key := make([]byte, 4)
+	binary.BigEndian.PutUint32(key, id)
+
+	return key
+}
+
+func keyToID(key []byte) uint32 {
+	return binary.BigEndian.Uint32(key)
+}
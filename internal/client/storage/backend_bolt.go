@@ -0,0 +1,297 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/gob"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	_ Backend = (*BoltBackend)(nil)
+)
+
+var (
+	bucketRecords       = []byte("records")
+	bucketIndexes       = []byte("indexes")
+	bucketRecordIndexes = []byte("record_indexes") // id -> index values, to clean old entries on put
+)
+
+// BoltBackend keeps every storage kind in own bbolt bucket:
+//
+//	<kind>/records/<id> -> record
+//	<kind>/indexes/<index>/<value>\x00<id> -> nil
+//	<kind>/record_indexes/<id> -> gob map of index values
+type BoltBackend struct {
+	db *bolt.DB
+}
+
+func OpenBoltBackend(filePathDB string) (*BoltBackend, error) {
+	db, err := bolt.Open(filePathDB, 0600, &bolt.Options{Timeout: time.Second})
+
+	if err != nil {
+		return nil, err
+	}
+
+	b := BoltBackend{db: db}
+
+	return &b, nil
+}
+
+func (b *BoltBackend) NextID(kind string) (uint32, error) {
+	var id uint64
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		records, err := createRecordsBucket(tx, kind)
+
+		if err != nil {
+			return err
+		}
+
+		id, err = records.NextSequence()
+
+		return err
+	})
+
+	return uint32(id), err
+}
+
+func (b *BoltBackend) Get(kind string, id uint32) ([]byte, error) {
+	var value []byte
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		records := recordsBucket(tx, kind)
+
+		if records == nil {
+			return ErrNotFoundRecord
+		}
+
+		v := records.Get(idToKey(id))
+
+		if v == nil {
+			return ErrNotFoundRecord
+		}
+
+		// value is valid only inside transaction
+		value = append([]byte(nil), v...)
+
+		return nil
+	})
+
+	return value, err
+}
+
+func (b *BoltBackend) ForEach(kind string, fn func(id uint32, value []byte) error) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		records := recordsBucket(tx, kind)
+
+		if records == nil {
+			return nil
+		}
+
+		return records.ForEach(func(k, v []byte) error {
+			return fn(keyToID(k), append([]byte(nil), v...))
+		})
+	})
+}
+
+func (b *BoltBackend) Put(kind string, id uint32, value []byte, indexes map[string]string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		records, err := createRecordsBucket(tx, kind)
+
+		if err != nil {
+			return err
+		}
+
+		key := idToKey(id)
+
+		err = records.Put(key, value)
+
+		if err != nil {
+			return err
+		}
+
+		if uint64(id) > records.Sequence() {
+			err = records.SetSequence(uint64(id))
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return putIndexes(tx.Bucket([]byte(kind)), key, indexes)
+	})
+}
+
+func (b *BoltBackend) Delete(kind string, id uint32) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		kindBucket := tx.Bucket([]byte(kind))
+
+		if kindBucket == nil {
+			return nil
+		}
+
+		key := idToKey(id)
+
+		err := kindBucket.Bucket(bucketRecords).Delete(key)
+
+		if err != nil {
+			return err
+		}
+
+		return putIndexes(kindBucket, key, nil)
+	})
+}
+
+func (b *BoltBackend) FindByIndex(kind, index, value string) ([]uint32, error) {
+	ids := make([]uint32, 0)
+
+	if value == "" {
+		return ids, nil
+	}
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		kindBucket := tx.Bucket([]byte(kind))
+
+		if kindBucket == nil {
+			return nil
+		}
+
+		indexBucket := kindBucket.Bucket(bucketIndexes).Bucket([]byte(index))
+
+		if indexBucket == nil {
+			return nil
+		}
+
+		prefix := indexKeyPrefix(value)
+		c := indexBucket.Cursor()
+
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			ids = append(ids, keyToID(k[len(prefix):]))
+		}
+
+		return nil
+	})
+
+	return ids, err
+}
+
+func (b *BoltBackend) Clear() error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		kinds := make([][]byte, 0)
+
+		err := tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			kinds = append(kinds, append([]byte(nil), name...))
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+
+		for _, kind := range kinds {
+			err = tx.DeleteBucket(kind)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (b *BoltBackend) Close() error {
+	return b.db.Close()
+}
+
+func recordsBucket(tx *bolt.Tx, kind string) *bolt.Bucket {
+	kindBucket := tx.Bucket([]byte(kind))
+
+	if kindBucket == nil {
+		return nil
+	}
+
+	return kindBucket.Bucket(bucketRecords)
+}
+
+func createRecordsBucket(tx *bolt.Tx, kind string) (*bolt.Bucket, error) {
+	kindBucket, err := tx.CreateBucketIfNotExists([]byte(kind))
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range [][]byte{bucketIndexes, bucketRecordIndexes} {
+		if _, err := kindBucket.CreateBucketIfNotExists(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return kindBucket.CreateBucketIfNotExists(bucketRecords)
+}
+
+// putIndexes replaces index entries of record, nil indexes removes all of them
+func putIndexes(kindBucket *bolt.Bucket, key []byte, indexes map[string]string) error {
+	indexesBucket := kindBucket.Bucket(bucketIndexes)
+	recordIndexes := kindBucket.Bucket(bucketRecordIndexes)
+
+	if old := recordIndexes.Get(key); old != nil {
+		var oldIndexes map[string]string
+
+		err := gob.NewDecoder(bytes.NewReader(old)).Decode(&oldIndexes)
+
+		if err != nil {
+			return err
+		}
+
+		for index, value := range oldIndexes {
+			if indexBucket := indexesBucket.Bucket([]byte(index)); indexBucket != nil {
+				if err := indexBucket.Delete(append(indexKeyPrefix(value), key...)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	actualIndexes := make(map[string]string, len(indexes))
+
+	for index, value := range indexes {
+		if value == "" {
+			continue
+		}
+
+		indexBucket, err := indexesBucket.CreateBucketIfNotExists([]byte(index))
+
+		if err != nil {
+			return err
+		}
+
+		err = indexBucket.Put(append(indexKeyPrefix(value), key...), nil)
+
+		if err != nil {
+			return err
+		}
+
+		actualIndexes[index] = value
+	}
+
+	if len(actualIndexes) == 0 {
+		return recordIndexes.Delete(key)
+	}
+
+	var buffer bytes.Buffer
+
+	err := gob.NewEncoder(&buffer).Encode(actualIndexes)
+
+	if err != nil {
+		return err
+	}
+
+	return recordIndexes.Put(key, buffer.Bytes())
+}
+
+func indexKeyPrefix(value string) []byte {
+	return append([]byte(value), 0)
+}
@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"sort"
+	"sync"
+)
+
+var (
+	_ Backend = (*MemoryBackend)(nil)
+)
+
+type memoryKind struct {
+	sequence uint32
+	records  map[uint32][]byte
+	indexes  map[uint32]map[string]string
+}
+
+// MemoryBackend keeps records in memory only, it is used when nothing must be saved
+type MemoryBackend struct {
+	kinds map[string]*memoryKind
+
+	mux sync.RWMutex
+}
+
+func NewMemoryBackend() *MemoryBackend {
+	b := MemoryBackend{
+		kinds: make(map[string]*memoryKind),
+	}
+
+	return &b
+}
+
+// kind returns records of kind. Caller must hold b.mux
+func (b *MemoryBackend) kind(kind string) *memoryKind {
+	k, ok := b.kinds[kind]
+
+	if !ok {
+		k = &memoryKind{
+			records: make(map[uint32][]byte),
+			indexes: make(map[uint32]map[string]string),
+		}
+		b.kinds[kind] = k
+	}
+
+	return k
+}
+
+func (b *MemoryBackend) NextID(kind string) (uint32, error) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	k := b.kind(kind)
+	k.sequence++
+
+	return k.sequence, nil
+}
+
+func (b *MemoryBackend) Get(kind string, id uint32) ([]byte, error) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	value, ok := b.kind(kind).records[id]
+
+	if !ok {
+		return nil, ErrNotFoundRecord
+	}
+
+	return append([]byte(nil), value...), nil
+}
+
+func (b *MemoryBackend) ForEach(kind string, fn func(id uint32, value []byte) error) error {
+	b.mux.Lock()
+	k := b.kind(kind)
+
+	ids := make([]uint32, 0, len(k.records))
+	values := make(map[uint32][]byte, len(k.records))
+
+	for id, value := range k.records {
+		ids = append(ids, id)
+		values[id] = append([]byte(nil), value...)
+	}
+	b.mux.Unlock()
+
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+
+	for _, id := range ids {
+		if err := fn(id, values[id]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *MemoryBackend) Put(kind string, id uint32, value []byte, indexes map[string]string) error {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	k := b.kind(kind)
+
+	k.records[id] = append([]byte(nil), value...)
+	k.indexes[id] = copyMetaData(indexes)
+
+	if id > k.sequence {
+		k.sequence = id
+	}
+
+	return nil
+}
+
+func (b *MemoryBackend) Delete(kind string, id uint32) error {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	k := b.kind(kind)
+
+	delete(k.records, id)
+	delete(k.indexes, id)
+
+	return nil
+}
+
+func (b *MemoryBackend) FindByIndex(kind, index, value string) ([]uint32, error) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	ids := make([]uint32, 0)
+
+	if value == "" {
+		return ids, nil
+	}
+
+	for id, indexes := range b.kind(kind).indexes {
+		if indexes[index] == value {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+
+	return ids, nil
+}
+
+func (b *MemoryBackend) Clear() error {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	b.kinds = make(map[string]*memoryKind)
+
+	return nil
+}
+
+func (b *MemoryBackend) Close() error {
+	return nil
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackend(t *testing.T) {
+	backends := map[string]func(t *testing.T) Backend{
+		"memory": func(t *testing.T) Backend {
+			return NewMemoryBackend()
+		},
+		"bolt": func(t *testing.T) Backend {
+			b, err := OpenBoltBackend(path.Join(t.TempDir(), "vault.bolt"))
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				_ = b.Close()
+			})
+
+			return b
+		},
+	}
+
+	for name, newBackend := range backends {
+		t.Run(name+" put get delete", func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			b := newBackend(t)
+
+			id, err := b.NextID("site-login")
+			require.NoError(err)
+			assert.Equal(uint32(1), id)
+
+			require.NoError(b.Put("site-login", id, []byte("value"), map[string]string{IndexSite: "vk.com"}))
+
+			value, err := b.Get("site-login", id)
+			require.NoError(err)
+			assert.Equal([]byte("value"), value)
+
+			_, err = b.Get("file", id)
+			assert.ErrorIs(err, ErrNotFoundRecord)
+
+			require.NoError(b.Delete("site-login", id))
+
+			_, err = b.Get("site-login", id)
+			assert.ErrorIs(err, ErrNotFoundRecord)
+
+			ids, err := b.FindByIndex("site-login", IndexSite, "vk.com")
+			require.NoError(err)
+			assert.Empty(ids)
+		})
+
+		t.Run(name+" indexes are replaced on put", func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			b := newBackend(t)
+
+			require.NoError(b.Put("site-login", 1, []byte("1"), map[string]string{IndexSite: "vk.com", IndexExternalID: ""}))
+			require.NoError(b.Put("site-login", 2, []byte("2"), map[string]string{IndexSite: "vk.com", IndexExternalID: "ext-2"}))
+			require.NoError(b.Put("site-login", 1, []byte("1"), map[string]string{IndexSite: "ya.ru", IndexExternalID: "ext-1"}))
+
+			ids, err := b.FindByIndex("site-login", IndexSite, "vk.com")
+			require.NoError(err)
+			assert.Equal([]uint32{2}, ids)
+
+			ids, err = b.FindByIndex("site-login", IndexExternalID, "ext-1")
+			require.NoError(err)
+			assert.Equal([]uint32{1}, ids)
+
+			ids, err = b.FindByIndex("site-login", IndexExternalID, "")
+			require.NoError(err)
+			assert.Empty(ids)
+		})
+
+		t.Run(name+" next id after put", func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			b := newBackend(t)
+
+			require.NoError(b.Put("file", 5, []byte("5"), nil))
+			require.NoError(b.Put("file", 3, []byte("3"), nil))
+
+			id, err := b.NextID("file")
+			require.NoError(err)
+			assert.Equal(uint32(6), id)
+
+			ids := make([]uint32, 0)
+			err = b.ForEach("file", func(id uint32, _ []byte) error {
+				ids = append(ids, id)
+				return nil
+			})
+			require.NoError(err)
+			assert.Equal([]uint32{3, 5}, ids)
+		})
+
+		t.Run(name+" clear", func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			b := newBackend(t)
+
+			require.NoError(b.Put("file", 1, []byte("1"), map[string]string{IndexExternalID: "ext-1"}))
+			require.NoError(b.Put("site-login", 1, []byte("1"), nil))
+
+			require.NoError(b.Clear())
+
+			_, err := b.Get("file", 1)
+			assert.ErrorIs(err, ErrNotFoundRecord)
+
+			ids, err := b.FindByIndex("file", IndexExternalID, "ext-1")
+			require.NoError(err)
+			assert.Empty(ids)
+
+			id, err := b.NextID("site-login")
+			require.NoError(err)
+			assert.Equal(uint32(1), id)
+		})
+	}
+}
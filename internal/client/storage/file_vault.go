@@ -10,9 +10,8 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"sync"
-	"sync/atomic"
-	"time"
 
 	"github.com/jaevor/go-nanoid"
 	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultclient"
@@ -31,15 +30,10 @@ var (
 	_ vaultsync.StorageSyncer = (*FileVaultStorage)(nil)
 )
 
-var fileLastIndex uint32 = 0
-
-func fileLoadNextIndex() uint32 {
-	return atomic.AddUint32(&fileLastIndex, 1)
-}
-
 var FileMetaDataNameKey = "file-name"
 var FileMetaDataExtensionKey = "extension"
 var FileMetaDataEncryptedKey = "encrypted-name"
+var FileMetaDataSizeKey = "size"
 
 type FileVaultModel struct {
 	ID         uint32
@@ -54,6 +48,20 @@ type FileVaultModel struct {
 	IsUpdate   bool // for sync
 	IsDelete   bool // for sync
 	IsConflict bool // for sync
+
+	Conflict *FileVaultConflict // remote version while IsConflict
+
+	FieldVersions vaultdata.FieldVersions // for merge
+	DirtyFields   map[string]bool         // for merge, fields changed after last sync
+}
+
+type FileVaultConflict struct {
+	Version       int
+	IsDeleted     bool
+	Data          []byte
+	MetaData      map[string]string
+	S3URL         string
+	FieldVersions vaultdata.FieldVersions
 }
 
 func (m *FileVaultModel) GetID() uint32 {
@@ -80,6 +88,10 @@ func (m *FileVaultModel) GetIsUpdate() bool {
 	return m.IsUpdate
 }
 
+func (m *FileVaultModel) GetIsConflict() bool {
+	return m.IsConflict
+}
+
 func (m *FileVaultModel) GetS3URL() string {
 	return m.S3URL
 }
@@ -118,15 +130,66 @@ func (m *FileVaultModel) GetEncryptedName() string {
 	return encryptedName
 }
 
+// GetSize returns size of original file, zero for files uploaded before size was saved
+func (m *FileVaultModel) GetSize() int64 {
+	size, _ := strconv.ParseInt(m.MetaData[FileMetaDataSizeKey], 10, 64)
+
+	return size
+}
+
+func (m *FileVaultModel) SetSize(size int64) {
+	m.MetaData[FileMetaDataSizeKey] = strconv.FormatInt(size, 10)
+}
+
+func (m *FileVaultModel) markDirty(field string) {
+	if m.DirtyFields == nil {
+		m.DirtyFields = make(map[string]bool)
+	}
+
+	m.DirtyFields[field] = true
+}
+
+// confirmFieldVersions moves dirty fields to version accepted by server
+func (m *FileVaultModel) confirmFieldVersions(version int) {
+	m.FieldVersions = m.FieldVersions.Copy()
+
+	for _, field := range fileFields {
+		if _, ok := m.FieldVersions[field]; !ok {
+			m.FieldVersions[field] = 0
+		}
+
+		if m.DirtyFields[field] {
+			m.FieldVersions[field] = version
+		}
+	}
+
+	m.DirtyFields = nil
+}
+
 type fileVaultStored struct {
-	Data     []byte
-	MetaData map[string]string
+	Data          []byte
+	MetaData      map[string]string
+	FieldVersions vaultdata.FieldVersions
 }
 
 func fileVaultStoredFromModel(model *FileVaultModel) *fileVaultStored {
+	fieldVersions := model.FieldVersions.Copy()
+
+	for _, field := range fileFields {
+		if _, ok := fieldVersions[field]; !ok || model.IsNew {
+			fieldVersions[field] = 0
+		}
+
+		if model.DirtyFields[field] && !model.IsNew {
+			// server increments version on update
+			fieldVersions[field] = model.Version + 1
+		}
+	}
+
 	v := fileVaultStored{
-		Data:     model.Data,
-		MetaData: model.MetaData,
+		Data:          model.Data,
+		MetaData:      model.MetaData,
+		FieldVersions: fieldVersions,
 	}
 
 	return &v
@@ -134,8 +197,6 @@ func fileVaultStoredFromModel(model *FileVaultModel) *fileVaultStored {
 
 func NewFileVaultModel() *FileVaultModel {
 	m := FileVaultModel{
-		ID: fileLoadNextIndex(),
-
 		MetaData: make(map[string]string),
 
 		IsNew:    true,
@@ -150,8 +211,7 @@ type FileSecreteData struct {
 }
 
 type FileVaultStorage struct {
-	storage              map[uint32]*FileVaultModel
-	indexIDAndExternalID map[string]uint32
+	backend Backend
 
 	vclient *vaultclient.Client
 	crypt   *vaultcrypt.VaultCrypt
@@ -162,13 +222,12 @@ type FileVaultStorage struct {
 func NewFileVaultStorage(
 	crypt *vaultcrypt.VaultCrypt,
 	vclient *vaultclient.Client,
+	backend Backend,
 ) *FileVaultStorage {
 	s := FileVaultStorage{
 		crypt:   crypt,
 		vclient: vclient,
-
-		storage:              make(map[uint32]*FileVaultModel),
-		indexIDAndExternalID: make(map[string]uint32),
+		backend: backend,
 	}
 
 	return &s
@@ -179,74 +238,135 @@ type FileSavedStorage struct {
 	IndexIDAndExternalID map[string]uint32
 }
 
-func (s *FileVaultStorage) LoadFromLocalFile(filePathDB string) error {
+// MigrateFromGobFile moves records from gob file of previous versions to backend and renames the file
+func (s *FileVaultStorage) MigrateFromGobFile(filePathDB string) error {
 	s.mux.Lock()
 	defer s.mux.Unlock()
 
-	var file *os.File
-	var err error
+	var savedStorage FileSavedStorage
 
-	file, err = os.Open(filePathDB)
-	defer file.Close()
+	isExists, err := readGobFile(filePathDB, &savedStorage)
 
-	if err != nil {
-		if !errors.Is(err, os.ErrNotExist) {
-			return err
-		}
+	if err != nil || !isExists {
+		return err
+	}
 
-		file, err = os.Create(filePathDB)
+	for id, model := range savedStorage.Storage {
+		model.ID = id
+
+		err = s.put(model)
 
 		if err != nil {
 			return err
 		}
 	}
 
-	fileInfo, err := file.Stat()
+	return os.Rename(filePathDB, filePathDB+migratedFileSuffix)
+}
+
+// get reads model from backend. Caller must hold s.mux
+func (s *FileVaultStorage) get(id uint32) (*FileVaultModel, error) {
+	value, err := s.backend.Get(FileVaultStorageType, id)
+
+	if errors.Is(err, ErrNotFoundRecord) {
+		return nil, vaultdata.ErrNotFoundVaultInStorage
+	}
+
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	savedStorage := FileSavedStorage{
-		Storage:              make(map[uint32]*FileVaultModel),
-		IndexIDAndExternalID: make(map[string]uint32),
+	var model FileVaultModel
+
+	err = gob.NewDecoder(bytes.NewReader(value)).Decode(&model)
+
+	if err != nil {
+		return nil, err
 	}
 
-	if fileInfo.Size() == 0 {
-		return nil
+	if model.MetaData == nil {
+		model.MetaData = make(map[string]string)
 	}
 
-	err = gob.NewDecoder(file).Decode(&savedStorage)
+	return &model, nil
+}
+
+// getByExternalID reads model by server ID. Caller must hold s.mux
+func (s *FileVaultStorage) getByExternalID(externalID string) (*FileVaultModel, error) {
+	ids, err := s.backend.FindByIndex(FileVaultStorageType, IndexExternalID, externalID)
+
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	s.storage = savedStorage.Storage
-	s.indexIDAndExternalID = savedStorage.IndexIDAndExternalID
+	if len(ids) == 0 {
+		return nil, vaultdata.ErrNotFoundVaultInStorage
+	}
 
-	return nil
+	return s.get(ids[0])
 }
 
-func (s *FileVaultStorage) SaveToFile(filePathDB string) error {
-	s.mux.Lock()
-	defer s.mux.Unlock()
+// all reads every model ordered by ID. Caller must hold s.mux
+func (s *FileVaultStorage) all() ([]*FileVaultModel, error) {
+	arr := make([]*FileVaultModel, 0)
+
+	err := s.backend.ForEach(FileVaultStorageType, func(_ uint32, value []byte) error {
+		var model FileVaultModel
+
+		if err := gob.NewDecoder(bytes.NewReader(value)).Decode(&model); err != nil {
+			return err
+		}
+
+		if model.MetaData == nil {
+			model.MetaData = make(map[string]string)
+		}
+
+		arr = append(arr, &model)
 
-	file, err := os.OpenFile(filePathDB, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0644)
+		return nil
+	})
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	defer file.Sync()
-	defer file.Close()
+	sort.Slice(arr, func(i, j int) bool {
+		return arr[i].ID < arr[j].ID
+	})
+
+	return arr, nil
+}
+
+// put saves model, new model gets ID. Caller must hold s.mux
+func (s *FileVaultStorage) put(model *FileVaultModel) error {
+	if model.ID == 0 {
+		id, err := s.backend.NextID(FileVaultStorageType)
 
-	savedStorage := FileSavedStorage{
-		Storage:              s.storage,
-		IndexIDAndExternalID: s.indexIDAndExternalID,
+		if err != nil {
+			return err
+		}
+
+		model.ID = id
 	}
 
-	err = gob.NewEncoder(file).Encode(&savedStorage)
+	var buffer bytes.Buffer
 
-	return err
+	err := gob.NewEncoder(&buffer).Encode(model)
+
+	if err != nil {
+		return err
+	}
+
+	indexes := map[string]string{
+		IndexExternalID: model.ExternalID,
+	}
+
+	return s.backend.Put(FileVaultStorageType, model.ID, buffer.Bytes(), indexes)
+}
+
+// remove deletes model. Caller must hold s.mux
+func (s *FileVaultStorage) remove(id uint32) error {
+	return s.backend.Delete(FileVaultStorageType, id)
 }
 
 func (s *FileVaultStorage) GetKind() string {
@@ -257,27 +377,19 @@ func (s *FileVaultStorage) LoadForSync() ([]vaultsync.DataSyncer, error) {
 	s.mux.RLock()
 	defer s.mux.RUnlock()
 
-	arr := make([]vaultsync.DataSyncer, 0, len(s.storage))
+	models, err := s.all()
 
-	for _, model := range s.storage {
-		arr = append(arr, model)
+	if err != nil {
+		return nil, err
 	}
 
-	return arr, nil
-}
-
-func (s *FileVaultStorage) SetConflictFlag(id uint32) error {
-	s.mux.Lock()
-	defer s.mux.Unlock()
+	arr := make([]vaultsync.DataSyncer, 0, len(models))
 
-	model, ok := s.storage[id]
-	if !ok {
-		return vaultdata.ErrNotFoundVaultInStorage
+	for _, model := range models {
+		arr = append(arr, model)
 	}
 
-	model.IsConflict = true
-
-	return nil
+	return arr, nil
 }
 
 func (s *FileVaultStorage) SerializeToVault(data interface{}) ([]byte, error) {
@@ -311,54 +423,63 @@ func (s *FileVaultStorage) DeserializeFromVault(dst []byte) (interface{}, error)
 }
 
 func (s *FileVaultStorage) UpdateAfterSyncByID(model vaultsync.DataSyncer, externalID string, version int) error {
-	id := model.GetID()
+	s.mux.Lock()
+	defer s.mux.Unlock()
 
-	siteLoginModel, ok := s.storage[id]
+	fileModel, err := s.get(model.GetID())
 
-	if !ok {
-		return vaultdata.ErrNotFoundVaultInStorage
+	if err != nil {
+		return err
 	}
 
-	siteLoginModel.ExternalID = externalID
-	siteLoginModel.Version = version
-	s.indexIDAndExternalID[externalID] = id
+	fileModel.ExternalID = externalID
+	fileModel.Version = version
+	fileModel.IsNew = false
+	fileModel.IsUpdate = false
+	fileModel.confirmFieldVersions(version)
 
-	return nil
+	return s.put(fileModel)
 }
 
 func (s *FileVaultStorage) ConfirmDeleteAfterSyncByID(model vaultsync.DataSyncer) error {
-	id := model.GetID()
+	s.mux.Lock()
+	defer s.mux.Unlock()
 
-	_, ok := s.storage[id]
+	_, err := s.get(model.GetID())
 
-	if !ok {
-		return vaultdata.ErrNotFoundVaultInStorage
+	if err != nil {
+		return err
 	}
 
-	delete(s.indexIDAndExternalID, model.GetVaultID())
-	delete(s.storage, id)
-
-	return nil
+	return s.remove(model.GetID())
 }
 
 func (s *FileVaultStorage) CreateDataStorage(externalID string, version int, data interface{}, s3URL string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
 	vs, ok := data.(*fileVaultStored)
 
 	if !ok {
 		return ErrInvalidType
 	}
 
-	_, ok = s.indexIDAndExternalID[externalID]
+	_, err := s.getByExternalID(externalID)
 
-	if ok {
+	if err == nil {
 		// TODO: Logs or replace
 		return nil
 	}
 
+	if !errors.Is(err, vaultdata.ErrNotFoundVaultInStorage) {
+		return err
+	}
+
 	fileVaultModel := NewFileVaultModel()
 
 	fileVaultModel.Data = vs.Data
 	fileVaultModel.MetaData = vs.MetaData
+	fileVaultModel.FieldVersions = vs.FieldVersions
 	fileVaultModel.Version = version
 	fileVaultModel.ExternalID = externalID
 	fileVaultModel.IsNew = false
@@ -367,31 +488,23 @@ func (s *FileVaultStorage) CreateDataStorage(externalID string, version int, dat
 		fileVaultModel.S3URL = s3URL
 	}
 
-	s.storage[fileVaultModel.ID] = fileVaultModel
-	s.indexIDAndExternalID[externalID] = fileVaultModel.ID
-
-	return nil
+	return s.put(fileVaultModel)
 }
 
 func (s *FileVaultStorage) UpdateDataStorage(externalID string, version int, data interface{}) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
 	vs, ok := data.(*fileVaultStored)
 
 	if !ok {
 		return ErrInvalidType
 	}
 
-	id, ok := s.indexIDAndExternalID[externalID]
-
-	if !ok {
-		return vaultdata.ErrNotFoundVaultInStorage
-	}
-
-	model, ok := s.storage[id]
+	model, err := s.getByExternalID(externalID)
 
-	if !ok {
-		delete(s.indexIDAndExternalID, externalID)
-		// TODO: logs
-		return vaultdata.ErrNotFoundVaultInStorage
+	if err != nil {
+		return err
 	}
 
 	if model.IsNeedSync() {
@@ -406,25 +519,21 @@ func (s *FileVaultStorage) UpdateDataStorage(externalID string, version int, dat
 
 	model.Data = vs.Data
 	model.MetaData = vs.MetaData
+	model.FieldVersions = vs.FieldVersions
 	model.IsNew = false
 	model.Version = version
 
-	return nil
+	return s.put(model)
 }
 
 func (s *FileVaultStorage) DeleteDataStorage(externalID string, version int) error {
-	id, ok := s.indexIDAndExternalID[externalID]
-
-	if !ok {
-		return vaultdata.ErrNotFoundVaultInStorage
-	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
 
-	model, ok := s.storage[id]
+	model, err := s.getByExternalID(externalID)
 
-	if !ok {
-		delete(s.indexIDAndExternalID, externalID)
-		// TODO: Need logs
-		return vaultdata.ErrNotFoundVaultInStorage
+	if err != nil {
+		return err
 	}
 
 	if model.IsNeedSync() {
@@ -437,49 +546,46 @@ func (s *FileVaultStorage) DeleteDataStorage(externalID string, version int) err
 		return nil
 	}
 
-	delete(s.indexIDAndExternalID, externalID)
-	delete(s.storage, id)
-
-	return nil
+	return s.remove(model.ID)
 }
 
 // For storage!
 
-func (s *FileVaultStorage) GetAll() []*FileVaultModel {
+func (s *FileVaultStorage) GetAll() ([]*FileVaultModel, error) {
 	s.mux.RLock()
 	defer s.mux.RUnlock()
 
-	arr := make([]*FileVaultModel, 0, len(s.storage))
-
-	for _, model := range s.storage {
-		arr = append(arr, model)
-	}
-
-	sort.Slice(arr, func(i, j int) bool {
-		return arr[i].ID < arr[j].ID
-	})
-
-	return arr
+	return s.all()
 }
 
-func (s *FileVaultStorage) UploadFile(ctx context.Context, file *os.File) error {
-	s.mux.Lock()
-	defer s.mux.Unlock()
+func (s *FileVaultStorage) GetByID(id uint32) (*FileVaultModel, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
 
-	fileInfo, err := file.Stat()
+	model, err := s.get(id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	if model.IsDelete {
+		return nil, vaultdata.ErrNotFoundVaultInStorage
+	}
+
+	return model, nil
+}
+
+// UploadFile encrypts and uploads data of file. Storage is locked only to save model after upload
+func (s *FileVaultStorage) UploadFile(ctx context.Context, r io.Reader, fileName string, size int64) error {
 	newM := NewFileVaultModel()
-	newM.SetFileName(fileInfo.Name())
-	newM.SetExtensionName(filepath.Ext(fileInfo.Name()))
+	newM.SetFileName(fileName)
+	newM.SetExtensionName(filepath.Ext(fileName))
+	newM.SetSize(size)
 
 	encryptedName := randID()
 	newM.SetEncryptedName(encryptedName)
 
 	encryptedKey := make([]byte, 256)
-	_, err = rand.Read(encryptedKey)
+	_, err := rand.Read(encryptedKey)
 	if err != nil {
 		return err
 	}
@@ -515,8 +621,10 @@ func (s *FileVaultStorage) UploadFile(ctx context.Context, file *os.File) error
 	g := new(errgroup.Group)
 
 	g.Go(func() error {
-		defer writer.Close()
-		_, err = io.Copy(w, file)
+		_, err := io.Copy(w, r)
+
+		// Upload must fail on read error, otherwise truncated file is saved
+		_ = writer.CloseWithError(err)
 
 		return err
 	})
@@ -534,19 +642,18 @@ func (s *FileVaultStorage) UploadFile(ctx context.Context, file *os.File) error
 
 	newM.S3URL = result
 
-	s.storage[newM.ID] = newM
+	s.mux.Lock()
+	defer s.mux.Unlock()
 
-	return nil
+	return s.put(newM)
 }
 
-func (s *FileVaultStorage) DownloadFile(ctx context.Context, id uint32, filePath string) error {
-	s.mux.RLock()
-	defer s.mux.RUnlock()
-
-	model, ok := s.storage[id]
+// DownloadFile downloads and decrypts file to w. Storage is locked only to read model
+func (s *FileVaultStorage) DownloadFile(ctx context.Context, id uint32, w io.Writer) error {
+	model, err := s.GetByID(id)
 
-	if !ok || model.IsDelete {
-		return vaultdata.ErrNotFoundVaultInStorage
+	if err != nil {
+		return err
 	}
 
 	decryptedData, err := s.crypt.Decrypt(model.Data)
@@ -566,19 +673,7 @@ func (s *FileVaultStorage) DownloadFile(ctx context.Context, id uint32, filePath
 		return errors.New("invalid data")
 	}
 
-	file, err := os.Create(filepath.Join(filePath, model.GetFileName()))
-
-	if err != nil {
-		return err
-	}
-
-	defer file.Sync()
-	defer file.Close()
-
-	ctxWithTimeout, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
-
-	body, err := s.vclient.VaultDownload(ctxWithTimeout, model.S3URL)
+	body, err := s.vclient.VaultDownload(ctx, model.S3URL)
 	if err != nil {
 		return err
 	}
@@ -590,26 +685,23 @@ func (s *FileVaultStorage) DownloadFile(ctx context.Context, id uint32, filePath
 		return err
 	}
 
-	_, err = io.Copy(file, r)
-	if err != nil {
-		return err
-	}
+	_, err = io.Copy(w, r)
 
-	return nil
+	return err
 }
 
 func (s *FileVaultStorage) DeleteFile(_ context.Context, id uint32) error {
 	s.mux.Lock()
 	defer s.mux.Unlock()
 
-	model, ok := s.storage[id]
+	model, err := s.get(id)
 
-	if !ok {
-		return vaultdata.ErrNotFoundVaultInStorage
+	if err != nil {
+		return err
 	}
 
 	model.IsUpdate = false
 	model.IsDelete = !model.IsDelete
 
-	return nil
+	return s.put(model)
 }
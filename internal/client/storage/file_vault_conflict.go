@@ -0,0 +1,384 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultdata"
+)
+
+const (
+	FileFieldName    = "name"
+	FileFieldContent = "content" // key and S3 object are changed only together
+)
+
+var fileFields = []string{FileFieldName, FileFieldContent}
+
+func (s *FileVaultStorage) SetConflict(id uint32, remote vaultdata.VaultSyncData, data interface{}) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	model, err := s.get(id)
+	if err != nil {
+		return err
+	}
+
+	conflict := FileVaultConflict{
+		Version:   remote.Version,
+		IsDeleted: remote.IsDeleted,
+		S3URL:     remote.S3URL,
+	}
+
+	if !remote.IsDeleted {
+		vs, ok := data.(*fileVaultStored)
+
+		if !ok {
+			return ErrInvalidType
+		}
+
+		conflict.Data = vs.Data
+		conflict.MetaData = vs.MetaData
+		conflict.FieldVersions = vs.FieldVersions
+	}
+
+	model.IsConflict = true
+	model.Conflict = &conflict
+
+	return s.put(model)
+}
+
+// MergeConflict merges remote version into local when different fields were changed.
+// It returns false without changes when the same field was changed on both sides.
+func (s *FileVaultStorage) MergeConflict(id uint32, remote vaultdata.VaultSyncData, data interface{}) (bool, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	model, err := s.get(id)
+	if err != nil {
+		return false, err
+	}
+
+	if remote.IsDeleted || model.IsDelete || !model.IsUpdate {
+		return false, nil
+	}
+
+	vs, ok := data.(*fileVaultStored)
+
+	if !ok {
+		return false, ErrInvalidType
+	}
+
+	local, err := s.decryptSecret(model.Data)
+
+	if err != nil {
+		return false, err
+	}
+
+	remoteSecret, err := s.decryptSecret(vs.Data)
+
+	if err != nil {
+		return false, err
+	}
+
+	merge := vaultdata.MergeFields(
+		fileFields,
+		model.Version,
+		model.DirtyFields,
+		vs.FieldVersions,
+		fileFieldValues(local, model.MetaData, model.S3URL),
+		fileFieldValues(remoteSecret, vs.MetaData, remote.S3URL),
+	)
+
+	if merge.HasCollisions() {
+		return false, nil
+	}
+
+	for _, field := range merge.Remote {
+		switch field {
+		case FileFieldName:
+			model.MetaData = copyMetaData(model.MetaData)
+			model.MetaData[FileMetaDataNameKey] = vs.MetaData[FileMetaDataNameKey]
+			model.MetaData[FileMetaDataExtensionKey] = vs.MetaData[FileMetaDataExtensionKey]
+		case FileFieldContent:
+			model.MetaData = copyMetaData(model.MetaData)
+			model.MetaData[FileMetaDataEncryptedKey] = vs.MetaData[FileMetaDataEncryptedKey]
+			model.MetaData[FileMetaDataSizeKey] = vs.MetaData[FileMetaDataSizeKey]
+			model.Data = vs.Data
+			model.S3URL = remote.S3URL
+		}
+	}
+
+	fieldVersions := vs.FieldVersions.Copy()
+
+	for _, field := range fileFields {
+		if _, ok := fieldVersions[field]; !ok {
+			fieldVersions[field] = remote.Version
+		}
+	}
+
+	model.FieldVersions = fieldVersions
+	model.Version = remote.Version
+	model.IsConflict = false
+	model.Conflict = nil
+
+	return true, s.put(model)
+}
+
+func (s *FileVaultStorage) GetConflicts() ([]vaultdata.Conflict, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	models, err := s.all()
+
+	if err != nil {
+		return nil, err
+	}
+
+	conflicts := make([]vaultdata.Conflict, 0)
+
+	for _, model := range models {
+		if !model.IsConflict || model.Conflict == nil {
+			continue
+		}
+
+		conflict := vaultdata.Conflict{
+			Kind:            FileVaultStorageType,
+			ID:              model.ID,
+			VaultID:         model.ExternalID,
+			LocalVersion:    model.Version,
+			RemoteVersion:   model.Conflict.Version,
+			IsLocalDeleted:  model.IsDelete,
+			IsRemoteDeleted: model.Conflict.IsDeleted,
+		}
+
+		fields, err := s.diffConflict(model)
+
+		if err != nil {
+			return nil, err
+		}
+
+		conflict.Fields = fields
+
+		conflicts = append(conflicts, conflict)
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].ID < conflicts[j].ID
+	})
+
+	return conflicts, nil
+}
+
+func (s *FileVaultStorage) diffConflict(model *FileVaultModel) ([]vaultdata.ConflictFieldDiff, error) {
+	local, err := s.decryptSecret(model.Data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	remote := &FileSecreteData{}
+	remoteName := ""
+	remoteS3URL := ""
+
+	if !model.Conflict.IsDeleted {
+		remote, err = s.decryptSecret(model.Conflict.Data)
+
+		if err != nil {
+			return nil, err
+		}
+
+		remoteName = model.Conflict.MetaData[FileMetaDataNameKey]
+		remoteS3URL = model.Conflict.S3URL
+	}
+
+	return []vaultdata.ConflictFieldDiff{
+		{Name: FileFieldName, Local: model.GetFileName(), Remote: remoteName},
+		{
+			Name:     FileFieldContent,
+			Local:    fmt.Sprintf("%s %x", model.S3URL, local.Key),
+			Remote:   fmt.Sprintf("%s %x", remoteS3URL, remote.Key),
+			IsSecret: true,
+		},
+	}, nil
+}
+
+func (s *FileVaultStorage) ResolveConflict(externalID string, resolve vaultdata.ConflictResolve) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	model, err := s.getByExternalID(externalID)
+	if errors.Is(err, vaultdata.ErrNotFoundVaultInStorage) {
+		return vaultdata.ErrNotFoundConflict
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !model.IsConflict || model.Conflict == nil {
+		return vaultdata.ErrNotFoundConflict
+	}
+
+	conflict := model.Conflict
+
+	if conflict.IsDeleted || model.IsDelete {
+		if resolve.Strategy == vaultdata.ResolveBoth || resolve.Strategy == vaultdata.ResolveMerge {
+			return fmt.Errorf("%w: vault was deleted, use local or remote", vaultdata.ErrInvalidResolve)
+		}
+	}
+
+	switch resolve.Strategy {
+	case vaultdata.ResolveLocal:
+		if conflict.IsDeleted {
+			// Server doesn't have vault anymore, upload local as new one
+			model.ExternalID = ""
+			model.Version = 0
+			model.IsNew = true
+			model.IsUpdate = false
+
+			break
+		}
+
+		err := s.markDirtyAgainstRemote(model)
+
+		if err != nil {
+			return err
+		}
+
+		model.Version = conflict.Version
+		model.FieldVersions = conflict.FieldVersions
+		model.IsUpdate = !model.IsDelete
+	case vaultdata.ResolveRemote:
+		if conflict.IsDeleted {
+			return s.remove(model.ID)
+		}
+
+		s.applyRemote(model)
+	case vaultdata.ResolveBoth:
+		copyModel := NewFileVaultModel()
+		copyModel.Data = model.Data
+		copyModel.MetaData = copyMetaData(model.MetaData)
+		copyModel.S3URL = model.S3URL
+
+		err := s.put(copyModel)
+
+		if err != nil {
+			return err
+		}
+
+		s.applyRemote(model)
+	case vaultdata.ResolveMerge:
+		err := s.mergeConflict(model, resolve.Fields)
+
+		if err != nil {
+			return err
+		}
+	default:
+		return vaultdata.ErrInvalidResolve
+	}
+
+	model.IsConflict = false
+	model.Conflict = nil
+
+	return s.put(model)
+}
+
+func (s *FileVaultStorage) applyRemote(model *FileVaultModel) {
+	model.Data = model.Conflict.Data
+	model.MetaData = model.Conflict.MetaData
+	model.S3URL = model.Conflict.S3URL
+	model.FieldVersions = model.Conflict.FieldVersions
+	model.DirtyFields = nil
+	model.Version = model.Conflict.Version
+	model.IsUpdate = false
+	model.IsDelete = false
+}
+
+func (s *FileVaultStorage) mergeConflict(model *FileVaultModel, fields map[string]vaultdata.ResolveStrategy) error {
+	for name := range fields {
+		if name != FileFieldName && name != FileFieldContent {
+			return fmt.Errorf("%w: unknown field %q", vaultdata.ErrInvalidResolve, name)
+		}
+	}
+
+	metaData := copyMetaData(model.MetaData)
+
+	if fields[FileFieldName] == vaultdata.ResolveRemote {
+		metaData[FileMetaDataNameKey] = model.Conflict.MetaData[FileMetaDataNameKey]
+		metaData[FileMetaDataExtensionKey] = model.Conflict.MetaData[FileMetaDataExtensionKey]
+	}
+
+	if fields[FileFieldContent] == vaultdata.ResolveRemote {
+		metaData[FileMetaDataEncryptedKey] = model.Conflict.MetaData[FileMetaDataEncryptedKey]
+		model.Data = model.Conflict.Data
+		model.S3URL = model.Conflict.S3URL
+	}
+
+	model.MetaData = metaData
+
+	err := s.markDirtyAgainstRemote(model)
+
+	if err != nil {
+		return err
+	}
+
+	model.Version = model.Conflict.Version
+	model.FieldVersions = model.Conflict.FieldVersions
+	model.IsUpdate = true
+
+	return nil
+}
+
+// markDirtyAgainstRemote marks fields which differ from remote version, they will be uploaded as local changes
+func (s *FileVaultStorage) markDirtyAgainstRemote(model *FileVaultModel) error {
+	local, err := s.decryptSecret(model.Data)
+
+	if err != nil {
+		return err
+	}
+
+	remote, err := s.decryptSecret(model.Conflict.Data)
+
+	if err != nil {
+		return err
+	}
+
+	localValues := fileFieldValues(local, model.MetaData, model.S3URL)
+	remoteValues := fileFieldValues(remote, model.Conflict.MetaData, model.Conflict.S3URL)
+
+	model.DirtyFields = nil
+
+	for _, field := range fileFields {
+		if localValues[field] != remoteValues[field] {
+			model.markDirty(field)
+		}
+	}
+
+	return nil
+}
+
+func fileFieldValues(secret *FileSecreteData, metaData map[string]string, s3URL string) map[string]string {
+	return map[string]string{
+		FileFieldName:    metaData[FileMetaDataNameKey] + metaData[FileMetaDataExtensionKey],
+		FileFieldContent: fmt.Sprintf("%s %x %s", s3URL, secret.Key, metaData[FileMetaDataEncryptedKey]),
+	}
+}
+
+func (s *FileVaultStorage) decryptSecret(data []byte) (*FileSecreteData, error) {
+	decryptedData, err := s.crypt.Decrypt(data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var secret FileSecreteData
+
+	if err := gob.NewDecoder(bytes.NewReader(decryptedData)).Decode(&secret); err != nil {
+		return nil, err
+	}
+
+	return &secret, nil
+}
@@ -10,81 +10,91 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-func TestFileVaultStorage_LoadFromLocalFile(t *testing.T) {
-	t.Run("Success open file", func(t *testing.T) {
+func TestFileVaultStorage_MigrateFromGobFile(t *testing.T) {
+	t.Run("Success migrate file", func(t *testing.T) {
 		assert := assert.New(t)
 		require := require.New(t)
-		pwd, _ := os.Getwd()
-		fileTestDataDB := path.Join(pwd, "testdata", "files-base.db")
+		fileTestDataDB := copyTestData(t, "files-base.db")
 
 		vcrypto := vaultcrypt.New()
 		_ = vcrypto.SetMasterPassword("Alex", "123")
 
-		fileStorage := NewFileVaultStorage(vcrypto, nil)
+		fileStorage := NewFileVaultStorage(vcrypto, nil, NewMemoryBackend())
 
-		err := fileStorage.LoadFromLocalFile(fileTestDataDB)
-		require.Nil(err, "failed load db file")
+		err := fileStorage.MigrateFromGobFile(fileTestDataDB)
+		require.Nil(err, "failed migrate db file")
 
-		require.Len(fileStorage.storage, 3, "incorrect length storage")
+		all, err := fileStorage.GetAll()
+		require.Nil(err)
+		require.Len(all, 3, "incorrect length storage")
 
-		assert.Equal(fileStorage.storage[1].GetFileName(), "screen.png")
-		assert.Equal(fileStorage.storage[2].GetFileName(), "screen.png")
-		assert.Equal(fileStorage.storage[3].GetFileName(), "screen.png")
+		assert.Equal(all[0].GetFileName(), "screen.png")
+		assert.Equal(all[1].GetFileName(), "screen.png")
+		assert.Equal(all[2].GetFileName(), "screen.png")
+
+		_, err = os.Stat(fileTestDataDB + migratedFileSuffix)
+		assert.Nil(err)
 	})
 
-	t.Run("Success create file if not exists", func(t *testing.T) {
+	t.Run("Skip if file not exists", func(t *testing.T) {
 		require := require.New(t)
 		fileTestDataDB := path.Join(t.TempDir(), "files-new.db")
 
 		vcrypto := vaultcrypt.New()
 		_ = vcrypto.SetMasterPassword("Alex", "123")
 
-		fileStorage := NewFileVaultStorage(vcrypto, nil)
-
-		err := fileStorage.LoadFromLocalFile(fileTestDataDB)
+		fileStorage := NewFileVaultStorage(vcrypto, nil, NewMemoryBackend())
 
-		require.Nil(err, "failed load db file")
-		require.Len(fileStorage.storage, 0, "incorrect length storage")
+		err := fileStorage.MigrateFromGobFile(fileTestDataDB)
+		require.Nil(err, "failed migrate db file")
 
-		file, err := os.Open(fileTestDataDB)
-		require.Nil(err, "cant find file after create")
-		defer file.Close()
+		all, err := fileStorage.GetAll()
+		require.Nil(err)
+		require.Len(all, 0, "incorrect length storage")
 	})
 }
 
-func TestFileVaultStorage_SaveToFile(t *testing.T) {
-	t.Run("", func(t *testing.T) {
+func TestFileVaultStorage_Persist(t *testing.T) {
+	t.Run("Success reopen backend", func(t *testing.T) {
 		assert := assert.New(t)
 		require := require.New(t)
 
-		filesTestDataDB := path.Join(t.TempDir(), "files-new.db")
+		backendPath := path.Join(t.TempDir(), "vault.bolt")
 
 		vcrypto := vaultcrypt.New()
 		_ = vcrypto.SetMasterPassword("Alex", "123")
 
-		fileStorage := NewFileVaultStorage(vcrypto, nil)
+		backend, err := OpenBoltBackend(backendPath)
+		require.Nil(err)
+
+		fileStorage := NewFileVaultStorage(vcrypto, nil, backend)
 
 		model := FileVaultModel{
-			ID:         1,
-			ExternalID: "",
+			ExternalID: "vault-1",
 			Data:       []byte{},
 			MetaData:   map[string]string{},
 			S3URL:      "",
 		}
 		model.SetFileName("screen.png")
 
-		fileStorage.storage[1] = &model
+		err = fileStorage.put(&model)
+		require.Nil(err, "cant save model")
+		require.Nil(backend.Close())
+
+		backend, err = OpenBoltBackend(backendPath)
+		require.Nil(err)
+		defer backend.Close()
 
-		err := fileStorage.SaveToFile(filesTestDataDB)
-		require.Nil(err, "cant save to file")
+		fileStorage = NewFileVaultStorage(vcrypto, nil, backend)
 
-		fileStorage = NewFileVaultStorage(vcrypto, nil)
-		err = fileStorage.LoadFromLocalFile(filesTestDataDB)
+		saved, err := fileStorage.GetByID(1)
+		require.Nil(err, "failed load model")
 
-		require.Nil(err, "failed load db file")
-		require.Len(fileStorage.storage, 1, "incorrect length storage")
+		assert.Equal(saved.GetID(), uint32(1))
+		assert.Equal(saved.GetFileName(), "screen.png")
 
-		assert.Equal(fileStorage.storage[1].GetID(), uint32(1))
-		assert.Equal(fileStorage.storage[1].GetFileName(), "screen.png")
+		saved, err = fileStorage.getByExternalID("vault-1")
+		require.Nil(err, "failed load model by external id")
+		assert.Equal(saved.GetID(), uint32(1))
 	})
 }
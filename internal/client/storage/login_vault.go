@@ -7,7 +7,6 @@ import (
 	"os"
 	"sort"
 	"sync"
-	"sync/atomic"
 
 	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultcrypt"
 	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultdata"
@@ -21,12 +20,6 @@ var (
 	_ vaultsync.StorageSyncer = (*LoginVaultStorage)(nil)
 )
 
-var siteLoginLastIndex uint32 = 0
-
-func siteLoginLoadNextIndex() uint32 {
-	return atomic.AddUint32(&siteLoginLastIndex, 1)
-}
-
 var LoginMetaDataSiteURLKey = "siteURL"
 
 type LoginVaultModel struct {
@@ -41,6 +34,19 @@ type LoginVaultModel struct {
 	IsUpdate   bool // for sync
 	IsDelete   bool // for sync
 	IsConflict bool // for sync
+
+	Conflict *LoginVaultConflict // remote version while IsConflict
+
+	FieldVersions vaultdata.FieldVersions // for merge
+	DirtyFields   map[string]bool         // for merge, fields changed after last sync
+}
+
+type LoginVaultConflict struct {
+	Version       int
+	IsDeleted     bool
+	Data          []byte
+	MetaData      map[string]string
+	FieldVersions vaultdata.FieldVersions
 }
 
 func (m *LoginVaultModel) GetID() uint32 {
@@ -67,6 +73,10 @@ func (m *LoginVaultModel) GetIsUpdate() bool {
 	return m.IsUpdate
 }
 
+func (m *LoginVaultModel) GetIsConflict() bool {
+	return m.IsConflict
+}
+
 func (m *LoginVaultModel) GetS3URL() string {
 	return ""
 }
@@ -85,15 +95,55 @@ func (m *LoginVaultModel) GetSite() string {
 	return siteURL
 }
 
+func (m *LoginVaultModel) markDirty(field string) {
+	if m.DirtyFields == nil {
+		m.DirtyFields = make(map[string]bool)
+	}
+
+	m.DirtyFields[field] = true
+}
+
+// confirmFieldVersions moves dirty fields to version accepted by server
+func (m *LoginVaultModel) confirmFieldVersions(version int) {
+	m.FieldVersions = m.FieldVersions.Copy()
+
+	for _, field := range loginFields {
+		if _, ok := m.FieldVersions[field]; !ok {
+			m.FieldVersions[field] = 0
+		}
+
+		if m.DirtyFields[field] {
+			m.FieldVersions[field] = version
+		}
+	}
+
+	m.DirtyFields = nil
+}
+
 type siteLoginVaultStored struct {
-	Data     []byte
-	MetaData map[string]string
+	Data          []byte
+	MetaData      map[string]string
+	FieldVersions vaultdata.FieldVersions
 }
 
 func siteLoginVaultStoredFromModel(model *LoginVaultModel) *siteLoginVaultStored {
+	fieldVersions := model.FieldVersions.Copy()
+
+	for _, field := range loginFields {
+		if _, ok := fieldVersions[field]; !ok || model.IsNew {
+			fieldVersions[field] = 0
+		}
+
+		if model.DirtyFields[field] && !model.IsNew {
+			// server increments version on update
+			fieldVersions[field] = model.Version + 1
+		}
+	}
+
 	v := siteLoginVaultStored{
-		Data:     model.Data,
-		MetaData: model.MetaData,
+		Data:          model.Data,
+		MetaData:      model.MetaData,
+		FieldVersions: fieldVersions,
 	}
 
 	return &v
@@ -101,8 +151,6 @@ func siteLoginVaultStoredFromModel(model *LoginVaultModel) *siteLoginVaultStored
 
 func NewLoginVaultModel() *LoginVaultModel {
 	m := LoginVaultModel{
-		ID: siteLoginLoadNextIndex(),
-
 		MetaData: make(map[string]string),
 
 		IsNew:    true,
@@ -118,8 +166,7 @@ type LoginSecreteData struct {
 }
 
 type LoginVaultStorage struct {
-	storage              map[uint32]*LoginVaultModel
-	indexIDAndExternalID map[string]uint32
+	backend Backend
 
 	crypt *vaultcrypt.VaultCrypt
 
@@ -128,12 +175,11 @@ type LoginVaultStorage struct {
 
 func NewLoginVaultStorage(
 	crypt *vaultcrypt.VaultCrypt,
+	backend Backend,
 ) *LoginVaultStorage {
 	s := LoginVaultStorage{
-		crypt: crypt,
-
-		storage:              make(map[uint32]*LoginVaultModel),
-		indexIDAndExternalID: make(map[string]uint32),
+		crypt:   crypt,
+		backend: backend,
 	}
 
 	return &s
@@ -144,74 +190,136 @@ type SiteLoginSavedStorage struct {
 	IndexIDAndExternalID map[string]uint32
 }
 
-func (s *LoginVaultStorage) LoadFromLocalFile(filePathDB string) error {
+// MigrateFromGobFile moves records from gob file of previous versions to backend and renames the file
+func (s *LoginVaultStorage) MigrateFromGobFile(filePathDB string) error {
 	s.mux.Lock()
 	defer s.mux.Unlock()
 
-	var file *os.File
-	var err error
+	var savedStorage SiteLoginSavedStorage
 
-	file, err = os.Open(filePathDB)
-	defer file.Close()
+	isExists, err := readGobFile(filePathDB, &savedStorage)
 
-	if err != nil {
-		if !errors.Is(err, os.ErrNotExist) {
-			return err
-		}
+	if err != nil || !isExists {
+		return err
+	}
 
-		file, err = os.Create(filePathDB)
+	for id, model := range savedStorage.Storage {
+		model.ID = id
+
+		err = s.put(model)
 
 		if err != nil {
 			return err
 		}
 	}
 
-	fileInfo, err := file.Stat()
+	return os.Rename(filePathDB, filePathDB+migratedFileSuffix)
+}
+
+// get reads model from backend. Caller must hold s.mux
+func (s *LoginVaultStorage) get(id uint32) (*LoginVaultModel, error) {
+	value, err := s.backend.Get(SiteLoginVaultStorageType, id)
+
+	if errors.Is(err, ErrNotFoundRecord) {
+		return nil, vaultdata.ErrNotFoundVaultInStorage
+	}
+
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	savedStorage := SiteLoginSavedStorage{
-		Storage:              make(map[uint32]*LoginVaultModel),
-		IndexIDAndExternalID: make(map[string]uint32),
+	var model LoginVaultModel
+
+	err = gob.NewDecoder(bytes.NewReader(value)).Decode(&model)
+
+	if err != nil {
+		return nil, err
 	}
 
-	if fileInfo.Size() == 0 {
-		return nil
+	if model.MetaData == nil {
+		model.MetaData = make(map[string]string)
 	}
 
-	err = gob.NewDecoder(file).Decode(&savedStorage)
+	return &model, nil
+}
+
+// getByExternalID reads model by server ID. Caller must hold s.mux
+func (s *LoginVaultStorage) getByExternalID(externalID string) (*LoginVaultModel, error) {
+	ids, err := s.backend.FindByIndex(SiteLoginVaultStorageType, IndexExternalID, externalID)
+
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	s.storage = savedStorage.Storage
-	s.indexIDAndExternalID = savedStorage.IndexIDAndExternalID
+	if len(ids) == 0 {
+		return nil, vaultdata.ErrNotFoundVaultInStorage
+	}
 
-	return nil
+	return s.get(ids[0])
 }
 
-func (s *LoginVaultStorage) SaveToFile(filePathDB string) error {
-	s.mux.Lock()
-	defer s.mux.Unlock()
+// all reads every model ordered by ID. Caller must hold s.mux
+func (s *LoginVaultStorage) all() ([]*LoginVaultModel, error) {
+	arr := make([]*LoginVaultModel, 0)
+
+	err := s.backend.ForEach(SiteLoginVaultStorageType, func(_ uint32, value []byte) error {
+		var model LoginVaultModel
+
+		if err := gob.NewDecoder(bytes.NewReader(value)).Decode(&model); err != nil {
+			return err
+		}
 
-	file, err := os.OpenFile(filePathDB, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0644)
+		if model.MetaData == nil {
+			model.MetaData = make(map[string]string)
+		}
+
+		arr = append(arr, &model)
+
+		return nil
+	})
 
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	sort.Slice(arr, func(i, j int) bool {
+		return arr[i].ID < arr[j].ID
+	})
+
+	return arr, nil
+}
+
+// put saves model, new model gets ID. Caller must hold s.mux
+func (s *LoginVaultStorage) put(model *LoginVaultModel) error {
+	if model.ID == 0 {
+		id, err := s.backend.NextID(SiteLoginVaultStorageType)
+
+		if err != nil {
+			return err
+		}
+
+		model.ID = id
 	}
 
-	defer file.Sync()
-	defer file.Close()
+	var buffer bytes.Buffer
+
+	err := gob.NewEncoder(&buffer).Encode(model)
 
-	savedStorage := SiteLoginSavedStorage{
-		Storage:              s.storage,
-		IndexIDAndExternalID: s.indexIDAndExternalID,
+	if err != nil {
+		return err
 	}
 
-	err = gob.NewEncoder(file).Encode(&savedStorage)
+	indexes := map[string]string{
+		IndexExternalID: model.ExternalID,
+		IndexSite:       model.GetSite(),
+	}
 
-	return err
+	return s.backend.Put(SiteLoginVaultStorageType, model.ID, buffer.Bytes(), indexes)
+}
+
+// remove deletes model. Caller must hold s.mux
+func (s *LoginVaultStorage) remove(id uint32) error {
+	return s.backend.Delete(SiteLoginVaultStorageType, id)
 }
 
 func (s *LoginVaultStorage) GetKind() string {
@@ -222,27 +330,19 @@ func (s *LoginVaultStorage) LoadForSync() ([]vaultsync.DataSyncer, error) {
 	s.mux.RLock()
 	defer s.mux.RUnlock()
 
-	arr := make([]vaultsync.DataSyncer, 0, len(s.storage))
+	models, err := s.all()
 
-	for _, model := range s.storage {
-		arr = append(arr, model)
+	if err != nil {
+		return nil, err
 	}
 
-	return arr, nil
-}
-
-func (s *LoginVaultStorage) SetConflictFlag(id uint32) error {
-	s.mux.Lock()
-	defer s.mux.Unlock()
+	arr := make([]vaultsync.DataSyncer, 0, len(models))
 
-	model, ok := s.storage[id]
-	if !ok {
-		return vaultdata.ErrNotFoundVaultInStorage
+	for _, model := range models {
+		arr = append(arr, model)
 	}
 
-	model.IsConflict = true
-
-	return nil
+	return arr, nil
 }
 
 func (s *LoginVaultStorage) SerializeToVault(data interface{}) ([]byte, error) {
@@ -276,84 +376,84 @@ func (s *LoginVaultStorage) DeserializeFromVault(dst []byte) (interface{}, error
 }
 
 func (s *LoginVaultStorage) UpdateAfterSyncByID(model vaultsync.DataSyncer, externalID string, version int) error {
-	id := model.GetID()
+	s.mux.Lock()
+	defer s.mux.Unlock()
 
-	siteLoginModel, ok := s.storage[id]
+	siteLoginModel, err := s.get(model.GetID())
 
-	if !ok {
-		return vaultdata.ErrNotFoundVaultInStorage
+	if err != nil {
+		return err
 	}
 
 	siteLoginModel.ExternalID = externalID
 	siteLoginModel.Version = version
 	siteLoginModel.IsNew = false
-	s.indexIDAndExternalID[externalID] = id
+	siteLoginModel.IsUpdate = false
+	siteLoginModel.confirmFieldVersions(version)
 
-	return nil
+	return s.put(siteLoginModel)
 }
 
 func (s *LoginVaultStorage) ConfirmDeleteAfterSyncByID(model vaultsync.DataSyncer) error {
-	id := model.GetID()
+	s.mux.Lock()
+	defer s.mux.Unlock()
 
-	_, ok := s.storage[id]
+	_, err := s.get(model.GetID())
 
-	if !ok {
-		return vaultdata.ErrNotFoundVaultInStorage
+	if err != nil {
+		return err
 	}
 
-	delete(s.indexIDAndExternalID, model.GetVaultID())
-	delete(s.storage, id)
-
-	return nil
+	return s.remove(model.GetID())
 }
 
 func (s *LoginVaultStorage) CreateDataStorage(externalID string, version int, data interface{}, _ string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
 	vs, ok := data.(*siteLoginVaultStored)
 
 	if !ok {
 		return ErrInvalidType
 	}
 
-	_, ok = s.indexIDAndExternalID[externalID]
+	_, err := s.getByExternalID(externalID)
 
-	if ok {
+	if err == nil {
 		// TODO: Logs or replace
 		return nil
 	}
 
+	if !errors.Is(err, vaultdata.ErrNotFoundVaultInStorage) {
+		return err
+	}
+
 	loginVaultModel := NewLoginVaultModel()
 
 	loginVaultModel.Data = vs.Data
 	loginVaultModel.MetaData = vs.MetaData
+	loginVaultModel.FieldVersions = vs.FieldVersions
 	loginVaultModel.Version = version
 	loginVaultModel.ExternalID = externalID
 	loginVaultModel.IsNew = false
 
-	s.storage[loginVaultModel.ID] = loginVaultModel
-	s.indexIDAndExternalID[externalID] = loginVaultModel.ID
-
-	return nil
+	return s.put(loginVaultModel)
 }
 
 func (s *LoginVaultStorage) UpdateDataStorage(externalID string, version int, data interface{}) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
 	vs, ok := data.(*siteLoginVaultStored)
 
 	if !ok {
 		return ErrInvalidType
 	}
 
-	id, ok := s.indexIDAndExternalID[externalID]
+	model, err := s.getByExternalID(externalID)
 
-	if !ok {
-		return vaultdata.ErrNotFoundVaultInStorage
-	}
-
-	model, ok := s.storage[id]
-
-	if !ok {
-		delete(s.indexIDAndExternalID, externalID)
-		// TODO: logs
-		return vaultdata.ErrNotFoundVaultInStorage
+	if err != nil {
+		return err
 	}
 
 	if model.IsNeedSync() {
@@ -368,24 +468,20 @@ func (s *LoginVaultStorage) UpdateDataStorage(externalID string, version int, da
 
 	model.Data = vs.Data
 	model.MetaData = vs.MetaData
+	model.FieldVersions = vs.FieldVersions
 	model.Version = version
 
-	return nil
+	return s.put(model)
 }
 
 func (s *LoginVaultStorage) DeleteDataStorage(externalID string, version int) error {
-	id, ok := s.indexIDAndExternalID[externalID]
-
-	if !ok {
-		return vaultdata.ErrNotFoundVaultInStorage
-	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
 
-	model, ok := s.storage[id]
+	model, err := s.getByExternalID(externalID)
 
-	if !ok {
-		delete(s.indexIDAndExternalID, externalID)
-		// TODO: Need logs
-		return vaultdata.ErrNotFoundVaultInStorage
+	if err != nil {
+		return err
 	}
 
 	if model.IsNeedSync() {
@@ -398,10 +494,7 @@ func (s *LoginVaultStorage) DeleteDataStorage(externalID string, version int) er
 		return nil
 	}
 
-	delete(s.indexIDAndExternalID, externalID)
-	delete(s.storage, id)
-
-	return nil
+	return s.remove(model.ID)
 }
 
 // For storage!
@@ -427,35 +520,72 @@ func (s *LoginVaultStorage) Create(data *LoginSecreteData, siteURL string) error
 	newM.Data = encryptedData
 	newM.SetSite(siteURL)
 
-	s.storage[newM.ID] = newM
-	//s.indexIDAndExternalID[newM.ExternalID] = newM.ID
+	return s.put(newM)
+}
+
+func (s *LoginVaultStorage) GetAll() ([]*LoginVaultModel, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
 
-	return nil
+	return s.all()
 }
 
-func (s *LoginVaultStorage) GetAll() []*LoginVaultModel {
+// GetBySite returns not deleted models of site ordered by ID
+func (s *LoginVaultStorage) GetBySite(siteURL string) ([]*LoginVaultModel, error) {
 	s.mux.RLock()
 	defer s.mux.RUnlock()
 
-	arr := make([]*LoginVaultModel, 0, len(s.storage))
+	ids, err := s.backend.FindByIndex(SiteLoginVaultStorageType, IndexSite, siteURL)
+
+	if err != nil {
+		return nil, err
+	}
+
+	arr := make([]*LoginVaultModel, 0, len(ids))
+
+	for _, id := range ids {
+		model, err := s.get(id)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if model.IsDelete {
+			continue
+		}
 
-	for _, model := range s.storage {
 		arr = append(arr, model)
 	}
 
-	sort.Slice(arr, func(i, j int) bool {
-		return arr[i].ID < arr[j].ID
-	})
+	return arr, nil
+}
+
+func (s *LoginVaultStorage) GetByID(id uint32) (*LoginVaultModel, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	model, err := s.get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if model.IsDelete {
+		return nil, vaultdata.ErrNotFoundVaultInStorage
+	}
 
-	return arr
+	return model, nil
 }
 
 func (s *LoginVaultStorage) ViewDataByID(id uint32) (*LoginSecreteData, error) {
 	s.mux.RLock()
 	defer s.mux.RUnlock()
 
-	model, ok := s.storage[id]
-	if !ok || model.IsDelete {
+	model, err := s.get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if model.IsDelete {
 		return nil, vaultdata.ErrNotFoundVaultInStorage
 	}
 
@@ -478,49 +608,60 @@ func (s *LoginVaultStorage) DeleteByID(id uint32) error {
 	s.mux.Lock()
 	defer s.mux.Unlock()
 
-	model, ok := s.storage[id]
+	model, err := s.get(id)
 
-	if !ok {
-		return vaultdata.ErrNotFoundVaultInStorage
+	if err != nil {
+		return err
 	}
 
 	model.IsUpdate = false
 	model.IsDelete = !model.IsDelete
 
-	return nil
+	return s.put(model)
 }
 
 func (s *LoginVaultStorage) UpdateByID(id uint32, login, password string) error {
 	s.mux.Lock()
 	defer s.mux.Unlock()
 
-	model, ok := s.storage[id]
+	model, err := s.get(id)
+
+	if err != nil {
+		return err
+	}
 
-	if !ok || model.IsDelete {
+	if model.IsDelete {
 		return vaultdata.ErrNotFoundVaultInStorage
 	}
 
+	oldData, err := s.decryptSecret(model.Data)
+
+	if err != nil {
+		return err
+	}
+
 	loginData := LoginSecreteData{
 		Login:    login,
 		Password: password,
 	}
 
-	var buffer bytes.Buffer
-	err := gob.NewEncoder(&buffer).Encode(loginData)
+	encrypted, err := s.encryptSecret(&loginData)
 
 	if err != nil {
 		return err
 	}
 
-	encrypted, err := s.crypt.Encrypt(buffer.Bytes())
+	model.Data = encrypted
 
-	if err != nil {
-		return err
+	if oldData.Login != login {
+		model.markDirty(LoginFieldLogin)
 	}
 
-	model.Data = encrypted
+	if oldData.Password != password {
+		model.markDirty(LoginFieldPassword)
+	}
 
-	model.IsUpdate = false
+	model.IsUpdate = !model.IsNew
 
-	return nil
+	return s.put(model)
 }
@@ -0,0 +1,424 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultdata"
+)
+
+const (
+	LoginFieldLogin    = "login"
+	LoginFieldPassword = "password"
+	LoginFieldSite     = "site"
+)
+
+var loginFields = []string{LoginFieldLogin, LoginFieldPassword, LoginFieldSite}
+
+func (s *LoginVaultStorage) SetConflict(id uint32, remote vaultdata.VaultSyncData, data interface{}) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	model, err := s.get(id)
+	if err != nil {
+		return err
+	}
+
+	conflict := LoginVaultConflict{
+		Version:   remote.Version,
+		IsDeleted: remote.IsDeleted,
+	}
+
+	if !remote.IsDeleted {
+		vs, ok := data.(*siteLoginVaultStored)
+
+		if !ok {
+			return ErrInvalidType
+		}
+
+		conflict.Data = vs.Data
+		conflict.MetaData = vs.MetaData
+		conflict.FieldVersions = vs.FieldVersions
+	}
+
+	model.IsConflict = true
+	model.Conflict = &conflict
+
+	return s.put(model)
+}
+
+// MergeConflict merges remote version into local when different fields were changed.
+// It returns false without changes when the same field was changed on both sides.
+func (s *LoginVaultStorage) MergeConflict(id uint32, remote vaultdata.VaultSyncData, data interface{}) (bool, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	model, err := s.get(id)
+	if err != nil {
+		return false, err
+	}
+
+	if remote.IsDeleted || model.IsDelete || !model.IsUpdate {
+		return false, nil
+	}
+
+	vs, ok := data.(*siteLoginVaultStored)
+
+	if !ok {
+		return false, ErrInvalidType
+	}
+
+	local, err := s.decryptSecret(model.Data)
+
+	if err != nil {
+		return false, err
+	}
+
+	remoteSecret, err := s.decryptSecret(vs.Data)
+
+	if err != nil {
+		return false, err
+	}
+
+	remoteSite := vs.MetaData[LoginMetaDataSiteURLKey]
+
+	merge := vaultdata.MergeFields(
+		loginFields,
+		model.Version,
+		model.DirtyFields,
+		vs.FieldVersions,
+		loginFieldValues(local, model.GetSite()),
+		loginFieldValues(remoteSecret, remoteSite),
+	)
+
+	if merge.HasCollisions() {
+		return false, nil
+	}
+
+	metaData := copyMetaData(vs.MetaData)
+	metaData[LoginMetaDataSiteURLKey] = model.GetSite()
+
+	for _, field := range merge.Remote {
+		switch field {
+		case LoginFieldLogin:
+			local.Login = remoteSecret.Login
+		case LoginFieldPassword:
+			local.Password = remoteSecret.Password
+		case LoginFieldSite:
+			metaData[LoginMetaDataSiteURLKey] = remoteSite
+		}
+	}
+
+	encrypted, err := s.encryptSecret(local)
+
+	if err != nil {
+		return false, err
+	}
+
+	fieldVersions := vs.FieldVersions.Copy()
+
+	for _, field := range loginFields {
+		if _, ok := fieldVersions[field]; !ok {
+			fieldVersions[field] = remote.Version
+		}
+	}
+
+	model.Data = encrypted
+	model.MetaData = metaData
+	model.FieldVersions = fieldVersions
+	model.Version = remote.Version
+	model.IsConflict = false
+	model.Conflict = nil
+
+	return true, s.put(model)
+}
+
+func (s *LoginVaultStorage) GetConflicts() ([]vaultdata.Conflict, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	models, err := s.all()
+
+	if err != nil {
+		return nil, err
+	}
+
+	conflicts := make([]vaultdata.Conflict, 0)
+
+	for _, model := range models {
+		if !model.IsConflict || model.Conflict == nil {
+			continue
+		}
+
+		conflict := vaultdata.Conflict{
+			Kind:            SiteLoginVaultStorageType,
+			ID:              model.ID,
+			VaultID:         model.ExternalID,
+			LocalVersion:    model.Version,
+			RemoteVersion:   model.Conflict.Version,
+			IsLocalDeleted:  model.IsDelete,
+			IsRemoteDeleted: model.Conflict.IsDeleted,
+		}
+
+		fields, err := s.diffConflict(model)
+
+		if err != nil {
+			return nil, err
+		}
+
+		conflict.Fields = fields
+
+		conflicts = append(conflicts, conflict)
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].ID < conflicts[j].ID
+	})
+
+	return conflicts, nil
+}
+
+func (s *LoginVaultStorage) diffConflict(model *LoginVaultModel) ([]vaultdata.ConflictFieldDiff, error) {
+	local, err := s.decryptSecret(model.Data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	remote := &LoginSecreteData{}
+	remoteSite := ""
+
+	if !model.Conflict.IsDeleted {
+		remote, err = s.decryptSecret(model.Conflict.Data)
+
+		if err != nil {
+			return nil, err
+		}
+
+		remoteSite = model.Conflict.MetaData[LoginMetaDataSiteURLKey]
+	}
+
+	return []vaultdata.ConflictFieldDiff{
+		{Name: LoginFieldLogin, Local: local.Login, Remote: remote.Login},
+		{Name: LoginFieldPassword, Local: local.Password, Remote: remote.Password, IsSecret: true},
+		{Name: LoginFieldSite, Local: model.GetSite(), Remote: remoteSite},
+	}, nil
+}
+
+func (s *LoginVaultStorage) ResolveConflict(externalID string, resolve vaultdata.ConflictResolve) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	model, err := s.getByExternalID(externalID)
+	if errors.Is(err, vaultdata.ErrNotFoundVaultInStorage) {
+		return vaultdata.ErrNotFoundConflict
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !model.IsConflict || model.Conflict == nil {
+		return vaultdata.ErrNotFoundConflict
+	}
+
+	conflict := model.Conflict
+
+	if conflict.IsDeleted || model.IsDelete {
+		if resolve.Strategy == vaultdata.ResolveBoth || resolve.Strategy == vaultdata.ResolveMerge {
+			return fmt.Errorf("%w: vault was deleted, use local or remote", vaultdata.ErrInvalidResolve)
+		}
+	}
+
+	switch resolve.Strategy {
+	case vaultdata.ResolveLocal:
+		if conflict.IsDeleted {
+			// Server doesn't have vault anymore, upload local as new one
+			model.ExternalID = ""
+			model.Version = 0
+			model.IsNew = true
+			model.IsUpdate = false
+
+			break
+		}
+
+		err := s.markDirtyAgainstRemote(model, loginFields)
+
+		if err != nil {
+			return err
+		}
+
+		model.Version = conflict.Version
+		model.FieldVersions = conflict.FieldVersions
+		model.IsUpdate = !model.IsDelete
+	case vaultdata.ResolveRemote:
+		if conflict.IsDeleted {
+			return s.remove(model.ID)
+		}
+
+		s.applyRemote(model)
+	case vaultdata.ResolveBoth:
+		copyModel := NewLoginVaultModel()
+		copyModel.Data = model.Data
+		copyModel.MetaData = copyMetaData(model.MetaData)
+
+		err := s.put(copyModel)
+
+		if err != nil {
+			return err
+		}
+
+		s.applyRemote(model)
+	case vaultdata.ResolveMerge:
+		err := s.mergeConflict(model, resolve.Fields)
+
+		if err != nil {
+			return err
+		}
+	default:
+		return vaultdata.ErrInvalidResolve
+	}
+
+	model.IsConflict = false
+	model.Conflict = nil
+
+	return s.put(model)
+}
+
+func (s *LoginVaultStorage) applyRemote(model *LoginVaultModel) {
+	model.Data = model.Conflict.Data
+	model.MetaData = model.Conflict.MetaData
+	model.FieldVersions = model.Conflict.FieldVersions
+	model.DirtyFields = nil
+	model.Version = model.Conflict.Version
+	model.IsUpdate = false
+	model.IsDelete = false
+}
+
+func (s *LoginVaultStorage) mergeConflict(model *LoginVaultModel, fields map[string]vaultdata.ResolveStrategy) error {
+	for name := range fields {
+		if name != LoginFieldLogin && name != LoginFieldPassword && name != LoginFieldSite {
+			return fmt.Errorf("%w: unknown field %q", vaultdata.ErrInvalidResolve, name)
+		}
+	}
+
+	local, err := s.decryptSecret(model.Data)
+
+	if err != nil {
+		return err
+	}
+
+	remote, err := s.decryptSecret(model.Conflict.Data)
+
+	if err != nil {
+		return err
+	}
+
+	if fields[LoginFieldLogin] == vaultdata.ResolveRemote {
+		local.Login = remote.Login
+	}
+
+	if fields[LoginFieldPassword] == vaultdata.ResolveRemote {
+		local.Password = remote.Password
+	}
+
+	encrypted, err := s.encryptSecret(local)
+
+	if err != nil {
+		return err
+	}
+
+	model.Data = encrypted
+
+	if fields[LoginFieldSite] == vaultdata.ResolveRemote {
+		model.MetaData = copyMetaData(model.MetaData)
+		model.SetSite(model.Conflict.MetaData[LoginMetaDataSiteURLKey])
+	}
+
+	err = s.markDirtyAgainstRemote(model, loginFields)
+
+	if err != nil {
+		return err
+	}
+
+	model.Version = model.Conflict.Version
+	model.FieldVersions = model.Conflict.FieldVersions
+	model.IsUpdate = true
+
+	return nil
+}
+
+// markDirtyAgainstRemote marks fields which differ from remote version, they will be uploaded as local changes
+func (s *LoginVaultStorage) markDirtyAgainstRemote(model *LoginVaultModel, fields []string) error {
+	local, err := s.decryptSecret(model.Data)
+
+	if err != nil {
+		return err
+	}
+
+	remote, err := s.decryptSecret(model.Conflict.Data)
+
+	if err != nil {
+		return err
+	}
+
+	localValues := loginFieldValues(local, model.GetSite())
+	remoteValues := loginFieldValues(remote, model.Conflict.MetaData[LoginMetaDataSiteURLKey])
+
+	model.DirtyFields = nil
+
+	for _, field := range fields {
+		if localValues[field] != remoteValues[field] {
+			model.markDirty(field)
+		}
+	}
+
+	return nil
+}
+
+func loginFieldValues(secret *LoginSecreteData, site string) map[string]string {
+	return map[string]string{
+		LoginFieldLogin:    secret.Login,
+		LoginFieldPassword: secret.Password,
+		LoginFieldSite:     site,
+	}
+}
+
+func (s *LoginVaultStorage) decryptSecret(data []byte) (*LoginSecreteData, error) {
+	decryptedData, err := s.crypt.Decrypt(data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var secret LoginSecreteData
+
+	if err := gob.NewDecoder(bytes.NewReader(decryptedData)).Decode(&secret); err != nil {
+		return nil, err
+	}
+
+	return &secret, nil
+}
+
+func (s *LoginVaultStorage) encryptSecret(secret *LoginSecreteData) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	if err := gob.NewEncoder(&buffer).Encode(secret); err != nil {
+		return nil, err
+	}
+
+	return s.crypt.Encrypt(buffer.Bytes())
+}
+
+func copyMetaData(metaData map[string]string) map[string]string {
+	c := make(map[string]string, len(metaData))
+
+	for k, v := range metaData {
+		c[k] = v
+	}
+
+	return c
+}
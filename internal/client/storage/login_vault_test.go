@@ -6,30 +6,44 @@ import (
 	"testing"
 
 	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultcrypt"
+	"github.com/shreyner/gophkeeper/internal/client/pkg/vaultdata"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-func TestLoginVaultStorage_LoadFromLocalFile(t *testing.T) {
-	t.Run("Success open file", func(t *testing.T) {
+// copyTestData copies gob file from testdata, migration renames source file
+func copyTestData(t *testing.T, name string) string {
+	data, err := os.ReadFile(path.Join("testdata", name))
+	require.NoError(t, err)
+
+	filePathDB := path.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(filePathDB, data, 0600))
+
+	return filePathDB
+}
+
+func TestLoginVaultStorage_MigrateFromGobFile(t *testing.T) {
+	t.Run("Success migrate file", func(t *testing.T) {
 		assert := assert.New(t)
 		require := require.New(t)
-		pwd, _ := os.Getwd()
-		siteloginTestDataDB := path.Join(pwd, "testdata", "site-login-base.db")
+		siteloginTestDataDB := copyTestData(t, "site-login-base.db")
 
 		vcrypto := vaultcrypt.New()
 		_ = vcrypto.SetMasterPassword("Alex", "123")
 
-		siteLoginStorage := NewLoginVaultStorage(vcrypto)
+		siteLoginStorage := NewLoginVaultStorage(vcrypto, NewMemoryBackend())
 
-		err := siteLoginStorage.LoadFromLocalFile(siteloginTestDataDB)
+		err := siteLoginStorage.MigrateFromGobFile(siteloginTestDataDB)
+		require.Nil(err, "failed migrate db file")
 
-		require.Nil(err, "failed load db file")
-		require.Len(siteLoginStorage.storage, 5, "incorrect length storage")
+		all, err := siteLoginStorage.GetAll()
+		require.Nil(err)
+		require.Len(all, 5, "incorrect length storage")
 
-		assert.Equal(siteLoginStorage.storage[1].GetSite(), "vk.vom")
-		assert.Equal(siteLoginStorage.storage[3].GetSite(), "vk.vom")
-		assert.Equal(siteLoginStorage.storage[5].GetSite(), "vk.vom")
+		assert.Equal(uint32(1), all[0].ID)
+		assert.Equal(all[0].GetSite(), "vk.vom")
+		assert.Equal(all[2].GetSite(), "vk.vom")
+		assert.Equal(all[4].GetSite(), "vk.vom")
 
 		secret1, err := siteLoginStorage.ViewDataByID(1)
 		require.Nil(err, "error encrypted data")
@@ -40,39 +54,54 @@ func TestLoginVaultStorage_LoadFromLocalFile(t *testing.T) {
 		require.Nil(err, "error encrypted data")
 		assert.Equal(secret5.Login, "Alexx")
 		assert.Equal(secret5.Password, "444")
+
+		_, err = os.Stat(siteloginTestDataDB)
+		assert.ErrorIs(err, os.ErrNotExist)
+
+		_, err = os.Stat(siteloginTestDataDB + migratedFileSuffix)
+		assert.Nil(err)
+
+		err = siteLoginStorage.Create(&LoginSecreteData{Login: "new", Password: "new"}, "new.com")
+		require.Nil(err)
+
+		all, err = siteLoginStorage.GetAll()
+		require.Nil(err)
+		require.Len(all, 6)
+		assert.Equal(uint32(6), all[5].ID, "new record must not reuse migrated id")
 	})
 
-	t.Run("Success create file if not exists", func(t *testing.T) {
+	t.Run("Skip if file not exists", func(t *testing.T) {
 		require := require.New(t)
 		siteloginTestDataDB := path.Join(t.TempDir(), "site-login-new.db")
 
 		vcrypto := vaultcrypt.New()
 		_ = vcrypto.SetMasterPassword("Alex", "123")
 
-		siteLoginStorage := NewLoginVaultStorage(vcrypto)
-
-		err := siteLoginStorage.LoadFromLocalFile(siteloginTestDataDB)
+		siteLoginStorage := NewLoginVaultStorage(vcrypto, NewMemoryBackend())
 
-		require.Nil(err, "failed load db file")
-		require.Len(siteLoginStorage.storage, 0, "incorrect length storage")
+		err := siteLoginStorage.MigrateFromGobFile(siteloginTestDataDB)
+		require.Nil(err, "failed migrate db file")
 
-		file, err := os.Open(siteloginTestDataDB)
-		require.Nil(err, "cant find file after create")
-		defer file.Close()
+		all, err := siteLoginStorage.GetAll()
+		require.Nil(err)
+		require.Len(all, 0, "incorrect length storage")
 	})
 }
 
-func TestLoginVaultStorage_SaveToFile(t *testing.T) {
-	t.Run("Success save folder", func(t *testing.T) {
+func TestLoginVaultStorage_Persist(t *testing.T) {
+	t.Run("Success reopen backend", func(t *testing.T) {
 		assert := assert.New(t)
 		require := require.New(t)
 
-		siteloginTestDataDB := path.Join(t.TempDir(), "site-login-base.db")
+		backendPath := path.Join(t.TempDir(), "vault.bolt")
 
 		vcrypto := vaultcrypt.New()
 		_ = vcrypto.SetMasterPassword("Alex", "123")
 
-		siteLoginStorage := NewLoginVaultStorage(vcrypto)
+		backend, err := OpenBoltBackend(backendPath)
+		require.Nil(err)
+
+		siteLoginStorage := NewLoginVaultStorage(vcrypto, backend)
 
 		secretData1 := LoginSecreteData{
 			Login:    "alex",
@@ -83,32 +112,292 @@ func TestLoginVaultStorage_SaveToFile(t *testing.T) {
 			Password: "321",
 		}
 
-		err := siteLoginStorage.Create(&secretData1, "vk.com")
+		err = siteLoginStorage.Create(&secretData1, "vk.com")
 		require.Nil(err, "error create data site login")
 
 		err = siteLoginStorage.Create(&secretData2, "yandex.ru")
 		require.Nil(err, "error create data site login")
 
-		err = siteLoginStorage.SaveToFile(siteloginTestDataDB)
-		require.Nil(err, "cant save to file")
+		require.Nil(siteLoginStorage.UpdateByID(2, "polly", "456"))
+		require.Nil(backend.Close())
 
-		siteLoginStorage = NewLoginVaultStorage(vcrypto)
-		err = siteLoginStorage.LoadFromLocalFile(siteloginTestDataDB)
+		backend, err = OpenBoltBackend(backendPath)
+		require.Nil(err)
+		defer backend.Close()
 
-		require.Nil(err, "failed load db file")
-		require.Len(siteLoginStorage.storage, 2, "incorrect length storage")
+		siteLoginStorage = NewLoginVaultStorage(vcrypto, backend)
 
-		assert.Equal(siteLoginStorage.storage[1].GetSite(), "vk.com")
-		assert.Equal(siteLoginStorage.storage[2].GetSite(), "yandex.ru")
+		all, err := siteLoginStorage.GetAll()
+		require.Nil(err)
+		require.Len(all, 2, "incorrect length storage")
+
+		assert.Equal(all[0].GetSite(), "vk.com")
+		assert.Equal(all[1].GetSite(), "yandex.ru")
+		assert.True(all[1].IsNew)
 
 		secret1, err := siteLoginStorage.ViewDataByID(1)
 		require.Nil(err, "error encrypted data")
 		assert.Equal(secret1.Login, "alex")
 		assert.Equal(secret1.Password, "123")
 
-		secret5, err := siteLoginStorage.ViewDataByID(2)
+		secret2, err := siteLoginStorage.ViewDataByID(2)
 		require.Nil(err, "error encrypted data")
-		assert.Equal(secret5.Login, "polly")
-		assert.Equal(secret5.Password, "321")
+		assert.Equal(secret2.Login, "polly")
+		assert.Equal(secret2.Password, "456")
+	})
+}
+
+func TestLoginVaultStorage_GetBySite(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	vcrypto := vaultcrypt.New()
+	_ = vcrypto.SetMasterPassword("Alex", "123")
+
+	siteLoginStorage := NewLoginVaultStorage(vcrypto, NewMemoryBackend())
+
+	require.Nil(siteLoginStorage.Create(&LoginSecreteData{Login: "alex", Password: "1"}, "vk.com"))
+	require.Nil(siteLoginStorage.Create(&LoginSecreteData{Login: "polly", Password: "2"}, "yandex.ru"))
+	require.Nil(siteLoginStorage.Create(&LoginSecreteData{Login: "bob", Password: "3"}, "vk.com"))
+	require.Nil(siteLoginStorage.DeleteByID(3))
+
+	models, err := siteLoginStorage.GetBySite("vk.com")
+	require.Nil(err)
+	require.Len(models, 1)
+	assert.Equal(uint32(1), models[0].ID)
+
+	models, err = siteLoginStorage.GetBySite("google.com")
+	require.Nil(err)
+	assert.Len(models, 0)
+}
+
+func newConflictedLoginStorage(t *testing.T) (*LoginVaultStorage, *LoginVaultModel) {
+	require := require.New(t)
+
+	vcrypto := vaultcrypt.New()
+	_ = vcrypto.SetMasterPassword("Alex", "123")
+
+	siteLoginStorage := NewLoginVaultStorage(vcrypto, NewMemoryBackend())
+
+	err := siteLoginStorage.Create(&LoginSecreteData{Login: "alex", Password: "local"}, "vk.com")
+	require.Nil(err)
+
+	all, err := siteLoginStorage.GetAll()
+	require.Nil(err)
+
+	model := all[0]
+	require.Nil(siteLoginStorage.UpdateAfterSyncByID(model, "vault-1", 1))
+	require.Nil(siteLoginStorage.UpdateByID(model.ID, "alex", "local"))
+
+	remoteData, err := siteLoginStorage.encryptSecret(&LoginSecreteData{Login: "alex-remote", Password: "remote"})
+	require.Nil(err)
+
+	remote := siteLoginVaultStored{
+		Data:     remoteData,
+		MetaData: map[string]string{LoginMetaDataSiteURLKey: "vk.ru"},
+	}
+
+	err = siteLoginStorage.SetConflict(model.ID, vaultdata.VaultSyncData{ID: "vault-1", Version: 3}, &remote)
+	require.Nil(err)
+
+	return siteLoginStorage, model
+}
+
+func TestLoginVaultStorage_GetConflicts(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	siteLoginStorage, model := newConflictedLoginStorage(t)
+
+	conflicts, err := siteLoginStorage.GetConflicts()
+	require.Nil(err)
+	require.Len(conflicts, 1)
+
+	assert.Equal(model.ID, conflicts[0].ID)
+	assert.Equal("vault-1", conflicts[0].VaultID)
+	assert.Equal(1, conflicts[0].LocalVersion)
+	assert.Equal(3, conflicts[0].RemoteVersion)
+	assert.Equal([]vaultdata.ConflictFieldDiff{
+		{Name: LoginFieldLogin, Local: "alex", Remote: "alex-remote"},
+		{Name: LoginFieldPassword, Local: "local", Remote: "remote", IsSecret: true},
+		{Name: LoginFieldSite, Local: "vk.com", Remote: "vk.ru"},
+	}, conflicts[0].Fields)
+}
+
+func TestLoginVaultStorage_ResolveConflict(t *testing.T) {
+	t.Run("local", func(t *testing.T) {
+		assert := assert.New(t)
+		siteLoginStorage, model := newConflictedLoginStorage(t)
+
+		err := siteLoginStorage.ResolveConflict("vault-1", vaultdata.ConflictResolve{Strategy: vaultdata.ResolveLocal})
+		assert.Nil(err)
+
+		model, err = siteLoginStorage.GetByID(model.ID)
+		assert.Nil(err)
+
+		secret, _ := siteLoginStorage.ViewDataByID(model.ID)
+		assert.Equal("local", secret.Password)
+		assert.Equal(3, model.Version)
+		assert.True(model.IsUpdate)
+		assert.False(model.IsConflict)
+	})
+
+	t.Run("remote", func(t *testing.T) {
+		assert := assert.New(t)
+		siteLoginStorage, model := newConflictedLoginStorage(t)
+
+		err := siteLoginStorage.ResolveConflict("vault-1", vaultdata.ConflictResolve{Strategy: vaultdata.ResolveRemote})
+		assert.Nil(err)
+
+		model, err = siteLoginStorage.GetByID(model.ID)
+		assert.Nil(err)
+
+		secret, _ := siteLoginStorage.ViewDataByID(model.ID)
+		assert.Equal("remote", secret.Password)
+		assert.Equal("vk.ru", model.GetSite())
+		assert.Equal(3, model.Version)
+		assert.False(model.IsUpdate)
+	})
+
+	t.Run("both", func(t *testing.T) {
+		assert := assert.New(t)
+		siteLoginStorage, model := newConflictedLoginStorage(t)
+
+		err := siteLoginStorage.ResolveConflict("vault-1", vaultdata.ConflictResolve{Strategy: vaultdata.ResolveBoth})
+		assert.Nil(err)
+
+		all, err := siteLoginStorage.GetAll()
+		assert.Nil(err)
+		assert.Len(all, 2)
+
+		copySecret, _ := siteLoginStorage.ViewDataByID(all[1].ID)
+		assert.Equal("local", copySecret.Password)
+		assert.True(all[1].IsNew)
+
+		secret, _ := siteLoginStorage.ViewDataByID(model.ID)
+		assert.Equal("remote", secret.Password)
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		assert := assert.New(t)
+		siteLoginStorage, model := newConflictedLoginStorage(t)
+
+		err := siteLoginStorage.ResolveConflict("vault-1", vaultdata.ConflictResolve{
+			Strategy: vaultdata.ResolveMerge,
+			Fields: map[string]vaultdata.ResolveStrategy{
+				LoginFieldPassword: vaultdata.ResolveRemote,
+			},
+		})
+		assert.Nil(err)
+
+		model, err = siteLoginStorage.GetByID(model.ID)
+		assert.Nil(err)
+
+		secret, _ := siteLoginStorage.ViewDataByID(model.ID)
+		assert.Equal("alex", secret.Login)
+		assert.Equal("remote", secret.Password)
+		assert.Equal("vk.com", model.GetSite())
+		assert.Equal(3, model.Version)
+		assert.True(model.IsUpdate)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		siteLoginStorage, _ := newConflictedLoginStorage(t)
+
+		err := siteLoginStorage.ResolveConflict("vault-2", vaultdata.ConflictResolve{Strategy: vaultdata.ResolveLocal})
+		assert.ErrorIs(t, err, vaultdata.ErrNotFoundConflict)
+	})
+}
+
+func TestLoginVaultStorage_MergeConflict(t *testing.T) {
+	newSyncedStorage := func(t *testing.T) (*LoginVaultStorage, *LoginVaultModel) {
+		require := require.New(t)
+
+		vcrypto := vaultcrypt.New()
+		_ = vcrypto.SetMasterPassword("Alex", "123")
+
+		siteLoginStorage := NewLoginVaultStorage(vcrypto, NewMemoryBackend())
+
+		err := siteLoginStorage.Create(&LoginSecreteData{Login: "alex", Password: "old"}, "vk.com")
+		require.Nil(err)
+
+		all, err := siteLoginStorage.GetAll()
+		require.Nil(err)
+
+		model := all[0]
+		require.Nil(siteLoginStorage.UpdateAfterSyncByID(model, "vault-1", 2))
+
+		return siteLoginStorage, model
+	}
+
+	remoteVault := func(s *LoginVaultStorage, secret *LoginSecreteData, site string, versions vaultdata.FieldVersions) *siteLoginVaultStored {
+		data, _ := s.encryptSecret(secret)
+
+		return &siteLoginVaultStored{
+			Data:          data,
+			MetaData:      map[string]string{LoginMetaDataSiteURLKey: site},
+			FieldVersions: versions,
+		}
+	}
+
+	t.Run("different fields", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+		siteLoginStorage, model := newSyncedStorage(t)
+
+		require.Nil(siteLoginStorage.UpdateByID(model.ID, "alex", "local"))
+
+		model, err := siteLoginStorage.GetByID(model.ID)
+		require.Nil(err)
+		assert.Equal(map[string]bool{LoginFieldPassword: true}, model.DirtyFields)
+
+		remote := remoteVault(
+			siteLoginStorage,
+			&LoginSecreteData{Login: "alex", Password: "old"},
+			"vk.ru",
+			vaultdata.FieldVersions{LoginFieldLogin: 0, LoginFieldPassword: 0, LoginFieldSite: 3},
+		)
+
+		isMerged, err := siteLoginStorage.MergeConflict(model.ID, vaultdata.VaultSyncData{ID: "vault-1", Version: 3}, remote)
+		require.Nil(err)
+		require.True(isMerged)
+
+		model, err = siteLoginStorage.GetByID(model.ID)
+		require.Nil(err)
+
+		secret, _ := siteLoginStorage.ViewDataByID(model.ID)
+		assert.Equal("local", secret.Password)
+		assert.Equal("vk.ru", model.GetSite())
+		assert.Equal(3, model.Version)
+		assert.True(model.IsUpdate)
+
+		stored := siteLoginVaultStoredFromModel(model)
+		assert.Equal(vaultdata.FieldVersions{LoginFieldLogin: 0, LoginFieldPassword: 4, LoginFieldSite: 3}, stored.FieldVersions)
+	})
+
+	t.Run("same field", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+		siteLoginStorage, model := newSyncedStorage(t)
+
+		require.Nil(siteLoginStorage.UpdateByID(model.ID, "alex", "local"))
+
+		remote := remoteVault(
+			siteLoginStorage,
+			&LoginSecreteData{Login: "alex", Password: "remote"},
+			"vk.com",
+			vaultdata.FieldVersions{LoginFieldLogin: 0, LoginFieldPassword: 3, LoginFieldSite: 0},
+		)
+
+		isMerged, err := siteLoginStorage.MergeConflict(model.ID, vaultdata.VaultSyncData{ID: "vault-1", Version: 3}, remote)
+		require.Nil(err)
+		assert.False(isMerged)
+
+		model, err = siteLoginStorage.GetByID(model.ID)
+		require.Nil(err)
+
+		secret, _ := siteLoginStorage.ViewDataByID(model.ID)
+		assert.Equal("local", secret.Password)
+		assert.Equal(2, model.Version)
 	})
 }
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"encoding/gob"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// migratedFileSuffix is added to gob file of previous versions after moving it to backend
+const migratedFileSuffix = ".migrated"
+
+// readGobFile decodes gob file of previous versions. It returns false when file doesn't exist or is empty
+func readGobFile(filePathDB string, dst interface{}) (bool, error) {
+	file, err := os.Open(filePathDB)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	if fileInfo.Size() == 0 {
+		return false, nil
+	}
+
+	err = gob.NewDecoder(file).Decode(dst)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// MigratedFiles are gob files of previous versions in data folder. They are kept after moving to backend
+// and are removed with local data of revoked device
+type MigratedFiles struct {
+	filePathsDB []string
+}
+
+// NewMigratedFiles takes names of gob files, files are looked for only inside dataFolder
+func NewMigratedFiles(dataFolder string, names ...string) *MigratedFiles {
+	filePathsDB := make([]string, 0, len(names))
+
+	for _, name := range names {
+		filePathsDB = append(filePathsDB, filepath.Join(dataFolder, filepath.Base(name)))
+	}
+
+	migratedFiles := MigratedFiles{filePathsDB: filePathsDB}
+
+	return &migratedFiles
+}
+
+// Remove deletes gob files and their migrated copies, missing files are skipped.
+// It returns first error after trying every file
+func (f *MigratedFiles) Remove() error {
+	var removeErr error
+
+	for _, filePathDB := range f.filePathsDB {
+		for _, filePath := range []string{filePathDB, filePathDB + migratedFileSuffix} {
+			err := os.Remove(filePath)
+
+			if err != nil && !errors.Is(err, os.ErrNotExist) && removeErr == nil {
+				removeErr = err
+			}
+		}
+	}
+
+	return removeErr
+}
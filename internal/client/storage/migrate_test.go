@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigratedFiles_Remove(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	root := t.TempDir()
+	dataFolder := path.Join(root, "data")
+	require.NoError(os.MkdirAll(dataFolder, 0700))
+
+	migrated := path.Join(dataFolder, "site-login.db"+migratedFileSuffix)
+	require.NoError(os.WriteFile(migrated, []byte("gob"), 0600))
+
+	outside := path.Join(root, "file.db")
+	require.NoError(os.WriteFile(outside, []byte("gob"), 0600))
+
+	migratedFiles := NewMigratedFiles(dataFolder, "site-login.db", "../file.db")
+
+	require.NoError(migratedFiles.Remove(), "missing files are skipped")
+
+	assert.NoFileExists(migrated)
+	assert.FileExists(outside, "files outside of data folder are not touched")
+}
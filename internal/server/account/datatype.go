@@ -0,0 +1,13 @@
+package account
+
+// UploadRef is not finished multipart upload of deleted account
+type UploadRef struct {
+	ObjectName    string
+	StoreUploadID string
+}
+
+// DeletedAccount lists objects of blob store which are left after account rows are deleted
+type DeletedAccount struct {
+	ObjectNames []string
+	Uploads     []UploadRef
+}
@@ -0,0 +1,5 @@
+package account
+
+import "errors"
+
+var ErrAccountNotFound = errors.New("account not found")
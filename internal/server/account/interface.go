@@ -0,0 +1,12 @@
+//go:generate ./bin/mockgen -source=./interface.go -destination=./mock/account.go -package=account
+package account
+
+import (
+	"github.com/google/uuid"
+	"golang.org/x/net/context"
+)
+
+type AccountRepository interface {
+	FindLogin(ctx context.Context, userID uuid.UUID) (string, error)
+	Delete(ctx context.Context, userID uuid.UUID) (*DeletedAccount, error)
+}
@@ -0,0 +1,171 @@
+package account
+
+import (
+	"database/sql"
+
+	"github.com/google/uuid"
+	"golang.org/x/net/context"
+
+	"github.com/shreyner/gophkeeper/internal/server/ratelimit"
+)
+
+// deleteQueries remove rows of user in order of foreign keys, blobs and uploads are deleted before them
+var deleteQueries = []string{
+	`delete from refresh_tokens where device_id in (select id from devices where user_id = $1);`,
+	`delete from devices where user_id = $1;`,
+	`delete from user_backup_codes where user_id = $1;`,
+	`delete from invites where created_by = $1;`,
+	`update invites set used_by = null where used_by = $1;`,
+	`delete from vaults where user_id = $1;`,
+	`delete from vault_change_seqs where user_id = $1;`,
+	`delete from user_usages where user_id = $1;`,
+	`delete from users where id = $1;`,
+}
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	repository := Repository{db: db}
+
+	return &repository
+}
+
+// FindLogin returns login of user, it is salt of vault key and is needed to decrypt exported vaults
+func (r *Repository) FindLogin(ctx context.Context, userID uuid.UUID) (string, error) {
+	var login string
+
+	err := r.db.QueryRowContext(ctx, `select login from users where id = $1;`, userID).Scan(&login)
+
+	if err == sql.ErrNoRows {
+		return "", ErrAccountNotFound
+	}
+
+	return login, err
+}
+
+// Delete removes user with vaults, blobs, uploads, devices, sessions and failures of rate limit in one transaction.
+// Returns objects of blob store to remove after commit
+func (r *Repository) Delete(ctx context.Context, userID uuid.UUID) (*DeletedAccount, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// Row lock waits for running writes of user, new ones fail on foreign key
+	var login string
+
+	err = tx.QueryRowContext(ctx, `select login from users where id = $1 for update;`, userID).Scan(&login)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrAccountNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := DeletedAccount{}
+
+	deleted.ObjectNames, err = deleteBlobs(ctx, tx, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	deleted.Uploads, err = deleteUploads(ctx, tx, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, query := range deleteQueries {
+		if _, err = tx.ExecContext(ctx, query, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Failures of deleted login must not lock out new account registered with the same login
+	_, err = tx.ExecContext(
+		ctx,
+		`delete from rate_limits where key in ($1, $2);`,
+		ratelimit.AccountKey(ratelimit.LoginAccount(login)),
+		ratelimit.AccountKey(ratelimit.UserAccount(userID)),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &deleted, nil
+}
+
+func deleteBlobs(ctx context.Context, tx *sql.Tx, userID uuid.UUID) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, `delete from blobs where user_id = $1 returning object_name;`, userID)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	objectNames := make([]string, 0)
+
+	for rows.Next() {
+		var objectName string
+
+		if err = rows.Scan(&objectName); err != nil {
+			return nil, err
+		}
+
+		objectNames = append(objectNames, objectName)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return objectNames, nil
+}
+
+// deleteUploads removes uploads of user, their parts are removed by cascade. Returns only not completed
+// uploads, object of completed upload is deleted as blob
+func deleteUploads(ctx context.Context, tx *sql.Tx, userID uuid.UUID) ([]UploadRef, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		`with deleted as (delete from uploads where user_id = $1 returning object_name, s3_upload_id, completed_at) select object_name, s3_upload_id from deleted where completed_at is null;`,
+		userID,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	uploads := make([]UploadRef, 0)
+
+	for rows.Next() {
+		upload := UploadRef{}
+
+		if err = rows.Scan(&upload.ObjectName, &upload.StoreUploadID); err != nil {
+			return nil, err
+		}
+
+		uploads = append(uploads, upload)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return uploads, nil
+}
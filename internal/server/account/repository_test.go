@@ -0,0 +1,199 @@
+package account
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+var errQuery = errors.New("query failed")
+
+// recordDB is driver of database/sql recording statements of one connection in order
+type recordDB struct {
+	rows   map[string][][]driver.Value
+	failOn string
+
+	log  []string
+	args map[string][]driver.Value
+}
+
+func (db *recordDB) Connect(_ context.Context) (driver.Conn, error) {
+	return db, nil
+}
+
+func (db *recordDB) Driver() driver.Driver {
+	return nil
+}
+
+func (db *recordDB) Prepare(_ string) (driver.Stmt, error) {
+	return nil, errors.New("prepare is not supported")
+}
+
+func (db *recordDB) Close() error {
+	return nil
+}
+
+func (db *recordDB) Begin() (driver.Tx, error) {
+	db.log = append(db.log, "begin")
+
+	return db, nil
+}
+
+func (db *recordDB) Commit() error {
+	db.log = append(db.log, "commit")
+
+	return nil
+}
+
+func (db *recordDB) Rollback() error {
+	db.log = append(db.log, "rollback")
+
+	return nil
+}
+
+func (db *recordDB) record(query string, args []driver.NamedValue) error {
+	db.log = append(db.log, query)
+
+	values := make([]driver.Value, 0, len(args))
+
+	for _, arg := range args {
+		values = append(values, arg.Value)
+	}
+
+	db.args[query] = values
+
+	if db.failOn != "" && strings.HasPrefix(query, db.failOn) {
+		return errQuery
+	}
+
+	return nil
+}
+
+func (db *recordDB) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := db.record(query, args); err != nil {
+		return nil, err
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+func (db *recordDB) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := db.record(query, args); err != nil {
+		return nil, err
+	}
+
+	for prefix, values := range db.rows {
+		if strings.HasPrefix(query, prefix) {
+			return &recordRows{values: values}, nil
+		}
+	}
+
+	return &recordRows{}, nil
+}
+
+type recordRows struct {
+	values [][]driver.Value
+}
+
+func (r *recordRows) Columns() []string {
+	if len(r.values) == 0 {
+		return []string{"column"}
+	}
+
+	return make([]string, len(r.values[0]))
+}
+
+func (r *recordRows) Close() error {
+	return nil
+}
+
+func (r *recordRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+
+	return nil
+}
+
+func TestRepository_Delete(t *testing.T) {
+	userID := uuid.New()
+
+	const (
+		lockQuery      = `select login from users where id = $1 for update;`
+		blobsQuery     = `delete from blobs where user_id = $1 returning object_name;`
+		uploadsQuery   = `with deleted as (delete from uploads where user_id = $1 returning object_name, s3_upload_id, completed_at) select object_name, s3_upload_id from deleted where completed_at is null;`
+		rateLimitQuery = `delete from rate_limits where key in ($1, $2);`
+	)
+
+	accountRows := map[string][][]driver.Value{
+		lockQuery:    {{"alex"}},
+		blobsQuery:   {{"object-1"}, {"object-2"}},
+		uploadsQuery: {{"object-3", "upload-3"}},
+	}
+
+	committed := append([]string{"begin", lockQuery, blobsQuery, uploadsQuery}, deleteQueries...)
+	committed = append(committed, rateLimitQuery, "commit")
+
+	tests := []struct {
+		name    string
+		rows    map[string][][]driver.Value
+		failOn  string
+		wantLog []string
+		want    *DeletedAccount
+		wantErr error
+	}{
+		{
+			name:    "Rows are deleted in one transaction",
+			rows:    accountRows,
+			wantLog: committed,
+			want: &DeletedAccount{
+				ObjectNames: []string{"object-1", "object-2"},
+				Uploads:     []UploadRef{{ObjectName: "object-3", StoreUploadID: "upload-3"}},
+			},
+		},
+		{
+			name:    "Account not found",
+			wantLog: []string{"begin", lockQuery, "rollback"},
+			wantErr: ErrAccountNotFound,
+		},
+		{
+			name:    "Transaction is rolled back when query failed",
+			rows:    accountRows,
+			failOn:  `delete from vaults`,
+			wantLog: append(append([]string{"begin", lockQuery, blobsQuery, uploadsQuery}, deleteQueries[:6]...), "rollback"),
+			wantErr: errQuery,
+		},
+		{
+			name:    "Failures of rate limit are deleted with account",
+			rows:    accountRows,
+			failOn:  `delete from rate_limits`,
+			wantLog: append(committed[:len(committed)-1:len(committed)-1], "rollback"),
+			wantErr: errQuery,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := recordDB{rows: tt.rows, failOn: tt.failOn, args: make(map[string][]driver.Value)}
+			db := sql.OpenDB(&recorder)
+
+			got, err := NewRepository(db).Delete(context.Background(), userID)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				assert.Equal(t, []driver.Value{"account:login:alex", "account:user:" + userID.String()}, recorder.args[rateLimitQuery])
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantLog, recorder.log)
+		})
+	}
+}
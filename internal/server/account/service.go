@@ -0,0 +1,81 @@
+package account
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+
+	"github.com/shreyner/gophkeeper/internal/server/pgk/blobstore"
+)
+
+// cleanupTimeout limits removing of objects, it is not canceled with request of deleted account
+const cleanupTimeout = 5 * time.Minute
+
+var (
+	_ AccountRepository = (*Repository)(nil)
+)
+
+type Service struct {
+	log   *zap.Logger
+	rep   AccountRepository
+	store blobstore.Store
+}
+
+func NewService(log *zap.Logger, rep AccountRepository, store blobstore.Store) *Service {
+	service := Service{
+		log:   log,
+		rep:   rep,
+		store: store,
+	}
+
+	return &service
+}
+
+func (s *Service) FindLogin(ctx context.Context, userID uuid.UUID) (string, error) {
+	return s.rep.FindLogin(ctx, userID)
+}
+
+// Delete removes account and then its objects from blob store. Rows are already deleted when object
+// can't be removed, so failed object is logged and left in store
+func (s *Service) Delete(ctx context.Context, userID uuid.UUID) error {
+	deleted, err := s.rep.Delete(ctx, userID)
+
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+
+	failed := 0
+
+	for _, upload := range deleted.Uploads {
+		err = s.store.AbortMultipart(ctx, upload.ObjectName, upload.StoreUploadID)
+
+		if err != nil {
+			failed++
+			s.log.Error("can't abort upload of deleted account", zap.String("object", upload.ObjectName), zap.Error(err))
+		}
+	}
+
+	for _, objectName := range deleted.ObjectNames {
+		err = s.store.Delete(ctx, objectName)
+
+		if err != nil {
+			failed++
+			s.log.Error("can't delete object of deleted account", zap.String("object", objectName), zap.Error(err))
+		}
+	}
+
+	s.log.Info(
+		"account deleted",
+		zap.String("user_id", userID.String()),
+		zap.Int("objects", len(deleted.ObjectNames)),
+		zap.Int("uploads", len(deleted.Uploads)),
+		zap.Int("failed", failed),
+	)
+
+	return nil
+}
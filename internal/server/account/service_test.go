@@ -0,0 +1,103 @@
+package account_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+
+	"github.com/shreyner/gophkeeper/internal/server/account"
+	accountmock "github.com/shreyner/gophkeeper/internal/server/account/mock"
+	blobstoremock "github.com/shreyner/gophkeeper/internal/server/pgk/blobstore/mock"
+)
+
+func TestService_Delete(t *testing.T) {
+	userID := uuid.New()
+	errDB := errors.New("database is unavailable")
+	errStore := errors.New("store is unavailable")
+
+	deleted := account.DeletedAccount{
+		ObjectNames: []string{"object-1", "object-2"},
+		Uploads:     []account.UploadRef{{ObjectName: "object-3", StoreUploadID: "upload-3"}},
+	}
+
+	tests := []struct {
+		name      string
+		deleteErr error
+		storeErr  error
+		wantStore []string
+		wantErr   error
+	}{
+		{
+			name:      "Objects are removed after rows are deleted",
+			wantStore: []string{"abort object-3", "delete object-1", "delete object-2"},
+		},
+		{
+			name:      "Objects are kept when rows are not deleted",
+			deleteErr: errDB,
+			wantStore: []string{},
+			wantErr:   errDB,
+		},
+		{
+			name:      "Object which can't be removed is left in store",
+			storeErr:  errStore,
+			wantStore: []string{"abort object-3", "delete object-1", "delete object-2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			rep := accountmock.NewMockAccountRepository(ctrl)
+			store := blobstoremock.NewMockStore(ctrl)
+
+			// Request is canceled after commit, like client disconnected, removing of objects goes on
+			ctx, cancel := context.WithCancel(context.Background())
+
+			isCommitted := false
+
+			rep.EXPECT().Delete(gomock.Any(), userID).DoAndReturn(func(_ context.Context, _ uuid.UUID) (*account.DeletedAccount, error) {
+				if tt.deleteErr != nil {
+					return nil, tt.deleteErr
+				}
+
+				isCommitted = true
+				cancel()
+
+				return &deleted, nil
+			})
+
+			calls := make([]string, 0)
+
+			store.EXPECT().AbortMultipart(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, name, uploadID string) error {
+				assert.True(t, isCommitted, "object is removed after commit")
+				assert.NoError(t, ctx.Err())
+				assert.Equal(t, "upload-3", uploadID)
+
+				calls = append(calls, "abort "+name)
+
+				return tt.storeErr
+			}).AnyTimes()
+
+			store.EXPECT().Delete(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, name string) error {
+				assert.True(t, isCommitted, "object is removed after commit")
+				assert.NoError(t, ctx.Err())
+
+				calls = append(calls, "delete "+name)
+
+				return tt.storeErr
+			}).AnyTimes()
+
+			service := account.NewService(zap.NewNop(), rep, store)
+
+			err := service.Delete(ctx, userID)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantStore, calls)
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package auth
+
+import "errors"
+
+var ErrInvalidCredentials = errors.New("invalid login or password")
+
+var ErrRegistrationClosed = errors.New("registration is closed")
+
+var ErrInviteRequired = errors.New("invite code is required")
+
+var ErrInvalidLogin = errors.New("login must be 3-64 latin letters, digits, '.', '_' or '-' and start with letter or digit")
+
+var ErrWeakPassword = errors.New("password must be at least 8 characters")
+
+var ErrNotAdmin = errors.New("only admin can do it")
+
+var ErrUnknownRegistrationMode = errors.New("unknown registration mode")
+
+var ErrTOTPAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+
+var ErrTOTPNotEnabled = errors.New("two-factor authentication is not enabled")
+
+var ErrTOTPNotSetUp = errors.New("two-factor authentication isn't set up, run setup first")
+
+var ErrInvalidSecondFactor = errors.New("invalid two-factor code")
+
+var ErrSecondFactorRequired = errors.New("two-factor code is required")
@@ -0,0 +1,24 @@
+//go:generate ./bin/mockgen -source=./interface.go -destination=./mock/auth.go -package=auth
+package auth
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/net/context"
+
+	"github.com/shreyner/gophkeeper/internal/server/user"
+)
+
+type UserService interface {
+	Create(ctx context.Context, login, password string) (*user.UserModel, error)
+	CreateWithInvite(ctx context.Context, login, password, inviteCode string) (*user.UserModel, error)
+	CreateInvite(ctx context.Context, createdBy uuid.UUID, ttl time.Duration) (string, *user.InviteModel, error)
+	FindByID(ctx context.Context, id uuid.UUID) (*user.UserModel, error)
+	FindByLogin(ctx context.Context, login string) (*user.UserModel, error)
+	SetPendingTOTP(ctx context.Context, id uuid.UUID, secret string) error
+	EnableTOTP(ctx context.Context, id uuid.UUID, step int64) ([]string, error)
+	DisableTOTP(ctx context.Context, id uuid.UUID) error
+	UseTOTPStep(ctx context.Context, id uuid.UUID, step int64) error
+	UseBackupCode(ctx context.Context, id uuid.UUID, code string) error
+}
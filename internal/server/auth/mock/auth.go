@@ -0,0 +1,185 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/server/auth/interface.go
+
+// Package auth is a generated GoMock package.
+package auth
+
+import (
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+	user "github.com/shreyner/gophkeeper/internal/server/user"
+	context "golang.org/x/net/context"
+)
+
+// MockUserService is a mock of UserService interface.
+type MockUserService struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserServiceMockRecorder
+}
+
+// MockUserServiceMockRecorder is the mock recorder for MockUserService.
+type MockUserServiceMockRecorder struct {
+	mock *MockUserService
+}
+
+// NewMockUserService creates a new mock instance.
+func NewMockUserService(ctrl *gomock.Controller) *MockUserService {
+	mock := &MockUserService{ctrl: ctrl}
+	mock.recorder = &MockUserServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserService) EXPECT() *MockUserServiceMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockUserService) Create(ctx context.Context, login, password string) (*user.UserModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, login, password)
+	ret0, _ := ret[0].(*user.UserModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockUserServiceMockRecorder) Create(ctx, login, password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockUserService)(nil).Create), ctx, login, password)
+}
+
+// CreateInvite mocks base method.
+func (m *MockUserService) CreateInvite(ctx context.Context, createdBy uuid.UUID, ttl time.Duration) (string, *user.InviteModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateInvite", ctx, createdBy, ttl)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(*user.InviteModel)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// CreateInvite indicates an expected call of CreateInvite.
+func (mr *MockUserServiceMockRecorder) CreateInvite(ctx, createdBy, ttl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateInvite", reflect.TypeOf((*MockUserService)(nil).CreateInvite), ctx, createdBy, ttl)
+}
+
+// CreateWithInvite mocks base method.
+func (m *MockUserService) CreateWithInvite(ctx context.Context, login, password, inviteCode string) (*user.UserModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWithInvite", ctx, login, password, inviteCode)
+	ret0, _ := ret[0].(*user.UserModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateWithInvite indicates an expected call of CreateWithInvite.
+func (mr *MockUserServiceMockRecorder) CreateWithInvite(ctx, login, password, inviteCode interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWithInvite", reflect.TypeOf((*MockUserService)(nil).CreateWithInvite), ctx, login, password, inviteCode)
+}
+
+// DisableTOTP mocks base method.
+func (m *MockUserService) DisableTOTP(ctx context.Context, id uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableTOTP", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableTOTP indicates an expected call of DisableTOTP.
+func (mr *MockUserServiceMockRecorder) DisableTOTP(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTOTP", reflect.TypeOf((*MockUserService)(nil).DisableTOTP), ctx, id)
+}
+
+// EnableTOTP mocks base method.
+func (m *MockUserService) EnableTOTP(ctx context.Context, id uuid.UUID, step int64) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableTOTP", ctx, id, step)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnableTOTP indicates an expected call of EnableTOTP.
+func (mr *MockUserServiceMockRecorder) EnableTOTP(ctx, id, step interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableTOTP", reflect.TypeOf((*MockUserService)(nil).EnableTOTP), ctx, id, step)
+}
+
+// FindByID mocks base method.
+func (m *MockUserService) FindByID(ctx context.Context, id uuid.UUID) (*user.UserModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByID", ctx, id)
+	ret0, _ := ret[0].(*user.UserModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByID indicates an expected call of FindByID.
+func (mr *MockUserServiceMockRecorder) FindByID(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByID", reflect.TypeOf((*MockUserService)(nil).FindByID), ctx, id)
+}
+
+// FindByLogin mocks base method.
+func (m *MockUserService) FindByLogin(ctx context.Context, login string) (*user.UserModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByLogin", ctx, login)
+	ret0, _ := ret[0].(*user.UserModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByLogin indicates an expected call of FindByLogin.
+func (mr *MockUserServiceMockRecorder) FindByLogin(ctx, login interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByLogin", reflect.TypeOf((*MockUserService)(nil).FindByLogin), ctx, login)
+}
+
+// SetPendingTOTP mocks base method.
+func (m *MockUserService) SetPendingTOTP(ctx context.Context, id uuid.UUID, secret string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPendingTOTP", ctx, id, secret)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPendingTOTP indicates an expected call of SetPendingTOTP.
+func (mr *MockUserServiceMockRecorder) SetPendingTOTP(ctx, id, secret interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPendingTOTP", reflect.TypeOf((*MockUserService)(nil).SetPendingTOTP), ctx, id, secret)
+}
+
+// UseBackupCode mocks base method.
+func (m *MockUserService) UseBackupCode(ctx context.Context, id uuid.UUID, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseBackupCode", ctx, id, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseBackupCode indicates an expected call of UseBackupCode.
+func (mr *MockUserServiceMockRecorder) UseBackupCode(ctx, id, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseBackupCode", reflect.TypeOf((*MockUserService)(nil).UseBackupCode), ctx, id, code)
+}
+
+// UseTOTPStep mocks base method.
+func (m *MockUserService) UseTOTPStep(ctx context.Context, id uuid.UUID, step int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseTOTPStep", ctx, id, step)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseTOTPStep indicates an expected call of UseTOTPStep.
+func (mr *MockUserServiceMockRecorder) UseTOTPStep(ctx, id, step interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseTOTPStep", reflect.TypeOf((*MockUserService)(nil).UseTOTPStep), ctx, id, step)
+}
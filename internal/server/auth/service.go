@@ -2,33 +2,331 @@ package auth
 
 import (
 	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
-	"github.com/shreyner/gophkeeper/internal/server/user"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
 	"golang.org/x/net/context"
+
+	"github.com/shreyner/gophkeeper/internal/server/pgk/totp"
+	"github.com/shreyner/gophkeeper/internal/server/user"
 )
 
+// RegistrationMode tells who can create account
+type RegistrationMode string
+
+const (
+	RegistrationOpen   RegistrationMode = "open"
+	RegistrationInvite RegistrationMode = "invite"
+	RegistrationClosed RegistrationMode = "closed"
+)
+
+const minPasswordLength = 8
+
+// totpIssuer is shown by authenticator app near the code
+const totpIssuer = "Gophkeeper"
+
+var loginPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{2,63}$`)
+
+var (
+	_ UserService = (*user.Service)(nil)
+)
+
+func ParseRegistrationMode(mode string) (RegistrationMode, error) {
+	switch RegistrationMode(mode) {
+	case RegistrationOpen, RegistrationInvite, RegistrationClosed:
+		return RegistrationMode(mode), nil
+	default:
+		return "", fmt.Errorf("%q: %w", mode, ErrUnknownRegistrationMode)
+	}
+}
+
 type Service struct {
-	userService *user.Service
+	userService      UserService
+	registrationMode RegistrationMode
+	adminLogins      map[string]struct{}
+	inviteTTL        time.Duration
 }
 
-func NewService(userService *user.Service) *Service {
+func NewService(
+	userService UserService,
+	registrationMode RegistrationMode,
+	adminLogins []string,
+	inviteTTL time.Duration,
+) *Service {
+	admins := make(map[string]struct{}, len(adminLogins))
+
+	for _, login := range adminLogins {
+		admins[login] = struct{}{}
+	}
+
 	service := Service{
-		userService: userService,
+		userService:      userService,
+		registrationMode: registrationMode,
+		adminLogins:      admins,
+		inviteTTL:        inviteTTL,
 	}
 
 	return &service
 }
 
-func (s *Service) FindOrCreate(ctx context.Context, login, password string) (*user.UserModel, error) {
+// Register creates account according to registration mode, invite code is spent only in invite mode
+func (s *Service) Register(ctx context.Context, login, password, inviteCode string) (*user.UserModel, error) {
+	if err := validateLogin(login); err != nil {
+		return nil, err
+	}
+
+	if err := validatePassword(password); err != nil {
+		return nil, err
+	}
+
+	switch s.registrationMode {
+	case RegistrationOpen:
+		return s.userService.Create(ctx, login, password)
+	case RegistrationInvite:
+		if inviteCode == "" {
+			return nil, ErrInviteRequired
+		}
+
+		return s.userService.CreateWithInvite(ctx, login, password, inviteCode)
+	default:
+		return nil, ErrRegistrationClosed
+	}
+}
+
+// Login never creates account, unknown login and wrong password both return ErrInvalidCredentials
+func (s *Service) Login(ctx context.Context, login, password string) (*user.UserModel, error) {
 	userModel, err := s.userService.FindByLogin(ctx, login)
 
-	if err == nil {
+	if errors.Is(err, user.ErrUserNotFound) {
+		// Compare with dummy hash, so response time doesn't show that login exists
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+
+		return nil, ErrInvalidCredentials
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	valid, err := userModel.VerifyPassword(password)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !valid {
+		return nil, ErrInvalidCredentials
+	}
+
+	return userModel, nil
+}
+
+// CreateInvite generates invite code, only users from admin logins can do it
+func (s *Service) CreateInvite(ctx context.Context, userID uuid.UUID) (string, *user.InviteModel, error) {
+	userModel, err := s.userService.FindByID(ctx, userID)
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	if _, ok := s.adminLogins[userModel.Login]; !ok {
+		return "", nil, ErrNotAdmin
+	}
+
+	return s.userService.CreateInvite(ctx, userID, s.inviteTTL)
+}
+
+// SetupTOTP generates secret waiting for confirmation by EnableTOTP, it returns secret and its otpauth URI
+func (s *Service) SetupTOTP(ctx context.Context, userID uuid.UUID) (string, string, error) {
+	userModel, err := s.userService.FindByID(ctx, userID)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	if userModel.IsTOTPEnabled() {
+		return "", "", ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := s.userService.SetPendingTOTP(ctx, userID, secret); err != nil {
+		return "", "", err
+	}
+
+	return secret, totp.URI(totpIssuer, userModel.Login, secret), nil
+}
+
+// EnableTOTP enables second factor when code of pending secret is correct and returns backup codes
+func (s *Service) EnableTOTP(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	userModel, err := s.userService.FindByID(ctx, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if userModel.IsTOTPEnabled() {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	if userModel.TOTPPendingSecret == "" {
+		return nil, ErrTOTPNotSetUp
+	}
+
+	step, ok := totp.Validate(userModel.TOTPPendingSecret, code, time.Now())
+
+	if !ok {
+		return nil, ErrInvalidSecondFactor
+	}
+
+	return s.userService.EnableTOTP(ctx, userID, step)
+}
+
+// DisableTOTP turns second factor off, it is confirmed by code or backup code
+func (s *Service) DisableTOTP(ctx context.Context, userID uuid.UUID, code string) error {
+	userModel, err := s.userService.FindByID(ctx, userID)
+
+	if err != nil {
+		return err
+	}
+
+	if !userModel.IsTOTPEnabled() {
+		return ErrTOTPNotEnabled
+	}
+
+	if err := s.verifySecondFactor(ctx, userModel, code); err != nil {
+		return err
+	}
+
+	return s.userService.DisableTOTP(ctx, userID)
+}
+
+// Reauthenticate confirms dangerous action of logged in user by password and by second factor when it is enabled
+func (s *Service) Reauthenticate(ctx context.Context, userID uuid.UUID, password, code string) error {
+	userModel, err := s.userService.FindByID(ctx, userID)
+
+	if err != nil {
+		return err
+	}
+
+	valid, err := userModel.VerifyPassword(password)
+
+	if err != nil {
+		return err
+	}
+
+	if !valid {
+		return ErrInvalidCredentials
+	}
+
+	if !userModel.IsTOTPEnabled() {
+		return nil
+	}
+
+	if strings.TrimSpace(code) == "" {
+		return ErrSecondFactorRequired
+	}
+
+	return s.verifySecondFactor(ctx, userModel, code)
+}
+
+// VerifySecondFactor finishes login of user who passed password check
+func (s *Service) VerifySecondFactor(ctx context.Context, userID uuid.UUID, code string) (*user.UserModel, error) {
+	userModel, err := s.userService.FindByID(ctx, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !userModel.IsTOTPEnabled() {
 		return userModel, nil
 	}
 
-	if !errors.Is(err, user.ErrUserNotFound) {
+	if err := s.verifySecondFactor(ctx, userModel, code); err != nil {
 		return nil, err
 	}
 
-	return s.userService.Create(ctx, login, password)
+	return userModel, nil
+}
+
+// verifySecondFactor accepts code of authenticator app once, other codes are checked as backup codes
+func (s *Service) verifySecondFactor(ctx context.Context, userModel *user.UserModel, code string) error {
+	code = strings.TrimSpace(code)
+
+	if isTOTPCode(code) {
+		step, ok := totp.Validate(userModel.TOTPSecret, code, time.Now())
+
+		if !ok {
+			return ErrInvalidSecondFactor
+		}
+
+		err := s.userService.UseTOTPStep(ctx, userModel.ID, step)
+
+		if errors.Is(err, user.ErrTOTPStepUsed) {
+			return ErrInvalidSecondFactor
+		}
+
+		return err
+	}
+
+	err := s.userService.UseBackupCode(ctx, userModel.ID, code)
+
+	if errors.Is(err, user.ErrBackupCodeInvalid) {
+		return ErrInvalidSecondFactor
+	}
+
+	return err
+}
+
+func isTOTPCode(code string) bool {
+	if len(code) != totp.Digits {
+		return false
+	}
+
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func validateLogin(login string) error {
+	if !loginPattern.MatchString(login) {
+		return ErrInvalidLogin
+	}
+
+	return nil
+}
+
+func validatePassword(password string) error {
+	if utf8.RuneCountInString(password) < minPasswordLength {
+		return ErrWeakPassword
+	}
+
+	return nil
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+func dummyPasswordHash() []byte {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	})
+
+	return dummyHash
 }
@@ -0,0 +1,236 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	authmock "github.com/shreyner/gophkeeper/internal/server/auth/mock"
+	"github.com/shreyner/gophkeeper/internal/server/pgk/totp"
+	"github.com/shreyner/gophkeeper/internal/server/user"
+)
+
+func TestParseRegistrationMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		want    RegistrationMode
+		wantErr error
+	}{
+		{
+			name: "Open",
+			mode: "open",
+			want: RegistrationOpen,
+		},
+		{
+			name: "Invite",
+			mode: "invite",
+			want: RegistrationInvite,
+		},
+		{
+			name: "Closed",
+			mode: "closed",
+			want: RegistrationClosed,
+		},
+		{
+			name:    "Unknown",
+			mode:    "invite-only",
+			wantErr: ErrUnknownRegistrationMode,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRegistrationMode(tt.mode)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_validateLogin(t *testing.T) {
+	tests := []struct {
+		name    string
+		login   string
+		wantErr error
+	}{
+		{
+			name:  "Simple",
+			login: "alex",
+		},
+		{
+			name:  "With dots and dashes",
+			login: "alex.smith-2_work",
+		},
+		{
+			name:    "Too short",
+			login:   "al",
+			wantErr: ErrInvalidLogin,
+		},
+		{
+			name:    "Too long",
+			login:   strings.Repeat("a", 65),
+			wantErr: ErrInvalidLogin,
+		},
+		{
+			name:    "Starts with dot",
+			login:   ".alex",
+			wantErr: ErrInvalidLogin,
+		},
+		{
+			name:    "Space",
+			login:   "alex smith",
+			wantErr: ErrInvalidLogin,
+		},
+		{
+			name:    "Not latin",
+			login:   "алекс",
+			wantErr: ErrInvalidLogin,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, validateLogin(tt.login), tt.wantErr)
+		})
+	}
+}
+
+func Test_validatePassword(t *testing.T) {
+	assert.ErrorIs(t, validatePassword("1234567"), ErrWeakPassword)
+	assert.NoError(t, validatePassword("12345678"))
+	assert.NoError(t, validatePassword("пароль12"))
+}
+
+func Test_isTOTPCode(t *testing.T) {
+	assert.True(t, isTOTPCode("012345"))
+	assert.False(t, isTOTPCode("01234"))
+	assert.False(t, isTOTPCode("ABCD-EFGH"))
+	assert.False(t, isTOTPCode("01234a"))
+}
+
+func TestService_Reauthenticate(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+
+	step := totp.Step(time.Now())
+
+	validCode, err := totp.Code(secret, step)
+	require.NoError(t, err)
+
+	// wrongCode is not accepted at any step around now
+	wrongCode := ""
+
+	for i := 0; wrongCode == ""; i++ {
+		wrongCode = fmt.Sprintf("%06d", i)
+
+		for s := step - 2; s <= step+2; s++ {
+			if code, _ := totp.Code(secret, s); code == wrongCode {
+				wrongCode = ""
+			}
+		}
+	}
+
+	tests := []struct {
+		name          string
+		totpEnabled   bool
+		password      string
+		code          string
+		useStepErr    error
+		useBackupErr  error
+		wantUseStep   bool
+		wantUseBackup bool
+		wantErr       error
+	}{
+		{
+			name:     "Password without second factor",
+			password: "password",
+		},
+		{
+			name:     "Wrong password",
+			password: "wrong-password",
+			wantErr:  ErrInvalidCredentials,
+		},
+		{
+			name:        "Wrong password is checked before second factor",
+			totpEnabled: true,
+			password:    "wrong-password",
+			code:        validCode,
+			wantErr:     ErrInvalidCredentials,
+		},
+		{
+			name:        "Missing code of second factor",
+			totpEnabled: true,
+			password:    "password",
+			code:        " ",
+			wantErr:     ErrSecondFactorRequired,
+		},
+		{
+			name:        "Wrong code of second factor",
+			totpEnabled: true,
+			password:    "password",
+			code:        wrongCode,
+			wantErr:     ErrInvalidSecondFactor,
+		},
+		{
+			name:        "Valid code of second factor",
+			totpEnabled: true,
+			password:    "password",
+			code:        validCode,
+			wantUseStep: true,
+		},
+		{
+			name:        "Used code of second factor",
+			totpEnabled: true,
+			password:    "password",
+			code:        validCode,
+			useStepErr:  user.ErrTOTPStepUsed,
+			wantUseStep: true,
+			wantErr:     ErrInvalidSecondFactor,
+		},
+		{
+			name:          "Wrong backup code",
+			totpEnabled:   true,
+			password:      "password",
+			code:          "ABCD-EFGH",
+			useBackupErr:  user.ErrBackupCodeInvalid,
+			wantUseBackup: true,
+			wantErr:       ErrInvalidSecondFactor,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			userModel := user.UserModel{ID: uuid.New(), Login: "alex"}
+			require.NoError(t, userModel.SetPassword("password"))
+
+			if tt.totpEnabled {
+				userModel.TOTPSecret = secret
+			}
+
+			userService := authmock.NewMockUserService(ctrl)
+			userService.EXPECT().FindByID(gomock.Any(), userModel.ID).Return(&userModel, nil)
+
+			if tt.wantUseStep {
+				userService.EXPECT().UseTOTPStep(gomock.Any(), userModel.ID, step).Return(tt.useStepErr)
+			}
+
+			if tt.wantUseBackup {
+				userService.EXPECT().UseBackupCode(gomock.Any(), userModel.ID, tt.code).Return(tt.useBackupErr)
+			}
+
+			service := NewService(userService, RegistrationOpen, nil, time.Hour)
+
+			err := service.Reauthenticate(context.Background(), userModel.ID, tt.password, tt.code)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
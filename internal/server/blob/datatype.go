@@ -0,0 +1,15 @@
+package blob
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type BlobModel struct {
+	ObjectName string
+	UserID     uuid.UUID
+	VaultID    *uuid.UUID
+	Size       int64
+	CreatedAt  time.Time
+}
@@ -0,0 +1,5 @@
+package blob
+
+import "errors"
+
+var ErrBlobNotFound = errors.New("blob not found")
@@ -0,0 +1,23 @@
+//go:generate ./bin/mockgen -source=./interface.go -destination=./mock/blob.go -package=blob
+package blob
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/net/context"
+)
+
+type BlobRepository interface {
+	Create(ctx context.Context, userID uuid.UUID, objectName string, size int64) error
+	FindByName(ctx context.Context, userID uuid.UUID, objectName string) (*BlobModel, error)
+	Exists(ctx context.Context, objectName string) (bool, error)
+	ListByUser(ctx context.Context, userID uuid.UUID, afterName string, limit int) ([]BlobModel, error)
+	LoadOrphans(ctx context.Context, before time.Time, afterName string, limit int) ([]BlobModel, error)
+	DeleteOrphan(ctx context.Context, objectName string, before time.Time, remove func() error) (bool, error)
+}
+
+// QuotaReleaser returns bytes of collected blobs to quota of user
+type QuotaReleaser interface {
+	ReleaseBytes(ctx context.Context, userID uuid.UUID, size int64) error
+}
@@ -0,0 +1,164 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/server/blob/interface.go
+
+// Package blob is a generated GoMock package.
+package blob
+
+import (
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+	blob "github.com/shreyner/gophkeeper/internal/server/blob"
+	context "golang.org/x/net/context"
+)
+
+// MockBlobRepository is a mock of BlobRepository interface.
+type MockBlobRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockBlobRepositoryMockRecorder
+}
+
+// MockBlobRepositoryMockRecorder is the mock recorder for MockBlobRepository.
+type MockBlobRepositoryMockRecorder struct {
+	mock *MockBlobRepository
+}
+
+// NewMockBlobRepository creates a new mock instance.
+func NewMockBlobRepository(ctrl *gomock.Controller) *MockBlobRepository {
+	mock := &MockBlobRepository{ctrl: ctrl}
+	mock.recorder = &MockBlobRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBlobRepository) EXPECT() *MockBlobRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockBlobRepository) Create(ctx context.Context, userID uuid.UUID, objectName string, size int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, userID, objectName, size)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockBlobRepositoryMockRecorder) Create(ctx, userID, objectName, size interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockBlobRepository)(nil).Create), ctx, userID, objectName, size)
+}
+
+// DeleteOrphan mocks base method.
+func (m *MockBlobRepository) DeleteOrphan(ctx context.Context, objectName string, before time.Time, remove func() error) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOrphan", ctx, objectName, before, remove)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteOrphan indicates an expected call of DeleteOrphan.
+func (mr *MockBlobRepositoryMockRecorder) DeleteOrphan(ctx, objectName, before, remove interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOrphan", reflect.TypeOf((*MockBlobRepository)(nil).DeleteOrphan), ctx, objectName, before, remove)
+}
+
+// Exists mocks base method.
+func (m *MockBlobRepository) Exists(ctx context.Context, objectName string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Exists", ctx, objectName)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Exists indicates an expected call of Exists.
+func (mr *MockBlobRepositoryMockRecorder) Exists(ctx, objectName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Exists", reflect.TypeOf((*MockBlobRepository)(nil).Exists), ctx, objectName)
+}
+
+// FindByName mocks base method.
+func (m *MockBlobRepository) FindByName(ctx context.Context, userID uuid.UUID, objectName string) (*blob.BlobModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByName", ctx, userID, objectName)
+	ret0, _ := ret[0].(*blob.BlobModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByName indicates an expected call of FindByName.
+func (mr *MockBlobRepositoryMockRecorder) FindByName(ctx, userID, objectName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByName", reflect.TypeOf((*MockBlobRepository)(nil).FindByName), ctx, userID, objectName)
+}
+
+// ListByUser mocks base method.
+func (m *MockBlobRepository) ListByUser(ctx context.Context, userID uuid.UUID, afterName string, limit int) ([]blob.BlobModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByUser", ctx, userID, afterName, limit)
+	ret0, _ := ret[0].([]blob.BlobModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByUser indicates an expected call of ListByUser.
+func (mr *MockBlobRepositoryMockRecorder) ListByUser(ctx, userID, afterName, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByUser", reflect.TypeOf((*MockBlobRepository)(nil).ListByUser), ctx, userID, afterName, limit)
+}
+
+// LoadOrphans mocks base method.
+func (m *MockBlobRepository) LoadOrphans(ctx context.Context, before time.Time, afterName string, limit int) ([]blob.BlobModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadOrphans", ctx, before, afterName, limit)
+	ret0, _ := ret[0].([]blob.BlobModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadOrphans indicates an expected call of LoadOrphans.
+func (mr *MockBlobRepositoryMockRecorder) LoadOrphans(ctx, before, afterName, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadOrphans", reflect.TypeOf((*MockBlobRepository)(nil).LoadOrphans), ctx, before, afterName, limit)
+}
+
+// MockQuotaReleaser is a mock of QuotaReleaser interface.
+type MockQuotaReleaser struct {
+	ctrl     *gomock.Controller
+	recorder *MockQuotaReleaserMockRecorder
+}
+
+// MockQuotaReleaserMockRecorder is the mock recorder for MockQuotaReleaser.
+type MockQuotaReleaserMockRecorder struct {
+	mock *MockQuotaReleaser
+}
+
+// NewMockQuotaReleaser creates a new mock instance.
+func NewMockQuotaReleaser(ctrl *gomock.Controller) *MockQuotaReleaser {
+	mock := &MockQuotaReleaser{ctrl: ctrl}
+	mock.recorder = &MockQuotaReleaserMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockQuotaReleaser) EXPECT() *MockQuotaReleaserMockRecorder {
+	return m.recorder
+}
+
+// ReleaseBytes mocks base method.
+func (m *MockQuotaReleaser) ReleaseBytes(ctx context.Context, userID uuid.UUID, size int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseBytes", ctx, userID, size)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseBytes indicates an expected call of ReleaseBytes.
+func (mr *MockQuotaReleaserMockRecorder) ReleaseBytes(ctx, userID, size interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseBytes", reflect.TypeOf((*MockQuotaReleaser)(nil).ReleaseBytes), ctx, userID, size)
+}
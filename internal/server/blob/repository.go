@@ -0,0 +1,172 @@
+package blob
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/net/context"
+)
+
+// orphanCondition matches blob not claimed by vault before time $2 or blob of deleted vault
+const orphanCondition = `(b.vault_id is null and b.created_at < $2)
+	or exists(select 1 from vaults v where v.id = b.vault_id and v.is_deleted = true)`
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	repository := Repository{db: db}
+
+	return &repository
+}
+
+// Create registers blob, repeated registration of the same object is ignored
+func (r *Repository) Create(ctx context.Context, userID uuid.UUID, objectName string, size int64) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`insert into blobs (object_name, user_id, size) values ($1, $2, $3) on conflict do nothing;`,
+		objectName,
+		userID,
+		size,
+	)
+
+	return err
+}
+
+func (r *Repository) FindByName(ctx context.Context, userID uuid.UUID, objectName string) (*BlobModel, error) {
+	blob := BlobModel{}
+
+	err := r.db.QueryRowContext(
+		ctx,
+		`select object_name, user_id, vault_id, size, created_at from blobs where object_name = $1 and user_id = $2;`,
+		objectName,
+		userID,
+	).Scan(&blob.ObjectName, &blob.UserID, &blob.VaultID, &blob.Size, &blob.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrBlobNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &blob, nil
+}
+
+func (r *Repository) Exists(ctx context.Context, objectName string) (bool, error) {
+	var isExists bool
+
+	err := r.db.QueryRowContext(
+		ctx,
+		`select exists(select 1 from blobs where object_name = $1);`,
+		objectName,
+	).Scan(&isExists)
+
+	return isExists, err
+}
+
+// ListByUser returns page of blobs of user ordered by object name after afterName
+func (r *Repository) ListByUser(ctx context.Context, userID uuid.UUID, afterName string, limit int) ([]BlobModel, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`select object_name, user_id, vault_id, size, created_at from blobs
+		where user_id = $1 and object_name > $2
+		order by object_name
+		limit $3;`,
+		userID,
+		afterName,
+		limit,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanBlobs(rows)
+}
+
+// LoadOrphans returns page of blobs which could be collected ordered by object name after afterName.
+// Unclaimed blobs must be created before time
+func (r *Repository) LoadOrphans(ctx context.Context, before time.Time, afterName string, limit int) ([]BlobModel, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`select b.object_name, b.user_id, b.vault_id, b.size, b.created_at from blobs b
+		where b.object_name > $1 and (`+orphanCondition+`)
+		order by b.object_name
+		limit $3;`,
+		afterName,
+		before,
+		limit,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanBlobs(rows)
+}
+
+func scanBlobs(rows *sql.Rows) ([]BlobModel, error) {
+	blobs := make([]BlobModel, 0)
+
+	for rows.Next() {
+		blob := BlobModel{}
+
+		err := rows.Scan(&blob.ObjectName, &blob.UserID, &blob.VaultID, &blob.Size, &blob.CreatedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		blobs = append(blobs, blob)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return blobs, nil
+}
+
+// DeleteOrphan deletes blob if it is still orphan and calls remove in the same transaction.
+// Row lock keeps vault from claiming blob until object is removed. Returns false when blob is claimed
+func (r *Repository) DeleteOrphan(ctx context.Context, objectName string, before time.Time, remove func() error) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return false, err
+	}
+
+	result, err := tx.ExecContext(
+		ctx,
+		`delete from blobs b where b.object_name = $1 and (`+orphanCondition+`);`,
+		objectName,
+		before,
+	)
+
+	if err != nil {
+		_ = tx.Rollback()
+		return false, err
+	}
+
+	countAffected, err := result.RowsAffected()
+
+	if err != nil || countAffected == 0 {
+		_ = tx.Rollback()
+		return false, err
+	}
+
+	err = remove()
+
+	if err != nil {
+		_ = tx.Rollback()
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
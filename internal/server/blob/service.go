@@ -0,0 +1,160 @@
+package blob
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+
+	"github.com/shreyner/gophkeeper/internal/server/pgk/blobstore"
+	"github.com/shreyner/gophkeeper/internal/server/quota"
+)
+
+const collectPageSize = 100
+
+var (
+	_ BlobRepository = (*Repository)(nil)
+	_ QuotaReleaser  = (*quota.Service)(nil)
+)
+
+type Service struct {
+	log          *zap.Logger
+	rep          BlobRepository
+	store        blobstore.Store
+	quotaService QuotaReleaser
+
+	// grace is time for client to create vault with uploaded blob
+	grace time.Duration
+}
+
+func NewService(log *zap.Logger, rep BlobRepository, store blobstore.Store, quotaService QuotaReleaser, grace time.Duration) *Service {
+	service := Service{
+		log:          log,
+		rep:          rep,
+		store:        store,
+		quotaService: quotaService,
+		grace:        grace,
+	}
+
+	return &service
+}
+
+// Register saves owner of object, must be called before object is stored
+func (s *Service) Register(ctx context.Context, userID uuid.UUID, objectName string, size int64) error {
+	return s.rep.Create(ctx, userID, objectName, size)
+}
+
+// Open returns object of user for reading, object supports seek for range requests
+func (s *Service) Open(ctx context.Context, userID uuid.UUID, objectName string) (blobstore.Object, *blobstore.ObjectInfo, error) {
+	_, err := s.rep.FindByName(ctx, userID, objectName)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	object, info, err := s.store.Open(ctx, objectName)
+
+	if errors.Is(err, blobstore.ErrNotFound) {
+		return nil, nil, ErrBlobNotFound
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return object, info, nil
+}
+
+// List returns page of blobs of user after object name afterName
+func (s *Service) List(ctx context.Context, userID uuid.UUID, afterName string, limit int) ([]BlobModel, error) {
+	return s.rep.ListByUser(ctx, userID, afterName, limit)
+}
+
+// IsRegistered reports whether reserved bytes of object are released by collection
+func (s *Service) IsRegistered(ctx context.Context, objectName string) (bool, error) {
+	return s.rep.Exists(ctx, objectName)
+}
+
+// Run collects orphan blobs every interval until ctx is done
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		count, err := s.Collect(ctx)
+
+		if err != nil && ctx.Err() == nil {
+			s.log.Error("blob collection failed", zap.Error(err))
+		}
+
+		if count != 0 {
+			s.log.Info("orphan blobs collected", zap.Int("count", count))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Collect removes objects without vault after grace period and objects of deleted vaults.
+// Returns count of removed objects, failed object is skipped until the next run
+func (s *Service) Collect(ctx context.Context) (int, error) {
+	before := time.Now().Add(-s.grace)
+	afterName := ""
+	count := 0
+
+	for {
+		blobs, err := s.rep.LoadOrphans(ctx, before, afterName, collectPageSize)
+
+		if err != nil {
+			return count, err
+		}
+
+		for _, blob := range blobs {
+			isDeleted, err := s.collect(ctx, blob, before)
+
+			if err != nil {
+				if ctx.Err() != nil {
+					return count, ctx.Err()
+				}
+
+				s.log.Error("can't collect blob", zap.String("object", blob.ObjectName), zap.Error(err))
+				continue
+			}
+
+			if isDeleted {
+				count++
+			}
+		}
+
+		if len(blobs) < collectPageSize {
+			return count, nil
+		}
+
+		afterName = blobs[len(blobs)-1].ObjectName
+	}
+}
+
+func (s *Service) collect(ctx context.Context, blob BlobModel, before time.Time) (bool, error) {
+	// Removing of missing object succeeds, so blob of not finished upload is deleted too
+	isDeleted, err := s.rep.DeleteOrphan(ctx, blob.ObjectName, before, func() error {
+		return s.store.Delete(ctx, blob.ObjectName)
+	})
+
+	if err != nil || !isDeleted {
+		return false, err
+	}
+
+	err = s.quotaService.ReleaseBytes(ctx, blob.UserID, blob.Size)
+
+	if err != nil {
+		s.log.Error("can't release quota of collected blob", zap.String("object", blob.ObjectName), zap.Error(err))
+	}
+
+	return true, nil
+}
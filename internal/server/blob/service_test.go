@@ -0,0 +1,135 @@
+package blob_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+
+	"github.com/shreyner/gophkeeper/internal/server/blob"
+	blobmock "github.com/shreyner/gophkeeper/internal/server/blob/mock"
+	blobstoremock "github.com/shreyner/gophkeeper/internal/server/pgk/blobstore/mock"
+)
+
+func TestService_Collect(t *testing.T) {
+	userID := uuid.New()
+	errStore := errors.New("store is unavailable")
+
+	orphan := blob.BlobModel{ObjectName: "orphan", UserID: userID, Size: 10}
+	referenced := blob.BlobModel{ObjectName: "referenced", UserID: userID, Size: 20}
+
+	// deleteOrphan acts like repository: removes object of orphan blob in transaction, referenced blob is kept
+	deleteOrphan := func(_ context.Context, objectName string, _ time.Time, remove func() error) (bool, error) {
+		if objectName == referenced.ObjectName {
+			return false, nil
+		}
+
+		if err := remove(); err != nil {
+			return false, err
+		}
+
+		return true, nil
+	}
+
+	fullPage := make([]blob.BlobModel, 100)
+	fullPageNames := make([]string, 100)
+
+	for i := range fullPage {
+		fullPageNames[i] = fmt.Sprintf("orphan-%03d", i)
+		fullPage[i] = blob.BlobModel{ObjectName: fullPageNames[i], UserID: userID, Size: 1}
+	}
+
+	tests := []struct {
+		name        string
+		pages       [][]blob.BlobModel
+		loadErr     error
+		storeErr    error
+		wantDeleted []string
+		wantRelease int64
+		wantCount   int
+		wantErr     error
+	}{
+		{
+			name:        "Orphan is deleted and its bytes are released",
+			pages:       [][]blob.BlobModel{{orphan}},
+			wantDeleted: []string{"orphan"},
+			wantRelease: 10,
+			wantCount:   1,
+		},
+		{
+			name:  "Referenced blob is kept",
+			pages: [][]blob.BlobModel{{referenced}},
+		},
+		{
+			name:        "Orphan is kept on store error and others are collected",
+			pages:       [][]blob.BlobModel{{orphan, referenced}},
+			storeErr:    errStore,
+			wantDeleted: []string{"orphan"},
+		},
+		{
+			name:    "Error of loading orphans",
+			loadErr: errStore,
+			wantErr: errStore,
+		},
+		{
+			name:        "Orphans are loaded by pages",
+			pages:       [][]blob.BlobModel{fullPage, {orphan}},
+			wantDeleted: append(fullPageNames, "orphan"),
+			wantRelease: 110,
+			wantCount:   101,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			rep := blobmock.NewMockBlobRepository(ctrl)
+			store := blobstoremock.NewMockStore(ctrl)
+			quota := blobmock.NewMockQuotaReleaser(ctrl)
+
+			if tt.loadErr != nil {
+				rep.EXPECT().LoadOrphans(gomock.Any(), gomock.Any(), "", 100).Return(nil, tt.loadErr)
+			}
+
+			afterName := ""
+
+			for _, page := range tt.pages {
+				rep.EXPECT().LoadOrphans(gomock.Any(), gomock.Any(), afterName, 100).Return(page, nil)
+				afterName = page[len(page)-1].ObjectName
+			}
+
+			rep.EXPECT().DeleteOrphan(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(deleteOrphan).AnyTimes()
+
+			deleted := make([]string, 0)
+
+			store.EXPECT().Delete(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, name string) error {
+				deleted = append(deleted, name)
+
+				return tt.storeErr
+			}).AnyTimes()
+
+			var released int64
+
+			quota.EXPECT().ReleaseBytes(gomock.Any(), userID, gomock.Any()).DoAndReturn(func(_ context.Context, _ uuid.UUID, size int64) error {
+				released += size
+
+				return nil
+			}).AnyTimes()
+
+			service := blob.NewService(zap.NewNop(), rep, store, quota, time.Hour)
+
+			count, err := service.Collect(context.Background())
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantCount, count)
+			assert.Equal(t, append([]string{}, tt.wantDeleted...), deleted, "objects removed from store")
+			assert.Equal(t, tt.wantRelease, released)
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package device
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type DeviceModel struct {
+	ID             uuid.UUID
+	UserID         uuid.UUID
+	Name           string
+	Platform       string
+	CreatedAt      time.Time
+	LastSeenAt     time.Time
+	LastSyncCursor int64
+	RevokedAt      *time.Time
+}
@@ -0,0 +1,11 @@
+package device
+
+import "errors"
+
+var ErrDeviceNotFound = errors.New("device not found")
+
+var ErrDeviceRevoked = errors.New("device revoked")
+
+var ErrRefreshTokenInvalid = errors.New("refresh token is invalid or expired")
+
+var ErrRefreshTokenReused = errors.New("refresh token is reused")
@@ -0,0 +1,271 @@
+package device
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/net/context"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	repository := Repository{db: db}
+
+	return &repository
+}
+
+func (r *Repository) Create(ctx context.Context, device *DeviceModel) error {
+	return r.db.QueryRowContext(
+		ctx,
+		`insert into devices (id, user_id, name, platform) values ($1, $2, $3, $4) returning created_at, last_seen_at;`,
+		device.ID,
+		device.UserID,
+		device.Name,
+		device.Platform,
+	).Scan(&device.CreatedAt, &device.LastSeenAt)
+}
+
+// FindByID returns device of user including revoked one
+func (r *Repository) FindByID(ctx context.Context, userID, id uuid.UUID) (*DeviceModel, error) {
+	device := DeviceModel{}
+
+	err := r.db.QueryRowContext(
+		ctx,
+		`select id, user_id, name, platform, created_at, last_seen_at, last_sync_cursor, revoked_at
+		from devices where id = $1 and user_id = $2;`,
+		id,
+		userID,
+	).Scan(
+		&device.ID,
+		&device.UserID,
+		&device.Name,
+		&device.Platform,
+		&device.CreatedAt,
+		&device.LastSeenAt,
+		&device.LastSyncCursor,
+		&device.RevokedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrDeviceNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &device, nil
+}
+
+// ListActive returns not revoked devices of user, the last created first
+func (r *Repository) ListActive(ctx context.Context, userID uuid.UUID) ([]DeviceModel, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`select id, user_id, name, platform, created_at, last_seen_at, last_sync_cursor
+		from devices where user_id = $1 and revoked_at is null order by created_at desc;`,
+		userID,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := make([]DeviceModel, 0)
+
+	for rows.Next() {
+		device := DeviceModel{}
+
+		err := rows.Scan(
+			&device.ID,
+			&device.UserID,
+			&device.Name,
+			&device.Platform,
+			&device.CreatedAt,
+			&device.LastSeenAt,
+			&device.LastSyncCursor,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		devices = append(devices, device)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return devices, nil
+}
+
+func (r *Repository) Rename(ctx context.Context, userID, id uuid.UUID, name string) error {
+	result, err := r.db.ExecContext(
+		ctx,
+		`update devices set name = $3 where id = $1 and user_id = $2 and revoked_at is null;`,
+		id,
+		userID,
+		name,
+	)
+
+	return checkAffected(result, err)
+}
+
+func (r *Repository) Revoke(ctx context.Context, userID, id uuid.UUID) error {
+	result, err := r.db.ExecContext(
+		ctx,
+		`update devices set revoked_at = now() where id = $1 and user_id = $2 and revoked_at is null;`,
+		id,
+		userID,
+	)
+
+	return checkAffected(result, err)
+}
+
+// Touch updates last seen time when it is older than seenAfter, so every request doesn't write
+func (r *Repository) Touch(ctx context.Context, id uuid.UUID, seenAfter time.Time) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`update devices set last_seen_at = now() where id = $1 and last_seen_at < $2;`,
+		id,
+		seenAfter,
+	)
+
+	return err
+}
+
+// UpdateSyncCursor never moves cursor back, concurrent syncs of device could finish in any order
+func (r *Repository) UpdateSyncCursor(ctx context.Context, id uuid.UUID, cursor int64) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`update devices set last_sync_cursor = greatest(last_sync_cursor, $2) where id = $1;`,
+		id,
+		cursor,
+	)
+
+	return err
+}
+
+func (r *Repository) CreateRefreshToken(ctx context.Context, deviceID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`insert into refresh_tokens (token_hash, device_id, expires_at) values ($1, $2, $3);`,
+		tokenHash,
+		deviceID,
+		expiresAt,
+	)
+
+	return err
+}
+
+// RotateRefreshToken spends refresh token and saves the next one of the same device in one transaction.
+// Reused token revokes device, so both stolen and original sessions are closed
+func (r *Repository) RotateRefreshToken(ctx context.Context, tokenHash, nextHash string, nextExpiresAt time.Time) (*DeviceModel, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var (
+		device    DeviceModel
+		expiresAt time.Time
+		usedAt    *time.Time
+	)
+
+	err = tx.QueryRowContext(
+		ctx,
+		`select d.id, d.user_id, d.revoked_at, t.expires_at, t.used_at
+		from refresh_tokens t join devices d on d.id = t.device_id
+		where t.token_hash = $1 for update of t, d;`,
+		tokenHash,
+	).Scan(&device.ID, &device.UserID, &device.RevokedAt, &expiresAt, &usedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if device.RevokedAt != nil {
+		return nil, ErrDeviceRevoked
+	}
+
+	if usedAt != nil {
+		_, err = tx.ExecContext(ctx, `update devices set revoked_at = now() where id = $1;`, device.ID)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err = tx.Commit(); err != nil {
+			return nil, err
+		}
+
+		return &device, ErrRefreshTokenReused
+	}
+
+	if !expiresAt.After(time.Now()) {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	_, err = tx.ExecContext(ctx, `update refresh_tokens set used_at = now() where token_hash = $1;`, tokenHash)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Expired tokens can't be reused anymore, so they aren't needed for reuse detection
+	_, err = tx.ExecContext(ctx, `delete from refresh_tokens where device_id = $1 and expires_at < now();`, device.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`insert into refresh_tokens (token_hash, device_id, expires_at) values ($1, $2, $3);`,
+		nextHash,
+		device.ID,
+		nextExpiresAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &device, nil
+}
+
+func checkAffected(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+
+	countAffected, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if countAffected == 0 {
+		return ErrDeviceNotFound
+	}
+
+	return nil
+}
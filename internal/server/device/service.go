@@ -0,0 +1,160 @@
+package device
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/net/context"
+)
+
+const (
+	// lastSeenPrecision limits writes of last seen time to one per device in this period
+	lastSeenPrecision = time.Minute
+
+	defaultDeviceName = "unknown"
+	maxNameLength     = 128
+)
+
+type Service struct {
+	rep        *Repository
+	refreshTTL time.Duration
+}
+
+func NewService(rep *Repository, refreshTTL time.Duration) *Service {
+	service := Service{
+		rep:        rep,
+		refreshTTL: refreshTTL,
+	}
+
+	return &service
+}
+
+func (s *Service) Create(ctx context.Context, userID uuid.UUID, name, platform string) (*DeviceModel, error) {
+	device := DeviceModel{
+		ID:       uuid.New(),
+		UserID:   userID,
+		Name:     normalizeName(name),
+		Platform: truncate(platform, maxNameLength),
+	}
+
+	err := s.rep.Create(ctx, &device)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &device, nil
+}
+
+// Verify returns ErrDeviceRevoked when token of device must not be accepted. It is called on every request
+func (s *Service) Verify(ctx context.Context, userID, deviceID uuid.UUID) error {
+	device, err := s.rep.FindByID(ctx, userID, deviceID)
+
+	if errors.Is(err, ErrDeviceNotFound) {
+		return ErrDeviceRevoked
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if device.RevokedAt != nil {
+		return ErrDeviceRevoked
+	}
+
+	if time.Since(device.LastSeenAt) < lastSeenPrecision {
+		return nil
+	}
+
+	return s.rep.Touch(ctx, deviceID, time.Now().Add(-lastSeenPrecision))
+}
+
+func (s *Service) List(ctx context.Context, userID uuid.UUID) ([]DeviceModel, error) {
+	return s.rep.ListActive(ctx, userID)
+}
+
+func (s *Service) Rename(ctx context.Context, userID, deviceID uuid.UUID, name string) error {
+	return s.rep.Rename(ctx, userID, deviceID, normalizeName(name))
+}
+
+func (s *Service) Revoke(ctx context.Context, userID, deviceID uuid.UUID) error {
+	return s.rep.Revoke(ctx, userID, deviceID)
+}
+
+func (s *Service) UpdateSyncCursor(ctx context.Context, deviceID uuid.UUID, cursor int64) error {
+	return s.rep.UpdateSyncCursor(ctx, deviceID, cursor)
+}
+
+func normalizeName(name string) string {
+	if name == "" {
+		return defaultDeviceName
+	}
+
+	return truncate(name, maxNameLength)
+}
+
+func truncate(value string, maxLength int) string {
+	runes := []rune(value)
+
+	if len(runes) <= maxLength {
+		return value
+	}
+
+	return string(runes[:maxLength])
+}
+
+// IssueRefreshToken creates refresh token of device, token is returned only once
+func (s *Service) IssueRefreshToken(ctx context.Context, deviceID uuid.UUID) (string, error) {
+	token, err := generateRefreshToken()
+
+	if err != nil {
+		return "", err
+	}
+
+	err = s.rep.CreateRefreshToken(ctx, deviceID, hashRefreshToken(token), time.Now().Add(s.refreshTTL))
+
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// RotateRefreshToken exchanges refresh token to the next one. ErrRefreshTokenReused is returned
+// together with device when spent token is used again, device is revoked in this case
+func (s *Service) RotateRefreshToken(ctx context.Context, token string) (*DeviceModel, string, error) {
+	next, err := generateRefreshToken()
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	device, err := s.rep.RotateRefreshToken(ctx, hashRefreshToken(token), hashRefreshToken(next), time.Now().Add(s.refreshTTL))
+
+	if err != nil {
+		return device, "", err
+	}
+
+	return device, next, nil
+}
+
+func generateRefreshToken() (string, error) {
+	b := make([]byte, 32)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,21 @@
+package device
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_generateRefreshToken(t *testing.T) {
+	token, err := generateRefreshToken()
+	require.NoError(t, err)
+
+	other, err := generateRefreshToken()
+	require.NoError(t, err)
+
+	assert.Len(t, token, 43)
+	assert.NotEqual(t, token, other)
+	assert.NotEqual(t, hashRefreshToken(token), hashRefreshToken(other))
+	assert.Equal(t, hashRefreshToken(token), hashRefreshToken(token))
+}
@@ -0,0 +1,59 @@
+package httphandlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/shreyner/gophkeeper/internal/server/blob"
+	"github.com/shreyner/gophkeeper/internal/server/middlewares"
+)
+
+// BlobHandler streams uploaded objects to their owners, so bucket doesn't need public read
+type BlobHandler struct {
+	log         *zap.Logger
+	blobService *blob.Service
+}
+
+func NewBlobHandler(log *zap.Logger, blobService *blob.Service) *BlobHandler {
+	handler := BlobHandler{
+		log:         log,
+		blobService: blobService,
+	}
+
+	return &handler
+}
+
+// Download supports Range header, so client continues interrupted download
+func (h *BlobHandler) Download(wr http.ResponseWriter, r *http.Request) {
+	tokenData, ok := middlewares.GetTokenDataCtx(r.Context())
+
+	if !ok {
+		http.Error(wr, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	objectName := chi.URLParam(r, "objectName")
+
+	object, info, err := h.blobService.Open(r.Context(), tokenData.ID, objectName)
+
+	if errors.Is(err, blob.ErrBlobNotFound) {
+		http.Error(wr, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		h.log.Error("can't open blob", zap.Error(err))
+		http.Error(wr, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	defer object.Close()
+
+	wr.Header().Set("Content-Type", "application/octet-stream")
+	wr.Header().Set("Cache-Control", "private, no-store")
+
+	http.ServeContent(wr, r, objectName, info.ModTime, object)
+}
@@ -0,0 +1,46 @@
+package httphandlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/shreyner/gophkeeper/internal/server/pgk/stoken"
+)
+
+// jwksMaxAge is cache time of JWKS, it must be shorter than overlap of key rotation
+const jwksMaxAge = "max-age=300"
+
+// JWKSHandler publishes public keys of token signing
+type JWKSHandler struct {
+	log           *zap.Logger
+	stokenService *stoken.Service
+}
+
+func NewJWKSHandler(log *zap.Logger, stokenService *stoken.Service) *JWKSHandler {
+	handler := JWKSHandler{
+		log:           log,
+		stokenService: stokenService,
+	}
+
+	return &handler
+}
+
+// JWKS returns public keys to verify access tokens by other services
+func (h *JWKSHandler) JWKS(wr http.ResponseWriter, r *http.Request) {
+	set, err := h.stokenService.JWKS()
+
+	if err != nil {
+		h.log.Error("can't build jwks", zap.Error(err))
+		http.Error(wr, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+		return
+	}
+
+	wr.Header().Set("Content-Type", "application/json")
+	wr.Header().Set("Cache-Control", jwksMaxAge)
+	wr.WriteHeader(http.StatusOK)
+
+	_ = json.NewEncoder(wr).Encode(set)
+}
@@ -1,6 +1,7 @@
 package httphandlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -8,72 +9,129 @@ import (
 	"github.com/go-chi/chi/v5"
 	chiMiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/jaevor/go-nanoid"
-	"github.com/minio/minio-go/v7"
 	"github.com/shreyner/gophkeeper/internal/server/pgk/contenttype"
 	"go.uber.org/zap"
+	"golang.org/x/net/context"
 
+	"github.com/shreyner/gophkeeper/internal/server/blob"
+	"github.com/shreyner/gophkeeper/internal/server/device"
 	"github.com/shreyner/gophkeeper/internal/server/middlewares"
+	"github.com/shreyner/gophkeeper/internal/server/pgk/blobstore"
 	"github.com/shreyner/gophkeeper/internal/server/pgk/stoken"
+	"github.com/shreyner/gophkeeper/internal/server/quota"
+	"github.com/shreyner/gophkeeper/internal/server/ratelimit"
+	"github.com/shreyner/gophkeeper/internal/server/upload"
 )
 
 func NewRouter(
 	log *zap.Logger,
 	stokenService *stoken.Service,
-	s3minioClient *minio.Client,
+	deviceService *device.Service,
+	blobStore blobstore.Store,
+	uploadService *upload.Service,
+	quotaService *quota.Service,
+	blobService *blob.Service,
+	rateLimitService *ratelimit.Service,
 ) *chi.Mux {
 	randID, _ := nanoid.Standard(36)
 
 	r := chi.NewRouter()
 
 	r.Use(chiMiddleware.RequestID)
+	// Limiter keys on address of connection, RealIP replaces it by forwarded header
+	r.Use(middlewares.RateLimiter(log, rateLimitService))
 	r.Use(chiMiddleware.RealIP)
 	r.Use(chiMiddleware.Logger)
 	r.Use(chiMiddleware.Recoverer)
 
-	r.Use(chiMiddleware.Timeout(60 * time.Second))
+	r.Use(middlewares.Authenticator(log, stokenService, deviceService))
 
-	r.Use(middlewares.Authenticator(log, stokenService))
+	blobHandler := NewBlobHandler(log, blobService)
 
-	r.
-		With(chiMiddleware.AllowContentType(contenttype.ContentTypeBinary)).
-		Put("/upload", func(wr http.ResponseWriter, r *http.Request) {
-			_, ok := middlewares.GetTokenDataCtx(r.Context())
+	// Big file is downloaded longer than request timeout, so blobs are not in timeout group
+	r.Get("/blobs/{objectName}", blobHandler.Download)
 
-			if !ok {
-				http.Error(wr, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
-				return
-			}
+	r.Group(func(r chi.Router) {
+		r.Use(chiMiddleware.Timeout(60 * time.Second))
 
-			defer r.Body.Close()
+		r.
+			With(chiMiddleware.AllowContentType(contenttype.ContentTypeBinary)).
+			Put("/upload", func(wr http.ResponseWriter, r *http.Request) {
+				tokenData, ok := middlewares.GetTokenDataCtx(r.Context())
 
-			fileName := randID()
+				if !ok {
+					http.Error(wr, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+					return
+				}
 
-			uploadFileName, err := s3minioClient.PutObject(
-				r.Context(),
-				"vault",
-				fileName,
-				r.Body,
-				-1,
-				minio.PutObjectOptions{
-					DisableMultipart: false,
-				},
-			)
+				defer r.Body.Close()
 
-			if err != nil {
-				log.Error("can't upload object to s3", zap.Error(err))
-				http.Error(wr, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-				return
-			}
+				// Size is reserved in quota before upload, so it must be known
+				if r.ContentLength < 0 {
+					http.Error(wr, http.StatusText(http.StatusLengthRequired), http.StatusLengthRequired)
+					return
+				}
 
-			_, _ = fmt.Fprintln(wr, uploadFileName.Location)
-			wr.WriteHeader(http.StatusOK)
+				err := quotaService.ReserveBytes(r.Context(), tokenData.ID, r.ContentLength)
+
+				if errors.Is(err, quota.ErrQuotaExceeded) {
+					http.Error(wr, err.Error(), http.StatusRequestEntityTooLarge)
+					return
+				}
+
+				if err != nil {
+					log.Error("can't reserve quota", zap.Error(err))
+					http.Error(wr, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					return
+				}
+
+				fileName := randID()
+
+				err = blobService.Register(r.Context(), tokenData.ID, fileName, r.ContentLength)
+
+				if err != nil {
+					_ = quotaService.ReleaseBytes(context.Background(), tokenData.ID, r.ContentLength)
+
+					log.Error("can't register blob", zap.Error(err))
+					http.Error(wr, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					return
+				}
+
+				err = blobStore.Put(r.Context(), fileName, http.MaxBytesReader(wr, r.Body, r.ContentLength), r.ContentLength)
+
+				// Reserved bytes of failed upload are released by collection of registered blob
+				if err != nil {
+					log.Error("can't upload object to s3", zap.Error(err))
+					http.Error(wr, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					return
+				}
+
+				_, _ = fmt.Fprintln(wr, upload.Location(fileName))
+				wr.WriteHeader(http.StatusOK)
+			})
+
+		jwksHandler := NewJWKSHandler(log, stokenService)
+
+		r.Get("/.well-known/jwks.json", jwksHandler.JWKS)
+
+		uploadHandler := NewUploadHandler(log, uploadService)
+
+		r.Route("/uploads", func(r chi.Router) {
+			r.Post("/", uploadHandler.Create)
+			r.Head("/{uploadID}", uploadHandler.Offset)
+			r.
+				With(chiMiddleware.AllowContentType(contenttype.ContentTypeBinary)).
+				Patch("/{uploadID}", uploadHandler.WriteChunk)
+			r.Post("/{uploadID}/complete", uploadHandler.Complete)
+			r.Delete("/{uploadID}", uploadHandler.Abort)
 		})
 
-	r.Get("/", func(w http.ResponseWriter, wr *http.Request) {
-		w.Header().Set("Content-Type", "text/plain")
-		w.WriteHeader(http.StatusOK)
+		r.Get("/", func(w http.ResponseWriter, wr *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusOK)
 
-		fmt.Fprintln(w, "Hello world")
+			fmt.Fprintln(w, "Hello world")
+		})
 	})
 
 	return r
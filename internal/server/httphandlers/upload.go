@@ -1 +1,182 @@
 package httphandlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/shreyner/gophkeeper/internal/server/middlewares"
+	"github.com/shreyner/gophkeeper/internal/server/quota"
+	"github.com/shreyner/gophkeeper/internal/server/upload"
+)
+
+// HeaderUploadOffset is count of bytes saved by upload, chunk is accepted only at this offset
+const HeaderUploadOffset = "Upload-Offset"
+
+// UploadHandler implements resumable upload: create, send chunks by offset, complete.
+// Client asks offset with HEAD after failed chunk and continues from it
+type UploadHandler struct {
+	log           *zap.Logger
+	uploadService *upload.Service
+}
+
+func NewUploadHandler(log *zap.Logger, uploadService *upload.Service) *UploadHandler {
+	handler := UploadHandler{
+		log:           log,
+		uploadService: uploadService,
+	}
+
+	return &handler
+}
+
+func (h *UploadHandler) Create(wr http.ResponseWriter, r *http.Request) {
+	tokenData, ok := middlewares.GetTokenDataCtx(r.Context())
+
+	if !ok {
+		http.Error(wr, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	uploadModel, err := h.uploadService.Create(r.Context(), tokenData.ID)
+
+	if err != nil {
+		h.log.Error("can't create upload", zap.Error(err))
+		http.Error(wr, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	wr.Header().Set("Location", fmt.Sprintf("/uploads/%s", uploadModel.ID))
+	wr.Header().Set(HeaderUploadOffset, "0")
+	wr.WriteHeader(http.StatusCreated)
+
+	_, _ = fmt.Fprintln(wr, uploadModel.ID)
+}
+
+func (h *UploadHandler) Offset(wr http.ResponseWriter, r *http.Request) {
+	userID, uploadID, ok := h.parseRequest(wr, r)
+
+	if !ok {
+		return
+	}
+
+	offset, err := h.uploadService.Offset(r.Context(), userID, uploadID)
+
+	if err != nil {
+		h.writeError(wr, err)
+		return
+	}
+
+	wr.Header().Set("Cache-Control", "no-store")
+	wr.Header().Set(HeaderUploadOffset, strconv.FormatInt(offset, 10))
+	wr.WriteHeader(http.StatusOK)
+}
+
+func (h *UploadHandler) WriteChunk(wr http.ResponseWriter, r *http.Request) {
+	userID, uploadID, ok := h.parseRequest(wr, r)
+
+	if !ok {
+		return
+	}
+
+	defer r.Body.Close()
+
+	offset, err := strconv.ParseInt(r.Header.Get(HeaderUploadOffset), 10, 64)
+
+	if err != nil || offset < 0 {
+		http.Error(wr, "invalid upload offset", http.StatusBadRequest)
+		return
+	}
+
+	if r.ContentLength < 0 {
+		http.Error(wr, http.StatusText(http.StatusLengthRequired), http.StatusLengthRequired)
+		return
+	}
+
+	if r.ContentLength > upload.MaxChunkSize {
+		http.Error(wr, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	body := http.MaxBytesReader(wr, r.Body, r.ContentLength)
+
+	newOffset, err := h.uploadService.WriteChunk(r.Context(), userID, uploadID, offset, body, r.ContentLength)
+
+	if err != nil {
+		h.writeError(wr, err)
+		return
+	}
+
+	wr.Header().Set(HeaderUploadOffset, strconv.FormatInt(newOffset, 10))
+	wr.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UploadHandler) Complete(wr http.ResponseWriter, r *http.Request) {
+	userID, uploadID, ok := h.parseRequest(wr, r)
+
+	if !ok {
+		return
+	}
+
+	location, err := h.uploadService.Complete(r.Context(), userID, uploadID)
+
+	if err != nil {
+		h.writeError(wr, err)
+		return
+	}
+
+	_, _ = fmt.Fprintln(wr, location)
+}
+
+func (h *UploadHandler) Abort(wr http.ResponseWriter, r *http.Request) {
+	userID, uploadID, ok := h.parseRequest(wr, r)
+
+	if !ok {
+		return
+	}
+
+	err := h.uploadService.Abort(r.Context(), userID, uploadID)
+
+	if err != nil {
+		h.writeError(wr, err)
+		return
+	}
+
+	wr.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UploadHandler) parseRequest(wr http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	tokenData, ok := middlewares.GetTokenDataCtx(r.Context())
+
+	if !ok {
+		http.Error(wr, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	uploadID, err := uuid.Parse(chi.URLParam(r, "uploadID"))
+
+	if err != nil {
+		http.Error(wr, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return tokenData.ID, uploadID, true
+}
+
+func (h *UploadHandler) writeError(wr http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, upload.ErrUploadNotFound):
+		http.Error(wr, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	case errors.Is(err, upload.ErrOffsetMismatch), errors.Is(err, upload.ErrUploadFinished), errors.Is(err, upload.ErrPartInProgress):
+		http.Error(wr, err.Error(), http.StatusConflict)
+	case errors.Is(err, quota.ErrQuotaExceeded):
+		http.Error(wr, err.Error(), http.StatusRequestEntityTooLarge)
+	default:
+		h.log.Error("can't process upload", zap.Error(err))
+		http.Error(wr, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
@@ -2,7 +2,11 @@ package interceptor_auth
 
 import (
 	"context"
+	"errors"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/shreyner/gophkeeper/internal/server/device"
 	"github.com/shreyner/gophkeeper/internal/server/pgk/stoken"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -12,6 +16,18 @@ import (
 
 var headerAuthorizeToken = "token"
 
+const (
+	// MessageDeviceRevoked tells client to wipe local data of revoked device
+	MessageDeviceRevoked = "device revoked"
+	// MessageTokenExpired tells client to get new access token by refresh token
+	MessageTokenExpired = "token expired"
+)
+
+// DeviceVerifier rejects token of revoked device
+type DeviceVerifier interface {
+	Verify(ctx context.Context, userID, deviceID uuid.UUID) error
+}
+
 const tokenDataKet int = iota
 
 func SetTokenDataCtx(ctx context.Context, tokenData *stoken.Data) context.Context {
@@ -24,34 +40,158 @@ func GetTokenDataCtx(ctx context.Context) (*stoken.Data, bool) {
 	return v, ok
 }
 
-func Interceptor(stokenService *stoken.Service) grpc.UnaryServerInterceptor {
+func Interceptor(stokenService *stoken.Service, deviceVerifier DeviceVerifier) grpc.UnaryServerInterceptor {
 	return func(
 		ctx context.Context,
 		req interface{},
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler,
 	) (resp interface{}, err error) {
-		md, ok := metadata.FromIncomingContext(ctx)
+		ctx, err = authorize(ctx, stokenService, deviceVerifier)
 
-		if !ok {
-			return handler(ctx, req)
+		if err != nil {
+			return nil, err
 		}
 
-		var token string
-		if values := md.Get(headerAuthorizeToken); len(values) != 0 {
-			token = values[0]
+		return handler(ctx, req)
+	}
+}
+
+// sessionCheckInterval is how often device of open stream is verified again
+const sessionCheckInterval = time.Minute
+
+// StreamInterceptor authorizes stream when it is opened and keeps checking it while stream is open.
+// Stream is closed with codes.Unauthenticated when its token expires or device is revoked
+func StreamInterceptor(stokenService *stoken.Service, deviceVerifier DeviceVerifier) grpc.StreamServerInterceptor {
+	return streamInterceptor(stokenService, deviceVerifier, sessionCheckInterval)
+}
+
+func streamInterceptor(stokenService *stoken.Service, deviceVerifier DeviceVerifier, checkInterval time.Duration) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx, err := authorize(ss.Context(), stokenService, deviceVerifier)
+
+		if err != nil {
+			return err
 		}
 
-		if token == "" {
-			return handler(ctx, req)
+		tokenData, ok := GetTokenDataCtx(ctx)
+
+		if !ok {
+			return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
 		}
 
-		tokenData, err := stokenService.ParseToken(token)
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
 
-		if err != nil {
-			return nil, status.Error(codes.PermissionDenied, "invalid token")
+		sessionErr := make(chan error, 1)
+
+		go func() {
+			sessionErr <- watchSession(ctx, tokenData, deviceVerifier, checkInterval)
+
+			// Handler sees done context and returns, error of session is returned instead
+			cancel()
+		}()
+
+		err = handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+
+		cancel()
+
+		if errSession := <-sessionErr; errSession != nil {
+			return errSession
 		}
 
-		return handler(SetTokenDataCtx(ctx, tokenData), req)
+		return err
 	}
 }
+
+// watchSession returns error when token of open stream expires or device is revoked, nil when ctx is done
+func watchSession(ctx context.Context, tokenData *stoken.Data, deviceVerifier DeviceVerifier, checkInterval time.Duration) error {
+	expired := time.NewTimer(time.Until(tokenData.ExpiresAt))
+	defer expired.Stop()
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-expired.C:
+			return status.Error(codes.Unauthenticated, MessageTokenExpired)
+		case <-ticker.C:
+			err := verifyDevice(ctx, deviceVerifier, tokenData)
+
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// serverStream replaces context of grpc.ServerStream with authorized one
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func authorize(ctx context.Context, stokenService *stoken.Service, deviceVerifier DeviceVerifier) (context.Context, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+
+	if !ok {
+		return ctx, nil
+	}
+
+	var token string
+	if values := md.Get(headerAuthorizeToken); len(values) != 0 {
+		token = values[0]
+	}
+
+	if token == "" {
+		return ctx, nil
+	}
+
+	tokenData, err := stokenService.ParseToken(token)
+
+	if errors.Is(err, stoken.ErrTokenExpired) {
+		return nil, status.Error(codes.Unauthenticated, MessageTokenExpired)
+	}
+
+	if err != nil {
+		return nil, status.Error(codes.PermissionDenied, "invalid token")
+	}
+
+	err = verifyDevice(ctx, deviceVerifier, tokenData)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return SetTokenDataCtx(ctx, tokenData), nil
+}
+
+func verifyDevice(ctx context.Context, deviceVerifier DeviceVerifier, tokenData *stoken.Data) error {
+	err := deviceVerifier.Verify(ctx, tokenData.ID, tokenData.DeviceID)
+
+	if errors.Is(err, device.ErrDeviceRevoked) {
+		return status.Error(codes.Unauthenticated, MessageDeviceRevoked)
+	}
+
+	if err != nil {
+		return status.Error(codes.Internal, "error verify device")
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package interceptor_auth
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/shreyner/gophkeeper/internal/server/device"
+	"github.com/shreyner/gophkeeper/internal/server/pgk/stoken"
+)
+
+// fakeDeviceVerifier revokes device after allowed count of checks
+type fakeDeviceVerifier struct {
+	mux     sync.Mutex
+	checks  int
+	allowed int
+}
+
+func (f *fakeDeviceVerifier) Verify(_ context.Context, _, _ uuid.UUID) error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.checks++
+
+	if f.allowed >= 0 && f.checks > f.allowed {
+		return device.ErrDeviceRevoked
+	}
+
+	return nil
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (f *fakeServerStream) Context() context.Context {
+	return f.ctx
+}
+
+func TestStreamInterceptor(t *testing.T) {
+	tests := []struct {
+		name          string
+		tokenTTL      time.Duration
+		allowedChecks int // negative means device is never revoked
+		handlerStops  bool
+		wantCalled    bool
+		wantErr       error
+	}{
+		{
+			name:          "Device revoked before stream is opened",
+			tokenTTL:      time.Hour,
+			allowedChecks: 0,
+			wantErr:       status.Error(codes.Unauthenticated, MessageDeviceRevoked),
+		},
+		{
+			name:          "Device revoked while stream is open",
+			tokenTTL:      time.Hour,
+			allowedChecks: 3,
+			wantCalled:    true,
+			wantErr:       status.Error(codes.Unauthenticated, MessageDeviceRevoked),
+		},
+		{
+			name:          "Token expired while stream is open",
+			tokenTTL:      time.Second,
+			allowedChecks: -1,
+			wantCalled:    true,
+			wantErr:       status.Error(codes.Unauthenticated, MessageTokenExpired),
+		},
+		{
+			name:          "Handler finished",
+			tokenTTL:      time.Hour,
+			allowedChecks: -1,
+			handlerStops:  true,
+			wantCalled:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stokenService := stoken.NewService(stoken.NewHMACKeySet([]byte("secret")), tt.tokenTTL)
+
+			token, err := stokenService.CreateToken(&stoken.Data{ID: uuid.New(), DeviceID: uuid.New()})
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			stream := fakeServerStream{ctx: metadata.NewIncomingContext(ctx, metadata.Pairs(headerAuthorizeToken, token))}
+			verifier := fakeDeviceVerifier{allowed: tt.allowedChecks}
+
+			isCalled := false
+
+			// Handler waits for changes like WatchVaults until stream is done
+			handler := func(_ interface{}, ss grpc.ServerStream) error {
+				isCalled = true
+
+				if tt.handlerStops {
+					return nil
+				}
+
+				<-ss.Context().Done()
+
+				return nil
+			}
+
+			interceptor := streamInterceptor(stokenService, &verifier, 10*time.Millisecond)
+
+			err = interceptor(nil, &stream, &grpc.StreamServerInfo{}, handler)
+
+			assert.Equal(t, tt.wantCalled, isCalled)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, ctx.Err(), "stream is closed by interceptor")
+		})
+	}
+}
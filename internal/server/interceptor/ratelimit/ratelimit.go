@@ -0,0 +1,112 @@
+package interceptor_ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/shreyner/gophkeeper/internal/server/ratelimit"
+)
+
+var headerRetryAfter = "retry-after"
+
+// Limiter locks out account and ip after failed attempts. Attempt is reserved as failed before handler is called
+// and given back when it is not a failed check, so concurrent attempts can't pass lockout
+type Limiter interface {
+	Reserve(ctx context.Context, attempt ratelimit.Attempt) (time.Duration, error)
+	Release(ctx context.Context, attempt ratelimit.Attempt) error
+	Succeed(ctx context.Context, attempt ratelimit.Attempt) error
+}
+
+// AccountFunc returns account of request, empty account limits only ip. Context has token data of authorized user
+type AccountFunc func(ctx context.Context, req interface{}) string
+
+// Interceptor limits failed attempts of methods, other methods are passed through
+func Interceptor(log *zap.Logger, limiter Limiter, methods map[string]AccountFunc) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (resp interface{}, err error) {
+		accountFunc, ok := methods[info.FullMethod]
+
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		attempt := ratelimit.Attempt{
+			Source:  info.FullMethod,
+			IP:      peerIP(ctx),
+			Account: accountFunc(ctx, req),
+		}
+
+		retryAfter, err := limiter.Reserve(ctx, attempt)
+
+		if err != nil {
+			log.Error("can't check rate limit", zap.Error(err))
+			return nil, status.Error(codes.Internal, "error check rate limit")
+		}
+
+		if retryAfter > 0 {
+			seconds := int64(math.Ceil(retryAfter.Seconds()))
+
+			_ = grpc.SetHeader(ctx, metadata.Pairs(headerRetryAfter, strconv.FormatInt(seconds, 10)))
+
+			return nil, status.Error(
+				codes.ResourceExhausted,
+				fmt.Sprintf("too many failed attempts, retry after %v", time.Duration(seconds)*time.Second),
+			)
+		}
+
+		// Only failed checks of credentials reported by handler are counted, not every error
+		ctxFailure, isFailed := ratelimit.WithFailure(ctx)
+
+		resp, err = handler(ctxFailure, req)
+
+		// Canceled request must not keep reservation
+		ctxLimit := context.Background()
+
+		switch {
+		case isFailed():
+			log.Warn("failed attempt", zap.String("source", attempt.Source), zap.String("ip", attempt.IP), zap.String("account", attempt.Account))
+		case err == nil:
+			if errSucceed := limiter.Succeed(ctxLimit, attempt); errSucceed != nil {
+				log.Error("can't reset rate limit", zap.Error(errSucceed))
+			}
+		default:
+			if errRelease := limiter.Release(ctxLimit, attempt); errRelease != nil {
+				log.Error("can't release rate limit", zap.Error(errRelease))
+			}
+		}
+
+		return resp, err
+	}
+}
+
+// peerIP is address of connection without port, proxy headers are not trusted
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+
+	if err != nil {
+		return p.Addr.String()
+	}
+
+	return host
+}
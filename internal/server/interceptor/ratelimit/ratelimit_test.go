@@ -0,0 +1,189 @@
+package interceptor_ratelimit
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/shreyner/gophkeeper/internal/server/ratelimit"
+)
+
+const testMethod = "/gophkeeper.Gophkeeper/Login"
+
+// fakeTransportStream keeps headers set by interceptor
+type fakeTransportStream struct {
+	grpc.ServerTransportStream
+
+	mux    sync.Mutex
+	header metadata.MD
+}
+
+func (f *fakeTransportStream) Method() string {
+	return testMethod
+}
+
+func (f *fakeTransportStream) SetHeader(md metadata.MD) error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.header = metadata.Join(f.header, md)
+
+	return nil
+}
+
+func newTestInterceptor() grpc.UnaryServerInterceptor {
+	policy := ratelimit.Policy{
+		MaxFailures: 3,
+		Window:      15 * time.Minute,
+		BaseLockout: time.Minute,
+		MaxLockout:  10 * time.Minute,
+	}
+
+	limiter := ratelimit.NewService(zap.NewNop(), ratelimit.NewMemoryStore(), policy, ratelimit.Policy{})
+
+	return Interceptor(zap.NewNop(), limiter, map[string]AccountFunc{
+		testMethod: func(_ context.Context, req interface{}) string {
+			return ratelimit.LoginAccount(req.(string))
+		},
+	})
+}
+
+func newTestContext(stream *fakeTransportStream) context.Context {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5555}})
+
+	return grpc.NewContextWithServerTransportStream(ctx, stream)
+}
+
+// Handlers answer like Login: wrong password is reported as failed check
+var (
+	failedHandler = func(ctx context.Context, _ interface{}) (interface{}, error) {
+		ratelimit.Failed(ctx)
+		return nil, status.Error(codes.PermissionDenied, "invalid login or password")
+	}
+	successHandler = func(_ context.Context, _ interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	errorHandler = func(_ context.Context, _ interface{}) (interface{}, error) {
+		return nil, status.Error(codes.Internal, "database is unavailable")
+	}
+)
+
+func TestInterceptor(t *testing.T) {
+	tests := []struct {
+		name     string
+		handlers []grpc.UnaryHandler
+		wantCode codes.Code
+	}{
+		{
+			name:     "Locked out after max failures",
+			handlers: []grpc.UnaryHandler{failedHandler, failedHandler, failedHandler},
+			wantCode: codes.ResourceExhausted,
+		},
+		{
+			name:     "Failures are forgotten after success",
+			handlers: []grpc.UnaryHandler{failedHandler, failedHandler, successHandler, failedHandler, failedHandler},
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "Errors other than failed check are not counted",
+			handlers: []grpc.UnaryHandler{errorHandler, errorHandler, errorHandler, failedHandler, failedHandler},
+			wantCode: codes.PermissionDenied,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interceptor := newTestInterceptor()
+			info := grpc.UnaryServerInfo{FullMethod: testMethod}
+
+			for _, handler := range tt.handlers {
+				_, _ = interceptor(newTestContext(&fakeTransportStream{}), "alice", &info, handler)
+			}
+
+			_, err := interceptor(newTestContext(&fakeTransportStream{}), "alice", &info, failedHandler)
+
+			assert.Equal(t, tt.wantCode, status.Code(err))
+		})
+	}
+}
+
+func TestInterceptor_RetryAfter(t *testing.T) {
+	interceptor := newTestInterceptor()
+	info := grpc.UnaryServerInfo{FullMethod: testMethod}
+
+	for i := 0; i < 3; i++ {
+		_, _ = interceptor(newTestContext(&fakeTransportStream{}), "alice", &info, failedHandler)
+	}
+
+	stream := fakeTransportStream{}
+	isCalled := false
+
+	_, err := interceptor(newTestContext(&stream), "alice", &info, func(_ context.Context, _ interface{}) (interface{}, error) {
+		isCalled = true
+		return "ok", nil
+	})
+
+	assert.False(t, isCalled, "handler is not called while locked out")
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, []string{"60"}, stream.header.Get(headerRetryAfter))
+}
+
+func TestInterceptor_Concurrent(t *testing.T) {
+	interceptor := newTestInterceptor()
+	info := grpc.UnaryServerInfo{FullMethod: testMethod}
+
+	const attempts = 10
+
+	// Handlers wait until every attempt is checked, so no failure is reported before others start
+	release := make(chan struct{})
+	results := make(chan error, attempts)
+
+	var (
+		mux    sync.Mutex
+		called int
+	)
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		mux.Lock()
+		called++
+		mux.Unlock()
+
+		<-release
+
+		return failedHandler(ctx, req)
+	}
+
+	for i := 0; i < attempts; i++ {
+		go func() {
+			_, err := interceptor(newTestContext(&fakeTransportStream{}), "alice", &info, handler)
+			results <- err
+		}()
+	}
+
+	for i := 0; i < attempts-3; i++ {
+		select {
+		case err := <-results:
+			assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "attempts over limit are not rejected")
+		}
+	}
+
+	close(release)
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, codes.PermissionDenied, status.Code(<-results))
+	}
+
+	assert.Equal(t, 3, called, "only max failures attempts reach handler")
+}
@@ -2,16 +2,23 @@ package middlewares
 
 import (
 	"context"
+	"errors"
 	"net/http"
 
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 
+	"github.com/shreyner/gophkeeper/internal/server/device"
 	"github.com/shreyner/gophkeeper/internal/server/pgk/stoken"
+	"github.com/shreyner/gophkeeper/internal/server/ratelimit"
 )
 
 var HeaderAuthorizationKey = "Authorization"
 var TokenDataKeyCtx = "auth-token"
 
+// MessageTokenExpired is body of 401 response, client gets new access token by refresh token and repeats request
+var MessageTokenExpired = "token expired"
+
 func SetTokenDataCtx(ctx context.Context, tokenData *stoken.Data) context.Context {
 	return context.WithValue(ctx, TokenDataKeyCtx, tokenData)
 }
@@ -22,7 +29,12 @@ func GetTokenDataCtx(ctx context.Context) (*stoken.Data, bool) {
 	return v, ok
 }
 
-func Authenticator(log *zap.Logger, stokenService stoken.JWTService) func(next http.Handler) http.Handler {
+// DeviceVerifier rejects token of revoked device
+type DeviceVerifier interface {
+	Verify(ctx context.Context, userID, deviceID uuid.UUID) error
+}
+
+func Authenticator(log *zap.Logger, stokenService stoken.JWTService, deviceVerifier DeviceVerifier) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(wr http.ResponseWriter, r *http.Request) {
 			authToken := r.Header.Get(HeaderAuthorizationKey)
@@ -34,12 +46,34 @@ func Authenticator(log *zap.Logger, stokenService stoken.JWTService) func(next h
 
 			tokenData, err := stokenService.ParseToken(authToken)
 
+			if errors.Is(err, stoken.ErrTokenExpired) {
+				http.Error(wr, MessageTokenExpired, http.StatusUnauthorized)
+
+				return
+			}
+
 			if err != nil {
+				ratelimit.Failed(r.Context())
 				http.Error(wr, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 
 				return
 			}
 
+			err = deviceVerifier.Verify(r.Context(), tokenData.ID, tokenData.DeviceID)
+
+			if errors.Is(err, device.ErrDeviceRevoked) {
+				http.Error(wr, err.Error(), http.StatusUnauthorized)
+
+				return
+			}
+
+			if err != nil {
+				log.Error("can't verify device", zap.Error(err))
+				http.Error(wr, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+				return
+			}
+
 			next.ServeHTTP(wr, r.WithContext(SetTokenDataCtx(r.Context(), tokenData)))
 		})
 	}
@@ -0,0 +1,75 @@
+package middlewares
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/shreyner/gophkeeper/internal/server/ratelimit"
+)
+
+// Limiter locks out ip after failed attempts
+type Limiter interface {
+	Check(ctx context.Context, attempt ratelimit.Attempt) (time.Duration, error)
+	Fail(ctx context.Context, attempt ratelimit.Attempt) error
+}
+
+// RateLimiter counts failed checks of tokens by ip and answers 429 while ip is locked out.
+// Ip is address of connection, forwarded headers are set by client and must not pick the key,
+// so it is used before RealIP
+func RateLimiter(log *zap.Logger, limiter Limiter) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(wr http.ResponseWriter, r *http.Request) {
+			attempt := ratelimit.Attempt{
+				Source: r.Method + " " + r.URL.Path,
+				IP:     remoteIP(r),
+			}
+
+			retryAfter, err := limiter.Check(r.Context(), attempt)
+
+			if err != nil {
+				log.Error("can't check rate limit", zap.Error(err))
+				http.Error(wr, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+				return
+			}
+
+			if retryAfter > 0 {
+				wr.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(retryAfter.Seconds())), 10))
+				http.Error(wr, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+
+				return
+			}
+
+			ctx, isFailed := ratelimit.WithFailure(r.Context())
+
+			next.ServeHTTP(wr, r.WithContext(ctx))
+
+			if !isFailed() {
+				return
+			}
+
+			// Request context may be canceled when response is written
+			err = limiter.Fail(context.Background(), attempt)
+
+			if err != nil {
+				log.Error("can't count failed attempt", zap.Error(err))
+			}
+		})
+	}
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,70 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/shreyner/gophkeeper/internal/server/ratelimit"
+)
+
+type fakeLimiter struct {
+	failed []ratelimit.Attempt
+}
+
+func (f *fakeLimiter) Check(_ context.Context, _ ratelimit.Attempt) (time.Duration, error) {
+	return 0, nil
+}
+
+func (f *fakeLimiter) Fail(_ context.Context, attempt ratelimit.Attempt) error {
+	f.failed = append(f.failed, attempt)
+
+	return nil
+}
+
+func TestRateLimiter(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		wantFailed []ratelimit.Attempt
+	}{
+		{
+			name: "Failed check of token is counted by address of connection",
+			handler: func(wr http.ResponseWriter, r *http.Request) {
+				ratelimit.Failed(r.Context())
+				http.Error(wr, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			},
+			wantFailed: []ratelimit.Attempt{{Source: "GET /blobs/1", IP: "10.0.0.1"}},
+		},
+		{
+			name: "Expired token is not counted",
+			handler: func(wr http.ResponseWriter, r *http.Request) {
+				http.Error(wr, MessageTokenExpired, http.StatusUnauthorized)
+			},
+		},
+		{
+			name: "Forbidden answer is not counted",
+			handler: func(wr http.ResponseWriter, r *http.Request) {
+				http.Error(wr, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := fakeLimiter{}
+
+			r := httptest.NewRequest(http.MethodGet, "/blobs/1", nil)
+			r.RemoteAddr = "10.0.0.1:5555"
+			r.Header.Set("X-Forwarded-For", "1.2.3.4")
+
+			RateLimiter(zap.NewNop(), &limiter)(tt.handler).ServeHTTP(httptest.NewRecorder(), r)
+
+			assert.Equal(t, tt.wantFailed, limiter.failed)
+		})
+	}
+}
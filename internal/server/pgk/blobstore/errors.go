@@ -0,0 +1,8 @@
+package blobstore
+
+import "errors"
+
+var ErrNotFound = errors.New("blobstore: object not found")
+var ErrInvalidName = errors.New("blobstore: invalid object name")
+var ErrUploadNotFound = errors.New("blobstore: upload not found")
+var ErrShortWrite = errors.New("blobstore: data is shorter than size")
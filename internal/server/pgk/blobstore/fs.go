@@ -0,0 +1,284 @@
+package blobstore
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+var (
+	_ Store = (*FSStore)(nil)
+)
+
+const (
+	fsUploadsDir = ".uploads"
+	fsTempDir    = ".tmp"
+)
+
+// FSStore keeps every object as file in root folder. Parts of multipart upload are files
+// in own folder of upload, complete joins them into object file
+type FSStore struct {
+	root string
+}
+
+func NewFSStore(root string) (*FSStore, error) {
+	for _, dir := range []string{root, filepath.Join(root, fsUploadsDir), filepath.Join(root, fsTempDir)} {
+		err := os.MkdirAll(dir, 0o700)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	store := FSStore{root: root}
+
+	return &store, nil
+}
+
+func (s *FSStore) Put(_ context.Context, name string, r io.Reader, size int64) error {
+	objectPath, err := s.objectPath(name)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = s.writeFile(objectPath, io.LimitReader(r, size), size)
+
+	return err
+}
+
+func (s *FSStore) Open(_ context.Context, name string) (Object, *ObjectInfo, error) {
+	objectPath, err := s.objectPath(name)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.Open(objectPath)
+
+	if err != nil {
+		return nil, nil, mapFSError(err)
+	}
+
+	fileInfo, err := file.Stat()
+
+	if err != nil {
+		_ = file.Close()
+		return nil, nil, err
+	}
+
+	return file, &ObjectInfo{Size: fileInfo.Size(), ModTime: fileInfo.ModTime()}, nil
+}
+
+func (s *FSStore) Stat(_ context.Context, name string) (*ObjectInfo, error) {
+	objectPath, err := s.objectPath(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	fileInfo, err := os.Stat(objectPath)
+
+	if err != nil {
+		return nil, mapFSError(err)
+	}
+
+	return &ObjectInfo{Size: fileInfo.Size(), ModTime: fileInfo.ModTime()}, nil
+}
+
+func (s *FSStore) Delete(_ context.Context, name string) error {
+	objectPath, err := s.objectPath(name)
+
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(objectPath)
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+func (s *FSStore) NewMultipart(_ context.Context, name string) (string, error) {
+	if err := validateName(name); err != nil {
+		return "", err
+	}
+
+	randBytes := make([]byte, 16)
+
+	_, err := rand.Read(randBytes)
+
+	if err != nil {
+		return "", err
+	}
+
+	uploadID := hex.EncodeToString(randBytes)
+
+	err = os.Mkdir(filepath.Join(s.root, fsUploadsDir, uploadID), 0o700)
+
+	if err != nil {
+		return "", err
+	}
+
+	return uploadID, nil
+}
+
+func (s *FSStore) PutPart(_ context.Context, _, uploadID string, number int, r io.Reader, size int64) (*Part, error) {
+	uploadPath, err := s.uploadPath(uploadID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	hash := md5.New()
+
+	written, err := s.writeFile(
+		filepath.Join(uploadPath, strconv.Itoa(number)),
+		io.TeeReader(io.LimitReader(r, size), hash),
+		size,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Part{Number: number, ETag: hex.EncodeToString(hash.Sum(nil)), Size: written}, nil
+}
+
+func (s *FSStore) CompleteMultipart(_ context.Context, name, uploadID string, parts []Part) error {
+	objectPath, err := s.objectPath(name)
+
+	if err != nil {
+		return err
+	}
+
+	uploadPath, err := s.uploadPath(uploadID)
+
+	if err != nil {
+		return err
+	}
+
+	readers := make([]io.Reader, 0, len(parts))
+
+	for _, part := range parts {
+		partFile, err := os.Open(filepath.Join(uploadPath, strconv.Itoa(part.Number)))
+
+		if err != nil {
+			return mapFSError(err)
+		}
+
+		defer partFile.Close()
+
+		readers = append(readers, partFile)
+	}
+
+	_, err = s.writeFile(objectPath, io.MultiReader(readers...), -1)
+
+	if err != nil {
+		return err
+	}
+
+	return os.RemoveAll(uploadPath)
+}
+
+func (s *FSStore) AbortMultipart(_ context.Context, _, uploadID string) error {
+	uploadPath, err := s.uploadPath(uploadID)
+
+	if err != nil {
+		return err
+	}
+
+	return os.RemoveAll(uploadPath)
+}
+
+// writeFile writes data to temp file and renames it, so reader never sees half written file.
+// Temp file is removed when less than size is written, negative size is not checked
+func (s *FSStore) writeFile(filePath string, r io.Reader, size int64) (int64, error) {
+	tempFile, err := os.CreateTemp(filepath.Join(s.root, fsTempDir), "blob-*")
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer os.Remove(tempFile.Name())
+
+	written, err := io.Copy(tempFile, r)
+
+	if err == nil && size >= 0 && written != size {
+		err = fmt.Errorf("%w: written %d of %d bytes", ErrShortWrite, written, size)
+	}
+
+	if err == nil {
+		err = tempFile.Sync()
+	}
+
+	closeErr := tempFile.Close()
+
+	if err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return written, os.Rename(tempFile.Name(), filePath)
+}
+
+func (s *FSStore) objectPath(name string) (string, error) {
+	if err := validateName(name); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(s.root, name), nil
+}
+
+func (s *FSStore) uploadPath(uploadID string) (string, error) {
+	if err := validateName(uploadID); err != nil {
+		return "", ErrUploadNotFound
+	}
+
+	uploadPath := filepath.Join(s.root, fsUploadsDir, uploadID)
+
+	_, err := os.Stat(uploadPath)
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", ErrUploadNotFound
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return uploadPath, nil
+}
+
+// validateName keeps object inside root folder, names starting with dot are reserved for service folders
+func validateName(name string) error {
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, `/\`) {
+		return ErrInvalidName
+	}
+
+	return nil
+}
+
+func mapFSError(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+
+	return err
+}
@@ -0,0 +1,165 @@
+package blobstore
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func newTestFSStore(t *testing.T) *FSStore {
+	store, err := NewFSStore(t.TempDir())
+	require.NoError(t, err)
+
+	return store
+}
+
+func readObject(t *testing.T, store Store, name string) []byte {
+	object, info, err := store.Open(context.Background(), name)
+	require.NoError(t, err)
+
+	defer object.Close()
+
+	data, err := io.ReadAll(object)
+	require.NoError(t, err)
+	assert.Equal(t, info.Size, int64(len(data)))
+
+	return data
+}
+
+func TestFSStore_Object(t *testing.T) {
+	ctx := context.Background()
+	store := newTestFSStore(t)
+
+	err := store.Put(ctx, "object", strings.NewReader("secret data"), 11)
+	require.NoError(t, err)
+
+	assert.Equal(t, []byte("secret data"), readObject(t, store, "object"))
+
+	info, err := store.Stat(ctx, "object")
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), info.Size)
+
+	require.NoError(t, store.Delete(ctx, "object"))
+	require.NoError(t, store.Delete(ctx, "object"))
+
+	_, err = store.Stat(ctx, "object")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, _, err = store.Open(ctx, "object")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestFSStore_Multipart(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		chunks []string
+	}{
+		{
+			name:   "Parts are joined in order",
+			chunks: []string{"first ", "second ", "third"},
+		},
+		{
+			name: "Upload without parts creates empty object",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestFSStore(t)
+
+			uploadID, err := store.NewMultipart(ctx, "object")
+			require.NoError(t, err)
+
+			parts := make([]Part, 0, len(tt.chunks))
+
+			for i, chunk := range tt.chunks {
+				part, err := store.PutPart(ctx, "object", uploadID, i+1, strings.NewReader(chunk), int64(len(chunk)))
+				require.NoError(t, err)
+
+				parts = append(parts, *part)
+			}
+
+			require.NoError(t, store.CompleteMultipart(ctx, "object", uploadID, parts))
+
+			assert.Equal(t, []byte(strings.Join(tt.chunks, "")), readObject(t, store, "object"))
+
+			_, err = store.PutPart(ctx, "object", uploadID, 1, bytes.NewReader(nil), 0)
+			assert.ErrorIs(t, err, ErrUploadNotFound)
+		})
+	}
+
+	t.Run("Aborted upload doesn't create object", func(t *testing.T) {
+		store := newTestFSStore(t)
+
+		uploadID, err := store.NewMultipart(ctx, "object")
+		require.NoError(t, err)
+
+		_, err = store.PutPart(ctx, "object", uploadID, 1, strings.NewReader("data"), 4)
+		require.NoError(t, err)
+
+		require.NoError(t, store.AbortMultipart(ctx, "object", uploadID))
+
+		_, err = store.Stat(ctx, "object")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestFSStore_InvalidName(t *testing.T) {
+	ctx := context.Background()
+	store := newTestFSStore(t)
+
+	for _, name := range []string{"", ".uploads", "../object", "dir/object"} {
+		err := store.Put(ctx, name, strings.NewReader("data"), 4)
+		assert.ErrorIs(t, err, ErrInvalidName, name)
+	}
+}
+
+func TestFSStore_ShortWrite(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		write func(store *FSStore) error
+	}{
+		{
+			name: "Object",
+			write: func(store *FSStore) error {
+				return store.Put(ctx, "object", strings.NewReader("short"), 11)
+			},
+		},
+		{
+			name: "Part",
+			write: func(store *FSStore) error {
+				uploadID, err := store.NewMultipart(ctx, "object")
+				require.NoError(t, err)
+
+				_, err = store.PutPart(ctx, "object", uploadID, 1, strings.NewReader("short"), 11)
+
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestFSStore(t)
+
+			err := tt.write(store)
+			assert.ErrorIs(t, err, ErrShortWrite)
+
+			_, err = store.Stat(ctx, "object")
+			assert.ErrorIs(t, err, ErrNotFound)
+
+			tempFiles, err := os.ReadDir(filepath.Join(store.root, fsTempDir))
+			require.NoError(t, err)
+			assert.Empty(t, tempFiles)
+		})
+	}
+}
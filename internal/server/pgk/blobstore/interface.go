@@ -0,0 +1,40 @@
+//go:generate ./bin/mockgen -source=./interface.go -destination=./mock/blobstore.go -package=blobstore
+package blobstore
+
+import (
+	"io"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Store keeps uploaded objects by name. Objects are written by multipart upload or by one Put
+type Store interface {
+	Put(ctx context.Context, name string, r io.Reader, size int64) error
+	// Open returns object for reading, object supports seek for range requests
+	Open(ctx context.Context, name string) (Object, *ObjectInfo, error)
+	Stat(ctx context.Context, name string) (*ObjectInfo, error)
+	// Delete succeeds when object is missing
+	Delete(ctx context.Context, name string) error
+
+	NewMultipart(ctx context.Context, name string) (string, error)
+	PutPart(ctx context.Context, name, uploadID string, number int, r io.Reader, size int64) (*Part, error)
+	// CompleteMultipart joins parts in order, upload without parts creates empty object
+	CompleteMultipart(ctx context.Context, name, uploadID string, parts []Part) error
+	AbortMultipart(ctx context.Context, name, uploadID string) error
+}
+
+type Object interface {
+	io.ReadSeekCloser
+}
+
+type ObjectInfo struct {
+	Size    int64
+	ModTime time.Time
+}
+
+type Part struct {
+	Number int
+	ETag   string
+	Size   int64
+}
@@ -0,0 +1,221 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/server/pgk/blobstore/interface.go
+
+// Package blobstore is a generated GoMock package.
+package blobstore
+
+import (
+	io "io"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	blobstore "github.com/shreyner/gophkeeper/internal/server/pgk/blobstore"
+	context "golang.org/x/net/context"
+)
+
+// MockStore is a mock of Store interface.
+type MockStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockStoreMockRecorder
+}
+
+// MockStoreMockRecorder is the mock recorder for MockStore.
+type MockStoreMockRecorder struct {
+	mock *MockStore
+}
+
+// NewMockStore creates a new mock instance.
+func NewMockStore(ctrl *gomock.Controller) *MockStore {
+	mock := &MockStore{ctrl: ctrl}
+	mock.recorder = &MockStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStore) EXPECT() *MockStoreMockRecorder {
+	return m.recorder
+}
+
+// AbortMultipart mocks base method.
+func (m *MockStore) AbortMultipart(ctx context.Context, name, uploadID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AbortMultipart", ctx, name, uploadID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AbortMultipart indicates an expected call of AbortMultipart.
+func (mr *MockStoreMockRecorder) AbortMultipart(ctx, name, uploadID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AbortMultipart", reflect.TypeOf((*MockStore)(nil).AbortMultipart), ctx, name, uploadID)
+}
+
+// CompleteMultipart mocks base method.
+func (m *MockStore) CompleteMultipart(ctx context.Context, name, uploadID string, parts []blobstore.Part) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteMultipart", ctx, name, uploadID, parts)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CompleteMultipart indicates an expected call of CompleteMultipart.
+func (mr *MockStoreMockRecorder) CompleteMultipart(ctx, name, uploadID, parts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteMultipart", reflect.TypeOf((*MockStore)(nil).CompleteMultipart), ctx, name, uploadID, parts)
+}
+
+// Delete mocks base method.
+func (m *MockStore) Delete(ctx context.Context, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockStoreMockRecorder) Delete(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockStore)(nil).Delete), ctx, name)
+}
+
+// NewMultipart mocks base method.
+func (m *MockStore) NewMultipart(ctx context.Context, name string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewMultipart", ctx, name)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NewMultipart indicates an expected call of NewMultipart.
+func (mr *MockStoreMockRecorder) NewMultipart(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewMultipart", reflect.TypeOf((*MockStore)(nil).NewMultipart), ctx, name)
+}
+
+// Open mocks base method.
+func (m *MockStore) Open(ctx context.Context, name string) (blobstore.Object, *blobstore.ObjectInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Open", ctx, name)
+	ret0, _ := ret[0].(blobstore.Object)
+	ret1, _ := ret[1].(*blobstore.ObjectInfo)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Open indicates an expected call of Open.
+func (mr *MockStoreMockRecorder) Open(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Open", reflect.TypeOf((*MockStore)(nil).Open), ctx, name)
+}
+
+// Put mocks base method.
+func (m *MockStore) Put(ctx context.Context, name string, r io.Reader, size int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Put", ctx, name, r, size)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Put indicates an expected call of Put.
+func (mr *MockStoreMockRecorder) Put(ctx, name, r, size interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Put", reflect.TypeOf((*MockStore)(nil).Put), ctx, name, r, size)
+}
+
+// PutPart mocks base method.
+func (m *MockStore) PutPart(ctx context.Context, name, uploadID string, number int, r io.Reader, size int64) (*blobstore.Part, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutPart", ctx, name, uploadID, number, r, size)
+	ret0, _ := ret[0].(*blobstore.Part)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PutPart indicates an expected call of PutPart.
+func (mr *MockStoreMockRecorder) PutPart(ctx, name, uploadID, number, r, size interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutPart", reflect.TypeOf((*MockStore)(nil).PutPart), ctx, name, uploadID, number, r, size)
+}
+
+// Stat mocks base method.
+func (m *MockStore) Stat(ctx context.Context, name string) (*blobstore.ObjectInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stat", ctx, name)
+	ret0, _ := ret[0].(*blobstore.ObjectInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Stat indicates an expected call of Stat.
+func (mr *MockStoreMockRecorder) Stat(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stat", reflect.TypeOf((*MockStore)(nil).Stat), ctx, name)
+}
+
+// MockObject is a mock of Object interface.
+type MockObject struct {
+	ctrl     *gomock.Controller
+	recorder *MockObjectMockRecorder
+}
+
+// MockObjectMockRecorder is the mock recorder for MockObject.
+type MockObjectMockRecorder struct {
+	mock *MockObject
+}
+
+// NewMockObject creates a new mock instance.
+func NewMockObject(ctrl *gomock.Controller) *MockObject {
+	mock := &MockObject{ctrl: ctrl}
+	mock.recorder = &MockObjectMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockObject) EXPECT() *MockObjectMockRecorder {
+	return m.recorder
+}
+
+// Close mocks base method.
+func (m *MockObject) Close() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockObjectMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockObject)(nil).Close))
+}
+
+// Read mocks base method.
+func (m *MockObject) Read(p []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Read", p)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Read indicates an expected call of Read.
+func (mr *MockObjectMockRecorder) Read(p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockObject)(nil).Read), p)
+}
+
+// Seek mocks base method.
+func (m *MockObject) Seek(offset int64, whence int) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Seek", offset, whence)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Seek indicates an expected call of Seek.
+func (mr *MockObjectMockRecorder) Seek(offset, whence interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Seek", reflect.TypeOf((*MockObject)(nil).Seek), offset, whence)
+}
@@ -0,0 +1,117 @@
+package blobstore
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/minio/minio-go/v7"
+	"golang.org/x/net/context"
+)
+
+var (
+	_ Store = (*S3Store)(nil)
+)
+
+// S3Store keeps objects in one bucket, multipart upload is S3 multipart upload
+type S3Store struct {
+	s3     *minio.Core
+	bucket string
+}
+
+func NewS3Store(client *minio.Client, bucket string) *S3Store {
+	store := S3Store{
+		s3:     &minio.Core{Client: client},
+		bucket: bucket,
+	}
+
+	return &store
+}
+
+func (s *S3Store) Put(ctx context.Context, name string, r io.Reader, size int64) error {
+	_, err := s.s3.Client.PutObject(ctx, s.bucket, name, r, size, minio.PutObjectOptions{})
+
+	return err
+}
+
+func (s *S3Store) Open(ctx context.Context, name string) (Object, *ObjectInfo, error) {
+	object, err := s.s3.Client.GetObject(ctx, s.bucket, name, minio.GetObjectOptions{})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := object.Stat()
+
+	if err != nil {
+		_ = object.Close()
+		return nil, nil, mapS3Error(err)
+	}
+
+	return object, &ObjectInfo{Size: info.Size, ModTime: info.LastModified}, nil
+}
+
+func (s *S3Store) Stat(ctx context.Context, name string) (*ObjectInfo, error) {
+	info, err := s.s3.Client.StatObject(ctx, s.bucket, name, minio.StatObjectOptions{})
+
+	if err != nil {
+		return nil, mapS3Error(err)
+	}
+
+	return &ObjectInfo{Size: info.Size, ModTime: info.LastModified}, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, name string) error {
+	return s.s3.Client.RemoveObject(ctx, s.bucket, name, minio.RemoveObjectOptions{})
+}
+
+func (s *S3Store) NewMultipart(ctx context.Context, name string) (string, error) {
+	return s.s3.NewMultipartUpload(ctx, s.bucket, name, minio.PutObjectOptions{})
+}
+
+func (s *S3Store) PutPart(ctx context.Context, name, uploadID string, number int, r io.Reader, size int64) (*Part, error) {
+	objectPart, err := s.s3.PutObjectPart(ctx, s.bucket, name, uploadID, number, r, size, "", "", nil)
+
+	if err != nil {
+		return nil, mapS3Error(err)
+	}
+
+	return &Part{Number: number, ETag: objectPart.ETag, Size: objectPart.Size}, nil
+}
+
+func (s *S3Store) CompleteMultipart(ctx context.Context, name, uploadID string, parts []Part) error {
+	if len(parts) == 0 {
+		// S3 can't complete multipart upload without parts, empty object is put as is
+		err := s.AbortMultipart(ctx, name, uploadID)
+
+		if err != nil {
+			return err
+		}
+
+		return s.Put(ctx, name, bytes.NewReader(nil), 0)
+	}
+
+	completeParts := make([]minio.CompletePart, len(parts))
+
+	for i, part := range parts {
+		completeParts[i] = minio.CompletePart{PartNumber: part.Number, ETag: part.ETag}
+	}
+
+	_, err := s.s3.CompleteMultipartUpload(ctx, s.bucket, name, uploadID, completeParts, minio.PutObjectOptions{})
+
+	return mapS3Error(err)
+}
+
+func (s *S3Store) AbortMultipart(ctx context.Context, name, uploadID string) error {
+	return mapS3Error(s.s3.AbortMultipartUpload(ctx, s.bucket, name, uploadID))
+}
+
+func mapS3Error(err error) error {
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchKey":
+		return ErrNotFound
+	case "NoSuchUpload":
+		return ErrUploadNotFound
+	default:
+		return err
+	}
+}
@@ -25,7 +25,7 @@ type GRPCServer struct {
 	listen net.Listener
 }
 
-func NewGRPCServer(log *zap.Logger, cfg *config.Config, address string, interceptors ...grpc.UnaryServerInterceptor) (*GRPCServer, error) {
+func NewGRPCServer(log *zap.Logger, cfg *config.Config, address string, opts ...grpc.ServerOption) (*GRPCServer, error) {
 	cert, err := tls.X509KeyPair([]byte(cfg.CertFile), []byte(cfg.KeyFile))
 
 	if err != nil {
@@ -38,7 +38,7 @@ func NewGRPCServer(log *zap.Logger, cfg *config.Config, address string, intercep
 		log:    log,
 		errors: make(chan error),
 
-		Server: grpc.NewServer(grpc.Creds(creds), grpc.ChainUnaryInterceptor(interceptors...)),
+		Server: grpc.NewServer(append([]grpc.ServerOption{grpc.Creds(creds)}, opts...)...),
 	}
 
 	listen, err := net.Listen("tcp", address)
@@ -5,3 +5,5 @@ import "errors"
 var ErrTokenInvalid = errors.New("stoken: token invalid")
 var ErrTokenInvalidClaims = errors.New("stoken: token invalid claims")
 var ErrParsingData = errors.New("stoken: parsing data")
+var ErrTokenExpired = errors.New("stoken: token expired")
+var ErrUnknownKey = errors.New("stoken: unknown key")
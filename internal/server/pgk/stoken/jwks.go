@@ -0,0 +1,71 @@
+package stoken
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+)
+
+// JWK is public key in format of RFC 7517
+type JWK struct {
+	KTY string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	KID string `json:"kid"`
+
+	// Ed25519 key
+	CRV string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+
+	// RSA key
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+func NewJWK(key *Key) (*JWK, error) {
+	jwk := JWK{
+		Use: "sig",
+		Alg: key.Method.Alg(),
+		KID: key.KID,
+	}
+
+	switch verifyKey := key.VerifyKey.(type) {
+	case ed25519.PublicKey:
+		jwk.KTY = "OKP"
+		jwk.CRV = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(verifyKey)
+	case *rsa.PublicKey:
+		jwk.KTY = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(verifyKey.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(verifyKey.E)).Bytes())
+	default:
+		return nil, fmt.Errorf("%s %T: %w", "public key", key.VerifyKey, ErrUnknownKey)
+	}
+
+	return &jwk, nil
+}
+
+// JWKS returns public keys which verify tokens, key of the next rotation is published before it signs
+func (s *Service) JWKS() (*JWKSet, error) {
+	keys := s.keys.PublicKeys()
+
+	set := JWKSet{Keys: make([]JWK, 0, len(keys))}
+
+	for i := range keys {
+		jwk, err := NewJWK(&keys[i])
+
+		if err != nil {
+			return nil, err
+		}
+
+		set.Keys = append(set.Keys, *jwk)
+	}
+
+	return &set, nil
+}
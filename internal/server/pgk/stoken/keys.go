@@ -0,0 +1,58 @@
+package stoken
+
+import (
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var (
+	_ KeySet = (*HMACKeySet)(nil)
+)
+
+// Key signs tokens by Method. Kid of key is written to header of token, HMAC key has no kid
+type Key struct {
+	KID       string
+	Method    jwt.SigningMethod
+	SignKey   interface{}
+	VerifyKey interface{}
+}
+
+// KeySet gives key to sign new tokens and keys to verify tokens by kid.
+// Several keys verify tokens during rotation, PublicKeys are published as JWKS
+type KeySet interface {
+	SigningKey() (*Key, error)
+	VerifyingKey(kid string) (*Key, error)
+	PublicKeys() []Key
+}
+
+// HMACKeySet signs tokens by one shared secret, it has no public keys
+type HMACKeySet struct {
+	key Key
+}
+
+func NewHMACKeySet(secret []byte) *HMACKeySet {
+	keySet := HMACKeySet{
+		key: Key{
+			Method:    jwt.SigningMethodHS256,
+			SignKey:   secret,
+			VerifyKey: secret,
+		},
+	}
+
+	return &keySet
+}
+
+func (k *HMACKeySet) SigningKey() (*Key, error) {
+	return &k.key, nil
+}
+
+func (k *HMACKeySet) VerifyingKey(kid string) (*Key, error) {
+	if kid != "" {
+		return nil, ErrUnknownKey
+	}
+
+	return &k.key, nil
+}
+
+func (k *HMACKeySet) PublicKeys() []Key {
+	return nil
+}
@@ -0,0 +1,172 @@
+package stoken
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// testKeySet signs by current key and verifies by all keys
+type testKeySet struct {
+	current string
+	keys    map[string]Key
+}
+
+func (k *testKeySet) SigningKey() (*Key, error) {
+	key := k.keys[k.current]
+
+	return &key, nil
+}
+
+func (k *testKeySet) VerifyingKey(kid string) (*Key, error) {
+	key, ok := k.keys[kid]
+
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	return &key, nil
+}
+
+func (k *testKeySet) PublicKeys() []Key {
+	keys := make([]Key, 0, len(k.keys))
+
+	for _, key := range k.keys {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+func newTestEdDSAKey(t *testing.T, kid string) Key {
+	t.Helper()
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	return Key{KID: kid, Method: jwt.SigningMethodEdDSA, SignKey: private, VerifyKey: public}
+}
+
+func TestService_RotatedKeys(t *testing.T) {
+	keys := &testKeySet{
+		current: "old",
+		keys: map[string]Key{
+			"old": newTestEdDSAKey(t, "old"),
+			"new": newTestEdDSAKey(t, "new"),
+		},
+	}
+
+	s := NewService(keys, 15*time.Minute)
+
+	oldToken, err := s.CreateToken(&Data{ID: UUID1, DeviceID: DeviceUUID1})
+	if err != nil {
+		t.Fatalf("CreateToken() error = %v", err)
+	}
+
+	keys.current = "new"
+
+	newToken, err := s.CreateToken(&Data{ID: UUID1, DeviceID: DeviceUUID1})
+	if err != nil {
+		t.Fatalf("CreateToken() error = %v", err)
+	}
+
+	token, _, err := jwt.NewParser().ParseUnverified(newToken, jwt.MapClaims{})
+	if err != nil {
+		t.Fatalf("ParseUnverified() error = %v", err)
+	}
+
+	if token.Header["kid"] != "new" || token.Header["alg"] != "EdDSA" {
+		t.Errorf("CreateToken() header = %v, want kid new and alg EdDSA", token.Header)
+	}
+
+	for _, tokenString := range []string{oldToken, newToken} {
+		if _, err = s.ParseToken(tokenString); err != nil {
+			t.Errorf("ParseToken() error = %v", err)
+		}
+	}
+
+	delete(keys.keys, "old")
+
+	if _, err = s.ParseToken(oldToken); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("ParseToken() of removed key error = %v, want %v", err, ErrUnknownKey)
+	}
+}
+
+func TestService_ParseTokenAlgorithmOfKey(t *testing.T) {
+	key := newTestEdDSAKey(t, "ed")
+	s := NewService(&testKeySet{current: "ed", keys: map[string]Key{"ed": key}}, 15*time.Minute)
+
+	// Public key is known to everyone from JWKS, it must not be accepted as HMAC secret
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"id":     UUID1.String(),
+		"device": DeviceUUID1.String(),
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	})
+	token.Header["kid"] = "ed"
+
+	tokenString, err := token.SignedString([]byte(key.VerifyKey.(ed25519.PublicKey)))
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+
+	if _, err = s.ParseToken(tokenString); err == nil {
+		t.Errorf("ParseToken() accepted HS256 token signed by public key")
+	}
+}
+
+func TestService_JWKS(t *testing.T) {
+	edKey := newTestEdDSAKey(t, "ed")
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	keys := &testKeySet{
+		current: "ed",
+		keys: map[string]Key{
+			"ed":  edKey,
+			"rsa": {KID: "rsa", Method: jwt.SigningMethodRS256, SignKey: rsaKey, VerifyKey: &rsaKey.PublicKey},
+		},
+	}
+
+	set, err := NewService(keys, 15*time.Minute).JWKS()
+	if err != nil {
+		t.Fatalf("JWKS() error = %v", err)
+	}
+
+	if len(set.Keys) != 2 {
+		t.Fatalf("JWKS() keys = %v, want 2 keys", len(set.Keys))
+	}
+
+	for _, jwk := range set.Keys {
+		switch jwk.KID {
+		case "ed":
+			if jwk.KTY != "OKP" || jwk.CRV != "Ed25519" || jwk.Alg != "EdDSA" || len(jwk.X) != 43 {
+				t.Errorf("JWKS() ed25519 key = %+v", jwk)
+			}
+		case "rsa":
+			if jwk.KTY != "RSA" || jwk.Alg != "RS256" || jwk.E != "AQAB" || len(jwk.N) != 342 {
+				t.Errorf("JWKS() rsa key = %+v", jwk)
+			}
+		default:
+			t.Errorf("JWKS() unexpected key %v", jwk.KID)
+		}
+
+		if jwk.Use != "sig" {
+			t.Errorf("JWKS() use = %v, want sig", jwk.Use)
+		}
+	}
+
+	hmacSet, err := NewService(NewHMACKeySet([]byte("123")), 15*time.Minute).JWKS()
+	if err != nil || len(hmacSet.Keys) != 0 {
+		t.Errorf("JWKS() of HMAC = %v, %v, want no keys", hmacSet, err)
+	}
+}
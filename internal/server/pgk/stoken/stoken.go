@@ -2,7 +2,9 @@
 package stoken
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
@@ -13,29 +15,75 @@ var (
 )
 
 type Data struct {
-	ID uuid.UUID // User uuid
+	ID       uuid.UUID // User uuid
+	DeviceID uuid.UUID // Device uuid, token stops working when device is revoked
+
+	ExpiresAt time.Time // Set by ParseToken, CreateToken uses ttl of service
 }
 
+// challengeTTL limits time to enter second factor code after password
+const challengeTTL = 5 * time.Minute
+
+const challengePurpose = "second-factor"
+
 type Service struct {
-	signKey []byte
+	keys KeySet
+	ttl  time.Duration // lifetime of access token, session is continued by refresh token
+
+	now func() time.Time
 }
 
-func NewService(signKey []byte) *Service {
+func NewService(keys KeySet, ttl time.Duration) *Service {
 	service := Service{
-		signKey: signKey,
+		keys: keys,
+		ttl:  ttl,
+		now:  time.Now,
 	}
 
 	return &service
 }
 
+// keyFunc finds key by kid of token. Algorithm of token must be algorithm of key,
+// otherwise public key could be used as HMAC secret
+func (s *Service) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key, err := s.keys.VerifyingKey(kid)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return key.VerifyKey, nil
+}
+
+// sign signs claims by current key of key set
+func (s *Service) sign(claims jwt.MapClaims) (string, error) {
+	key, err := s.keys.SigningKey()
+
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+
+	if key.KID != "" {
+		token.Header["kid"] = key.KID
+	}
+
+	return token.SignedString(key.SignKey)
+}
+
 func (s *Service) ParseToken(tokenString string) (*Data, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
+	token, err := jwt.Parse(tokenString, s.keyFunc)
 
-		return s.signKey, nil
-	})
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, ErrTokenExpired
+	}
 
 	if err != nil {
 		return nil, err
@@ -51,7 +99,14 @@ func (s *Service) ParseToken(tokenString string) (*Data, error) {
 		return nil, ErrTokenInvalidClaims
 	}
 
-	id := claim["id"].(string)
+	// Token without expiration was issued before access tokens became short-lived
+	exp, ok := claim["exp"].(float64)
+
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", "missing exp", ErrParsingData)
+	}
+
+	id, _ := claim["id"].(string)
 
 	userID, err := uuid.Parse(id)
 
@@ -59,20 +114,90 @@ func (s *Service) ParseToken(tokenString string) (*Data, error) {
 		return nil, fmt.Errorf("%s: %w", "invalid id", ErrParsingData)
 	}
 
+	device, ok := claim["device"].(string)
+
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", "missing device", ErrParsingData)
+	}
+
+	deviceID, err := uuid.Parse(device)
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", "invalid device", ErrParsingData)
+	}
+
 	data := Data{
-		ID: userID,
+		ID:        userID,
+		DeviceID:  deviceID,
+		ExpiresAt: time.Unix(int64(exp), 0),
 	}
 
 	return &data, nil
-
 }
 
 func (s *Service) CreateToken(data *Data) (string, error) {
+	now := s.now()
+
+	mapClaims := jwt.MapClaims{
+		"id":     data.ID.String(),
+		"device": data.DeviceID.String(),
+		"jti":    uuid.NewString(),
+		"iat":    now.Unix(),
+		"exp":    now.Add(s.ttl).Unix(),
+	}
+
+	return s.sign(mapClaims)
+}
+
+// CreateChallenge returns token of user who passed password check and must send second factor code.
+// It has no device claim, so it can't be used as access token
+func (s *Service) CreateChallenge(userID uuid.UUID) (string, error) {
+	now := s.now()
+
 	mapClaims := jwt.MapClaims{
-		"id": data.ID.String(),
+		"id":      userID.String(),
+		"purpose": challengePurpose,
+		"jti":     uuid.NewString(),
+		"iat":     now.Unix(),
+		"exp":     now.Add(challengeTTL).Unix(),
+	}
+
+	return s.sign(mapClaims)
+}
+
+// ParseChallenge returns user of challenge created by CreateChallenge
+func (s *Service) ParseChallenge(tokenString string) (uuid.UUID, error) {
+	token, err := jwt.Parse(tokenString, s.keyFunc)
+
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return uuid.Nil, ErrTokenExpired
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, mapClaims)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	claim, ok := token.Claims.(jwt.MapClaims)
+
+	if !ok || !token.Valid {
+		return uuid.Nil, ErrTokenInvalidClaims
+	}
+
+	if purpose, _ := claim["purpose"].(string); purpose != challengePurpose {
+		return uuid.Nil, fmt.Errorf("%s: %w", "not challenge", ErrTokenInvalid)
+	}
+
+	if _, ok := claim["exp"]; !ok {
+		return uuid.Nil, fmt.Errorf("%s: %w", "missing exp", ErrParsingData)
+	}
+
+	id, _ := claim["id"].(string)
+
+	userID, err := uuid.Parse(id)
+
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", "invalid id", ErrParsingData)
+	}
 
-	return token.SignedString(s.signKey)
+	return userID, nil
 }
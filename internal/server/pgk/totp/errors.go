@@ -0,0 +1,5 @@
+package totp
+
+import "errors"
+
+var ErrInvalidSecret = errors.New("totp: invalid secret")
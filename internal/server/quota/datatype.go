@@ -0,0 +1,12 @@
+package quota
+
+// Limits of one user, zero means unlimited
+type Limits struct {
+	MaxBlobBytes int64
+	MaxItems     int64
+}
+
+type Usage struct {
+	BlobBytes int64
+	Items     int64
+}
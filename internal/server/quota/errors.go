@@ -0,0 +1,5 @@
+package quota
+
+import "errors"
+
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
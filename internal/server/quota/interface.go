@@ -0,0 +1,18 @@
+package quota
+
+import (
+	"github.com/google/uuid"
+	"golang.org/x/net/context"
+)
+
+var (
+	_ UsageRepository = (*Repository)(nil)
+)
+
+// UsageRepository keeps reserved bytes of users
+type UsageRepository interface {
+	Usage(ctx context.Context, userID uuid.UUID) (*Usage, error)
+	// ReserveBytes returns ErrQuotaExceeded when usage doesn't fit maxBytes, zero maxBytes means unlimited
+	ReserveBytes(ctx context.Context, userID uuid.UUID, size, maxBytes int64) error
+	ReleaseBytes(ctx context.Context, userID uuid.UUID, size int64) error
+}
@@ -0,0 +1,78 @@
+package quota
+
+import (
+	"database/sql"
+
+	"github.com/google/uuid"
+	"golang.org/x/net/context"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	repository := Repository{db: db}
+
+	return &repository
+}
+
+// Usage returns reserved blob bytes and count of not deleted vaults
+func (r *Repository) Usage(ctx context.Context, userID uuid.UUID) (*Usage, error) {
+	usage := Usage{}
+
+	err := r.db.QueryRowContext(
+		ctx,
+		`select
+			coalesce((select blob_bytes from user_usages where user_id = $1), 0),
+			(select count(*) from vaults where user_id = $1 and is_deleted = false);`,
+		userID,
+	).Scan(&usage.BlobBytes, &usage.Items)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &usage, nil
+}
+
+// ReserveBytes adds size to user usage when result fits maxBytes, otherwise returns ErrQuotaExceeded.
+// Zero maxBytes means unlimited
+func (r *Repository) ReserveBytes(ctx context.Context, userID uuid.UUID, size, maxBytes int64) error {
+	result, err := r.db.ExecContext(
+		ctx,
+		`insert into user_usages as u (user_id, blob_bytes) values ($1, $2)
+		on conflict (user_id) do update set blob_bytes = u.blob_bytes + excluded.blob_bytes
+		where $3 = 0 or u.blob_bytes + excluded.blob_bytes <= $3;`,
+		userID,
+		size,
+		maxBytes,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	countAffected, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if countAffected == 0 {
+		return ErrQuotaExceeded
+	}
+
+	return nil
+}
+
+func (r *Repository) ReleaseBytes(ctx context.Context, userID uuid.UUID, size int64) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`update user_usages set blob_bytes = greatest(blob_bytes - $2, 0) where user_id = $1;`,
+		userID,
+		size,
+	)
+
+	return err
+}
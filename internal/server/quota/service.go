@@ -0,0 +1,42 @@
+package quota
+
+import (
+	"github.com/google/uuid"
+	"golang.org/x/net/context"
+)
+
+type Service struct {
+	rep    UsageRepository
+	limits Limits
+}
+
+func NewService(rep UsageRepository, limits Limits) *Service {
+	service := Service{
+		rep:    rep,
+		limits: limits,
+	}
+
+	return &service
+}
+
+func (s *Service) Limits() Limits {
+	return s.limits
+}
+
+func (s *Service) Usage(ctx context.Context, userID uuid.UUID) (*Usage, error) {
+	return s.rep.Usage(ctx, userID)
+}
+
+// ReserveBytes must be called before blob data is stored, reserved bytes are counted
+// until ReleaseBytes even if storing fails
+func (s *Service) ReserveBytes(ctx context.Context, userID uuid.UUID, size int64) error {
+	if s.limits.MaxBlobBytes != 0 && size > s.limits.MaxBlobBytes {
+		return ErrQuotaExceeded
+	}
+
+	return s.rep.ReserveBytes(ctx, userID, size, s.limits.MaxBlobBytes)
+}
+
+func (s *Service) ReleaseBytes(ctx context.Context, userID uuid.UUID, size int64) error {
+	return s.rep.ReleaseBytes(ctx, userID, size)
+}
@@ -0,0 +1,122 @@
+package quota
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// memoryRepository reserves bytes like queries of Repository
+type memoryRepository struct {
+	bytes map[uuid.UUID]int64
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{bytes: make(map[uuid.UUID]int64)}
+}
+
+func (r *memoryRepository) Usage(_ context.Context, userID uuid.UUID) (*Usage, error) {
+	return &Usage{BlobBytes: r.bytes[userID]}, nil
+}
+
+func (r *memoryRepository) ReserveBytes(_ context.Context, userID uuid.UUID, size, maxBytes int64) error {
+	if maxBytes != 0 && r.bytes[userID]+size > maxBytes {
+		return ErrQuotaExceeded
+	}
+
+	r.bytes[userID] += size
+
+	return nil
+}
+
+func (r *memoryRepository) ReleaseBytes(_ context.Context, userID uuid.UUID, size int64) error {
+	r.bytes[userID] -= size
+
+	if r.bytes[userID] < 0 {
+		r.bytes[userID] = 0
+	}
+
+	return nil
+}
+
+func TestService_ReserveRelease(t *testing.T) {
+	type step struct {
+		reserve int64
+		release int64
+		wantErr error
+	}
+
+	tests := []struct {
+		name      string
+		maxBytes  int64
+		steps     []step
+		wantUsage int64
+	}{
+		{
+			name:      "Reserve within limit",
+			maxBytes:  100,
+			steps:     []step{{reserve: 40}, {reserve: 60}},
+			wantUsage: 100,
+		},
+		{
+			name:      "Reserve over limit is refused",
+			maxBytes:  100,
+			steps:     []step{{reserve: 60}, {reserve: 50, wantErr: ErrQuotaExceeded}},
+			wantUsage: 60,
+		},
+		{
+			name:      "Blob bigger than limit is refused",
+			maxBytes:  100,
+			steps:     []step{{reserve: 101, wantErr: ErrQuotaExceeded}},
+			wantUsage: 0,
+		},
+		{
+			name:      "Deleted blob frees quota",
+			maxBytes:  100,
+			steps:     []step{{reserve: 60}, {release: 60}, {reserve: 90}},
+			wantUsage: 90,
+		},
+		{
+			name:      "Usage doesn't become negative",
+			maxBytes:  100,
+			steps:     []step{{reserve: 10}, {release: 30}},
+			wantUsage: 0,
+		},
+		{
+			name:      "Zero limit is unlimited",
+			steps:     []step{{reserve: 1 << 40}, {reserve: 1 << 40}},
+			wantUsage: 1 << 41,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			userID := uuid.New()
+
+			s := NewService(newMemoryRepository(), Limits{MaxBlobBytes: tt.maxBytes})
+
+			for _, st := range tt.steps {
+				var err error
+
+				if st.reserve != 0 {
+					err = s.ReserveBytes(ctx, userID, st.reserve)
+				} else {
+					err = s.ReleaseBytes(ctx, userID, st.release)
+				}
+
+				assert.ErrorIs(t, err, st.wantErr)
+			}
+
+			usage, err := s.Usage(ctx, userID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantUsage, usage.BlobBytes)
+
+			other, err := s.Usage(ctx, uuid.New())
+			require.NoError(t, err)
+			assert.Zero(t, other.BlobBytes, "usage of other user is not changed")
+		})
+	}
+}
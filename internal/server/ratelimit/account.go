@@ -0,0 +1,20 @@
+package ratelimit
+
+import (
+	"github.com/google/uuid"
+)
+
+// LoginAccount is account of attempt to log in by password
+func LoginAccount(login string) string {
+	return "login:" + login
+}
+
+// UserAccount is account of attempt of known user, like second factor of login
+func UserAccount(userID uuid.UUID) string {
+	return "user:" + userID.String()
+}
+
+// AccountKey is key of store keeping failures of account
+func AccountKey(account string) string {
+	return kindAccount + ":" + account
+}
@@ -0,0 +1,26 @@
+package ratelimit
+
+import "time"
+
+// Policy is the lockout of one kind of key. Lockout starts after MaxFailures and doubles with every next failure
+type Policy struct {
+	MaxFailures int
+	// Window is time after the last failure or lockout when failures are forgotten
+	Window      time.Duration
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+}
+
+// Entry is state of failures of one key
+type Entry struct {
+	Failures    int
+	LockedUntil time.Time
+	ExpiresAt   time.Time
+}
+
+// Attempt is a request checked by limiter. Account is empty when the request has no account, then only ip is limited
+type Attempt struct {
+	Source  string
+	IP      string
+	Account string
+}
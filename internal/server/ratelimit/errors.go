@@ -0,0 +1,5 @@
+package ratelimit
+
+import "errors"
+
+var ErrUnknownStore = errors.New("unknown rate limit store")
@@ -0,0 +1,30 @@
+package ratelimit
+
+import (
+	"sync/atomic"
+
+	"golang.org/x/net/context"
+)
+
+type failureKey struct{}
+
+// failure is reported by handler when check of credentials or second factor failed.
+// Other errors of request, like expired token, are not counted by limiter
+type failure struct {
+	isFailed atomic.Bool
+}
+
+// WithFailure returns context of limited request and func telling if handler reported failed check by Failed
+func WithFailure(ctx context.Context) (context.Context, func() bool) {
+	f := failure{}
+
+	return context.WithValue(ctx, failureKey{}, &f), f.isFailed.Load
+}
+
+// Failed marks attempt of request as failed check of credentials or second factor.
+// It does nothing when request is not limited
+func Failed(ctx context.Context) {
+	if f, ok := ctx.Value(failureKey{}).(*failure); ok {
+		f.isFailed.Store(true)
+	}
+}
@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"database/sql"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Repository is postgres store, limits are shared between replicas of server
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	repository := Repository{db: db}
+
+	return &repository
+}
+
+func (r *Repository) Get(ctx context.Context, key string) (Entry, error) {
+	entry := Entry{}
+
+	err := r.db.QueryRowContext(
+		ctx,
+		`select failures, locked_until, expires_at from rate_limits where key = $1;`,
+		key,
+	).Scan(&entry.Failures, &entry.LockedUntil, &entry.ExpiresAt)
+
+	if err == sql.ErrNoRows {
+		return Entry{}, nil
+	}
+
+	if err != nil {
+		return Entry{}, err
+	}
+
+	return entry, nil
+}
+
+// Update locks row of the key, so concurrent failures on different replicas are all counted
+func (r *Repository) Update(ctx context.Context, key string, fn func(entry *Entry)) (Entry, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return Entry{}, err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	_, err = tx.ExecContext(ctx, `insert into rate_limits (key) values ($1) on conflict (key) do nothing;`, key)
+
+	if err != nil {
+		return Entry{}, err
+	}
+
+	entry := Entry{}
+
+	err = tx.QueryRowContext(
+		ctx,
+		`select failures, locked_until, expires_at from rate_limits where key = $1 for update;`,
+		key,
+	).Scan(&entry.Failures, &entry.LockedUntil, &entry.ExpiresAt)
+
+	if err != nil {
+		return Entry{}, err
+	}
+
+	fn(&entry)
+
+	_, err = tx.ExecContext(
+		ctx,
+		`update rate_limits set failures = $2, locked_until = $3, expires_at = $4 where key = $1;`,
+		key,
+		entry.Failures,
+		entry.LockedUntil,
+		entry.ExpiresAt,
+	)
+
+	if err != nil {
+		return Entry{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return Entry{}, err
+	}
+
+	return entry, nil
+}
+
+func (r *Repository) Delete(ctx context.Context, key string) error {
+	_, err := r.db.ExecContext(ctx, `delete from rate_limits where key = $1;`, key)
+
+	return err
+}
+
+func (r *Repository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `delete from rate_limits where expires_at < $1;`, now)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,264 @@
+package ratelimit
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+)
+
+const (
+	kindAccount = "account"
+	kindIP      = "ip"
+)
+
+// Service counts failed attempts by account and by ip and locks them out.
+// Policy with zero MaxFailures disables limit of its kind
+type Service struct {
+	log     *zap.Logger
+	store   Store
+	account Policy
+	ip      Policy
+
+	now func() time.Time
+}
+
+func NewService(log *zap.Logger, store Store, account, ip Policy) *Service {
+	service := Service{
+		log:     log,
+		store:   store,
+		account: account,
+		ip:      ip,
+		now:     time.Now,
+	}
+
+	return &service
+}
+
+type limitKey struct {
+	kind   string
+	key    string
+	policy Policy
+}
+
+func (s *Service) keys(attempt Attempt) []limitKey {
+	keys := make([]limitKey, 0, 2)
+
+	if attempt.Account != "" && s.account.MaxFailures > 0 {
+		keys = append(keys, limitKey{kind: kindAccount, key: AccountKey(attempt.Account), policy: s.account})
+	}
+
+	if attempt.IP != "" && s.ip.MaxFailures > 0 {
+		keys = append(keys, limitKey{kind: kindIP, key: kindIP + ":" + attempt.IP, policy: s.ip})
+	}
+
+	return keys
+}
+
+// Check returns time to wait when account or ip of attempt is locked out, zero when attempt is allowed
+func (s *Service) Check(ctx context.Context, attempt Attempt) (time.Duration, error) {
+	now := s.now()
+
+	var retryAfter time.Duration
+
+	for _, k := range s.keys(attempt) {
+		entry, err := s.store.Get(ctx, k.key)
+
+		if err != nil {
+			return 0, err
+		}
+
+		if wait := entry.LockedUntil.Sub(now); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+
+	return retryAfter, nil
+}
+
+// Fail counts failed attempt for account and ip
+func (s *Service) Fail(ctx context.Context, attempt Attempt) error {
+	now := s.now()
+
+	fields := []zap.Field{
+		zap.String("source", attempt.Source),
+		zap.String("ip", attempt.IP),
+		zap.String("account", attempt.Account),
+	}
+
+	for _, k := range s.keys(attempt) {
+		policy := k.policy
+
+		entry, err := s.store.Update(ctx, k.key, func(entry *Entry) {
+			registerFailure(entry, policy, now)
+		})
+
+		if err != nil {
+			return err
+		}
+
+		fields = append(fields, zap.Int(k.kind+"_failures", entry.Failures))
+
+		if entry.LockedUntil.After(now) {
+			fields = append(fields, zap.Time(k.kind+"_locked_until", entry.LockedUntil))
+		}
+	}
+
+	s.log.Warn("failed attempt", fields...)
+
+	return nil
+}
+
+// Reserve counts attempt as failed before it is made and returns time to wait when account or ip is locked out.
+// Concurrent attempts can't pass lockout this way, only MaxFailures of them are reserved. Attempt which is not
+// a failed check must be given back by Release or Succeed
+func (s *Service) Reserve(ctx context.Context, attempt Attempt) (time.Duration, error) {
+	now := s.now()
+
+	keys := s.keys(attempt)
+
+	for i, k := range keys {
+		policy := k.policy
+
+		var retryAfter time.Duration
+
+		entry, err := s.store.Update(ctx, k.key, func(entry *Entry) {
+			if entry.LockedUntil.After(now) {
+				retryAfter = entry.LockedUntil.Sub(now)
+				return
+			}
+
+			registerFailure(entry, policy, now)
+		})
+
+		if err == nil && retryAfter == 0 {
+			if entry.LockedUntil.After(now) {
+				s.log.Warn(
+					"locked out",
+					zap.String("source", attempt.Source),
+					zap.String("key", k.key),
+					zap.Int("failures", entry.Failures),
+					zap.Time("locked_until", entry.LockedUntil),
+				)
+			}
+
+			continue
+		}
+
+		if errRelease := s.release(ctx, keys[:i], now); errRelease != nil && err == nil {
+			err = errRelease
+		}
+
+		return retryAfter, err
+	}
+
+	return 0, nil
+}
+
+// Release gives back reservation of attempt which is not a failed check
+func (s *Service) Release(ctx context.Context, attempt Attempt) error {
+	return s.release(ctx, s.keys(attempt), s.now())
+}
+
+// Succeed forgets failures of account and gives back reservation of ip. Failures of ip are kept,
+// otherwise one valid account resets guessing of others
+func (s *Service) Succeed(ctx context.Context, attempt Attempt) error {
+	keys := s.keys(attempt)
+	ipKeys := make([]limitKey, 0, len(keys))
+
+	for _, k := range keys {
+		if k.kind != kindAccount {
+			ipKeys = append(ipKeys, k)
+			continue
+		}
+
+		if err := s.store.Delete(ctx, k.key); err != nil {
+			return err
+		}
+	}
+
+	return s.release(ctx, ipKeys, s.now())
+}
+
+func (s *Service) release(ctx context.Context, keys []limitKey, now time.Time) error {
+	for _, k := range keys {
+		policy := k.policy
+
+		_, err := s.store.Update(ctx, k.key, func(entry *Entry) {
+			releaseFailure(entry, policy, now)
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Run removes forgotten entries every interval until ctx is done
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		_, err := s.store.DeleteExpired(ctx, s.now())
+
+		if err != nil && ctx.Err() == nil {
+			s.log.Error("rate limit cleanup failed", zap.Error(err))
+		}
+	}
+}
+
+// registerFailure forgets failures after window, locks out from MaxFailures failures and extends window by lockout
+func registerFailure(entry *Entry, policy Policy, now time.Time) {
+	if now.After(entry.ExpiresAt) {
+		entry.Failures = 0
+	}
+
+	entry.Failures++
+
+	if entry.Failures >= policy.MaxFailures {
+		entry.LockedUntil = now.Add(lockout(policy, entry.Failures))
+	}
+
+	last := now
+	if entry.LockedUntil.After(last) {
+		last = entry.LockedUntil
+	}
+
+	entry.ExpiresAt = last.Add(policy.Window)
+}
+
+// releaseFailure undoes failure registered by reservation. Lockout is lifted when the rest of failures
+// doesn't reach MaxFailures, it was started by the released reservation
+func releaseFailure(entry *Entry, policy Policy, now time.Time) {
+	if entry.Failures > 0 {
+		entry.Failures--
+	}
+
+	if entry.Failures < policy.MaxFailures && entry.LockedUntil.After(now) {
+		entry.LockedUntil = time.Time{}
+	}
+}
+
+// lockout doubles BaseLockout with every failure after MaxFailures up to MaxLockout
+func lockout(policy Policy, failures int) time.Duration {
+	duration := policy.BaseLockout
+
+	for i := policy.MaxFailures; i < failures && duration < policy.MaxLockout; i++ {
+		duration *= 2
+	}
+
+	if duration > policy.MaxLockout {
+		duration = policy.MaxLockout
+	}
+
+	return duration
+}
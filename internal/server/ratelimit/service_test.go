@@ -0,0 +1,303 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var testNow = time.Date(2022, 12, 1, 10, 0, 0, 0, time.UTC)
+
+var testPolicy = Policy{
+	MaxFailures: 3,
+	Window:      15 * time.Minute,
+	BaseLockout: time.Minute,
+	MaxLockout:  10 * time.Minute,
+}
+
+func newTestService(now *time.Time) *Service {
+	s := NewService(zap.NewNop(), NewMemoryStore(), testPolicy, Policy{
+		MaxFailures: 5,
+		Window:      15 * time.Minute,
+		BaseLockout: time.Minute,
+		MaxLockout:  10 * time.Minute,
+	})
+	s.now = func() time.Time { return *now }
+
+	return s
+}
+
+func Test_lockout(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		want     time.Duration
+	}{
+		{name: "first lockout", failures: 3, want: time.Minute},
+		{name: "doubled", failures: 4, want: 2 * time.Minute},
+		{name: "doubled twice", failures: 5, want: 4 * time.Minute},
+		{name: "capped", failures: 7, want: 10 * time.Minute},
+		{name: "capped for many failures", failures: 1000, want: 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, lockout(testPolicy, tt.failures))
+		})
+	}
+}
+
+func Test_registerFailure(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry Entry
+		now   time.Time
+		want  Entry
+	}{
+		{
+			name:  "first failure",
+			entry: Entry{},
+			now:   testNow,
+			want:  Entry{Failures: 1, ExpiresAt: testNow.Add(15 * time.Minute)},
+		},
+		{
+			name:  "locked out on max failures",
+			entry: Entry{Failures: 2, ExpiresAt: testNow.Add(time.Minute)},
+			now:   testNow,
+			want: Entry{
+				Failures:    3,
+				LockedUntil: testNow.Add(time.Minute),
+				ExpiresAt:   testNow.Add(16 * time.Minute),
+			},
+		},
+		{
+			name:  "failures are forgotten after window",
+			entry: Entry{Failures: 2, ExpiresAt: testNow.Add(-time.Second)},
+			now:   testNow,
+			want:  Entry{Failures: 1, ExpiresAt: testNow.Add(15 * time.Minute)},
+		},
+		{
+			name: "failure after lockout doubles lockout",
+			entry: Entry{
+				Failures:    3,
+				LockedUntil: testNow.Add(-time.Second),
+				ExpiresAt:   testNow.Add(15 * time.Minute),
+			},
+			now: testNow,
+			want: Entry{
+				Failures:    4,
+				LockedUntil: testNow.Add(2 * time.Minute),
+				ExpiresAt:   testNow.Add(17 * time.Minute),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := tt.entry
+
+			registerFailure(&entry, testPolicy, tt.now)
+
+			assert.Equal(t, tt.want, entry)
+		})
+	}
+}
+
+func TestService_Fail(t *testing.T) {
+	ctx := context.Background()
+	now := testNow
+	s := newTestService(&now)
+
+	attempt := Attempt{Source: "login", IP: "10.0.0.1", Account: "alice"}
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, s.Fail(ctx, attempt))
+	}
+
+	retryAfter, err := s.Check(ctx, attempt)
+	require.NoError(t, err)
+	assert.Zero(t, retryAfter, "allowed before max failures")
+
+	require.NoError(t, s.Fail(ctx, attempt))
+
+	retryAfter, err = s.Check(ctx, attempt)
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, retryAfter, "account is locked out")
+
+	retryAfter, err = s.Check(ctx, Attempt{IP: "10.0.0.2", Account: "alice"})
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, retryAfter, "account is locked out from other ip")
+
+	retryAfter, err = s.Check(ctx, Attempt{IP: "10.0.0.1", Account: "bob"})
+	require.NoError(t, err)
+	assert.Zero(t, retryAfter, "ip is not locked out yet")
+
+	now = now.Add(time.Minute)
+
+	retryAfter, err = s.Check(ctx, attempt)
+	require.NoError(t, err)
+	assert.Zero(t, retryAfter, "lockout is over")
+
+	require.NoError(t, s.Fail(ctx, attempt))
+
+	retryAfter, err = s.Check(ctx, attempt)
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Minute, retryAfter, "lockout is doubled")
+}
+
+func TestService_FailByIP(t *testing.T) {
+	ctx := context.Background()
+	now := testNow
+	s := newTestService(&now)
+
+	for _, account := range []string{"a", "b", "c", "d", "e"} {
+		require.NoError(t, s.Fail(ctx, Attempt{IP: "10.0.0.1", Account: account}))
+	}
+
+	retryAfter, err := s.Check(ctx, Attempt{IP: "10.0.0.1", Account: "f"})
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, retryAfter, "ip is locked out for any account")
+
+	retryAfter, err = s.Check(ctx, Attempt{IP: "10.0.0.2", Account: "f"})
+	require.NoError(t, err)
+	assert.Zero(t, retryAfter)
+}
+
+func TestService_Succeed(t *testing.T) {
+	ctx := context.Background()
+	now := testNow
+	s := newTestService(&now)
+
+	attempt := Attempt{IP: "10.0.0.1", Account: "alice"}
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, s.Fail(ctx, attempt))
+	}
+
+	retryAfter, err := s.Reserve(ctx, attempt)
+	require.NoError(t, err)
+	assert.Zero(t, retryAfter)
+
+	require.NoError(t, s.Succeed(ctx, attempt))
+
+	account, err := s.store.Get(ctx, "account:alice")
+	require.NoError(t, err)
+	assert.Zero(t, account.Failures, "failures of account are forgotten")
+
+	ip, err := s.store.Get(ctx, "ip:10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, ip.Failures, "failures of ip are kept, reservation is given back")
+}
+
+func TestService_Reserve(t *testing.T) {
+	ctx := context.Background()
+	now := testNow
+	s := newTestService(&now)
+
+	attempt := Attempt{Source: "login", IP: "10.0.0.1", Account: "alice"}
+
+	results := make(chan time.Duration, 10)
+
+	for i := 0; i < 10; i++ {
+		go func() {
+			retryAfter, err := s.Reserve(ctx, attempt)
+			assert.NoError(t, err)
+
+			results <- retryAfter
+		}()
+	}
+
+	reserved := 0
+
+	for i := 0; i < 10; i++ {
+		if <-results == 0 {
+			reserved++
+		}
+	}
+
+	assert.Equal(t, testPolicy.MaxFailures, reserved, "concurrent attempts are reserved up to max failures")
+
+	ip, err := s.store.Get(ctx, "ip:10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, testPolicy.MaxFailures, ip.Failures, "attempt locked by account is not counted for ip")
+
+	require.NoError(t, s.Release(ctx, attempt))
+
+	retryAfter, err := s.Check(ctx, attempt)
+	require.NoError(t, err)
+	assert.Zero(t, retryAfter, "lockout is lifted when reservation is given back")
+
+	retryAfter, err = s.Reserve(ctx, attempt)
+	require.NoError(t, err)
+	assert.Zero(t, retryAfter)
+
+	retryAfter, err = s.Reserve(ctx, Attempt{IP: "10.0.0.2", Account: "alice"})
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, retryAfter, "account is locked out from other ip")
+
+	other, err := s.store.Get(ctx, "ip:10.0.0.2")
+	require.NoError(t, err)
+	assert.Zero(t, other.Failures, "locked attempt reserves nothing")
+}
+
+func Test_releaseFailure(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry Entry
+		want  Entry
+	}{
+		{
+			name:  "failure is given back",
+			entry: Entry{Failures: 2, ExpiresAt: testNow.Add(time.Minute)},
+			want:  Entry{Failures: 1, ExpiresAt: testNow.Add(time.Minute)},
+		},
+		{
+			name:  "lockout of released failure is lifted",
+			entry: Entry{Failures: 3, LockedUntil: testNow.Add(time.Minute), ExpiresAt: testNow.Add(16 * time.Minute)},
+			want:  Entry{Failures: 2, ExpiresAt: testNow.Add(16 * time.Minute)},
+		},
+		{
+			name:  "lockout of other failures is kept",
+			entry: Entry{Failures: 4, LockedUntil: testNow.Add(time.Minute), ExpiresAt: testNow.Add(16 * time.Minute)},
+			want:  Entry{Failures: 3, LockedUntil: testNow.Add(time.Minute), ExpiresAt: testNow.Add(16 * time.Minute)},
+		},
+		{
+			name:  "forgotten entry",
+			entry: Entry{},
+			want:  Entry{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := tt.entry
+
+			releaseFailure(&entry, testPolicy, testNow)
+
+			assert.Equal(t, tt.want, entry)
+		})
+	}
+}
+
+func TestMemoryStore_DeleteExpired(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	_, err := store.Update(ctx, "expired", func(entry *Entry) { entry.ExpiresAt = testNow.Add(-time.Second) })
+	require.NoError(t, err)
+
+	_, err = store.Update(ctx, "active", func(entry *Entry) { entry.ExpiresAt = testNow.Add(time.Second) })
+	require.NoError(t, err)
+
+	count, err := store.DeleteExpired(ctx, testNow)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	active, err := store.Get(ctx, "active")
+	require.NoError(t, err)
+	assert.Equal(t, testNow.Add(time.Second), active.ExpiresAt)
+}
@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Store keeps entries of limiter. Update must be atomic for the key, entries are shared between replicas by postgres store
+type Store interface {
+	Get(ctx context.Context, key string) (Entry, error)
+	Update(ctx context.Context, key string, fn func(entry *Entry)) (Entry, error)
+	Delete(ctx context.Context, key string) error
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+var (
+	_ Store = (*MemoryStore)(nil)
+	_ Store = (*Repository)(nil)
+)
+
+// MemoryStore keeps entries in memory of one replica
+type MemoryStore struct {
+	mux     sync.Mutex
+	entries map[string]Entry
+}
+
+func NewMemoryStore() *MemoryStore {
+	store := MemoryStore{
+		entries: make(map[string]Entry),
+	}
+
+	return &store
+}
+
+func (s *MemoryStore) Get(_ context.Context, key string) (Entry, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	return s.entries[key], nil
+}
+
+func (s *MemoryStore) Update(_ context.Context, key string, fn func(entry *Entry)) (Entry, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	entry := s.entries[key]
+	fn(&entry)
+	s.entries[key] = entry
+
+	return entry, nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, key string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	delete(s.entries, key)
+
+	return nil
+}
+
+func (s *MemoryStore) DeleteExpired(_ context.Context, now time.Time) (int64, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	var count int64
+
+	for key, entry := range s.entries {
+		if entry.ExpiresAt.Before(now) {
+			delete(s.entries, key)
+			count++
+		}
+	}
+
+	return count, nil
+}
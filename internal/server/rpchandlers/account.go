@@ -0,0 +1,145 @@
+package rpchandlers
+
+import (
+	"errors"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/shreyner/gophkeeper/internal/server/auth"
+	interceptorauth "github.com/shreyner/gophkeeper/internal/server/interceptor/auth"
+	"github.com/shreyner/gophkeeper/internal/server/ratelimit"
+	"github.com/shreyner/gophkeeper/internal/server/upload"
+	pb "github.com/shreyner/gophkeeper/proto"
+)
+
+const exportBlobPageSize = 500
+
+// AccountExport streams encrypted vaults and references of blobs of user, deleted vaults are skipped
+func (s *GophkeeperServer) AccountExport(_ *empty.Empty, stream pb.Gophkeeper_AccountExportServer) error {
+	ctx := stream.Context()
+
+	tokenData, ok := interceptorauth.GetTokenDataCtx(ctx)
+	if !ok {
+		return status.Error(codes.PermissionDenied, "Не авторизован")
+	}
+
+	login, err := s.accountService.FindLogin(ctx, tokenData.ID)
+
+	if err != nil {
+		s.log.Error("can't load login", zap.Error(err))
+		return status.Error(codes.Internal, "error export account")
+	}
+
+	err = stream.Send(&pb.AccountExportPage{Login: login})
+
+	if err != nil {
+		return err
+	}
+
+	var cursor int64
+
+	for {
+		changedVaults, nextCursor, hasMore, err := s.vaultService.LoadChangesPage(ctx, tokenData.ID, cursor, maxSyncPageSize, maxSyncPageBytes)
+
+		if err != nil {
+			s.log.Error("can't load vaults for export", zap.Error(err))
+			return status.Error(codes.Internal, "error export account")
+		}
+
+		vaults := vaultsToResponse(changedVaults)
+		liveVaults := vaults[:0]
+
+		for _, v := range vaults {
+			if !v.IsDeleted {
+				liveVaults = append(liveVaults, v)
+			}
+		}
+
+		if len(liveVaults) != 0 {
+			if err = stream.Send(&pb.AccountExportPage{Vaults: liveVaults}); err != nil {
+				return err
+			}
+		}
+
+		if !hasMore {
+			break
+		}
+
+		cursor = nextCursor
+	}
+
+	afterName := ""
+
+	for {
+		blobs, err := s.blobService.List(ctx, tokenData.ID, afterName, exportBlobPageSize)
+
+		if err != nil {
+			s.log.Error("can't load blobs for export", zap.Error(err))
+			return status.Error(codes.Internal, "error export account")
+		}
+
+		if len(blobs) == 0 {
+			return nil
+		}
+
+		responseBlobs := make([]*pb.AccountBlob, 0, len(blobs))
+
+		for _, b := range blobs {
+			responseBlob := pb.AccountBlob{
+				ObjectName: b.ObjectName,
+				Size:       b.Size,
+				CreatedAt:  timestamppb.New(b.CreatedAt),
+				Location:   upload.Location(b.ObjectName),
+			}
+
+			if b.VaultID != nil {
+				responseBlob.VaultId = wrapperspb.String(b.VaultID.String())
+			}
+
+			responseBlobs = append(responseBlobs, &responseBlob)
+		}
+
+		if err = stream.Send(&pb.AccountExportPage{Blobs: responseBlobs}); err != nil {
+			return err
+		}
+
+		afterName = blobs[len(blobs)-1].ObjectName
+	}
+}
+
+// AccountDelete removes account after password and second factor are checked again
+func (s *GophkeeperServer) AccountDelete(ctx context.Context, in *pb.AccountDeleteRequest) (*empty.Empty, error) {
+	tokenData, ok := interceptorauth.GetTokenDataCtx(ctx)
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, "Не авторизован")
+	}
+
+	err := s.authService.Reauthenticate(ctx, tokenData.ID, in.Password, in.Code)
+
+	switch {
+	case err == nil:
+	case errors.Is(err, auth.ErrInvalidCredentials), errors.Is(err, auth.ErrInvalidSecondFactor):
+		ratelimit.Failed(ctx)
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, auth.ErrSecondFactorRequired):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		s.log.Error("can't reauthenticate user", zap.Error(err))
+		return nil, status.Error(codes.Internal, "error delete account")
+	}
+
+	err = s.accountService.Delete(ctx, tokenData.ID)
+
+	if err != nil {
+		s.log.Error("can't delete account", zap.Error(err))
+		return nil, status.Error(codes.Internal, "error delete account")
+	}
+
+	return &empty.Empty{}, nil
+}
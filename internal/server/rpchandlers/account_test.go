@@ -0,0 +1,122 @@
+package rpchandlers_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/shreyner/gophkeeper/internal/server/account"
+	accountmock "github.com/shreyner/gophkeeper/internal/server/account/mock"
+	"github.com/shreyner/gophkeeper/internal/server/auth"
+	authmock "github.com/shreyner/gophkeeper/internal/server/auth/mock"
+	interceptorauth "github.com/shreyner/gophkeeper/internal/server/interceptor/auth"
+	blobstoremock "github.com/shreyner/gophkeeper/internal/server/pgk/blobstore/mock"
+	"github.com/shreyner/gophkeeper/internal/server/pgk/stoken"
+	"github.com/shreyner/gophkeeper/internal/server/ratelimit"
+	"github.com/shreyner/gophkeeper/internal/server/rpchandlers"
+	"github.com/shreyner/gophkeeper/internal/server/user"
+	pb "github.com/shreyner/gophkeeper/proto"
+)
+
+func TestGophkeeperServer_AccountDelete(t *testing.T) {
+	errDB := errors.New("database is unavailable")
+
+	tests := []struct {
+		name        string
+		totpEnabled bool
+		request     *pb.AccountDeleteRequest
+		deleteErr   error
+		wantDeleted bool
+		wantFailed  bool
+		wantCode    codes.Code
+	}{
+		{
+			name:        "Account is deleted after password is checked",
+			request:     &pb.AccountDeleteRequest{Password: "password"},
+			wantDeleted: true,
+			wantCode:    codes.OK,
+		},
+		{
+			name:       "Wrong password",
+			request:    &pb.AccountDeleteRequest{Password: "wrong-password"},
+			wantFailed: true,
+			wantCode:   codes.PermissionDenied,
+		},
+		{
+			name:        "Missing code of second factor",
+			totpEnabled: true,
+			request:     &pb.AccountDeleteRequest{Password: "password"},
+			wantCode:    codes.FailedPrecondition,
+		},
+		{
+			name:        "Wrong code of second factor",
+			totpEnabled: true,
+			request:     &pb.AccountDeleteRequest{Password: "password", Code: "ABCD-EFGH"},
+			wantFailed:  true,
+			wantCode:    codes.PermissionDenied,
+		},
+		{
+			name:        "Error of deleting",
+			request:     &pb.AccountDeleteRequest{Password: "password"},
+			deleteErr:   errDB,
+			wantDeleted: true,
+			wantCode:    codes.Internal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			userModel := user.UserModel{ID: uuid.New(), Login: "alex"}
+			require.NoError(t, userModel.SetPassword("password"))
+
+			if tt.totpEnabled {
+				userModel.TOTPSecret = "JBSWY3DPEHPK3PXP"
+			}
+
+			userService := authmock.NewMockUserService(ctrl)
+			userService.EXPECT().FindByID(gomock.Any(), userModel.ID).Return(&userModel, nil)
+			userService.EXPECT().UseBackupCode(gomock.Any(), userModel.ID, gomock.Any()).Return(user.ErrBackupCodeInvalid).AnyTimes()
+
+			rep := accountmock.NewMockAccountRepository(ctrl)
+			store := blobstoremock.NewMockStore(ctrl)
+
+			if tt.wantDeleted {
+				deleteCall := rep.EXPECT().Delete(gomock.Any(), userModel.ID)
+
+				if tt.deleteErr != nil {
+					deleteCall.Return(nil, tt.deleteErr)
+				} else {
+					deleteCall.Return(&account.DeletedAccount{ObjectNames: []string{"object-1"}}, nil)
+
+					// Object is removed from store only after rows are deleted
+					store.EXPECT().Delete(gomock.Any(), "object-1").Return(nil).After(deleteCall)
+				}
+			}
+
+			server := rpchandlers.NewGophkeeperServer(
+				zap.NewNop(),
+				auth.NewService(userService, auth.RegistrationOpen, nil, time.Hour),
+				nil, nil, nil, nil, nil, nil,
+				account.NewService(zap.NewNop(), rep, store),
+			)
+
+			ctx := interceptorauth.SetTokenDataCtx(context.Background(), &stoken.Data{ID: userModel.ID, DeviceID: uuid.New()})
+			ctx, isFailed := ratelimit.WithFailure(ctx)
+
+			_, err := server.AccountDelete(ctx, tt.request)
+
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			assert.Equal(t, tt.wantFailed, isFailed(), "failed check is counted by rate limit")
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package rpchandlers
+
+import (
+	"errors"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/shreyner/gophkeeper/internal/server/device"
+	interceptorauth "github.com/shreyner/gophkeeper/internal/server/interceptor/auth"
+	pb "github.com/shreyner/gophkeeper/proto"
+)
+
+func (s *GophkeeperServer) DeviceList(ctx context.Context, _ *empty.Empty) (*pb.DeviceListResponse, error) {
+	tokenData, ok := interceptorauth.GetTokenDataCtx(ctx)
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, "Не авторизован")
+	}
+
+	devices, err := s.deviceService.List(ctx, tokenData.ID)
+
+	if err != nil {
+		s.log.Error("can't load devices", zap.Error(err))
+		return nil, status.Error(codes.Internal, "error load devices")
+	}
+
+	responseDevices := make([]*pb.Device, 0, len(devices))
+
+	for _, d := range devices {
+		responseDevice := pb.Device{
+			Id:             d.ID.String(),
+			Name:           d.Name,
+			Platform:       d.Platform,
+			CreatedAt:      timestamppb.New(d.CreatedAt),
+			LastSeenAt:     timestamppb.New(d.LastSeenAt),
+			LastSyncCursor: d.LastSyncCursor,
+			IsCurrent:      d.ID == tokenData.DeviceID,
+		}
+
+		responseDevices = append(responseDevices, &responseDevice)
+	}
+
+	response := pb.DeviceListResponse{
+		Devices: responseDevices,
+	}
+
+	return &response, nil
+}
+
+func (s *GophkeeperServer) DeviceRename(ctx context.Context, in *pb.DeviceRenameRequest) (*empty.Empty, error) {
+	tokenData, ok := interceptorauth.GetTokenDataCtx(ctx)
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, "Не авторизован")
+	}
+
+	deviceID, err := uuid.Parse(in.Id)
+
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid device ID")
+	}
+
+	err = s.deviceService.Rename(ctx, tokenData.ID, deviceID, in.Name)
+
+	if errors.Is(err, device.ErrDeviceNotFound) {
+		return nil, status.Error(codes.NotFound, "device not found")
+	}
+
+	if err != nil {
+		s.log.Error("can't rename device", zap.Error(err))
+		return nil, status.Error(codes.Internal, "error rename device")
+	}
+
+	return &empty.Empty{}, nil
+}
+
+// DeviceRevoke stops token of device at once, device could be the current one
+func (s *GophkeeperServer) DeviceRevoke(ctx context.Context, in *pb.DeviceRevokeRequest) (*empty.Empty, error) {
+	tokenData, ok := interceptorauth.GetTokenDataCtx(ctx)
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, "Не авторизован")
+	}
+
+	deviceID, err := uuid.Parse(in.Id)
+
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid device ID")
+	}
+
+	err = s.deviceService.Revoke(ctx, tokenData.ID, deviceID)
+
+	if errors.Is(err, device.ErrDeviceNotFound) {
+		return nil, status.Error(codes.NotFound, "device not found")
+	}
+
+	if err != nil {
+		s.log.Error("can't revoke device", zap.Error(err))
+		return nil, status.Error(codes.Internal, "error revoke device")
+	}
+
+	return &empty.Empty{}, nil
+}
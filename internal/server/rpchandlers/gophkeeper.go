@@ -10,10 +10,17 @@ import (
 	"golang.org/x/net/context"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 
+	"github.com/shreyner/gophkeeper/internal/server/account"
 	"github.com/shreyner/gophkeeper/internal/server/auth"
+	"github.com/shreyner/gophkeeper/internal/server/blob"
+	"github.com/shreyner/gophkeeper/internal/server/device"
 	interceptorauth "github.com/shreyner/gophkeeper/internal/server/interceptor/auth"
+	"github.com/shreyner/gophkeeper/internal/server/quota"
+	"github.com/shreyner/gophkeeper/internal/server/ratelimit"
+	"github.com/shreyner/gophkeeper/internal/server/user"
 	"github.com/shreyner/gophkeeper/internal/server/vault"
 	pb "github.com/shreyner/gophkeeper/proto"
 )
@@ -22,13 +29,26 @@ var (
 	_ pb.GophkeeperServer = (*GophkeeperServer)(nil)
 )
 
+const (
+	maxBatchOperations = 500
+
+	defaultSyncPageSize = 100
+	maxSyncPageSize     = 500
+	maxSyncPageBytes    = 1 << 20 // keeps page far below default grpc message limit of 4MB
+)
+
 type GophkeeperServer struct {
 	pb.UnimplementedGophkeeperServer
 
-	log          *zap.Logger
-	authService  *auth.Service
-	vaultService *vault.Service
-	stoken       *stoken.Service
+	log            *zap.Logger
+	authService    *auth.Service
+	vaultService   *vault.Service
+	vaultNotifier  *vault.Notifier
+	quotaService   *quota.Service
+	deviceService  *device.Service
+	blobService    *blob.Service
+	accountService *account.Service
+	stoken         *stoken.Service
 }
 
 func NewGophkeeperServer(
@@ -36,35 +56,119 @@ func NewGophkeeperServer(
 	authService *auth.Service,
 	stoken *stoken.Service,
 	vaultService *vault.Service,
+	vaultNotifier *vault.Notifier,
+	quotaService *quota.Service,
+	deviceService *device.Service,
+	blobService *blob.Service,
+	accountService *account.Service,
 ) *GophkeeperServer {
 	return &GophkeeperServer{
-		log:          log,
-		authService:  authService,
-		stoken:       stoken,
-		vaultService: vaultService,
+		log:            log,
+		authService:    authService,
+		stoken:         stoken,
+		vaultService:   vaultService,
+		vaultNotifier:  vaultNotifier,
+		quotaService:   quotaService,
+		deviceService:  deviceService,
+		blobService:    blobService,
+		accountService: accountService,
+	}
+}
+
+func (s *GophkeeperServer) Register(ctx context.Context, in *pb.RegisterRequest) (*pb.LoginResponse, error) {
+	userModel, err := s.authService.Register(ctx, in.Login, in.Password, in.InviteCode)
+
+	switch {
+	case err == nil:
+	case errors.Is(err, auth.ErrInvalidLogin), errors.Is(err, auth.ErrWeakPassword), errors.Is(err, auth.ErrInviteRequired):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, auth.ErrRegistrationClosed):
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, user.ErrInviteInvalid):
+		ratelimit.Failed(ctx)
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, user.ErrLoginAlreadyExist):
+		// Taken logins must not be enumerated
+		ratelimit.Failed(ctx)
+		return nil, status.Error(codes.AlreadyExists, "login already exist")
+	default:
+		s.log.Error("can't register user", zap.Error(err))
+		return nil, status.Error(codes.Internal, "error register user")
 	}
+
+	return s.issueToken(ctx, userModel.ID, in.DeviceName, in.DevicePlatform)
 }
 
 func (s *GophkeeperServer) Login(ctx context.Context, in *pb.LoginRequest) (*pb.LoginResponse, error) {
-	user, err := s.authService.FindOrCreate(ctx, in.Login, in.Password)
+	userModel, err := s.authService.Login(ctx, in.Login, in.Password)
+
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		ratelimit.Failed(ctx)
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
 
 	if err != nil {
-		s.log.Error("can't create or find user", zap.Error(err))
-		return nil, status.Error(codes.Internal, "error create or find user")
+		s.log.Error("can't auth user", zap.Error(err))
+		return nil, status.Error(codes.Internal, "error auth user")
+	}
+
+	if !userModel.IsTOTPEnabled() {
+		return s.issueToken(ctx, userModel.ID, in.DeviceName, in.DevicePlatform)
 	}
 
-	valid, err := user.VerifyPassword(in.Password)
+	challenge, err := s.stoken.CreateChallenge(userModel.ID)
+
+	if err != nil {
+		s.log.Error("can't create challenge", zap.Error(err))
+		return nil, status.Error(codes.Internal, "error auth user")
+	}
+
+	response := pb.LoginResponse{
+		SecondFactorRequired: true,
+		Challenge:            challenge,
+	}
+
+	return &response, nil
+}
+
+// LoginSecondFactor finishes login by code of authenticator app or by backup code
+func (s *GophkeeperServer) LoginSecondFactor(ctx context.Context, in *pb.LoginSecondFactorRequest) (*pb.LoginResponse, error) {
+	userID, err := s.stoken.ParseChallenge(in.Challenge)
+
+	if errors.Is(err, stoken.ErrTokenExpired) {
+		return nil, status.Error(codes.Unauthenticated, "login challenge expired, login again")
+	}
+
+	if err != nil {
+		ratelimit.Failed(ctx)
+		return nil, status.Error(codes.Unauthenticated, "invalid login challenge")
+	}
+
+	userModel, err := s.authService.VerifySecondFactor(ctx, userID, in.Code)
+
+	if errors.Is(err, auth.ErrInvalidSecondFactor) {
+		ratelimit.Failed(ctx)
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
 
 	if err != nil {
-		s.log.Error("can't verification password", zap.Error(err))
+		s.log.Error("can't verify second factor", zap.Error(err))
 		return nil, status.Error(codes.Internal, "error auth user")
 	}
 
-	if !valid {
-		return nil, status.Error(codes.PermissionDenied, "invalid password")
+	return s.issueToken(ctx, userModel.ID, in.DeviceName, in.DevicePlatform)
+}
+
+// issueToken registers device of new session and creates token bound to it
+func (s *GophkeeperServer) issueToken(ctx context.Context, userID uuid.UUID, deviceName, devicePlatform string) (*pb.LoginResponse, error) {
+	deviceModel, err := s.deviceService.Create(ctx, userID, deviceName, devicePlatform)
+
+	if err != nil {
+		s.log.Error("can't create device", zap.Error(err))
+		return nil, status.Error(codes.Internal, "error auth user")
 	}
 
-	tokenData := stoken.Data{ID: user.ID}
+	tokenData := stoken.Data{ID: userID, DeviceID: deviceModel.ID}
 
 	token, err := s.stoken.CreateToken(&tokenData)
 
@@ -73,13 +177,87 @@ func (s *GophkeeperServer) Login(ctx context.Context, in *pb.LoginRequest) (*pb.
 		return nil, status.Error(codes.Internal, "error auth user")
 	}
 
+	refreshToken, err := s.deviceService.IssueRefreshToken(ctx, deviceModel.ID)
+
+	if err != nil {
+		s.log.Error("can't create refresh token", zap.Error(err))
+		return nil, status.Error(codes.Internal, "error auth user")
+	}
+
 	loginResponse := pb.LoginResponse{
-		AuthToken: token,
+		AuthToken:    token,
+		DeviceId:     deviceModel.ID.String(),
+		RefreshToken: refreshToken,
 	}
 
 	return &loginResponse, nil
 }
 
+// RefreshToken rotates refresh token and creates new access token of the same device
+func (s *GophkeeperServer) RefreshToken(ctx context.Context, in *pb.RefreshTokenRequest) (*pb.LoginResponse, error) {
+	deviceModel, refreshToken, err := s.deviceService.RotateRefreshToken(ctx, in.RefreshToken)
+
+	switch {
+	case err == nil:
+	case errors.Is(err, device.ErrRefreshTokenReused):
+		s.log.Warn(
+			"refresh token is reused, device is revoked",
+			zap.String("userID", deviceModel.UserID.String()),
+			zap.String("deviceID", deviceModel.ID.String()),
+		)
+		return nil, status.Error(codes.Unauthenticated, interceptorauth.MessageDeviceRevoked)
+	case errors.Is(err, device.ErrDeviceRevoked):
+		return nil, status.Error(codes.Unauthenticated, interceptorauth.MessageDeviceRevoked)
+	case errors.Is(err, device.ErrRefreshTokenInvalid):
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	default:
+		s.log.Error("can't rotate refresh token", zap.Error(err))
+		return nil, status.Error(codes.Internal, "error refresh token")
+	}
+
+	tokenData := stoken.Data{ID: deviceModel.UserID, DeviceID: deviceModel.ID}
+
+	token, err := s.stoken.CreateToken(&tokenData)
+
+	if err != nil {
+		s.log.Error("can't create token", zap.Error(err))
+		return nil, status.Error(codes.Internal, "error refresh token")
+	}
+
+	response := pb.LoginResponse{
+		AuthToken:    token,
+		DeviceId:     deviceModel.ID.String(),
+		RefreshToken: refreshToken,
+	}
+
+	return &response, nil
+}
+
+func (s *GophkeeperServer) InviteCreate(ctx context.Context, _ *empty.Empty) (*pb.InviteCreateResponse, error) {
+	tokenData, ok := interceptorauth.GetTokenDataCtx(ctx)
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, "Не авторизован")
+	}
+
+	code, invite, err := s.authService.CreateInvite(ctx, tokenData.ID)
+
+	if errors.Is(err, auth.ErrNotAdmin) {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	if err != nil {
+		s.log.Error("can't create invite", zap.Error(err))
+		return nil, status.Error(codes.Internal, "error create invite")
+	}
+
+	response := pb.InviteCreateResponse{
+		Code:      code,
+		ExpiresAt: timestamppb.New(invite.ExpiresAt),
+	}
+
+	return &response, nil
+}
+
 func (s *GophkeeperServer) CheckAuth(ctx context.Context, _ *empty.Empty) (*pb.CheckAuthResponse, error) {
 	_, ok := interceptorauth.GetTokenDataCtx(ctx)
 	if !ok {
@@ -93,6 +271,31 @@ func (s *GophkeeperServer) CheckAuth(ctx context.Context, _ *empty.Empty) (*pb.C
 	return &response, nil
 }
 
+func (s *GophkeeperServer) Usage(ctx context.Context, _ *empty.Empty) (*pb.UsageResponse, error) {
+	tokenData, ok := interceptorauth.GetTokenDataCtx(ctx)
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, "Не авторизован")
+	}
+
+	usage, err := s.quotaService.Usage(ctx, tokenData.ID)
+
+	if err != nil {
+		s.log.Error("can't load usage", zap.Error(err))
+		return nil, status.Error(codes.Internal, "error load usage")
+	}
+
+	limits := s.quotaService.Limits()
+
+	response := pb.UsageResponse{
+		BlobBytes:    usage.BlobBytes,
+		MaxBlobBytes: limits.MaxBlobBytes,
+		Items:        usage.Items,
+		MaxItems:     limits.MaxItems,
+	}
+
+	return &response, nil
+}
+
 func (s *GophkeeperServer) VaultCreate(ctx context.Context, in *pb.VaultCreateRequest) (*pb.VaultCreateResponse, error) {
 	tokenData, ok := interceptorauth.GetTokenDataCtx(ctx)
 	if !ok {
@@ -109,6 +312,14 @@ func (s *GophkeeperServer) VaultCreate(ctx context.Context, in *pb.VaultCreateRe
 
 	vaultModel, err := s.vaultService.Create(ctx, userID, in.Vault, s3ulr)
 
+	if errors.Is(err, vault.ErrBlobNotFound) {
+		return nil, status.Error(codes.FailedPrecondition, "uploaded file not found")
+	}
+
+	if errors.Is(err, vault.ErrItemsQuotaExceeded) {
+		return nil, status.Error(codes.ResourceExhausted, "vault items quota exceeded")
+	}
+
 	if err != nil {
 		s.log.Error("can't create vault", zap.Error(err))
 		return nil, status.Error(codes.Internal, "error create vault")
@@ -135,7 +346,17 @@ func (s *GophkeeperServer) VaultUpdate(ctx context.Context, in *pb.VaultUpdateRe
 		return nil, status.Error(codes.InvalidArgument, "invalid vault ID")
 	}
 
-	updatedVersion, err := s.vaultService.Update(ctx, userID, vaultID, in.Vault, int(in.Version))
+	var s3ulr *string
+
+	if in.S3 != nil {
+		s3ulr = &(in.S3.Value)
+	}
+
+	updatedVersion, err := s.vaultService.Update(ctx, userID, vaultID, in.Vault, int(in.Version), s3ulr)
+
+	if errors.Is(err, vault.ErrBlobNotFound) {
+		return nil, status.Error(codes.FailedPrecondition, "uploaded file not found")
+	}
 
 	if errors.Is(err, vault.ErrVaultNotFound) {
 		return nil, status.Error(codes.NotFound, "vault not found")
@@ -186,6 +407,94 @@ func (s *GophkeeperServer) VaultDelete(ctx context.Context, in *pb.VaultDeleteRe
 	return &empty.Empty{}, nil
 }
 
+func (s *GophkeeperServer) VaultBatch(ctx context.Context, in *pb.VaultBatchRequest) (*pb.VaultBatchResponse, error) {
+	tokenData, ok := interceptorauth.GetTokenDataCtx(ctx)
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, "Не авторизован")
+	}
+
+	if len(in.Operations) > maxBatchOperations {
+		return nil, status.Errorf(codes.InvalidArgument, "too many operations, max %d", maxBatchOperations)
+	}
+
+	operations := make([]vault.BatchOperation, len(in.Operations))
+
+	for i, op := range in.Operations {
+		operation := vault.BatchOperation{
+			Vault:   op.Vault,
+			Version: int(op.Version),
+		}
+
+		if op.S3 != nil {
+			operation.S3 = &(op.S3.Value)
+		}
+
+		switch op.Type {
+		case pb.VaultBatchRequest_Operation_CREATE:
+			operation.Type = vault.BatchOperationCreate
+		case pb.VaultBatchRequest_Operation_UPDATE:
+			operation.Type = vault.BatchOperationUpdate
+		case pb.VaultBatchRequest_Operation_DELETE:
+			operation.Type = vault.BatchOperationDelete
+		default:
+			return nil, status.Error(codes.InvalidArgument, "invalid operation type")
+		}
+
+		if operation.Type != vault.BatchOperationCreate {
+			id, err := uuid.Parse(op.Id)
+
+			if err != nil {
+				return nil, status.Error(codes.InvalidArgument, "invalid vault ID")
+			}
+
+			operation.ID = id
+		}
+
+		operations[i] = operation
+	}
+
+	results, err := s.vaultService.Batch(ctx, tokenData.ID, operations)
+
+	if errors.Is(err, vault.ErrBlobNotFound) {
+		return nil, status.Error(codes.FailedPrecondition, "uploaded file not found")
+	}
+
+	if errors.Is(err, vault.ErrItemsQuotaExceeded) {
+		return nil, status.Error(codes.ResourceExhausted, "vault items quota exceeded")
+	}
+
+	if err != nil {
+		s.log.Error("can't apply vault batch", zap.Error(err))
+		return nil, status.Error(codes.Internal, "error apply batch")
+	}
+
+	responseResults := make([]*pb.VaultBatchResponse_Result, len(results))
+
+	for i, result := range results {
+		responseResult := pb.VaultBatchResponse_Result{
+			Status:  pb.VaultBatchResponse_Result_OK,
+			Id:      result.ID.String(),
+			Version: int32(result.Version),
+		}
+
+		if errors.Is(result.Err, vault.ErrVaultConflict) {
+			responseResult.Status = pb.VaultBatchResponse_Result_CONFLICT
+		}
+
+		if errors.Is(result.Err, vault.ErrVaultNotFound) {
+			responseResult.Status = pb.VaultBatchResponse_Result_NOT_FOUND
+		}
+
+		responseResults[i] = &responseResult
+	}
+
+	response := pb.VaultBatchResponse{
+		Results: responseResults,
+	}
+
+	return &response, nil
+}
+
 func (s *GophkeeperServer) VaultSync(ctx context.Context, in *pb.VaultSyncRequest) (*pb.VaultSyncResponse, error) {
 	tokenData, ok := interceptorauth.GetTokenDataCtx(ctx)
 	if !ok {
@@ -218,29 +527,192 @@ func (s *GophkeeperServer) VaultSync(ctx context.Context, in *pb.VaultSyncReques
 		return nil, status.Error(codes.Internal, "error load vault")
 	}
 
-	responseVaults := make([]*pb.VaultSyncResponse_Vault, 0, len(newVaults))
+	response := pb.VaultSyncResponse{
+		UpdatedVaults: vaultsToResponse(newVaults),
+	}
 
-	for _, newVault := range newVaults {
-		var s3Response *wrapperspb.StringValue
+	return &response, nil
+}
+
+func (s *GophkeeperServer) VaultChanges(ctx context.Context, in *pb.VaultChangesRequest) (*pb.VaultChangesResponse, error) {
+	tokenData, ok := interceptorauth.GetTokenDataCtx(ctx)
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, "Не авторизован")
+	}
 
-		if newVault.S3 != nil {
-			s3Response = wrapperspb.String(*newVault.S3)
+	if in.SinceCursor < 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid cursor")
+	}
+
+	changedVaults, cursor, err := s.vaultService.LoadChanges(ctx, tokenData.ID, in.SinceCursor)
+
+	if err != nil {
+		s.log.Error("can't load changes vault", zap.Error(err))
+		return nil, status.Error(codes.Internal, "error load vault")
+	}
+
+	s.saveSyncCursor(ctx, tokenData.DeviceID, cursor)
+
+	response := pb.VaultChangesResponse{
+		Vaults: vaultsToResponse(changedVaults),
+		Cursor: cursor,
+	}
+
+	return &response, nil
+}
+
+// VaultSyncStream sends changes after cursor by pages, so big vault is not loaded in one message
+func (s *GophkeeperServer) VaultSyncStream(in *pb.VaultSyncStreamRequest, stream pb.Gophkeeper_VaultSyncStreamServer) error {
+	ctx := stream.Context()
+
+	tokenData, ok := interceptorauth.GetTokenDataCtx(ctx)
+	if !ok {
+		return status.Error(codes.PermissionDenied, "Не авторизован")
+	}
+
+	cursor := in.SinceCursor
+
+	if in.PageToken != "" {
+		tokenCursor, err := vault.DecodePageToken(in.PageToken)
+
+		if err != nil {
+			return status.Error(codes.InvalidArgument, "invalid page token")
 		}
 
-		v := pb.VaultSyncResponse_Vault{
-			Id:        newVault.ID.String(),
-			Vault:     newVault.Vault,
-			Version:   int32(newVault.Version),
-			IsDeleted: newVault.IsDeleted,
-			S3:        s3Response,
+		cursor = tokenCursor
+	}
+
+	if cursor < 0 {
+		return status.Error(codes.InvalidArgument, "invalid cursor")
+	}
+
+	pageSize := int(in.PageSize)
+
+	if pageSize <= 0 {
+		pageSize = defaultSyncPageSize
+	}
+
+	if pageSize > maxSyncPageSize {
+		pageSize = maxSyncPageSize
+	}
+
+	for {
+		changedVaults, nextCursor, hasMore, err := s.vaultService.LoadChangesPage(ctx, tokenData.ID, cursor, pageSize, maxSyncPageBytes)
+
+		if err != nil {
+			s.log.Error("can't load changes page vault", zap.Error(err))
+			return status.Error(codes.Internal, "error load vault")
 		}
 
-		responseVaults = append(responseVaults, &v)
+		page := pb.VaultSyncPage{
+			Vaults: vaultsToResponse(changedVaults),
+			Cursor: nextCursor,
+		}
+
+		if hasMore {
+			page.NextPageToken = vault.EncodePageToken(nextCursor)
+		}
+
+		err = stream.Send(&page)
+
+		if err != nil {
+			return err
+		}
+
+		if !hasMore {
+			s.saveSyncCursor(ctx, tokenData.DeviceID, nextCursor)
+
+			return nil
+		}
+
+		cursor = nextCursor
 	}
+}
 
-	response := pb.VaultSyncResponse{
-		UpdatedVaults: responseVaults,
+func (s *GophkeeperServer) WatchVaults(in *pb.VaultChangesRequest, stream pb.Gophkeeper_WatchVaultsServer) error {
+	ctx := stream.Context()
+
+	tokenData, ok := interceptorauth.GetTokenDataCtx(ctx)
+	if !ok {
+		return status.Error(codes.PermissionDenied, "Не авторизован")
 	}
 
-	return &response, nil
+	if in.SinceCursor < 0 {
+		return status.Error(codes.InvalidArgument, "invalid cursor")
+	}
+
+	// Subscribe before first load, so changes between load and subscribe are not lost
+	changes, cancel := s.vaultNotifier.Subscribe(tokenData.ID)
+	defer cancel()
+
+	cursor := in.SinceCursor
+
+	for {
+		changedVaults, nextCursor, err := s.vaultService.LoadChanges(ctx, tokenData.ID, cursor)
+
+		if err != nil {
+			s.log.Error("can't load changes vault", zap.Error(err))
+			return status.Error(codes.Internal, "error load vault")
+		}
+
+		if len(changedVaults) != 0 {
+			response := pb.VaultChangesResponse{
+				Vaults: vaultsToResponse(changedVaults),
+				Cursor: nextCursor,
+			}
+
+			err = stream.Send(&response)
+
+			if err != nil {
+				return err
+			}
+
+			s.saveSyncCursor(ctx, tokenData.DeviceID, nextCursor)
+		}
+
+		cursor = nextCursor
+
+		// Context is done also when auth interceptor closes stream of revoked device or expired token
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-changes:
+			if !ok {
+				return status.Error(codes.Unavailable, "server is stopping")
+			}
+		}
+	}
+}
+
+// saveSyncCursor remembers how far device is synced, failure doesn't break sync
+func (s *GophkeeperServer) saveSyncCursor(ctx context.Context, deviceID uuid.UUID, cursor int64) {
+	err := s.deviceService.UpdateSyncCursor(ctx, deviceID, cursor)
+
+	if err != nil {
+		s.log.Error("can't save sync cursor of device", zap.Error(err))
+	}
+}
+
+func vaultsToResponse(vaults []vault.VaultModel) []*pb.VaultSyncResponse_Vault {
+	responseVaults := make([]*pb.VaultSyncResponse_Vault, 0, len(vaults))
+
+	for _, v := range vaults {
+		var s3Response *wrapperspb.StringValue
+
+		if v.S3 != nil {
+			s3Response = wrapperspb.String(*v.S3)
+		}
+
+		responseVault := pb.VaultSyncResponse_Vault{
+			Id:        v.ID.String(),
+			Vault:     v.Vault,
+			Version:   int32(v.Version),
+			IsDeleted: v.IsDeleted,
+			S3:        s3Response,
+		}
+
+		responseVaults = append(responseVaults, &responseVault)
+	}
+
+	return responseVaults
 }
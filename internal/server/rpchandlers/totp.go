@@ -0,0 +1,91 @@
+package rpchandlers
+
+import (
+	"errors"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/shreyner/gophkeeper/internal/server/auth"
+	interceptorauth "github.com/shreyner/gophkeeper/internal/server/interceptor/auth"
+	"github.com/shreyner/gophkeeper/internal/server/ratelimit"
+	pb "github.com/shreyner/gophkeeper/proto"
+)
+
+func (s *GophkeeperServer) TOTPSetup(ctx context.Context, _ *empty.Empty) (*pb.TOTPSetupResponse, error) {
+	tokenData, ok := interceptorauth.GetTokenDataCtx(ctx)
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, "Не авторизован")
+	}
+
+	secret, uri, err := s.authService.SetupTOTP(ctx, tokenData.ID)
+
+	if errors.Is(err, auth.ErrTOTPAlreadyEnabled) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	if err != nil {
+		s.log.Error("can't setup totp", zap.Error(err))
+		return nil, status.Error(codes.Internal, "error setup two-factor authentication")
+	}
+
+	response := pb.TOTPSetupResponse{
+		Secret: secret,
+		Uri:    uri,
+	}
+
+	return &response, nil
+}
+
+func (s *GophkeeperServer) TOTPEnable(ctx context.Context, in *pb.TOTPCodeRequest) (*pb.TOTPEnableResponse, error) {
+	tokenData, ok := interceptorauth.GetTokenDataCtx(ctx)
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, "Не авторизован")
+	}
+
+	backupCodes, err := s.authService.EnableTOTP(ctx, tokenData.ID, in.Code)
+
+	switch {
+	case err == nil:
+	case errors.Is(err, auth.ErrTOTPAlreadyEnabled), errors.Is(err, auth.ErrTOTPNotSetUp):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, auth.ErrInvalidSecondFactor):
+		ratelimit.Failed(ctx)
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	default:
+		s.log.Error("can't enable totp", zap.Error(err))
+		return nil, status.Error(codes.Internal, "error enable two-factor authentication")
+	}
+
+	response := pb.TOTPEnableResponse{
+		BackupCodes: backupCodes,
+	}
+
+	return &response, nil
+}
+
+func (s *GophkeeperServer) TOTPDisable(ctx context.Context, in *pb.TOTPCodeRequest) (*empty.Empty, error) {
+	tokenData, ok := interceptorauth.GetTokenDataCtx(ctx)
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, "Не авторизован")
+	}
+
+	err := s.authService.DisableTOTP(ctx, tokenData.ID, in.Code)
+
+	switch {
+	case err == nil:
+	case errors.Is(err, auth.ErrTOTPNotEnabled):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, auth.ErrInvalidSecondFactor):
+		ratelimit.Failed(ctx)
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	default:
+		s.log.Error("can't disable totp", zap.Error(err))
+		return nil, status.Error(codes.Internal, "error disable two-factor authentication")
+	}
+
+	return &empty.Empty{}, nil
+}
@@ -0,0 +1,116 @@
+package rpchandlers_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/shreyner/gophkeeper/internal/server/auth"
+	authmock "github.com/shreyner/gophkeeper/internal/server/auth/mock"
+	interceptorauth "github.com/shreyner/gophkeeper/internal/server/interceptor/auth"
+	"github.com/shreyner/gophkeeper/internal/server/pgk/stoken"
+	"github.com/shreyner/gophkeeper/internal/server/pgk/totp"
+	"github.com/shreyner/gophkeeper/internal/server/ratelimit"
+	"github.com/shreyner/gophkeeper/internal/server/rpchandlers"
+	"github.com/shreyner/gophkeeper/internal/server/user"
+	pb "github.com/shreyner/gophkeeper/proto"
+)
+
+const testTOTPSecret = "JBSWY3DPEHPK3PXP"
+
+func TestGophkeeperServer_TOTP(t *testing.T) {
+	validCode, err := totp.Code(testTOTPSecret, totp.Step(time.Now()))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		userModel  user.UserModel
+		call       func(ctx context.Context, server *rpchandlers.GophkeeperServer) error
+		prepare    func(userService *authmock.MockUserService, userID uuid.UUID)
+		wantFailed bool
+		wantCode   codes.Code
+	}{
+		{
+			name:      "Disable by wrong code",
+			userModel: user.UserModel{TOTPSecret: testTOTPSecret},
+			call: func(ctx context.Context, server *rpchandlers.GophkeeperServer) error {
+				_, err := server.TOTPDisable(ctx, &pb.TOTPCodeRequest{Code: "ABCD-EFGH"})
+				return err
+			},
+			prepare: func(userService *authmock.MockUserService, userID uuid.UUID) {
+				userService.EXPECT().UseBackupCode(gomock.Any(), userID, "ABCD-EFGH").Return(user.ErrBackupCodeInvalid)
+			},
+			wantFailed: true,
+			wantCode:   codes.InvalidArgument,
+		},
+		{
+			name:      "Disable by valid code",
+			userModel: user.UserModel{TOTPSecret: testTOTPSecret},
+			call: func(ctx context.Context, server *rpchandlers.GophkeeperServer) error {
+				_, err := server.TOTPDisable(ctx, &pb.TOTPCodeRequest{Code: validCode})
+				return err
+			},
+			prepare: func(userService *authmock.MockUserService, userID uuid.UUID) {
+				userService.EXPECT().UseTOTPStep(gomock.Any(), userID, gomock.Any()).Return(nil)
+				userService.EXPECT().DisableTOTP(gomock.Any(), userID).Return(nil)
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name:      "Disable when second factor is off",
+			userModel: user.UserModel{},
+			call: func(ctx context.Context, server *rpchandlers.GophkeeperServer) error {
+				_, err := server.TOTPDisable(ctx, &pb.TOTPCodeRequest{Code: validCode})
+				return err
+			},
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			name:      "Enable by wrong code",
+			userModel: user.UserModel{TOTPPendingSecret: testTOTPSecret},
+			call: func(ctx context.Context, server *rpchandlers.GophkeeperServer) error {
+				_, err := server.TOTPEnable(ctx, &pb.TOTPCodeRequest{Code: "ABCDEF"})
+				return err
+			},
+			wantFailed: true,
+			wantCode:   codes.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			userModel := tt.userModel
+			userModel.ID = uuid.New()
+
+			userService := authmock.NewMockUserService(ctrl)
+			userService.EXPECT().FindByID(gomock.Any(), userModel.ID).Return(&userModel, nil)
+
+			if tt.prepare != nil {
+				tt.prepare(userService, userModel.ID)
+			}
+
+			server := rpchandlers.NewGophkeeperServer(
+				zap.NewNop(),
+				auth.NewService(userService, auth.RegistrationOpen, nil, time.Hour),
+				nil, nil, nil, nil, nil, nil, nil,
+			)
+
+			ctx := interceptorauth.SetTokenDataCtx(context.Background(), &stoken.Data{ID: userModel.ID, DeviceID: uuid.New()})
+			ctx, isFailed := ratelimit.WithFailure(ctx)
+
+			err := tt.call(ctx, server)
+
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			assert.Equal(t, tt.wantFailed, isFailed(), "failed check is counted by rate limit")
+		})
+	}
+}
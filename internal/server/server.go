@@ -1,35 +1,69 @@
 package server
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 	"github.com/shreyner/gophkeeper/internal/server/config"
 	"go.uber.org/zap"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc"
 
+	"github.com/shreyner/gophkeeper/internal/server/account"
 	"github.com/shreyner/gophkeeper/internal/server/auth"
+	"github.com/shreyner/gophkeeper/internal/server/blob"
+	"github.com/shreyner/gophkeeper/internal/server/device"
 	"github.com/shreyner/gophkeeper/internal/server/httphandlers"
 	interceptor_auth "github.com/shreyner/gophkeeper/internal/server/interceptor/auth"
+	interceptor_ratelimit "github.com/shreyner/gophkeeper/internal/server/interceptor/ratelimit"
+	"github.com/shreyner/gophkeeper/internal/server/pgk/blobstore"
 	"github.com/shreyner/gophkeeper/internal/server/pgk/database"
 	"github.com/shreyner/gophkeeper/internal/server/pgk/grcserver"
 	"github.com/shreyner/gophkeeper/internal/server/pgk/httpserver"
 	"github.com/shreyner/gophkeeper/internal/server/pgk/stoken"
+	"github.com/shreyner/gophkeeper/internal/server/quota"
+	"github.com/shreyner/gophkeeper/internal/server/ratelimit"
 	"github.com/shreyner/gophkeeper/internal/server/rpchandlers"
+	"github.com/shreyner/gophkeeper/internal/server/signkey"
+	"github.com/shreyner/gophkeeper/internal/server/upload"
 	"github.com/shreyner/gophkeeper/internal/server/user"
 	"github.com/shreyner/gophkeeper/internal/server/vault"
 	pb "github.com/shreyner/gophkeeper/proto"
 )
 
+const jwtAlgorithmHS256 = "HS256"
+
+// signKeyRotateInterval is how often replicas create and reload jwt keys, it must be shorter than key overlap
+const signKeyRotateInterval = time.Minute
+
 func NewGophKeeperServer(logger *zap.Logger, cfg *config.Config) error {
 	ctxBase := context.Background()
 
 	logger.Info("Start GophKeeper server...")
 
+	registrationMode, err := auth.ParseRegistrationMode(cfg.RegistrationMode)
+	if err != nil {
+		logger.Error("Invalid registration mode", zap.Error(err))
+		return err
+	}
+
+	err = validateJWTConfig(cfg)
+	if err != nil {
+		logger.Error("Invalid jwt config", zap.Error(err))
+		return err
+	}
+
+	if cfg.JWTAlgorithm == jwtAlgorithmHS256 && cfg.JWTSign == config.DefaultJWTSign {
+		logger.Warn("Tokens are signed by default JWT_SIGN, it is allowed only in dev mode")
+	}
+
 	logger.Info("Connect to database...")
 	db, err := database.NewDataBase(ctxBase, cfg.DBDSN)
 	if err != nil {
@@ -37,29 +71,80 @@ func NewGophKeeperServer(logger *zap.Logger, cfg *config.Config) error {
 		return err
 	}
 
-	logger.Info("Initialize S3 Minio client ...")
-	s3minioCLient, err := minio.New(
-		cfg.S3MinioEndpoint,
-		&minio.Options{
-			Creds: credentials.NewStaticV4(cfg.S3MinioAccessKeyID, cfg.S3MinioSecretAccessKey, ""),
-		},
-	)
+	logger.Info("Initialize blob store ...", zap.String("store", cfg.BlobStore))
+	blobStore, err := newBlobStore(cfg)
 
 	if err != nil {
-		logger.Error("Can't create S3 Minio client", zap.Error(err))
+		logger.Error("Can't create blob store", zap.Error(err))
 		return err
 	}
 
+	logger.Info("Initialize rate limit store ...", zap.String("store", cfg.RateLimitStore))
+	rateLimitStore, err := newRateLimitStore(cfg, db)
+
+	if err != nil {
+		logger.Error("Can't create rate limit store", zap.Error(err))
+		return err
+	}
+
+	logger.Info("Initialize jwt keys ...", zap.String("algorithm", cfg.JWTAlgorithm))
+	var (
+		keySet         stoken.KeySet = stoken.NewHMACKeySet([]byte(cfg.JWTSign))
+		signKeyService *signkey.Service
+	)
+
+	if cfg.JWTAlgorithm != jwtAlgorithmHS256 {
+		signKeyService = signkey.NewService(logger, signkey.NewRepository(db), cfg.JWTAlgorithm, cfg.JWTKeyRotation, cfg.JWTKeyOverlap)
+
+		err = signKeyService.Rotate(ctxBase)
+		if err != nil {
+			logger.Error("Can't load jwt keys", zap.Error(err))
+			return err
+		}
+
+		keySet = signKeyService
+	}
+
 	userRepository := user.NewRepository(db)
 	vaultRepository := vault.NewRepository(db)
+	uploadRepository := upload.NewRepository(db)
+	quotaRepository := quota.NewRepository(db)
+	blobRepository := blob.NewRepository(db)
+	deviceRepository := device.NewRepository(db)
+	accountRepository := account.NewRepository(db)
 
-	vaultService := vault.NewService(vaultRepository)
-	stokenService := stoken.NewService([]byte(cfg.JWTSign))
+	quotaService := quota.NewService(quotaRepository, quota.Limits{
+		MaxBlobBytes: cfg.QuotaMaxBlobBytes,
+		MaxItems:     cfg.QuotaMaxItems,
+	})
+	blobService := blob.NewService(logger, blobRepository, blobStore, quotaService, cfg.BlobGCGrace)
+	vaultService := vault.NewService(vaultRepository, cfg.QuotaMaxItems)
+	vaultNotifier := vault.NewNotifier(logger, db)
+	stokenService := stoken.NewService(keySet, cfg.AccessTokenTTL)
 	userService := user.NewService(userRepository)
-	authService := auth.NewService(userService)
+	deviceService := device.NewService(deviceRepository, cfg.RefreshTokenTTL)
+	authService := auth.NewService(userService, registrationMode, cfg.AdminLogins, cfg.InviteTTL)
+	uploadService := upload.NewService(logger, uploadRepository, blobStore, quotaService, blobService, cfg.BlobGCGrace)
+	accountService := account.NewService(logger, accountRepository, blobStore)
+	rateLimitService := ratelimit.NewService(
+		logger,
+		rateLimitStore,
+		ratelimit.Policy{
+			MaxFailures: cfg.RateLimitAccountFailures,
+			Window:      cfg.RateLimitWindow,
+			BaseLockout: cfg.RateLimitLockout,
+			MaxLockout:  cfg.RateLimitMaxLockout,
+		},
+		ratelimit.Policy{
+			MaxFailures: cfg.RateLimitIPFailures,
+			Window:      cfg.RateLimitWindow,
+			BaseLockout: cfg.RateLimitLockout,
+			MaxLockout:  cfg.RateLimitMaxLockout,
+		},
+	)
 
 	logger.Info("Create http router...")
-	router := httphandlers.NewRouter(logger, stokenService, s3minioCLient)
+	router := httphandlers.NewRouter(logger, stokenService, deviceService, blobStore, uploadService, quotaService, blobService, rateLimitService)
 
 	logger.Info("Create http server...")
 	hserver, err := httpserver.NewHTTPServer(
@@ -78,18 +163,45 @@ func NewGophKeeperServer(logger *zap.Logger, cfg *config.Config) error {
 		logger,
 		cfg,
 		fmt.Sprintf(":%v", cfg.GRPCServerPort),
-		interceptor_auth.Interceptor(stokenService),
+		grpc.ChainUnaryInterceptor(
+			interceptor_auth.Interceptor(stokenService, deviceService),
+			interceptor_ratelimit.Interceptor(logger, rateLimitService, rateLimitedMethods(stokenService)),
+		),
+		grpc.ChainStreamInterceptor(interceptor_auth.StreamInterceptor(stokenService, deviceService)),
 	)
 	if err != nil {
 		logger.Error("Can't start grpc server", zap.Error(err))
 		return err
 	}
 
-	rpcGophkeeperServer := rpchandlers.NewGophkeeperServer(logger, authService, stokenService, vaultService)
+	rpcGophkeeperServer := rpchandlers.NewGophkeeperServer(logger, authService, stokenService, vaultService, vaultNotifier, quotaService, deviceService, blobService, accountService)
 
 	pb.RegisterGophkeeperServer(gserver.Server, rpcGophkeeperServer)
 
 	logger.Info("Start services ...")
+	ctxNotifier, cancelNotifier := context.WithCancel(ctxBase)
+	defer cancelNotifier()
+
+	go vaultNotifier.Run(ctxNotifier)
+
+	ctxBlobGC, cancelBlobGC := context.WithCancel(ctxBase)
+	defer cancelBlobGC()
+
+	go blobService.Run(ctxBlobGC, cfg.BlobGCInterval)
+	go uploadService.Run(ctxBlobGC, cfg.BlobGCInterval)
+
+	ctxRateLimit, cancelRateLimit := context.WithCancel(ctxBase)
+	defer cancelRateLimit()
+
+	go rateLimitService.Run(ctxRateLimit, cfg.RateLimitCleanup)
+
+	if signKeyService != nil {
+		ctxSignKey, cancelSignKey := context.WithCancel(ctxBase)
+		defer cancelSignKey()
+
+		go signKeyService.Run(ctxSignKey, signKeyRotateInterval)
+	}
+
 	_ = hserver.Start()
 	_ = gserver.Start()
 
@@ -108,6 +220,9 @@ func NewGophKeeperServer(logger *zap.Logger, cfg *config.Config) error {
 
 	logger.Info("Stopping server...")
 
+	// Close watch streams, otherwise graceful stop waits them
+	cancelNotifier()
+
 	err = gserver.Stop(context.Background())
 	if err != nil {
 		logger.Error("Got an error when stopping the grpc server", zap.Error(err))
@@ -122,3 +237,94 @@ func NewGophKeeperServer(logger *zap.Logger, cfg *config.Config) error {
 
 	return nil
 }
+
+func newBlobStore(cfg *config.Config) (blobstore.Store, error) {
+	switch cfg.BlobStore {
+	case "fs":
+		return blobstore.NewFSStore(cfg.BlobStorePath)
+	case "s3":
+		s3minioClient, err := minio.New(
+			cfg.S3MinioEndpoint,
+			&minio.Options{
+				Creds: credentials.NewStaticV4(cfg.S3MinioAccessKeyID, cfg.S3MinioSecretAccessKey, ""),
+			},
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return blobstore.NewS3Store(s3minioClient, cfg.S3MinioBucket), nil
+	default:
+		return nil, fmt.Errorf("unknown blob store %q", cfg.BlobStore)
+	}
+}
+
+func newRateLimitStore(cfg *config.Config, db *sql.DB) (ratelimit.Store, error) {
+	switch cfg.RateLimitStore {
+	case "memory":
+		return ratelimit.NewMemoryStore(), nil
+	case "postgres":
+		return ratelimit.NewRepository(db), nil
+	default:
+		return nil, fmt.Errorf("%w %q", ratelimit.ErrUnknownStore, cfg.RateLimitStore)
+	}
+}
+
+// rateLimitedMethods are methods guessing credentials. Second factor is limited by user of challenge,
+// so passed password does not reset failures of codes. Methods of logged in user are limited by user of token,
+// stolen token must not allow to guess password or codes from many addresses
+func rateLimitedMethods(stokenService *stoken.Service) map[string]interceptor_ratelimit.AccountFunc {
+	return map[string]interceptor_ratelimit.AccountFunc{
+		"/gophkeeper.Gophkeeper/Login": func(_ context.Context, req interface{}) string {
+			return ratelimit.LoginAccount(req.(*pb.LoginRequest).Login)
+		},
+		"/gophkeeper.Gophkeeper/LoginSecondFactor": func(_ context.Context, req interface{}) string {
+			userID, err := stokenService.ParseChallenge(req.(*pb.LoginSecondFactorRequest).Challenge)
+
+			if err != nil {
+				return ""
+			}
+
+			return ratelimit.UserAccount(userID)
+		},
+		"/gophkeeper.Gophkeeper/Register": func(_ context.Context, _ interface{}) string {
+			return ""
+		},
+		"/gophkeeper.Gophkeeper/AccountDelete": tokenUserAccount,
+		"/gophkeeper.Gophkeeper/TOTPEnable":    tokenUserAccount,
+		"/gophkeeper.Gophkeeper/TOTPDisable":   tokenUserAccount,
+	}
+}
+
+// tokenUserAccount is account of user authorized by token. Limiter runs after auth interceptor
+func tokenUserAccount(ctx context.Context, _ interface{}) string {
+	tokenData, ok := interceptor_auth.GetTokenDataCtx(ctx)
+
+	if !ok {
+		return ""
+	}
+
+	return ratelimit.UserAccount(tokenData.ID)
+}
+
+func validateJWTConfig(cfg *config.Config) error {
+	switch cfg.JWTAlgorithm {
+	case jwtAlgorithmHS256:
+		if cfg.JWTSign == config.DefaultJWTSign && !cfg.DevMode {
+			return errors.New("default JWT_SIGN is allowed only with DEV_MODE, set JWT_SIGN or use asymmetric JWT_ALG")
+		}
+	case signkey.AlgorithmEdDSA, signkey.AlgorithmRS256:
+		if cfg.JWTKeyOverlap < cfg.AccessTokenTTL || cfg.JWTKeyOverlap <= signKeyRotateInterval {
+			return fmt.Errorf("JWT_KEY_OVERLAP must be longer than ACCESS_TOKEN_TTL and %v", signKeyRotateInterval)
+		}
+
+		if cfg.JWTKeyRotation <= cfg.JWTKeyOverlap {
+			return errors.New("JWT_KEY_ROTATION must be longer than JWT_KEY_OVERLAP")
+		}
+	default:
+		return fmt.Errorf("%w %q", signkey.ErrUnknownAlgorithm, cfg.JWTAlgorithm)
+	}
+
+	return nil
+}
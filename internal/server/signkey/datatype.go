@@ -0,0 +1,21 @@
+package signkey
+
+import (
+	"crypto"
+	"time"
+)
+
+const (
+	AlgorithmEdDSA = "EdDSA"
+	AlgorithmRS256 = "RS256"
+)
+
+// KeyModel signs tokens from ActivatesAt until the next key activates and verifies them until ExpiresAt
+type KeyModel struct {
+	KID         string
+	Algorithm   string
+	PrivateKey  crypto.Signer
+	CreatedAt   time.Time
+	ActivatesAt time.Time
+	ExpiresAt   time.Time
+}
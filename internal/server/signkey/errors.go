@@ -0,0 +1,7 @@
+package signkey
+
+import "errors"
+
+var ErrUnknownAlgorithm = errors.New("signkey: unknown algorithm")
+
+var ErrNoSigningKey = errors.New("signkey: no active signing key")
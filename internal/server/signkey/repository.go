@@ -0,0 +1,167 @@
+package signkey
+
+import (
+	"crypto"
+	"crypto/x509"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// rotateLockID is key of advisory lock, only one replica creates the next key
+const rotateLockID = 4903
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	repository := Repository{db: db}
+
+	return &repository
+}
+
+// queryer is implemented by *sql.DB and *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// FindActive returns keys not expired at now ordered by activation
+func (r *Repository) FindActive(ctx context.Context, now time.Time) ([]*KeyModel, error) {
+	return r.findActive(ctx, r.db, `where expires_at > $1`, now)
+}
+
+func (r *Repository) findActive(ctx context.Context, q queryer, where string, args ...any) ([]*KeyModel, error) {
+	rows, err := q.QueryContext(
+		ctx,
+		`select kid, algorithm, private_key, created_at, activates_at, expires_at
+		from signing_keys `+where+` order by activates_at;`,
+		args...,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	keys := make([]*KeyModel, 0)
+
+	for rows.Next() {
+		var (
+			key        KeyModel
+			privateKey []byte
+		)
+
+		err = rows.Scan(&key.KID, &key.Algorithm, &privateKey, &key.CreatedAt, &key.ActivatesAt, &key.ExpiresAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		key.PrivateKey, err = parsePrivateKey(privateKey)
+
+		if err != nil {
+			return nil, fmt.Errorf("key %v: %w", key.KID, err)
+		}
+
+		keys = append(keys, &key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Rotate passes keys of algorithm to fn under lock shared by replicas and saves key returned by fn
+func (r *Repository) Rotate(
+	ctx context.Context,
+	algorithm string,
+	now time.Time,
+	fn func(keys []*KeyModel) (*KeyModel, error),
+) (*KeyModel, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	_, err = tx.ExecContext(ctx, `select pg_advisory_xact_lock($1);`, rotateLockID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := r.findActive(ctx, tx, `where algorithm = $1 and expires_at > $2`, algorithm, now)
+
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := fn(keys)
+
+	if err != nil || key == nil {
+		return nil, err
+	}
+
+	privateKey, err := x509.MarshalPKCS8PrivateKey(key.PrivateKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRowContext(
+		ctx,
+		`insert into signing_keys (kid, algorithm, private_key, activates_at, expires_at)
+		values ($1, $2, $3, $4, $5) returning created_at;`,
+		key.KID,
+		key.Algorithm,
+		privateKey,
+		key.ActivatesAt,
+		key.ExpiresAt,
+	).Scan(&key.CreatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (r *Repository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `delete from signing_keys where expires_at <= $1;`, now)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func parsePrivateKey(der []byte) (crypto.Signer, error) {
+	key, err := x509.ParsePKCS8PrivateKey(der)
+
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+
+	if !ok {
+		return nil, fmt.Errorf("%T: %w", key, ErrUnknownAlgorithm)
+	}
+
+	return signer, nil
+}
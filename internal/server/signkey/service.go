@@ -0,0 +1,268 @@
+package signkey
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+
+	"github.com/shreyner/gophkeeper/internal/server/pgk/stoken"
+)
+
+var (
+	_ stoken.KeySet = (*Service)(nil)
+)
+
+const rsaKeyBits = 2048
+
+// Service keeps keys of database in memory and rotates them. The next key is created overlap before
+// current key retires, so it is published in JWKS before it signs. Retired key verifies tokens overlap more
+type Service struct {
+	log       *zap.Logger
+	rep       *Repository
+	algorithm string
+	rotation  time.Duration
+	overlap   time.Duration
+
+	mux  sync.RWMutex
+	keys []*KeyModel
+
+	now func() time.Time
+}
+
+func NewService(log *zap.Logger, rep *Repository, algorithm string, rotation, overlap time.Duration) *Service {
+	service := Service{
+		log:       log,
+		rep:       rep,
+		algorithm: algorithm,
+		rotation:  rotation,
+		overlap:   overlap,
+		now:       time.Now,
+	}
+
+	return &service
+}
+
+// Rotate creates the next key when it is time, removes expired keys and reloads keys from database
+func (s *Service) Rotate(ctx context.Context) error {
+	now := s.now()
+
+	key, err := s.rep.Rotate(ctx, s.algorithm, now, func(keys []*KeyModel) (*KeyModel, error) {
+		activatesAt, ok := nextActivation(keys, now, s.rotation, s.overlap)
+
+		if !ok {
+			return nil, nil
+		}
+
+		return generateKey(s.algorithm, activatesAt, s.rotation, s.overlap)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if key != nil {
+		s.log.Info(
+			"signing key created",
+			zap.String("kid", key.KID),
+			zap.String("algorithm", key.Algorithm),
+			zap.Time("activates_at", key.ActivatesAt),
+			zap.Time("expires_at", key.ExpiresAt),
+		)
+	}
+
+	_, err = s.rep.DeleteExpired(ctx, now)
+
+	if err != nil {
+		return err
+	}
+
+	keys, err := s.rep.FindActive(ctx, now)
+
+	if err != nil {
+		return err
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.keys = keys
+
+	return nil
+}
+
+// Run rotates keys every interval until ctx is done. Interval must be shorter than overlap,
+// so every replica knows the next key before other replica signs by it
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := s.Rotate(ctx)
+
+		if err != nil && ctx.Err() == nil {
+			s.log.Error("signing key rotation failed", zap.Error(err))
+		}
+	}
+}
+
+// SigningKey returns the last activated key of configured algorithm
+func (s *Service) SigningKey() (*stoken.Key, error) {
+	now := s.now()
+
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	for i := len(s.keys) - 1; i >= 0; i-- {
+		key := s.keys[i]
+
+		if key.Algorithm == s.algorithm && !key.ActivatesAt.After(now) && key.ExpiresAt.After(now) {
+			return toKey(key)
+		}
+	}
+
+	return nil, ErrNoSigningKey
+}
+
+// VerifyingKey returns not expired key, keys of previous algorithm verify tokens until they expire
+func (s *Service) VerifyingKey(kid string) (*stoken.Key, error) {
+	now := s.now()
+
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	for _, key := range s.keys {
+		if key.KID == kid && key.ExpiresAt.After(now) {
+			return toKey(key)
+		}
+	}
+
+	return nil, stoken.ErrUnknownKey
+}
+
+// PublicKeys returns not expired keys including the next key, which is not active yet
+func (s *Service) PublicKeys() []stoken.Key {
+	now := s.now()
+
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	keys := make([]stoken.Key, 0, len(s.keys))
+
+	for _, key := range s.keys {
+		if !key.ExpiresAt.After(now) {
+			continue
+		}
+
+		k, err := toKey(key)
+
+		if err != nil {
+			continue
+		}
+
+		keys = append(keys, *k)
+	}
+
+	return keys
+}
+
+// nextActivation returns activation of key to create. The next key is created overlap before
+// the last key retires, without keys the first key activates at once
+func nextActivation(keys []*KeyModel, now time.Time, rotation, overlap time.Duration) (time.Time, bool) {
+	if len(keys) == 0 {
+		return now, true
+	}
+
+	last := keys[0].ActivatesAt
+
+	for _, key := range keys[1:] {
+		if key.ActivatesAt.After(last) {
+			last = key.ActivatesAt
+		}
+	}
+
+	retiresAt := last.Add(rotation)
+
+	if now.Before(retiresAt.Add(-overlap)) {
+		return time.Time{}, false
+	}
+
+	// Server was stopped longer than rotation, the last key must not sign anymore
+	if retiresAt.Before(now) {
+		return now, true
+	}
+
+	return retiresAt, true
+}
+
+func generateKey(algorithm string, activatesAt time.Time, rotation, overlap time.Duration) (*KeyModel, error) {
+	var (
+		privateKey crypto.Signer
+		err        error
+	)
+
+	switch algorithm {
+	case AlgorithmEdDSA:
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	case AlgorithmRS256:
+		privateKey, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownAlgorithm, algorithm)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	kid := make([]byte, 12)
+
+	if _, err = rand.Read(kid); err != nil {
+		return nil, err
+	}
+
+	key := KeyModel{
+		KID:         base64.RawURLEncoding.EncodeToString(kid),
+		Algorithm:   algorithm,
+		PrivateKey:  privateKey,
+		ActivatesAt: activatesAt,
+		ExpiresAt:   activatesAt.Add(rotation + overlap),
+	}
+
+	return &key, nil
+}
+
+func toKey(key *KeyModel) (*stoken.Key, error) {
+	var method jwt.SigningMethod
+
+	switch key.Algorithm {
+	case AlgorithmEdDSA:
+		method = jwt.SigningMethodEdDSA
+	case AlgorithmRS256:
+		method = jwt.SigningMethodRS256
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownAlgorithm, key.Algorithm)
+	}
+
+	k := stoken.Key{
+		KID:       key.KID,
+		Method:    method,
+		SignKey:   key.PrivateKey,
+		VerifyKey: key.PrivateKey.Public(),
+	}
+
+	return &k, nil
+}
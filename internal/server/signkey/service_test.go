@@ -0,0 +1,139 @@
+package signkey
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shreyner/gophkeeper/internal/server/pgk/stoken"
+)
+
+var testNow = time.Date(2022, 12, 1, 10, 0, 0, 0, time.UTC)
+
+const (
+	testRotation = 720 * time.Hour
+	testOverlap  = time.Hour
+)
+
+func Test_nextActivation(t *testing.T) {
+	tests := []struct {
+		name        string
+		activations []time.Time
+		want        time.Time
+		wantCreate  bool
+	}{
+		{
+			name:       "first key activates at once",
+			want:       testNow,
+			wantCreate: true,
+		},
+		{
+			name:        "current key is not retiring",
+			activations: []time.Time{testNow.Add(-testRotation + testOverlap + time.Minute)},
+		},
+		{
+			name:        "next key activates when current retires",
+			activations: []time.Time{testNow.Add(-testRotation + testOverlap)},
+			want:        testNow.Add(testOverlap),
+			wantCreate:  true,
+		},
+		{
+			name: "next key is created already",
+			activations: []time.Time{
+				testNow.Add(-testRotation + time.Minute),
+				testNow.Add(time.Minute),
+			},
+		},
+		{
+			name:        "server was stopped longer than rotation",
+			activations: []time.Time{testNow.Add(-2 * testRotation)},
+			want:        testNow,
+			wantCreate:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := make([]*KeyModel, 0, len(tt.activations))
+
+			for _, activatesAt := range tt.activations {
+				keys = append(keys, &KeyModel{ActivatesAt: activatesAt})
+			}
+
+			got, ok := nextActivation(keys, testNow, testRotation, testOverlap)
+
+			assert.Equal(t, tt.wantCreate, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_generateKey(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmEdDSA, AlgorithmRS256} {
+		t.Run(algorithm, func(t *testing.T) {
+			key, err := generateKey(algorithm, testNow, testRotation, testOverlap)
+			require.NoError(t, err)
+
+			assert.Len(t, key.KID, 16)
+			assert.Equal(t, testNow.Add(testRotation+testOverlap), key.ExpiresAt)
+
+			der, err := x509.MarshalPKCS8PrivateKey(key.PrivateKey)
+			require.NoError(t, err)
+
+			parsed, err := parsePrivateKey(der)
+			require.NoError(t, err)
+			assert.Equal(t, key.PrivateKey, parsed)
+
+			k, err := toKey(key)
+			require.NoError(t, err)
+			assert.Equal(t, algorithm, k.Method.Alg())
+
+			_, err = stoken.NewJWK(k)
+			assert.NoError(t, err)
+		})
+	}
+
+	_, err := generateKey("HS256", testNow, testRotation, testOverlap)
+	assert.ErrorIs(t, err, ErrUnknownAlgorithm)
+}
+
+func TestService_Keys(t *testing.T) {
+	newKey := func(algorithm string, activatesAt time.Time) *KeyModel {
+		key, err := generateKey(algorithm, activatesAt, testRotation, testOverlap)
+		require.NoError(t, err)
+
+		return key
+	}
+
+	expired := newKey(AlgorithmEdDSA, testNow.Add(-testRotation-2*testOverlap))
+	previousAlgorithm := newKey(AlgorithmRS256, testNow.Add(-time.Hour))
+	current := newKey(AlgorithmEdDSA, testNow.Add(-time.Hour))
+	next := newKey(AlgorithmEdDSA, testNow.Add(time.Minute))
+
+	s := NewService(nil, nil, AlgorithmEdDSA, testRotation, testOverlap)
+	s.now = func() time.Time { return testNow }
+	s.keys = []*KeyModel{expired, previousAlgorithm, current, next}
+
+	signing, err := s.SigningKey()
+	require.NoError(t, err)
+	assert.Equal(t, current.KID, signing.KID, "next key does not sign before activation")
+
+	for _, key := range []*KeyModel{previousAlgorithm, current, next} {
+		verifying, err := s.VerifyingKey(key.KID)
+		require.NoError(t, err)
+		assert.Equal(t, key.Algorithm, verifying.Method.Alg())
+	}
+
+	_, err = s.VerifyingKey(expired.KID)
+	assert.ErrorIs(t, err, stoken.ErrUnknownKey)
+
+	assert.Len(t, s.PublicKeys(), 3)
+
+	s.keys = []*KeyModel{previousAlgorithm}
+
+	_, err = s.SigningKey()
+	assert.ErrorIs(t, err, ErrNoSigningKey)
+}
@@ -0,0 +1,25 @@
+package upload
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type UploadModel struct {
+	ID            uuid.UUID
+	UserID        uuid.UUID
+	ObjectName    string
+	StoreUploadID string
+	CreatedAt     time.Time
+	// CompletedAt is set when object is joined, completed upload is kept for grace period
+	CompletedAt *time.Time
+}
+
+type PartModel struct {
+	Number int
+	ETag   string
+	Size   int64
+	// PendingSince is set while part is uploaded to store, size of pending part is reserved in quota
+	PendingSince *time.Time
+}
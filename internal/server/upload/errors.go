@@ -0,0 +1,11 @@
+package upload
+
+import "errors"
+
+var ErrUploadNotFound = errors.New("upload not found")
+
+var ErrOffsetMismatch = errors.New("upload offset mismatch")
+
+var ErrUploadFinished = errors.New("upload is finished by last chunk")
+
+var ErrPartInProgress = errors.New("chunk of upload is being written")
@@ -0,0 +1,241 @@
+package upload
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/net/context"
+)
+
+// queryer is implemented by *sql.DB and *sql.Tx
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type Repository struct {
+	db *sql.DB
+	q  queryer
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	repository := Repository{db: db, q: db}
+
+	return &repository
+}
+
+// InTx runs fn with repository bound to one transaction. Transaction is committed when fn returns nil
+func (r *Repository) InTx(ctx context.Context, fn func(txRep *Repository) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	txRepository := Repository{db: r.db, q: tx}
+
+	err = fn(&txRepository)
+
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *Repository) Create(ctx context.Context, upload *UploadModel) error {
+	return r.db.QueryRowContext(
+		ctx,
+		`insert into uploads (id, user_id, object_name, s3_upload_id) values ($1, $2, $3, $4) returning created_at;`,
+		upload.ID,
+		upload.UserID,
+		upload.ObjectName,
+		upload.StoreUploadID,
+	).Scan(&upload.CreatedAt)
+}
+
+func (r *Repository) FindByID(ctx context.Context, userID, id uuid.UUID) (*UploadModel, error) {
+	return r.findByID(
+		ctx,
+		`select id, user_id, object_name, s3_upload_id, created_at, completed_at from uploads where id = $1 and user_id = $2;`,
+		userID,
+		id,
+	)
+}
+
+// LockByID finds upload and locks its row until end of transaction, so parts of upload are reserved one by one
+func (r *Repository) LockByID(ctx context.Context, userID, id uuid.UUID) (*UploadModel, error) {
+	return r.findByID(
+		ctx,
+		`select id, user_id, object_name, s3_upload_id, created_at, completed_at from uploads where id = $1 and user_id = $2 for update;`,
+		userID,
+		id,
+	)
+}
+
+func (r *Repository) findByID(ctx context.Context, query string, userID, id uuid.UUID) (*UploadModel, error) {
+	upload := UploadModel{}
+
+	err := r.q.QueryRowContext(ctx, query, id, userID).Scan(
+		&upload.ID,
+		&upload.UserID,
+		&upload.ObjectName,
+		&upload.StoreUploadID,
+		&upload.CreatedAt,
+		&upload.CompletedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrUploadNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &upload, nil
+}
+
+// LoadParts returns parts ordered by number, pending parts included
+func (r *Repository) LoadParts(ctx context.Context, uploadID uuid.UUID) ([]PartModel, error) {
+	rows, err := r.q.QueryContext(
+		ctx,
+		`select part_number, etag, size, pending_since from upload_parts where upload_id = $1 order by part_number;`,
+		uploadID,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	parts := make([]PartModel, 0)
+
+	for rows.Next() {
+		part := PartModel{}
+
+		if err := rows.Scan(&part.Number, &part.ETag, &part.Size, &part.PendingSince); err != nil {
+			return nil, err
+		}
+
+		parts = append(parts, part)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return parts, nil
+}
+
+// AddPendingPart reserves number of part before its upload, part gets etag by SavePart.
+// Part with the same number is rejected as offset mismatch, though writer holding row lock of upload never meets it
+func (r *Repository) AddPendingPart(ctx context.Context, uploadID uuid.UUID, part *PartModel) error {
+	err := r.q.QueryRowContext(
+		ctx,
+		`insert into upload_parts (upload_id, part_number, etag, size, pending_since) values ($1, $2, '', $3, now())
+		on conflict do nothing returning pending_since;`,
+		uploadID,
+		part.Number,
+		part.Size,
+	).Scan(&part.PendingSince)
+
+	if err == sql.ErrNoRows {
+		return ErrOffsetMismatch
+	}
+
+	return err
+}
+
+// SavePart saves etag of uploaded part. Pending part is matched by pending_since, so part reserved again
+// after upload of the writer is considered abandoned is not overwritten
+func (r *Repository) SavePart(ctx context.Context, uploadID uuid.UUID, part PartModel) error {
+	result, err := r.q.ExecContext(
+		ctx,
+		`update upload_parts set etag = $3, size = $4, pending_since = null
+		where upload_id = $1 and part_number = $2 and pending_since = $5;`,
+		uploadID,
+		part.Number,
+		part.ETag,
+		part.Size,
+		part.PendingSince,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	countAffected, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if countAffected == 0 {
+		return ErrOffsetMismatch
+	}
+
+	return nil
+}
+
+// DeletePendingPart removes part which was not uploaded, reports whether part was removed
+func (r *Repository) DeletePendingPart(ctx context.Context, uploadID uuid.UUID, part PartModel) (bool, error) {
+	result, err := r.q.ExecContext(
+		ctx,
+		`delete from upload_parts where upload_id = $1 and part_number = $2 and pending_since = $3;`,
+		uploadID,
+		part.Number,
+		part.PendingSince,
+	)
+
+	if err != nil {
+		return false, err
+	}
+
+	countAffected, err := result.RowsAffected()
+
+	if err != nil {
+		return false, err
+	}
+
+	return countAffected != 0, nil
+}
+
+func (r *Repository) Delete(ctx context.Context, uploadID uuid.UUID) error {
+	_, err := r.q.ExecContext(
+		ctx,
+		`delete from uploads where id = $1;`,
+		uploadID,
+	)
+
+	return err
+}
+
+// MarkCompleted keeps upload after its object is joined, so repeated complete returns the same object
+func (r *Repository) MarkCompleted(ctx context.Context, uploadID uuid.UUID) error {
+	_, err := r.q.ExecContext(
+		ctx,
+		`update uploads set completed_at = now() where id = $1 and completed_at is null;`,
+		uploadID,
+	)
+
+	return err
+}
+
+// DeleteCompleted removes uploads completed before time before, returns count of removed uploads
+func (r *Repository) DeleteCompleted(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.q.ExecContext(
+		ctx,
+		`delete from uploads where completed_at < $1;`,
+		before,
+	)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
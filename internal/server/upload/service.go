@@ -0,0 +1,392 @@
+package upload
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jaevor/go-nanoid"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+
+	"github.com/shreyner/gophkeeper/internal/server/blob"
+	"github.com/shreyner/gophkeeper/internal/server/pgk/blobstore"
+	"github.com/shreyner/gophkeeper/internal/server/quota"
+)
+
+const (
+	// MinChunkSize is minimal S3 part size, only the last chunk can be smaller
+	MinChunkSize = 5 << 20
+	MaxChunkSize = 64 << 20
+
+	// pendingPartTimeout is longer than request timeout of chunk, older pending part is left by crashed writer
+	pendingPartTimeout = 2 * time.Minute
+)
+
+type Service struct {
+	log          *zap.Logger
+	rep          *Repository
+	store        blobstore.Store
+	quotaService *quota.Service
+	blobService  *blob.Service
+	randID       func() string
+
+	// grace is time for client to repeat complete after lost response
+	grace time.Duration
+}
+
+func NewService(
+	log *zap.Logger,
+	rep *Repository,
+	store blobstore.Store,
+	quotaService *quota.Service,
+	blobService *blob.Service,
+	grace time.Duration,
+) *Service {
+	randID, _ := nanoid.Standard(36)
+
+	service := Service{
+		log:          log,
+		rep:          rep,
+		store:        store,
+		quotaService: quotaService,
+		blobService:  blobService,
+		randID:       randID,
+		grace:        grace,
+	}
+
+	return &service
+}
+
+// Create starts multipart upload of store for new object
+func (s *Service) Create(ctx context.Context, userID uuid.UUID) (*UploadModel, error) {
+	objectName := s.randID()
+
+	storeUploadID, err := s.store.NewMultipart(ctx, objectName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	upload := UploadModel{
+		ID:            uuid.New(),
+		UserID:        userID,
+		ObjectName:    objectName,
+		StoreUploadID: storeUploadID,
+	}
+
+	err = s.rep.Create(ctx, &upload)
+
+	if err != nil {
+		_ = s.store.AbortMultipart(ctx, objectName, storeUploadID)
+		return nil, err
+	}
+
+	return &upload, nil
+}
+
+// Offset returns count of bytes saved by upload
+func (s *Service) Offset(ctx context.Context, userID, id uuid.UUID) (int64, error) {
+	_, err := s.rep.FindByID(ctx, userID, id)
+
+	if err != nil {
+		return 0, err
+	}
+
+	parts, err := s.rep.LoadParts(ctx, id)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return partsSize(savedParts(parts)), nil
+}
+
+// WriteChunk uploads chunk as the next part. Chunk is accepted only at current offset of upload,
+// so repeated chunk after lost response is rejected. Returns offset after chunk
+func (s *Service) WriteChunk(ctx context.Context, userID, id uuid.UUID, offset int64, r io.Reader, size int64) (int64, error) {
+	err := s.quotaService.ReserveBytes(ctx, userID, size)
+
+	if err != nil {
+		return 0, err
+	}
+
+	upload, part, err := s.reservePart(ctx, userID, id, offset, size)
+
+	if err != nil {
+		s.releaseBytes(userID, size)
+		return 0, err
+	}
+
+	// Chunk is read from client outside of transaction, slow client holds neither connection nor row lock
+	storePart, err := s.store.PutPart(ctx, upload.ObjectName, upload.StoreUploadID, part.Number, r, size)
+
+	if err != nil {
+		s.dropPendingPart(userID, id, part)
+		return 0, err
+	}
+
+	part.ETag = storePart.ETag
+	part.Size = storePart.Size
+
+	err = s.rep.SavePart(ctx, id, part)
+
+	if err != nil {
+		s.dropPendingPart(userID, id, part)
+		return 0, err
+	}
+
+	return offset + part.Size, nil
+}
+
+// reservePart adds pending part at offset under row lock of upload, so concurrent chunk at the same offset
+// gets part in progress instead of the same part number. Part abandoned by crashed writer is replaced
+func (s *Service) reservePart(ctx context.Context, userID, id uuid.UUID, offset int64, size int64) (*UploadModel, PartModel, error) {
+	var (
+		upload    *UploadModel
+		part      PartModel
+		abandoned *PartModel
+	)
+
+	err := s.rep.InTx(ctx, func(txRep *Repository) error {
+		var err error
+
+		upload, err = txRep.LockByID(ctx, userID, id)
+
+		if err != nil {
+			return err
+		}
+
+		if upload.CompletedAt != nil {
+			return ErrUploadFinished
+		}
+
+		parts, err := txRep.LoadParts(ctx, id)
+
+		if err != nil {
+			return err
+		}
+
+		if len(parts) != 0 && parts[len(parts)-1].PendingSince != nil {
+			lastPart := parts[len(parts)-1]
+
+			if time.Since(*lastPart.PendingSince) < pendingPartTimeout {
+				return ErrPartInProgress
+			}
+
+			isDeleted, err := txRep.DeletePendingPart(ctx, id, lastPart)
+
+			if err != nil {
+				return err
+			}
+
+			if isDeleted {
+				abandoned = &lastPart
+			}
+
+			parts = parts[:len(parts)-1]
+		}
+
+		if partsSize(parts) != offset {
+			return ErrOffsetMismatch
+		}
+
+		if len(parts) != 0 && parts[len(parts)-1].Size < MinChunkSize {
+			return ErrUploadFinished
+		}
+
+		part = PartModel{Number: len(parts) + 1, Size: size}
+
+		return txRep.AddPendingPart(ctx, id, &part)
+	})
+
+	if err != nil {
+		return nil, PartModel{}, err
+	}
+
+	// Bytes of abandoned part were reserved by its writer
+	if abandoned != nil {
+		s.releaseBytes(userID, abandoned.Size)
+	}
+
+	return upload, part, nil
+}
+
+// Complete joins parts to object and returns its location. Repeated complete returns the same location
+// until completed upload is removed after grace period
+func (s *Service) Complete(ctx context.Context, userID, id uuid.UUID) (string, error) {
+	upload, err := s.rep.FindByID(ctx, userID, id)
+
+	if err != nil {
+		return "", err
+	}
+
+	if upload.CompletedAt != nil {
+		return Location(upload.ObjectName), nil
+	}
+
+	parts, err := s.rep.LoadParts(ctx, id)
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(savedParts(parts)) != len(parts) {
+		return "", ErrPartInProgress
+	}
+
+	// Blob is registered before object appears, so object is collected if client never claims it
+	err = s.blobService.Register(ctx, userID, upload.ObjectName, partsSize(parts))
+
+	if err != nil {
+		return "", err
+	}
+
+	storeParts := make([]blobstore.Part, len(parts))
+
+	for i, part := range parts {
+		storeParts[i] = blobstore.Part{Number: part.Number, ETag: part.ETag, Size: part.Size}
+	}
+
+	err = s.store.CompleteMultipart(ctx, upload.ObjectName, upload.StoreUploadID, storeParts)
+
+	if err != nil {
+		return "", err
+	}
+
+	err = s.rep.MarkCompleted(ctx, id)
+
+	if err != nil {
+		return "", err
+	}
+
+	return Location(upload.ObjectName), nil
+}
+
+// Abort drops upload and uploaded parts. Completed upload is not aborted, its object is owned by blob
+func (s *Service) Abort(ctx context.Context, userID, id uuid.UUID) error {
+	upload, err := s.rep.FindByID(ctx, userID, id)
+
+	if err != nil {
+		return err
+	}
+
+	if upload.CompletedAt != nil {
+		return ErrUploadFinished
+	}
+
+	err = s.store.AbortMultipart(ctx, upload.ObjectName, upload.StoreUploadID)
+
+	if err != nil {
+		return err
+	}
+
+	var parts []PartModel
+
+	// Parts are loaded under row lock, so part reserved by concurrent chunk is either released here or by its writer
+	err = s.rep.InTx(ctx, func(txRep *Repository) error {
+		lockedUpload, err := txRep.LockByID(ctx, userID, id)
+
+		if err != nil {
+			return err
+		}
+
+		if lockedUpload.CompletedAt != nil {
+			return ErrUploadFinished
+		}
+
+		parts, err = txRep.LoadParts(ctx, id)
+
+		if err != nil {
+			return err
+		}
+
+		return txRep.Delete(ctx, id)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	// Blob is registered by failed complete, its bytes are released by collection
+	isRegistered, err := s.blobService.IsRegistered(ctx, upload.ObjectName)
+
+	if err != nil || isRegistered {
+		return err
+	}
+
+	// Bytes of pending parts are released here too, writer of removed part doesn't release them
+	return s.quotaService.ReleaseBytes(ctx, userID, partsSize(parts))
+}
+
+// Run removes completed uploads after grace period every interval until ctx is done
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		_, err := s.rep.DeleteCompleted(ctx, time.Now().Add(-s.grace))
+
+		if err != nil && ctx.Err() == nil {
+			s.log.Error("completed uploads cleanup failed", zap.Error(err))
+		}
+	}
+}
+
+// dropPendingPart removes part of failed chunk and returns its bytes. Bytes are released only by the one
+// who removed part, so part removed by abort or by replace of abandoned part is not released twice
+func (s *Service) dropPendingPart(userID, id uuid.UUID, part PartModel) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	isDeleted, err := s.rep.DeletePendingPart(ctx, id, part)
+
+	if err != nil || !isDeleted {
+		return
+	}
+
+	_ = s.quotaService.ReleaseBytes(ctx, userID, part.Size)
+}
+
+// releaseBytes returns reserved bytes of failed chunk, request context could be already canceled
+func (s *Service) releaseBytes(userID uuid.UUID, size int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_ = s.quotaService.ReleaseBytes(ctx, userID, size)
+}
+
+// Location returns url of object download, path is relative to server address
+func Location(objectName string) string {
+	return fmt.Sprintf("/blobs/%s", objectName)
+}
+
+func partsSize(parts []PartModel) int64 {
+	var size int64
+
+	for _, part := range parts {
+		size += part.Size
+	}
+
+	return size
+}
+
+// savedParts returns parts uploaded to store, pending parts are skipped
+func savedParts(parts []PartModel) []PartModel {
+	saved := make([]PartModel, 0, len(parts))
+
+	for _, part := range parts {
+		if part.PendingSince == nil {
+			saved = append(saved, part)
+		}
+	}
+
+	return saved
+}
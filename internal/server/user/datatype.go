@@ -2,6 +2,7 @@ package user
 
 import (
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
@@ -11,6 +12,15 @@ type UserModel struct {
 	ID       uuid.UUID
 	Login    string
 	password string
+
+	// TOTPSecret is set when second factor is enabled, TOTPPendingSecret waits for confirmation by code
+	TOTPSecret        string
+	TOTPPendingSecret string
+	TOTPLastStep      int64
+}
+
+func (m *UserModel) IsTOTPEnabled() bool {
+	return m.TOTPSecret != ""
 }
 
 func (m *UserModel) SetPassword(password string) error {
@@ -37,3 +47,11 @@ func (m *UserModel) VerifyPassword(password string) (bool, error) {
 
 	return true, nil
 }
+
+// InviteModel allows registration when server is invite-only, code itself is shown once and isn't stored
+type InviteModel struct {
+	CodeHash  string
+	CreatedBy uuid.UUID
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
@@ -5,3 +5,9 @@ import "errors"
 var ErrLoginAlreadyExist = errors.New("login already exist")
 
 var ErrUserNotFound = errors.New("not found")
+
+var ErrInviteInvalid = errors.New("invite code is invalid, expired or already used")
+
+var ErrTOTPStepUsed = errors.New("totp code is already used")
+
+var ErrBackupCodeInvalid = errors.New("backup code is invalid or already used")
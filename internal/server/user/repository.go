@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5/pgconn"
 	"golang.org/x/net/context"
@@ -20,8 +21,61 @@ func NewRepository(db *sql.DB) *Repository {
 	return &userRepository
 }
 
+// execer is implemented by *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
 func (r *Repository) Create(ctx context.Context, user *UserModel) error {
-	_, err := r.db.ExecContext(
+	return insertUser(ctx, r.db, user)
+}
+
+// CreateWithInvite creates user and spends invite in one transaction, ErrInviteInvalid is returned
+// when invite doesn't exist, is expired or already used
+func (r *Repository) CreateWithInvite(ctx context.Context, user *UserModel, codeHash string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	err = insertUser(ctx, tx, user)
+
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(
+		ctx,
+		`update invites set used_by = $2, used_at = now()
+		 where code_hash = $1 and used_at is null and expires_at > now();`,
+		codeHash,
+		user.ID,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrInviteInvalid
+	}
+
+	return tx.Commit()
+}
+
+func insertUser(ctx context.Context, q execer, user *UserModel) error {
+	_, err := q.ExecContext(
 		ctx,
 		`insert into users (id, login, password) values ($1, $2, $3);`,
 		user.ID,
@@ -49,7 +103,8 @@ func (r *Repository) Create(ctx context.Context, user *UserModel) error {
 func (r *Repository) FindByLogin(ctx context.Context, login string) (*UserModel, error) {
 	row := r.db.QueryRowContext(
 		ctx,
-		`select id, login, password from users u where u.login = $1 limit 1;`,
+		`select id, login, password, coalesce(totp_secret, ''), coalesce(totp_pending_secret, ''), totp_last_step
+		from users u where u.login = $1 limit 1;`,
 		login,
 	)
 
@@ -59,7 +114,48 @@ func (r *Repository) FindByLogin(ctx context.Context, login string) (*UserModel,
 
 	userModel := UserModel{}
 
-	err := row.Scan(&userModel.ID, &userModel.Login, &userModel.password)
+	err := row.Scan(
+		&userModel.ID,
+		&userModel.Login,
+		&userModel.password,
+		&userModel.TOTPSecret,
+		&userModel.TOTPPendingSecret,
+		&userModel.TOTPLastStep,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+
+		return nil, err
+	}
+
+	return &userModel, nil
+}
+
+func (r *Repository) FindByID(ctx context.Context, id uuid.UUID) (*UserModel, error) {
+	row := r.db.QueryRowContext(
+		ctx,
+		`select id, login, password, coalesce(totp_secret, ''), coalesce(totp_pending_secret, ''), totp_last_step
+		from users u where u.id = $1 limit 1;`,
+		id,
+	)
+
+	if row.Err() != nil {
+		return nil, row.Err()
+	}
+
+	userModel := UserModel{}
+
+	err := row.Scan(
+		&userModel.ID,
+		&userModel.Login,
+		&userModel.password,
+		&userModel.TOTPSecret,
+		&userModel.TOTPPendingSecret,
+		&userModel.TOTPLastStep,
+	)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -71,3 +167,164 @@ func (r *Repository) FindByLogin(ctx context.Context, login string) (*UserModel,
 
 	return &userModel, nil
 }
+
+func (r *Repository) CreateInvite(ctx context.Context, invite *InviteModel) error {
+	row := r.db.QueryRowContext(
+		ctx,
+		`insert into invites (code_hash, created_by, expires_at) values ($1, $2, $3) returning created_at;`,
+		invite.CodeHash,
+		invite.CreatedBy,
+		invite.ExpiresAt,
+	)
+
+	return row.Scan(&invite.CreatedAt)
+}
+
+func (r *Repository) SetPendingTOTP(ctx context.Context, id uuid.UUID, secret string) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`update users set totp_pending_secret = $2 where id = $1;`,
+		id,
+		secret,
+	)
+
+	return err
+}
+
+// EnableTOTP moves pending secret to enabled one and replaces backup codes in one transaction
+func (r *Repository) EnableTOTP(ctx context.Context, id uuid.UUID, step int64, backupCodeHashes []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	_, err = tx.ExecContext(
+		ctx,
+		`update users set totp_secret = totp_pending_secret, totp_pending_secret = null, totp_last_step = $2
+		where id = $1 and totp_pending_secret is not null;`,
+		id,
+		step,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	err = replaceBackupCodes(ctx, tx, id, backupCodeHashes)
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *Repository) DisableTOTP(ctx context.Context, id uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	_, err = tx.ExecContext(
+		ctx,
+		`update users set totp_secret = null, totp_pending_secret = null, totp_last_step = 0 where id = $1;`,
+		id,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	err = replaceBackupCodes(ctx, tx, id, nil)
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func replaceBackupCodes(ctx context.Context, q execer, id uuid.UUID, codeHashes []string) error {
+	_, err := q.ExecContext(ctx, `delete from user_backup_codes where user_id = $1;`, id)
+
+	if err != nil {
+		return err
+	}
+
+	for _, codeHash := range codeHashes {
+		_, err = q.ExecContext(
+			ctx,
+			`insert into user_backup_codes (user_id, code_hash) values ($1, $2);`,
+			id,
+			codeHash,
+		)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UseTOTPStep saves step of accepted code, ErrTOTPStepUsed is returned when the same or later step is already used
+func (r *Repository) UseTOTPStep(ctx context.Context, id uuid.UUID, step int64) error {
+	result, err := r.db.ExecContext(
+		ctx,
+		`update users set totp_last_step = $2 where id = $1 and totp_last_step < $2;`,
+		id,
+		step,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrTOTPStepUsed
+	}
+
+	return nil
+}
+
+// UseBackupCode spends backup code, ErrBackupCodeInvalid is returned for unknown or used code
+func (r *Repository) UseBackupCode(ctx context.Context, id uuid.UUID, codeHash string) error {
+	result, err := r.db.ExecContext(
+		ctx,
+		`update user_backup_codes set used_at = now() where user_id = $1 and code_hash = $2 and used_at is null;`,
+		id,
+		codeHash,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrBackupCodeInvalid
+	}
+
+	return nil
+}
@@ -1,10 +1,24 @@
 package user
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"strings"
+	"time"
+	"unicode"
+
 	"github.com/google/uuid"
 	"golang.org/x/net/context"
 )
 
+const (
+	inviteCodeSize = 10
+	backupCodeSize = 5
+	backupCodes    = 10
+)
+
 type Service struct {
 	rep *Repository
 }
@@ -18,6 +32,60 @@ func NewService(rep *Repository) *Service {
 }
 
 func (s *Service) Create(ctx context.Context, login, password string) (*UserModel, error) {
+	userModel, err := newUser(login, password)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.rep.Create(ctx, userModel); err != nil {
+		return nil, err
+	}
+
+	return userModel, nil
+}
+
+// CreateWithInvite creates user and spends invite code, ErrInviteInvalid is returned for unknown, expired or used code
+func (s *Service) CreateWithInvite(ctx context.Context, login, password, inviteCode string) (*UserModel, error) {
+	userModel, err := newUser(login, password)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.rep.CreateWithInvite(ctx, userModel, hashCode(inviteCode)); err != nil {
+		return nil, err
+	}
+
+	return userModel, nil
+}
+
+// CreateInvite generates invite code valid during ttl, code is returned only once
+func (s *Service) CreateInvite(ctx context.Context, createdBy uuid.UUID, ttl time.Duration) (string, *InviteModel, error) {
+	code, err := generateCode(inviteCodeSize)
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	invite := InviteModel{
+		CodeHash:  hashCode(code),
+		CreatedBy: createdBy,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+
+	if err := s.rep.CreateInvite(ctx, &invite); err != nil {
+		return "", nil, err
+	}
+
+	return code, &invite, nil
+}
+
+func (s *Service) FindByID(ctx context.Context, id uuid.UUID) (*UserModel, error) {
+	return s.rep.FindByID(ctx, id)
+}
+
+func newUser(login, password string) (*UserModel, error) {
 	userModel := UserModel{
 		ID:    uuid.New(),
 		Login: login,
@@ -27,13 +95,81 @@ func (s *Service) Create(ctx context.Context, login, password string) (*UserMode
 		return nil, err
 	}
 
-	if err := s.rep.Create(ctx, &userModel); err != nil {
-		return nil, err
+	return &userModel, nil
+}
+
+// generateCode returns random bytes as base32 groups, e.g. ABCD-EFGH-IJKL-MNOP for 10 bytes
+func generateCode(size int) (string, error) {
+	b := make([]byte, size)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
 	}
 
-	return &userModel, nil
+	encoded := base32.StdEncoding.EncodeToString(b)
+
+	groups := make([]string, 0, len(encoded)/4)
+	for i := 0; i < len(encoded); i += 4 {
+		groups = append(groups, encoded[i:i+4])
+	}
+
+	return strings.Join(groups, "-"), nil
+}
+
+// hashCode ignores case, spaces and dashes, so code can be typed by hand
+func hashCode(code string) string {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || unicode.IsSpace(r) {
+			return -1
+		}
+
+		return unicode.ToUpper(r)
+	}, code)
+
+	sum := sha256.Sum256([]byte(normalized))
+
+	return hex.EncodeToString(sum[:])
 }
 
 func (s *Service) FindByLogin(ctx context.Context, login string) (*UserModel, error) {
 	return s.rep.FindByLogin(ctx, login)
 }
+
+func (s *Service) SetPendingTOTP(ctx context.Context, id uuid.UUID, secret string) error {
+	return s.rep.SetPendingTOTP(ctx, id, secret)
+}
+
+// EnableTOTP enables pending secret confirmed by code of step and returns new backup codes, they are shown once
+func (s *Service) EnableTOTP(ctx context.Context, id uuid.UUID, step int64) ([]string, error) {
+	codes := make([]string, 0, backupCodes)
+	hashes := make([]string, 0, backupCodes)
+
+	for i := 0; i < backupCodes; i++ {
+		code, err := generateCode(backupCodeSize)
+
+		if err != nil {
+			return nil, err
+		}
+
+		codes = append(codes, code)
+		hashes = append(hashes, hashCode(code))
+	}
+
+	if err := s.rep.EnableTOTP(ctx, id, step, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (s *Service) DisableTOTP(ctx context.Context, id uuid.UUID) error {
+	return s.rep.DisableTOTP(ctx, id)
+}
+
+func (s *Service) UseTOTPStep(ctx context.Context, id uuid.UUID, step int64) error {
+	return s.rep.UseTOTPStep(ctx, id, step)
+}
+
+func (s *Service) UseBackupCode(ctx context.Context, id uuid.UUID, code string) error {
+	return s.rep.UseBackupCode(ctx, id, hashCode(code))
+}
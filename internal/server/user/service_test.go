@@ -0,0 +1,72 @@
+package user
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_generateCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		pattern string
+	}{
+		{
+			name:    "Invite code",
+			size:    inviteCodeSize,
+			pattern: `^[A-Z2-7]{4}-[A-Z2-7]{4}-[A-Z2-7]{4}-[A-Z2-7]{4}$`,
+		},
+		{
+			name:    "Backup code",
+			size:    backupCodeSize,
+			pattern: `^[A-Z2-7]{4}-[A-Z2-7]{4}$`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := generateCode(tt.size)
+			require.NoError(t, err)
+
+			assert.Regexp(t, regexp.MustCompile(tt.pattern), code)
+
+			other, err := generateCode(tt.size)
+			require.NoError(t, err)
+
+			assert.NotEqual(t, code, other)
+		})
+	}
+}
+
+func Test_hashCode(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+	}{
+		{
+			name: "Same code",
+			code: "ABCD-EFGH-IJKL-MNOP",
+		},
+		{
+			name: "Lower case",
+			code: "abcd-efgh-ijkl-mnop",
+		},
+		{
+			name: "Without dashes",
+			code: "ABCDEFGHIJKLMNOP",
+		},
+		{
+			name: "With spaces",
+			code: " ABCD EFGH IJKL MNOP ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, hashCode("ABCD-EFGH-IJKL-MNOP"), hashCode(tt.code))
+		})
+	}
+
+	assert.NotEqual(t, hashCode("ABCD-EFGH-IJKL-MNOP"), hashCode("ABCD-EFGH-IJKL-MNOQ"))
+}
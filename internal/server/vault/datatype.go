@@ -14,4 +14,28 @@ type VaultModel struct {
 	Version   int
 	IsDeleted bool
 	S3        *string
+	ChangeSeq int64
+}
+
+type BatchOperationType int
+
+const (
+	BatchOperationCreate BatchOperationType = iota
+	BatchOperationUpdate
+	BatchOperationDelete
+)
+
+type BatchOperation struct {
+	Type    BatchOperationType
+	ID      uuid.UUID // for update and delete
+	Vault   []byte
+	Version int
+	S3      *string
+}
+
+// BatchResult of one operation. Err is ErrVaultConflict or ErrVaultNotFound when operation is skipped
+type BatchResult struct {
+	ID      uuid.UUID
+	Version int
+	Err     error
 }
@@ -5,3 +5,11 @@ import "errors"
 var ErrVaultNotFound = errors.New("vault not found")
 
 var ErrVaultConflict = errors.New("vault conflict")
+
+var ErrInvalidBatchOperation = errors.New("invalid batch operation")
+
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+var ErrItemsQuotaExceeded = errors.New("vault items quota exceeded")
+
+var ErrBlobNotFound = errors.New("blob not found")
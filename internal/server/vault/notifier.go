@@ -0,0 +1,208 @@
+package vault
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/stdlib"
+	"go.uber.org/zap"
+	"golang.org/x/net/context"
+)
+
+const (
+	notifyChannel = "vault_changes"
+
+	notifierReconnectDelayMin = time.Second
+	notifierReconnectDelayMax = 30 * time.Second
+)
+
+type changeEvent struct {
+	UserID uuid.UUID `json:"user_id"`
+	Cursor int64     `json:"cursor"`
+}
+
+// Notifier listens vault changes from Postgres and fans out them to subscribers of this replica
+type Notifier struct {
+	log *zap.Logger
+	db  *sql.DB
+
+	mux         sync.Mutex
+	subscribers map[uuid.UUID]map[chan int64]struct{}
+	isClosed    bool
+}
+
+func NewNotifier(log *zap.Logger, db *sql.DB) *Notifier {
+	notifier := Notifier{
+		log:         log,
+		db:          db,
+		subscribers: make(map[uuid.UUID]map[chan int64]struct{}),
+	}
+
+	return &notifier
+}
+
+// Subscribe returns channel with cursors of user changes. Call cancel to unsubscribe.
+// Channel is closed when notifier stops
+func (n *Notifier) Subscribe(userID uuid.UUID) (<-chan int64, func()) {
+	ch := make(chan int64, 1)
+
+	n.mux.Lock()
+	defer n.mux.Unlock()
+
+	if n.isClosed {
+		close(ch)
+		return ch, func() {}
+	}
+
+	if _, ok := n.subscribers[userID]; !ok {
+		n.subscribers[userID] = make(map[chan int64]struct{})
+	}
+
+	n.subscribers[userID][ch] = struct{}{}
+
+	cancel := func() {
+		n.mux.Lock()
+		defer n.mux.Unlock()
+
+		if _, ok := n.subscribers[userID][ch]; !ok {
+			return
+		}
+
+		delete(n.subscribers[userID], ch)
+
+		if len(n.subscribers[userID]) == 0 {
+			delete(n.subscribers, userID)
+		}
+	}
+
+	return ch, cancel
+}
+
+func (n *Notifier) publish(userID uuid.UUID, cursor int64) {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+
+	for ch := range n.subscribers[userID] {
+		notify(ch, cursor)
+	}
+}
+
+// publishAll wakes up all subscribers, notifications could be lost while connection was down
+func (n *Notifier) publishAll() {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+
+	for _, userSubscribers := range n.subscribers {
+		for ch := range userSubscribers {
+			notify(ch, 0)
+		}
+	}
+}
+
+// notify keeps only the latest cursor, subscriber loads all changes after own cursor anyway
+func notify(ch chan int64, cursor int64) {
+	select {
+	case ch <- cursor:
+	default:
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- cursor
+	}
+}
+
+// Run listens notifications until ctx is done and reconnects on errors
+func (n *Notifier) Run(ctx context.Context) {
+	defer n.close()
+
+	delay := notifierReconnectDelayMin
+
+	for {
+		err := n.listen(ctx)
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		n.log.Error("vault notifier lost connection", zap.Error(err), zap.Duration("retry", delay))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > notifierReconnectDelayMax {
+			delay = notifierReconnectDelayMax
+		}
+	}
+}
+
+func (n *Notifier) close() {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+
+	n.isClosed = true
+
+	for userID, userSubscribers := range n.subscribers {
+		for ch := range userSubscribers {
+			close(ch)
+		}
+
+		delete(n.subscribers, userID)
+	}
+}
+
+func (n *Notifier) listen(ctx context.Context) error {
+	conn, err := n.db.Conn(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		stdlibConn, ok := driverConn.(*stdlib.Conn)
+
+		if !ok {
+			return errors.New("unsupported database driver for listen")
+		}
+
+		pgxConn := stdlibConn.Conn()
+
+		_, err := pgxConn.Exec(ctx, "listen "+notifyChannel)
+
+		if err != nil {
+			return err
+		}
+
+		n.log.Info("vault notifier listen", zap.String("channel", notifyChannel))
+		n.publishAll()
+
+		for {
+			notification, err := pgxConn.WaitForNotification(ctx)
+
+			if err != nil {
+				return err
+			}
+
+			var event changeEvent
+
+			err = json.Unmarshal([]byte(notification.Payload), &event)
+
+			if err != nil {
+				n.log.Error("invalid vault change notification", zap.Error(err), zap.String("payload", notification.Payload))
+				continue
+			}
+
+			n.publish(event.UserID, event.Cursor)
+		}
+	})
+}
@@ -0,0 +1,31 @@
+package vault
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+)
+
+// EncodePageToken returns opaque continuation token pointing after cursor
+func EncodePageToken(cursor int64) string {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(cursor))
+
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// DecodePageToken returns cursor from continuation token
+func DecodePageToken(token string) (int64, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(token)
+
+	if err != nil || len(buf) != 8 {
+		return 0, ErrInvalidPageToken
+	}
+
+	cursor := int64(binary.BigEndian.Uint64(buf))
+
+	if cursor < 0 {
+		return 0, ErrInvalidPageToken
+	}
+
+	return cursor, nil
+}
@@ -7,18 +7,46 @@ import (
 	"golang.org/x/net/context"
 )
 
+// queryer is implemented by *sql.DB and *sql.Tx
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 type Repository struct {
 	db *sql.DB
+	q  queryer
 }
 
 func NewRepository(db *sql.DB) *Repository {
-	repository := Repository{db: db}
+	repository := Repository{db: db, q: db}
 
 	return &repository
 }
 
+// InTx runs fn with repository bound to one transaction. Transaction is committed when fn returns nil
+func (r *Repository) InTx(ctx context.Context, fn func(txRep *Repository) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	txRepository := Repository{db: r.db, q: tx}
+
+	err = fn(&txRepository)
+
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
 func (r *Repository) Create(ctx context.Context, vault *VaultModel) error {
-	_, err := r.db.ExecContext(
+	_, err := r.q.ExecContext(
 		ctx,
 		`insert into vaults (id, user_id, vault, version, s3) values ($1, $2::uuid, $3::bytea, $4, $5);`,
 		vault.ID,
@@ -35,9 +63,57 @@ func (r *Repository) Create(ctx context.Context, vault *VaultModel) error {
 	return err
 }
 
+// ClaimBlob links uploaded blob of user to vault, previous blob of vault is unlinked and becomes orphan.
+// Blob of other vault or collected blob can't be claimed
+func (r *Repository) ClaimBlob(ctx context.Context, userID, vaultID uuid.UUID, objectName string) error {
+	result, err := r.q.ExecContext(
+		ctx,
+		`update blobs set vault_id = $1 where object_name = $2 and user_id = $3 and (vault_id is null or vault_id = $1);`,
+		vaultID,
+		objectName,
+		userID,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	countAffected, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if countAffected == 0 {
+		return ErrBlobNotFound
+	}
+
+	_, err = r.q.ExecContext(
+		ctx,
+		`update blobs set vault_id = null where vault_id = $1 and object_name <> $2;`,
+		vaultID,
+		objectName,
+	)
+
+	return err
+}
+
+// CountActive returns count of not deleted vaults of user
+func (r *Repository) CountActive(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var count int64
+
+	err := r.q.QueryRowContext(
+		ctx,
+		`select count(*) from vaults where user_id = $1 and is_deleted = false;`,
+		userID,
+	).Scan(&count)
+
+	return count, err
+}
+
 func (r *Repository) checkIsExists(ctx context.Context, userID, id uuid.UUID) error {
 	var check bool
-	err := r.db.QueryRowContext(
+	err := r.q.QueryRowContext(
 		ctx,
 		`select true from vaults where id = $1 and user_id = $2 and is_deleted = false;`,
 		id,
@@ -55,7 +131,7 @@ func (r *Repository) checkIsExists(ctx context.Context, userID, id uuid.UUID) er
 	return nil
 }
 
-func (r *Repository) UpdateVault(ctx context.Context, userID, id uuid.UUID, vault []byte, version int) (int, error) {
+func (r *Repository) UpdateVault(ctx context.Context, userID, id uuid.UUID, vault []byte, version int, s3URL *string) (int, error) {
 	err := r.checkIsExists(ctx, userID, id)
 
 	if err != nil {
@@ -64,12 +140,13 @@ func (r *Repository) UpdateVault(ctx context.Context, userID, id uuid.UUID, vaul
 
 	var updatedVersion int
 
-	err = r.db.QueryRowContext(
+	err = r.q.QueryRowContext(
 		ctx,
-		`update vaults set vault = $2, version = version + 1 where id = $1 and version = $3 returning version;`,
+		`update vaults set vault = $2, version = version + 1, s3 = coalesce($4, s3) where id = $1 and version = $3 returning version;`,
 		id,
 		vault,
 		version,
+		s3URL,
 	).Scan(&updatedVersion)
 
 	if err == sql.ErrNoRows {
@@ -84,7 +161,7 @@ func (r *Repository) UpdateVault(ctx context.Context, userID, id uuid.UUID, vaul
 }
 
 func (r *Repository) Delete(ctx context.Context, userID, id uuid.UUID, version int) error {
-	result, err := r.db.ExecContext(
+	result, err := r.q.ExecContext(
 		ctx,
 		`update vaults set vault = null, is_deleted = true where id = $1 and version = $2 and user_id = $3;`,
 		id,
@@ -117,7 +194,7 @@ func (r *Repository) LoadUpdatedVaults(ctx context.Context, userID uuid.UUID, dt
 		mapVaultsVersions[dto[i].ID.String()] = dto[i].Version
 	}
 
-	rows, err := r.db.QueryContext(
+	rows, err := r.q.QueryContext(
 		ctx,
 		`select id, version, is_deleted from vaults where user_id = $1;`,
 		userID,
@@ -166,7 +243,7 @@ func (r *Repository) LoadUpdatedVaults(ctx context.Context, userID uuid.UUID, dt
 
 	}
 
-	vaultRows, err := r.db.QueryContext(
+	vaultRows, err := r.q.QueryContext(
 		ctx,
 		`select id, user_id, vault, version, is_deleted, s3 from vaults where user_id = $1 and id = any($2);`,
 		userID,
@@ -203,3 +280,66 @@ func (r *Repository) LoadUpdatedVaults(ctx context.Context, userID uuid.UUID, dt
 
 	return vaults, nil
 }
+
+// LoadChanges returns vaults changed after cursor ordered by change sequence
+func (r *Repository) LoadChanges(ctx context.Context, userID uuid.UUID, cursor int64) ([]VaultModel, error) {
+	rows, err := r.q.QueryContext(
+		ctx,
+		`select id, user_id, vault, version, is_deleted, s3, change_seq from vaults where user_id = $1 and change_seq > $2 order by change_seq;`,
+		userID,
+		cursor,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanChanges(rows)
+}
+
+// LoadChangesPage returns at most limit vaults changed after cursor ordered by change sequence
+func (r *Repository) LoadChangesPage(ctx context.Context, userID uuid.UUID, cursor int64, limit int) ([]VaultModel, error) {
+	rows, err := r.q.QueryContext(
+		ctx,
+		`select id, user_id, vault, version, is_deleted, s3, change_seq from vaults where user_id = $1 and change_seq > $2 order by change_seq limit $3;`,
+		userID,
+		cursor,
+		limit,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanChanges(rows)
+}
+
+func scanChanges(rows *sql.Rows) ([]VaultModel, error) {
+	vaults := make([]VaultModel, 0)
+
+	for rows.Next() {
+		vault := VaultModel{}
+
+		if err := rows.Scan(
+			&vault.ID,
+			&vault.UserID,
+			&vault.Vault,
+			&vault.Version,
+			&vault.IsDeleted,
+			&vault.S3,
+			&vault.ChangeSeq,
+		); err != nil {
+			return nil, err
+		}
+
+		vaults = append(vaults, vault)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return vaults, nil
+}
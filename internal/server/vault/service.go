@@ -1,16 +1,24 @@
 package vault
 
 import (
+	"errors"
+	"strings"
+
 	"github.com/google/uuid"
 	"golang.org/x/net/context"
 )
 
 type Service struct {
-	rep *Repository
+	rep      *Repository
+	maxItems int64
 }
 
-func NewService(rep *Repository) *Service {
-	service := Service{rep: rep}
+// NewService creates service, zero maxItems means unlimited count of vaults per user
+func NewService(rep *Repository, maxItems int64) *Service {
+	service := Service{
+		rep:      rep,
+		maxItems: maxItems,
+	}
 
 	return &service
 }
@@ -25,7 +33,21 @@ func (s *Service) Create(ctx context.Context, userId uuid.UUID, vault []byte, s3
 		S3:        s3URL,
 	}
 
-	err := s.rep.Create(ctx, &vaultModel)
+	err := s.rep.InTx(ctx, func(txRep *Repository) error {
+		err := txRep.Create(ctx, &vaultModel)
+
+		if err != nil {
+			return err
+		}
+
+		err = claimBlob(ctx, txRep, userId, vaultModel.ID, s3URL)
+
+		if err != nil {
+			return err
+		}
+
+		return s.checkItemsQuota(ctx, txRep, userId)
+	})
 
 	if err != nil {
 		return nil, err
@@ -34,8 +56,40 @@ func (s *Service) Create(ctx context.Context, userId uuid.UUID, vault []byte, s3
 	return &vaultModel, nil
 }
 
-func (s *Service) Update(ctx context.Context, userId, vaultID uuid.UUID, vault []byte, version int) (int, error) {
-	newVersion, err := s.rep.UpdateVault(ctx, userId, vaultID, vault, version)
+// checkItemsQuota is called after insert in the same transaction. Insert locks user row of change
+// sequence until commit, so concurrent creates of one user are counted one after another
+func (s *Service) checkItemsQuota(ctx context.Context, rep *Repository, userID uuid.UUID) error {
+	if s.maxItems == 0 {
+		return nil
+	}
+
+	count, err := rep.CountActive(ctx, userID)
+
+	if err != nil {
+		return err
+	}
+
+	if count > s.maxItems {
+		return ErrItemsQuotaExceeded
+	}
+
+	return nil
+}
+
+func (s *Service) Update(ctx context.Context, userId, vaultID uuid.UUID, vault []byte, version int, s3URL *string) (int, error) {
+	var newVersion int
+
+	err := s.rep.InTx(ctx, func(txRep *Repository) error {
+		var err error
+
+		newVersion, err = txRep.UpdateVault(ctx, userId, vaultID, vault, version, s3URL)
+
+		if err != nil {
+			return err
+		}
+
+		return claimBlob(ctx, txRep, userId, vaultID, s3URL)
+	})
 
 	if err != nil {
 		return 0, err
@@ -44,14 +98,163 @@ func (s *Service) Update(ctx context.Context, userId, vaultID uuid.UUID, vault [
 	return newVersion, nil
 }
 
+// claimBlob links object of S3 url to vault, vault without url keeps own blob
+func claimBlob(ctx context.Context, rep *Repository, userID, vaultID uuid.UUID, s3URL *string) error {
+	if s3URL == nil {
+		return nil
+	}
+
+	return rep.ClaimBlob(ctx, userID, vaultID, blobObjectName(*s3URL))
+}
+
+// blobObjectName returns object name from url of uploaded object, object name is the last path segment
+func blobObjectName(s3URL string) string {
+	return s3URL[strings.LastIndex(s3URL, "/")+1:]
+}
+
 func (s *Service) Delete(ctx context.Context, userID, vaultID uuid.UUID, version int) error {
 	err := s.rep.Delete(ctx, userID, vaultID, version)
 
 	return err
 }
 
+// Batch applies operations in one transaction. Conflicted operations are skipped and reported in results,
+// any other error rolls back the whole batch
+func (s *Service) Batch(ctx context.Context, userID uuid.UUID, operations []BatchOperation) ([]BatchResult, error) {
+	results := make([]BatchResult, len(operations))
+	hasCreate := false
+
+	err := s.rep.InTx(ctx, func(txRep *Repository) error {
+		for i, operation := range operations {
+			result, err := applyBatchOperation(ctx, txRep, userID, operation)
+
+			if errors.Is(err, ErrVaultConflict) || errors.Is(err, ErrVaultNotFound) {
+				results[i] = BatchResult{ID: operation.ID, Version: operation.Version, Err: err}
+				continue
+			}
+
+			if err != nil {
+				return err
+			}
+
+			results[i] = result
+
+			if operation.Type == BatchOperationCreate {
+				hasCreate = true
+			}
+		}
+
+		if !hasCreate {
+			return nil
+		}
+
+		// Deletes of the same batch free place for creates
+		return s.checkItemsQuota(ctx, txRep, userID)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func applyBatchOperation(ctx context.Context, rep *Repository, userID uuid.UUID, operation BatchOperation) (BatchResult, error) {
+	switch operation.Type {
+	case BatchOperationCreate:
+		vaultModel := VaultModel{
+			ID:      uuid.New(),
+			UserID:  userID,
+			Vault:   operation.Vault,
+			Version: 0,
+			S3:      operation.S3,
+		}
+
+		err := rep.Create(ctx, &vaultModel)
+
+		if err == nil {
+			err = claimBlob(ctx, rep, userID, vaultModel.ID, operation.S3)
+		}
+
+		return BatchResult{ID: vaultModel.ID, Version: vaultModel.Version}, err
+	case BatchOperationUpdate:
+		newVersion, err := rep.UpdateVault(ctx, userID, operation.ID, operation.Vault, operation.Version, operation.S3)
+
+		if err == nil {
+			err = claimBlob(ctx, rep, userID, operation.ID, operation.S3)
+		}
+
+		return BatchResult{ID: operation.ID, Version: newVersion}, err
+	case BatchOperationDelete:
+		err := rep.Delete(ctx, userID, operation.ID, operation.Version)
+
+		return BatchResult{ID: operation.ID, Version: operation.Version}, err
+	default:
+		return BatchResult{}, ErrInvalidBatchOperation
+	}
+}
+
 func (s *Service) LoadUpdated(ctx context.Context, userID uuid.UUID, vaultsVersionsDTO []VaultVersionDTO) ([]VaultModel, error) {
 	newVaults, err := s.rep.LoadUpdatedVaults(ctx, userID, vaultsVersionsDTO)
 
 	return newVaults, err
 }
+
+// LoadChanges returns vaults changed after cursor and cursor for the next call
+func (s *Service) LoadChanges(ctx context.Context, userID uuid.UUID, cursor int64) ([]VaultModel, int64, error) {
+	vaults, err := s.rep.LoadChanges(ctx, userID, cursor)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	nextCursor := cursor
+
+	if len(vaults) != 0 {
+		nextCursor = vaults[len(vaults)-1].ChangeSeq
+	}
+
+	return vaults, nextCursor, nil
+}
+
+// LoadChangesPage returns one page of vaults changed after cursor, cursor for the next page and
+// whether the next page exists. Page is limited by count and by total size of vaults
+func (s *Service) LoadChangesPage(ctx context.Context, userID uuid.UUID, cursor int64, limit, maxBytes int) ([]VaultModel, int64, bool, error) {
+	// One extra row tells if there is next page
+	vaults, err := s.rep.LoadChangesPage(ctx, userID, cursor, limit+1)
+
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	vaults, hasMore := cutPage(vaults, limit, maxBytes)
+
+	nextCursor := cursor
+
+	if len(vaults) != 0 {
+		nextCursor = vaults[len(vaults)-1].ChangeSeq
+	}
+
+	return vaults, nextCursor, hasMore, nil
+}
+
+// cutPage keeps at least one vault, so page with huge vault still moves forward
+func cutPage(vaults []VaultModel, limit, maxBytes int) ([]VaultModel, bool) {
+	hasMore := len(vaults) > limit
+
+	if hasMore {
+		vaults = vaults[:limit]
+	}
+
+	size := 0
+
+	for i, v := range vaults {
+		size += len(v.Vault)
+
+		if i > 0 && size > maxBytes {
+			return vaults[:i], true
+		}
+	}
+
+	return vaults, hasMore
+}
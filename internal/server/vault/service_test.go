@@ -0,0 +1,114 @@
+package vault
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_cutPage(t *testing.T) {
+	vaultsOfSize := func(sizes ...int) []VaultModel {
+		vaults := make([]VaultModel, len(sizes))
+
+		for i, size := range sizes {
+			vaults[i] = VaultModel{Vault: make([]byte, size), ChangeSeq: int64(i + 1)}
+		}
+
+		return vaults
+	}
+
+	tests := []struct {
+		name        string
+		vaults      []VaultModel
+		limit       int
+		maxBytes    int
+		wantLen     int
+		wantHasMore bool
+	}{
+		{
+			name:        "Last page",
+			vaults:      vaultsOfSize(10, 10),
+			limit:       3,
+			maxBytes:    100,
+			wantLen:     2,
+			wantHasMore: false,
+		},
+		{
+			name:        "Cut by limit",
+			vaults:      vaultsOfSize(10, 10, 10, 10),
+			limit:       3,
+			maxBytes:    100,
+			wantLen:     3,
+			wantHasMore: true,
+		},
+		{
+			name:        "Cut by size",
+			vaults:      vaultsOfSize(40, 40, 40),
+			limit:       3,
+			maxBytes:    100,
+			wantLen:     2,
+			wantHasMore: true,
+		},
+		{
+			name:        "Huge vault is sent alone",
+			vaults:      vaultsOfSize(500, 10),
+			limit:       3,
+			maxBytes:    100,
+			wantLen:     1,
+			wantHasMore: true,
+		},
+		{
+			name:        "Empty",
+			vaults:      vaultsOfSize(),
+			limit:       3,
+			maxBytes:    100,
+			wantLen:     0,
+			wantHasMore: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, hasMore := cutPage(tt.vaults, tt.limit, tt.maxBytes)
+
+			assert.Len(t, got, tt.wantLen)
+			assert.Equal(t, tt.wantHasMore, hasMore)
+		})
+	}
+}
+
+func TestPageToken(t *testing.T) {
+	cursor, err := DecodePageToken(EncodePageToken(42))
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), cursor)
+
+	_, err = DecodePageToken("not-a-token")
+	assert.ErrorIs(t, err, ErrInvalidPageToken)
+
+	_, err = DecodePageToken(EncodePageToken(-1))
+	assert.ErrorIs(t, err, ErrInvalidPageToken)
+}
+
+func Test_blobObjectName(t *testing.T) {
+	tests := []struct {
+		name  string
+		s3URL string
+		want  string
+	}{
+		{
+			name:  "Location of completed upload",
+			s3URL: "http://localhost:9000/vault/V1StGXR8_Z5jdHi6B-myT",
+			want:  "V1StGXR8_Z5jdHi6B-myT",
+		},
+		{
+			name:  "Object name without url",
+			s3URL: "V1StGXR8_Z5jdHi6B-myT",
+			want:  "V1StGXR8_Z5jdHi6B-myT",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, blobObjectName(tt.s3URL))
+		})
+	}
+}
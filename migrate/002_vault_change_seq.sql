@@ -0,0 +1,48 @@
+-- Per user change sequence for incremental sync
+
+create table if not exists vault_change_seqs
+(
+    user_id uuid not null
+        constraint vault_change_seqs_pk primary key
+        constraint vault_change_seqs_users_fk references users (id),
+    seq     bigint default 0 not null
+);
+
+alter table vaults
+    add column if not exists change_seq bigint default 0 not null;
+
+create index if not exists vaults_user_id_change_seq_idx on vaults (user_id, change_seq);
+
+-- Row lock on vault_change_seqs serializes writes of one user, so seq order matches commit order
+create or replace function vaults_next_change_seq() returns trigger as
+$$
+begin
+    insert into vault_change_seqs (user_id, seq)
+    values (new.user_id, 1)
+    on conflict (user_id) do update set seq = vault_change_seqs.seq + 1
+    returning seq into new.change_seq;
+
+    return new;
+end;
+$$ language plpgsql;
+
+create trigger vaults_change_seq
+    before insert or update
+    on vaults
+    for each row
+execute function vaults_next_change_seq();
+
+-- Assign sequence to existing vaults
+update vaults set version = version;
+
+---- create above / drop below ----
+
+drop trigger vaults_change_seq on vaults;
+
+drop function vaults_next_change_seq();
+
+drop index vaults_user_id_change_seq_idx;
+
+alter table vaults drop column change_seq;
+
+drop table vault_change_seqs;
@@ -0,0 +1,25 @@
+-- Notify listeners of every server replica about vault changes, delivered on commit
+
+create or replace function vaults_notify_change() returns trigger as
+$$
+begin
+    perform pg_notify(
+            'vault_changes',
+            json_build_object('user_id', new.user_id, 'cursor', new.change_seq)::text
+        );
+
+    return null;
+end;
+$$ language plpgsql;
+
+create trigger vaults_change_notify
+    after insert or update
+    on vaults
+    for each row
+execute function vaults_notify_change();
+
+---- create above / drop below ----
+
+drop trigger vaults_change_notify on vaults;
+
+drop function vaults_notify_change();
@@ -0,0 +1,28 @@
+-- Resumable uploads, every upload is S3 multipart upload and every chunk is one part
+
+create table if not exists uploads
+(
+    id           uuid default gen_random_uuid() not null
+        constraint uploads_pk primary key,
+    user_id      uuid                           not null
+        constraint uploads_users_fk references users (id),
+    object_name  varchar                        not null,
+    s3_upload_id varchar                        not null,
+    created_at   timestamptz default now()      not null
+);
+
+create table if not exists upload_parts
+(
+    upload_id   uuid    not null
+        constraint upload_parts_uploads_fk references uploads (id) on delete cascade,
+    part_number integer not null,
+    etag        varchar not null,
+    size        bigint  not null,
+    constraint upload_parts_pk primary key (upload_id, part_number)
+);
+
+---- create above / drop below ----
+
+drop table upload_parts;
+
+drop table uploads;
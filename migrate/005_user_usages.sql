@@ -0,0 +1,17 @@
+-- Stored blob bytes of every user, bytes are reserved before chunk is written to S3
+
+create table if not exists user_usages
+(
+    user_id    uuid             not null
+        constraint user_usages_pk primary key
+        constraint user_usages_users_fk references users (id),
+    blob_bytes bigint default 0 not null
+);
+
+create index if not exists vaults_user_id_is_deleted_idx on vaults (user_id, is_deleted);
+
+---- create above / drop below ----
+
+drop index vaults_user_id_is_deleted_idx;
+
+drop table user_usages;
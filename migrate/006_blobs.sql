@@ -0,0 +1,28 @@
+-- Owner of every uploaded S3 object. Object without vault after grace period or of deleted vault is collected
+
+create table if not exists blobs
+(
+    object_name varchar                   not null
+        constraint blobs_pk primary key,
+    user_id     uuid                      not null
+        constraint blobs_users_fk references users (id),
+    vault_id    uuid
+        constraint blobs_vaults_fk references vaults (id),
+    size        bigint      default 0     not null,
+    created_at  timestamptz default now() not null
+);
+
+create index if not exists blobs_vault_id_idx on blobs (vault_id);
+
+create index if not exists blobs_created_at_idx on blobs (created_at) where vault_id is null;
+
+-- Objects uploaded before this migration belong to vaults which reference them, size is unknown
+insert into blobs (object_name, user_id, vault_id)
+select regexp_replace(s3, '^.*/', ''), user_id, id
+from vaults
+where s3 is not null
+on conflict do nothing;
+
+---- create above / drop below ----
+
+drop table blobs;
@@ -0,0 +1,21 @@
+-- Every login creates device, token is bound to device and stops working when device is revoked
+
+create table if not exists devices
+(
+    id               uuid        default gen_random_uuid() not null
+        constraint devices_pk primary key,
+    user_id          uuid                                  not null
+        constraint devices_users_fk references users (id),
+    name             varchar                               not null,
+    platform         varchar                               not null,
+    created_at       timestamptz default now()             not null,
+    last_seen_at     timestamptz default now()             not null,
+    last_sync_cursor bigint      default 0                 not null,
+    revoked_at       timestamptz
+);
+
+create index if not exists devices_user_id_idx on devices (user_id);
+
+---- create above / drop below ----
+
+drop table devices;
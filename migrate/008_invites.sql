@@ -0,0 +1,18 @@
+-- Invite codes for invite-only registration, only hash of code is stored
+
+create table if not exists invites
+(
+    code_hash  varchar                   not null
+        constraint invites_pk primary key,
+    created_by uuid                      not null
+        constraint invites_created_by_fk references users (id),
+    created_at timestamptz default now() not null,
+    expires_at timestamptz               not null,
+    used_by    uuid
+        constraint invites_used_by_fk references users (id),
+    used_at    timestamptz
+);
+
+---- create above / drop below ----
+
+drop table invites;
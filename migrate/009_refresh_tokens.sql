@@ -0,0 +1,19 @@
+-- Refresh tokens continue session of device, only hash of token is stored.
+-- Used token stays until expiration, so its reuse is detected and device is revoked
+
+create table if not exists refresh_tokens
+(
+    token_hash varchar                   not null
+        constraint refresh_tokens_pk primary key,
+    device_id  uuid                      not null
+        constraint refresh_tokens_devices_fk references devices (id),
+    created_at timestamptz default now() not null,
+    expires_at timestamptz               not null,
+    used_at    timestamptz
+);
+
+create index if not exists refresh_tokens_device_id_idx on refresh_tokens (device_id);
+
+---- create above / drop below ----
+
+drop table refresh_tokens;
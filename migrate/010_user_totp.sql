@@ -0,0 +1,25 @@
+-- TOTP second factor of login. Pending secret waits for confirmation by code before it is enabled.
+-- Last step protects from replay of intercepted code, backup codes are stored as hashes and used once
+
+alter table users
+    add column if not exists totp_secret         varchar,
+    add column if not exists totp_pending_secret varchar,
+    add column if not exists totp_last_step      bigint default 0 not null;
+
+create table if not exists user_backup_codes
+(
+    user_id   uuid    not null
+        constraint user_backup_codes_users_fk references users (id),
+    code_hash varchar not null,
+    used_at   timestamptz,
+    constraint user_backup_codes_pk primary key (user_id, code_hash)
+);
+
+---- create above / drop below ----
+
+drop table user_backup_codes;
+
+alter table users
+    drop column totp_secret,
+    drop column totp_pending_secret,
+    drop column totp_last_step;
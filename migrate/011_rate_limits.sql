@@ -0,0 +1,16 @@
+-- Failed attempts of login by account and ip, used when rate limit store is postgres
+
+create table if not exists rate_limits
+(
+    key          varchar                       not null
+        constraint rate_limits_pk primary key,
+    failures     integer     default 0         not null,
+    locked_until timestamptz default 'epoch'   not null,
+    expires_at   timestamptz default 'epoch'   not null
+);
+
+create index if not exists rate_limits_expires_at_idx on rate_limits (expires_at);
+
+---- create above / drop below ----
+
+drop table rate_limits;
@@ -0,0 +1,19 @@
+-- Keys of asymmetric JWT signing, private key is PKCS #8 DER.
+-- Key signs from activates_at until the next key activates and verifies tokens until expires_at
+
+create table if not exists signing_keys
+(
+    kid          varchar                   not null
+        constraint signing_keys_pk primary key,
+    algorithm    varchar                   not null,
+    private_key  bytea                     not null,
+    created_at   timestamptz default now() not null,
+    activates_at timestamptz               not null,
+    expires_at   timestamptz               not null
+);
+
+create index if not exists signing_keys_expires_at_idx on signing_keys (expires_at);
+
+---- create above / drop below ----
+
+drop table signing_keys;
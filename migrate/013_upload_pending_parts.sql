@@ -0,0 +1,12 @@
+-- Part is reserved by chunk before upload to S3 and saved with etag after it, so row lock of upload
+-- is not held while chunk is read from client. Part with pending_since is not uploaded yet
+
+alter table upload_parts
+    add column if not exists pending_since timestamptz;
+
+---- create above / drop below ----
+
+delete from upload_parts where pending_since is not null;
+
+alter table upload_parts
+    drop column pending_since;
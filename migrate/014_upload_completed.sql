@@ -0,0 +1,15 @@
+-- Completed upload is kept for grace period, so repeated complete after lost response returns the same blob
+
+alter table uploads
+    add column if not exists completed_at timestamptz;
+
+create index if not exists uploads_completed_at_idx on uploads (completed_at) where completed_at is not null;
+
+---- create above / drop below ----
+
+drop index uploads_completed_at_idx;
+
+delete from uploads where completed_at is not null;
+
+alter table uploads
+    drop column completed_at;